@@ -5,8 +5,10 @@ go 1.23.0
 toolchain go1.24.4
 
 require (
+	github.com/atotto/clipboard v0.1.4
 	github.com/franela/goblin v0.0.0-20211003143422-0a4f594942bf
 	github.com/gdamore/tcell/v2 v2.8.1
+	github.com/go-sql-driver/mysql v1.7.0
 	github.com/mattn/go-runewidth v0.0.16
 	github.com/rivo/tview v0.0.0-20240728114935-65571ae51e71
 	github.com/rivo/uniseg v0.4.7
@@ -22,7 +24,6 @@ require (
 require (
 	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/gdamore/encoding v1.0.1 // indirect
-	github.com/go-sql-driver/mysql v1.7.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
@@ -45,5 +46,4 @@ require (
 	modernc.org/libc v1.65.10 // indirect
 	modernc.org/mathutil v1.7.1 // indirect
 	modernc.org/memory v1.11.0 // indirect
-	modernc.org/sqlite v1.38.0 // indirect
 )
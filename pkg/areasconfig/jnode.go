@@ -7,6 +7,7 @@ import (
 	"github.com/askovpen/gossiped/pkg/config"
 	"github.com/askovpen/gossiped/pkg/database"
 	"github.com/askovpen/gossiped/pkg/msgapi"
+	"github.com/askovpen/gossiped/pkg/types"
 	"gorm.io/gorm"
 )
 
@@ -28,6 +29,8 @@ func jnodeConfigRead() error {
 
 	log.Printf("Connected to jnode database, loading areas...")
 
+	checkAddressLinks(db)
+
 	// Load echoareas from database
 	err = loadEchoareas(db)
 	if err != nil {
@@ -44,6 +47,54 @@ func jnodeConfigRead() error {
 	return nil
 }
 
+// checkAddressLinks warns when config.Address is not a link in the database,
+// as netmail routing then fails with "no route found"
+func checkAddressLinks(db *gorm.DB) {
+	var links []database.Link
+	if err := db.Find(&links).Error; err != nil {
+		log.Printf("Warning: cannot check address against links: %v", err)
+		return
+	}
+	if warning := addressLinkWarning(config.Config.Address, links); warning != "" {
+		log.Printf("Warning: %s", warning)
+	}
+}
+
+// addressLinkWarning returns an actionable warning if addr is not among links,
+// naming the nearest link (boss node, same net, same zone), or "" if it is
+func addressLinkWarning(addr *types.FidoAddr, links []database.Link) string {
+	var nearest *database.Link
+	rank := 0
+	for i, link := range links {
+		linkAddr := types.AddrFromString(link.FtnAddress)
+		if linkAddr == nil {
+			continue
+		}
+		if addr.Equal(linkAddr) {
+			return ""
+		}
+		r := 0
+		switch {
+		case addr.SameNode(linkAddr) && linkAddr.GetPoint() == 0:
+			r = 3
+		case linkAddr.GetZone() == addr.GetZone() && linkAddr.GetNet() == addr.GetNet():
+			r = 2
+		case linkAddr.GetZone() == addr.GetZone():
+			r = 1
+		}
+		if r > rank {
+			nearest, rank = &links[i], r
+		}
+	}
+	msg := fmt.Sprintf("address %s from config is not in the jnode links table", addr.String())
+	if nearest == nil {
+		return fmt.Sprintf("%s and no link is in zone %d; netmail routing will fail with \"no route found\", check that 'address' is this jnode node's identity",
+			msg, addr.GetZone())
+	}
+	return fmt.Sprintf("%s; nearest link is %s (%s); if netmail routing fails with \"no route found\", check that 'address' is this jnode node's identity",
+		msg, nearest.FtnAddress, nearest.StationName)
+}
+
 // loadEchoareas loads echo areas from the database
 func loadEchoareas(db *gorm.DB) error {
 	var echoareas []database.Echoarea
@@ -161,13 +212,78 @@ func GetDatabaseConnection() *gorm.DB {
 // RefreshAreas reloads areas from the database
 // Useful for runtime area management
 func RefreshAreas() error {
-	// Clear current areas
+	// Release and clear current areas
+	for _, area := range msgapi.Areas {
+		if sqlArea, ok := area.(*msgapi.SQLArea); ok {
+			sqlArea.Close()
+		}
+	}
 	msgapi.Areas = nil
 
 	// Reload from database
 	return jnodeConfigRead()
 }
 
+// SyncAreas adds echoareas created and removes echoareas deleted in the
+// database since the areas were loaded. Existing area objects and their
+// caches are preserved. It returns the number of added and removed areas.
+func SyncAreas() (int, int, error) {
+	db := database.GetDatabase()
+	if db == nil {
+		return 0, 0, fmt.Errorf("database connection not available")
+	}
+
+	var echoareas []database.Echoarea
+	if err := db.Find(&echoareas).Error; err != nil {
+		return 0, 0, fmt.Errorf("error querying echoareas: %w", err)
+	}
+	inDB := make(map[int64]bool, len(echoareas))
+	for _, echoarea := range echoareas {
+		inDB[echoarea.ID] = true
+	}
+
+	// Drop echoareas removed from the database
+	loaded := make(map[int64]bool, len(msgapi.Areas))
+	areas := msgapi.Areas[:0]
+	removed := 0
+	for _, area := range msgapi.Areas {
+		if sqlArea, ok := area.(*msgapi.SQLArea); ok && sqlArea.GetType() != msgapi.EchoAreaTypeNetmail {
+			if !inDB[sqlArea.GetAreaID()] {
+				log.Printf("Removed echoarea: %s", sqlArea.GetName())
+				sqlArea.Close()
+				removed++
+				continue
+			}
+			loaded[sqlArea.GetAreaID()] = true
+		}
+		areas = append(areas, area)
+	}
+	msgapi.Areas = areas
+
+	// Add echoareas created in the database
+	added := 0
+	for _, echoarea := range echoareas {
+		if loaded[echoarea.ID] {
+			continue
+		}
+		sqlArea := msgapi.NewSQLArea(db, echoarea)
+		if charset := findAreaCharset(echoarea.Name); charset != "" {
+			sqlArea.SetChrs(charset)
+		}
+		sqlArea.Init()
+		msgapi.Areas = append(msgapi.Areas, sqlArea)
+		added++
+		log.Printf("Added echoarea: %s (%s)", echoarea.Name, echoarea.Description)
+	}
+
+	if added > 0 {
+		if err := msgapi.RefreshMessageCounts(); err != nil {
+			log.Printf("Warning: Failed to refresh message counts cache: %v", err)
+		}
+	}
+	return added, removed, nil
+}
+
 // GetAreaByName finds an area by name in the loaded areas
 func GetAreaByName(name string) msgapi.AreaPrimitive {
 	for _, area := range msgapi.Areas {
@@ -0,0 +1,36 @@
+package areasconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/askovpen/gossiped/pkg/types"
+	. "github.com/franela/goblin"
+)
+
+func TestAddressLinkWarning(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check address against links", func() {
+		links := []database.Link{
+			{StationName: "Far Away", FtnAddress: "1:100/1"},
+			{StationName: "Same Net", FtnAddress: "2:5020/1"},
+			{StationName: "Boss", FtnAddress: "2:5020/9696"},
+		}
+		g.It("check known address", func() {
+			g.Assert(addressLinkWarning(types.AddrFromString("2:5020/9696"), links)).Equal("")
+		})
+		g.It("check nearest boss node", func() {
+			w := addressLinkWarning(types.AddrFromString("2:5020/9696.128"), links)
+			g.Assert(strings.Contains(w, "nearest link is 2:5020/9696 (Boss)")).IsTrue()
+		})
+		g.It("check nearest link in net", func() {
+			w := addressLinkWarning(types.AddrFromString("2:5020/100"), links)
+			g.Assert(strings.Contains(w, "nearest link is 2:5020/1 (Same Net)")).IsTrue()
+		})
+		g.It("check no link in zone", func() {
+			w := addressLinkWarning(types.AddrFromString("3:712/848"), links[1:])
+			g.Assert(strings.Contains(w, "no link is in zone 3")).IsTrue()
+		})
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -35,6 +36,10 @@ const (
 	ColorElementText        = "text"
 	ColorElementPrompt      = "prompt"
 	ColorElementWindow      = "window"
+	ColorElementType        = "type"
+
+	ColorElementScrollbar      = "scrollbar"
+	ColorElementScrollbarThumb = "scrollbar-thumb"
 )
 const (
 	StyleUnderline = "underline"
@@ -77,6 +82,7 @@ var (
 			ColorElementItem:        "silver",
 			ColorElementHighlight:   "bold silver",
 			ColorElementPrompt:      "silver",
+			ColorElementType:        "teal",
 		},
 		ColorAreaAreaListModal: {
 			ColorElementBorder:      "red",
@@ -98,14 +104,20 @@ var (
 			ColorElementHighlight:   "bold default",
 		},
 		ColorAreaEditor: {
-			"comment":  "bold yellow",
-			"comment2": "bold white",
-			"comment3": "bold cyan",
-			"comment4": "bold magenta",
-			"origin":   "bold white",
-			"tearline": "bold white",
-			"tagline":  "bold white",
-			"kludge":   "bold gray",
+			"comment":        "bold yellow",
+			"comment2":       "bold white",
+			"comment3":       "bold cyan",
+			"comment4":       "bold magenta",
+			"origin":         "bold white",
+			"tearline":       "bold white",
+			"tagline":        "bold white",
+			"kludge":         "bold gray",
+			"search":         "black, olive",
+			"search-current": "black, yellow",
+			"selection":      "black, silver",
+
+			ColorElementScrollbar:      "default",
+			ColorElementScrollbarThumb: "reverse default",
 		},
 		ColorAreaHelp: {
 			ColorElementBorder:      "bold blue",
@@ -157,6 +169,10 @@ var (
 		ColorElementText:        ElementTypeColor,
 		ColorElementPrompt:      ElementTypeColor,
 		ColorElementWindow:      ElementTypeColor,
+		ColorElementType:        ElementTypeColor,
+
+		ColorElementScrollbar:      ElementTypeColor,
+		ColorElementScrollbarThumb: ElementTypeColor,
 	}
 )
 
@@ -351,6 +367,29 @@ func GetColors(section string) *ColorScheme {
 	return uiColors[section]
 }
 
+// GetColorSections returns the names of the colorscheme sections in order
+func GetColorSections() []string {
+	sections := make([]string, 0, len(uiDefaultColors))
+	for section := range uiDefaultColors {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+	return sections
+}
+
+// GetColorElements returns the color elements of section in order, leaving
+// out settings like border_style which are no colors
+func GetColorElements(section string) []string {
+	var elements []string
+	for element := range *GetColors(section) {
+		if elementTypes[element] != ElementTypeBorderStyle {
+			elements = append(elements, element)
+		}
+	}
+	sort.Strings(elements)
+	return elements
+}
+
 func GetElementStyle(section string, element string) tcell.Style {
 	colors := GetColors(section)
 	value, ok := (*colors)[element]
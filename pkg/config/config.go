@@ -2,16 +2,21 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"log"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/askovpen/gossiped/pkg/database"
 	"github.com/askovpen/gossiped/pkg/nodelist"
 	"github.com/askovpen/gossiped/pkg/types"
+	"github.com/askovpen/gossiped/pkg/utils"
 	"github.com/gdamore/tcell/v2"
 	"gopkg.in/yaml.v3"
 )
@@ -21,6 +26,8 @@ type (
 	SortTypeMap map[string]string
 	configS     struct {
 		Username string
+		RealName string
+		AreaFrom map[string]string `yaml:"area_from"`
 		AreaFile struct {
 			Path string
 			Type string
@@ -38,36 +45,118 @@ type (
 			MaxOpenConns    int           `yaml:"max_open_conns"`
 			MaxIdleConns    int           `yaml:"max_idle_conns"`
 			ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+			AutoMigrate     bool          `yaml:"auto_migrate"`
+			Retries         int           `yaml:"retries"`
+			RetryBackoff    time.Duration `yaml:"retry_backoff"`
+			RetryMaxBackoff time.Duration `yaml:"retry_max_backoff"`
+			QueryTimeout    time.Duration `yaml:"query_timeout"`
 		}
 		LastRead struct {
 			Enabled      bool   `yaml:"enabled"`
 			DatabasePath string `yaml:"database_path"`
+			ExportPath   string `yaml:"export_path"`
+			Admin        bool   `yaml:"admin"`
 		}
 		Colorscheme string
 		Log         string
 		Address     *types.FidoAddr
+		Akas        []*types.FidoAddr
 		Origin      string
 		Tearline    string
 		Template    string
 		Chrs        struct {
-			Default      string
-			IBMPC        string
-			JnodeDefault string
+			Default       string
+			IBMPC         string
+			JnodeDefault  string
+			Detect        []string `yaml:"detect"`
+			DetectDefault string   `yaml:"detect_default"`
 		}
 		Statusbar struct {
-			Clock bool
+			Clock       bool
+			ClockFormat string `yaml:"clock_format"`
 		}
 		Quote struct {
-			Margin   int  `yaml:"margin"`
-			WrapHard bool `yaml:"wrap_hard"`
+			Margin     int      `yaml:"margin"`
+			WrapHard   bool     `yaml:"wrap_hard"`
+			Colors     []string `yaml:"colors"`
+			Stops      string   `yaml:"stops"`
+			MaxLen     int      `yaml:"max_len"`
+			KeepOrigin bool     `yaml:"keep_origin"`
 		}
-		Sorting      SortTypeMap
-		Colors       map[string]ColorMap
-		CityPath     string
-		NodelistPath string
+		Netmail struct {
+			Via            *bool  `yaml:"via"`
+			ShowVia        bool   `yaml:"show_via"`
+			AttachPath     string `yaml:"attach_path"`
+			Pointnet       uint16 `yaml:"pointnet"`
+			HoldUnroutable bool   `yaml:"hold_unroutable"`
+			Archive        struct {
+				Enabled bool   `yaml:"enabled"`
+				Days    int    `yaml:"days"`
+				Path    string `yaml:"path"`
+			}
+		}
+		Drafts struct {
+			Enabled     bool
+			Path        string
+			Interval    time.Duration
+			ConfirmQuit *bool `yaml:"confirm_quit"`
+		}
+		Edit struct {
+			AnyAuthor    bool `yaml:"any_author"`
+			Requeue      bool `yaml:"requeue"`
+			EchoNodelist bool `yaml:"echo_nodelist"`
+		}
+		Unread struct {
+			AutoAdvance string `yaml:"auto_advance"`
+			ReadPolicy  string `yaml:"read_policy"`
+		}
+		ConfirmSend      bool           `yaml:"confirm_send"`
+		AutoQuote        *bool          `yaml:"auto_quote"`
+		EchoDefaultTo    *string        `yaml:"echo_default_to"`
+		StartArea        string         `yaml:"start_area"`
+		HeaderFields     []string       `yaml:"header_fields"`
+		WriteLevel       *int64         `yaml:"write_level"`
+		Signature        string         `yaml:"signature"`
+		ReplyAttribution *string        `yaml:"reply_attribution"`
+		Scrollbar        bool           `yaml:"scrollbar"`
+		ShowKludges      bool           `yaml:"show_kludges"`
+		ShowAreaTypes    bool           `yaml:"show_area_types"`
+		ControlChars     string         `yaml:"control_chars"`
+		NotifyNewMail    string         `yaml:"notify_new_mail"`
+		NotifyInterval   time.Duration  `yaml:"notify_interval"`
+		CaseFolding      string         `yaml:"case_folding"`
+		MaxLineWidth     *int           `yaml:"max_line_width"`
+		AreaMaxLineWidth map[string]int `yaml:"area_max_line_width"`
+		LargeMessageSize int            `yaml:"large_message_size"`
+		Keys             map[string]string
+		Sorting          SortTypeMap
+		Colors           map[string]ColorMap
+		CityPath         string
+		NodelistPath     PathList
 	}
 )
 
+// PathList is a list of file paths, given as a single path or a list
+type PathList []string
+
+// UnmarshalYAML reads a single path or a list of paths, "" is none
+func (p *PathList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*p = nil
+		if single != "" {
+			*p = PathList{single}
+		}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*p = list
+	return nil
+}
+
 // vars
 var (
 	Version      string
@@ -109,6 +198,7 @@ func Read(fn string) error {
 	if Config.Address == nil {
 		return errors.New("Config.Address not defined")
 	}
+	types.SetDefaultZone(Config.Address.GetZone())
 	if Config.Chrs.Default == "" {
 		return errors.New("Config.Chrs.Default not defined")
 	}
@@ -130,8 +220,7 @@ func Read(fn string) error {
 	}
 	Config.CityPath = tryPath(rootPath, Config.CityPath)
 	err = readCity()
-	Config.NodelistPath = tryPath(rootPath, Config.NodelistPath)
-	nodelist.Read(Config.NodelistPath)
+	readNodelists(rootPath)
 	if err != nil {
 		return err
 	}
@@ -141,9 +230,35 @@ func Read(fn string) error {
 	// Set quote defaults if not specified
 	setQuoteDefaults()
 
+	setDraftsDefaults(rootPath)
+
+	setNetmailArchiveDefaults(rootPath)
+
+	if err := validateReplyAttribution(); err != nil {
+		return err
+	}
+
+	utils.ASCIIFolding = Config.CaseFolding == "ascii"
+
 	return nil
 }
 
+// readNodelists reads and merges the nodelists, pointlists and segments of
+// nodelistpath in order, later ones overriding earlier ones
+func readNodelists(rootPath string) {
+	var paths PathList
+	for _, fn := range Config.NodelistPath {
+		resolved := tryPath(rootPath, fn)
+		if resolved == "" {
+			log.Printf("nodelist file '%s' not found", fn)
+			continue
+		}
+		paths = append(paths, resolved)
+	}
+	Config.NodelistPath = paths
+	nodelist.ReadAll(paths)
+}
+
 // setDatabaseDefaults sets default values for database configuration
 func setDatabaseDefaults() {
 	if Config.Database.Driver == "" {
@@ -161,6 +276,44 @@ func setDatabaseDefaults() {
 	if Config.Database.ConnMaxLifetime == 0 {
 		Config.Database.ConnMaxLifetime = 5 * time.Minute
 	}
+	if Config.Database.Retries == 0 {
+		Config.Database.Retries = 3
+	}
+	if Config.Database.RetryBackoff == 0 {
+		Config.Database.RetryBackoff = 200 * time.Millisecond
+	}
+	if Config.Database.RetryMaxBackoff == 0 {
+		Config.Database.RetryMaxBackoff = 5 * time.Second
+	}
+	if Config.Database.QueryTimeout == 0 {
+		Config.Database.QueryTimeout = 30 * time.Second
+	}
+}
+
+// setDraftsDefaults sets default values for drafts configuration
+func setDraftsDefaults(rootPath string) {
+	if Config.Drafts.Path == "" {
+		Config.Drafts.Path = "drafts"
+	}
+	if !filepath.IsAbs(Config.Drafts.Path) {
+		Config.Drafts.Path = filepath.Join(rootPath, Config.Drafts.Path)
+	}
+	if Config.Drafts.Interval <= 0 {
+		Config.Drafts.Interval = 30 * time.Second
+	}
+}
+
+// setNetmailArchiveDefaults sets default values for archiving sent netmail
+func setNetmailArchiveDefaults(rootPath string) {
+	if Config.Netmail.Archive.Days <= 0 {
+		Config.Netmail.Archive.Days = 30
+	}
+	if Config.Netmail.Archive.Path == "" {
+		Config.Netmail.Archive.Path = "netmail-archive.mbox"
+	}
+	if !filepath.IsAbs(Config.Netmail.Archive.Path) {
+		Config.Netmail.Archive.Path = filepath.Join(rootPath, Config.Netmail.Archive.Path)
+	}
 }
 
 // setQuoteDefaults sets default values for quote configuration
@@ -169,6 +322,9 @@ func setQuoteDefaults() {
 		Config.Quote.Margin = 70
 	}
 	// WrapHard defaults to false (already zero value)
+	if len(Config.Quote.Colors) == 0 {
+		Config.Quote.Colors = []string{"comment", "comment2", "comment3", "comment4"}
+	}
 }
 
 // GetQuoteConfig returns the quote configuration with defaults applied
@@ -176,6 +332,298 @@ func GetQuoteConfig() (int, bool) {
 	return Config.Quote.Margin, Config.Quote.WrapHard
 }
 
+// GetQuoteMargin returns the width quoted lines of the area are wrapped at:
+// quote.margin, but never wider than the max_line_width of the area
+func GetQuoteMargin(areaName string) int {
+	margin := Config.Quote.Margin
+	if width := GetMaxLineWidth(areaName); width > 0 && (margin <= 0 || margin > width) {
+		margin = width
+	}
+	return margin
+}
+
+// Quote string defaults, as in GoldED+
+const (
+	DefaultQuoteStops  = "<\"'-"
+	DefaultMaxQuoteLen = 40
+)
+
+// GetQuoteStops returns the characters which end the search for the '>' of
+// a quote string: quote.stops, DefaultQuoteStops if not set
+func GetQuoteStops() string {
+	if Config.Quote.Stops == "" {
+		return DefaultQuoteStops
+	}
+	return Config.Quote.Stops
+}
+
+// GetMaxQuoteLen returns the longest quote string kept when quoting:
+// quote.max_len, DefaultMaxQuoteLen if not set
+func GetMaxQuoteLen() int {
+	if Config.Quote.MaxLen <= 0 {
+		return DefaultMaxQuoteLen
+	}
+	return Config.Quote.MaxLen
+}
+
+// GetQuoteColor returns the editor colorscheme group for quote level,
+// cycling through quote colors
+func GetQuoteColor(level int) string {
+	if level <= 0 || len(Config.Quote.Colors) == 0 {
+		return "comment"
+	}
+	return Config.Quote.Colors[(level-1)%len(Config.Quote.Colors)]
+}
+
+// GetFromName returns the From name for the area: the per-area override if
+// set, else RealName, else Username
+func GetFromName(areaName string) string {
+	for name, from := range Config.AreaFrom {
+		if strings.EqualFold(name, areaName) && from != "" {
+			return from
+		}
+	}
+	if Config.RealName != "" {
+		return Config.RealName
+	}
+	return Config.Username
+}
+
+// GetMaxLineWidth returns the maximum body line width for the area, 79 by
+// default. Zero or negative value means lines are not wrapped on save.
+func GetMaxLineWidth(areaName string) int {
+	for name, width := range Config.AreaMaxLineWidth {
+		if strings.EqualFold(name, areaName) {
+			return width
+		}
+	}
+	if Config.MaxLineWidth == nil {
+		return 79
+	}
+	return *Config.MaxLineWidth
+}
+
+// GetAkas returns the addresses new messages can be posted from, the main
+// address first
+func GetAkas() []*types.FidoAddr {
+	akas := []*types.FidoAddr{Config.Address}
+	for _, aka := range Config.Akas {
+		if aka == nil || slices.ContainsFunc(akas, aka.Equal) {
+			continue
+		}
+		akas = append(akas, aka)
+	}
+	return akas
+}
+
+// GetOriginAddr returns the address a message to dest is posted from: the
+// first AKA in the zone of dest, else the main address
+func GetOriginAddr(dest *types.FidoAddr) *types.FidoAddr {
+	if !dest.IsZero() {
+		for _, aka := range GetAkas() {
+			if aka.GetZone() == dest.GetZone() {
+				return aka
+			}
+		}
+	}
+	return Config.Address
+}
+
+// IsAka returns true if addr is the main address or one of the AKAs
+func IsAka(addr *types.FidoAddr) bool {
+	return slices.ContainsFunc(GetAkas(), addr.Equal)
+}
+
+// GetNetmailVia returns whether a Via kludge is added to saved netmail, true by default
+func GetNetmailVia() bool {
+	return Config.Netmail.Via == nil || *Config.Netmail.Via
+}
+
+// GetAutoQuote returns whether replies are pre-filled with the quoted original, true by default
+func GetAutoQuote() bool {
+	return Config.AutoQuote == nil || *Config.AutoQuote
+}
+
+// GetEchoDefaultTo returns the To name new echomail starts with, "All" by
+// default; netmail always starts without one
+func GetEchoDefaultTo() string {
+	if Config.EchoDefaultTo == nil {
+		return "All"
+	}
+	return *Config.EchoDefaultTo
+}
+
+// GetConfirmQuitUnsaved returns true if Ctrl-C asks first while a message
+// is still open in the editor, the default
+func GetConfirmQuitUnsaved() bool {
+	return Config.Drafts.ConfirmQuit == nil || *Config.Drafts.ConfirmQuit
+}
+
+// GetClockFormat returns the status bar clock layout, "15:04:05" by default
+func GetClockFormat() string {
+	if Config.Statusbar.ClockFormat == "" {
+		return "15:04:05"
+	}
+	return Config.Statusbar.ClockFormat
+}
+
+// GetUnreadAutoAdvance returns what the next-unread jump does past the last
+// unread message of an area: "ask" (default), "yes" or "no"
+func GetUnreadAutoAdvance() string {
+	switch Config.Unread.AutoAdvance {
+	case "yes", "no":
+		return Config.Unread.AutoAdvance
+	}
+	return "ask"
+}
+
+// GetReadPolicy returns when an opened message counts as read: "on_open"
+// (default) or "on_scroll_end" once its last line has been shown
+func GetReadPolicy() string {
+	if Config.Unread.ReadPolicy == "on_scroll_end" {
+		return Config.Unread.ReadPolicy
+	}
+	return "on_open"
+}
+
+// GetControlChars returns how control characters in message text are
+// shown: "strip" by default, "show" in caret notation or "raw" as they are
+func GetControlChars() string {
+	switch Config.ControlChars {
+	case "show", "raw":
+		return Config.ControlChars
+	}
+	return "strip"
+}
+
+// ReplyAttributionMacros are the macros of reply_attribution
+var ReplyAttributionMacros = []string{"@fromname", "@toname", "@date", "@msgid"}
+
+var attributionMacroRe = regexp.MustCompile(`@[A-Za-z]+`)
+
+// validateReplyAttribution checks reply_attribution is a single line using
+// only ReplyAttributionMacros
+func validateReplyAttribution() error {
+	if Config.ReplyAttribution == nil {
+		return nil
+	}
+	tpl := *Config.ReplyAttribution
+	if strings.ContainsAny(tpl, "\r\n") {
+		return errors.New("Config.ReplyAttribution must be a single line")
+	}
+	for _, macro := range attributionMacroRe.FindAllString(tpl, -1) {
+		if !slices.Contains(ReplyAttributionMacros, macro) {
+			return fmt.Errorf("Config.ReplyAttribution: unknown macro %s, use %s", macro,
+				strings.Join(ReplyAttributionMacros, ", "))
+		}
+	}
+	return nil
+}
+
+// GetReplyAttribution returns the reply_attribution template and true if it
+// is set, "" to leave the line out; false keeps the @Quoted lines of the
+// template file
+func GetReplyAttribution() (string, bool) {
+	if Config.ReplyAttribution == nil {
+		return "", false
+	}
+	return *Config.ReplyAttribution, true
+}
+
+// GetNotifyNewMail returns how new mail is announced when the unread
+// messages grow: "none" by default, "bell" or "flash" in the status bar
+func GetNotifyNewMail() string {
+	switch Config.NotifyNewMail {
+	case "bell", "flash":
+		return Config.NotifyNewMail
+	}
+	return "none"
+}
+
+// GetNotifyInterval returns how often new mail is looked for, a minute by
+// default
+func GetNotifyInterval() time.Duration {
+	if Config.NotifyInterval <= 0 {
+		return time.Minute
+	}
+	return Config.NotifyInterval
+}
+
+// GetStartArea returns where the area list starts: "top" by default, "last"
+// on the area read when gossiped last quit, or "resume" opening its last
+// read message too
+func GetStartArea() string {
+	switch Config.StartArea {
+	case "last", "resume":
+		return Config.StartArea
+	}
+	return "top"
+}
+
+// GetDetectCharsets returns the charsets text without a CHRS kludge is
+// guessed among, in order of preference; UTF-8, CP866 and LATIN-1 by default
+func GetDetectCharsets() []string {
+	if len(Config.Chrs.Detect) == 0 {
+		return []string{"UTF-8", "CP866", "LATIN-1"}
+	}
+	return Config.Chrs.Detect
+}
+
+// GetDetectDefault returns the charset of text without a CHRS kludge when
+// none of the detected ones fits, UTF-8 by default
+func GetDetectDefault() string {
+	if Config.Chrs.DetectDefault == "" {
+		return "UTF-8"
+	}
+	return strings.ToUpper(Config.Chrs.DetectDefault)
+}
+
+// GetWriteLevel returns the level compared with the write level of jnode
+// echoareas before composing in them, false if write_level is not set and
+// the levels are not checked
+func GetWriteLevel() (int64, bool) {
+	if Config.WriteLevel == nil {
+		return 0, false
+	}
+	return *Config.WriteLevel, true
+}
+
+// HeaderFields are the lines the message view header can show, the first
+// four of them by default
+var HeaderFields = []string{"msg", "from", "to", "subj", "date", "area", "addr"}
+
+// GetHeaderFields returns the lines of the message view header in order,
+// leaving out unknown and repeated names. The msg line holds the message
+// number input, so it is put first if not listed.
+func GetHeaderFields() []string {
+	var fields []string
+	for _, f := range Config.HeaderFields {
+		f = strings.ToLower(f)
+		if slices.Contains(HeaderFields, f) && !slices.Contains(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return slices.Clone(HeaderFields[:4])
+	}
+	if !slices.Contains(fields, "msg") {
+		fields = append([]string{"msg"}, fields...)
+	}
+	return fields
+}
+
+// GetLargeMessageSize returns the stored text length in characters above
+// which jnode-sql messages open as a preview, 262144 by default, 0 if disabled
+func GetLargeMessageSize() int {
+	switch {
+	case Config.LargeMessageSize < 0:
+		return 0
+	case Config.LargeMessageSize == 0:
+		return 256 * 1024
+	}
+	return Config.LargeMessageSize
+}
+
 // GetDatabaseConfig returns the database configuration with defaults applied
 func GetDatabaseConfig() database.DatabaseConfig {
 	return database.DatabaseConfig{
@@ -184,6 +632,11 @@ func GetDatabaseConfig() database.DatabaseConfig {
 		MaxOpenConns:    Config.Database.MaxOpenConns,
 		MaxIdleConns:    Config.Database.MaxIdleConns,
 		ConnMaxLifetime: Config.Database.ConnMaxLifetime,
+		AutoMigrate:     Config.Database.AutoMigrate,
+		Retries:         Config.Database.Retries,
+		RetryBackoff:    Config.Database.RetryBackoff,
+		RetryMaxBackoff: Config.Database.RetryMaxBackoff,
+		QueryTimeout:    Config.Database.QueryTimeout,
 	}
 }
 
@@ -192,6 +645,7 @@ func GetLastReadConfig() database.LastReadConfig {
 	return database.LastReadConfig{
 		Enabled:      Config.LastRead.Enabled,
 		DatabasePath: Config.LastRead.DatabasePath,
+		ExportPath:   Config.LastRead.ExportPath,
 	}
 }
 
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/askovpen/gossiped/pkg/types"
+	. "github.com/franela/goblin"
+	"gopkg.in/yaml.v3"
+)
+
+func TestAreaOverrides(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check per-area settings", func() {
+		g.It("check GetFromName()", func() {
+			Config.Username = "Alexander N. Skovpen"
+			Config.RealName = ""
+			Config.AreaFrom = map[string]string{"Handles.Only": "askovpen", "empty": ""}
+			defer func() { Config.AreaFrom = nil }()
+			g.Assert(GetFromName("ru.golang")).Equal("Alexander N. Skovpen")
+			g.Assert(GetFromName("handles.only")).Equal("askovpen")
+			Config.RealName = "Alexander Skovpen"
+			g.Assert(GetFromName("ru.golang")).Equal("Alexander Skovpen")
+			g.Assert(GetFromName("empty")).Equal("Alexander Skovpen")
+			g.Assert(GetFromName("HANDLES.ONLY")).Equal("askovpen")
+		})
+		g.It("check GetMaxLineWidth()", func() {
+			Config.MaxLineWidth = nil
+			Config.AreaMaxLineWidth = map[string]int{"wide.area": 120, "nowrap": 0}
+			defer func() { Config.AreaMaxLineWidth, Config.MaxLineWidth = nil, nil }()
+			g.Assert(GetMaxLineWidth("ru.golang")).Equal(79)
+			g.Assert(GetMaxLineWidth("WIDE.AREA")).Equal(120)
+			g.Assert(GetMaxLineWidth("nowrap")).Equal(0)
+			width := 0
+			Config.MaxLineWidth = &width
+			g.Assert(GetMaxLineWidth("ru.golang")).Equal(0)
+		})
+	})
+}
+
+func TestQuoteColors(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check quote colors", func() {
+		g.It("check GetQuoteColor() defaults", func() {
+			Config.Quote.Colors = nil
+			setQuoteDefaults()
+			g.Assert(GetQuoteColor(1)).Equal("comment")
+			g.Assert(GetQuoteColor(2)).Equal("comment2")
+			g.Assert(GetQuoteColor(4)).Equal("comment4")
+			g.Assert(GetQuoteColor(5)).Equal("comment")
+			g.Assert(GetQuoteColor(0)).Equal("comment")
+		})
+		g.It("check GetQuoteColor() custom cycle", func() {
+			Config.Quote.Colors = []string{"comment2", "comment"}
+			defer func() { Config.Quote.Colors = nil }()
+			g.Assert(GetQuoteColor(1)).Equal("comment2")
+			g.Assert(GetQuoteColor(2)).Equal("comment")
+			g.Assert(GetQuoteColor(3)).Equal("comment2")
+		})
+		g.It("check GetQuoteMargin()", func() {
+			Config.Quote.Margin = 0
+			setQuoteDefaults()
+			Config.MaxLineWidth = nil
+			Config.AreaMaxLineWidth = map[string]int{"narrow": 60, "nowrap": 0}
+			defer func() { Config.AreaMaxLineWidth = nil }()
+			g.Assert(GetQuoteMargin("ru.golang")).Equal(70)
+			g.Assert(GetQuoteMargin("narrow")).Equal(60)
+			g.Assert(GetQuoteMargin("nowrap")).Equal(70)
+		})
+	})
+}
+
+func TestAkas(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check AKAs", func() {
+		main := types.AddrFromString("2:5020/9696.128")
+		fido1 := types.AddrFromString("1:123/456")
+		g.Before(func() {
+			Config.Address = main
+			Config.Akas = []*types.FidoAddr{fido1, types.AddrFromString("2:5020/9696.128"), types.AddrFromString("21:1/100")}
+		})
+		g.After(func() {
+			Config.Address = nil
+			Config.Akas = nil
+		})
+		g.It("check GetAkas() puts the main address first once", func() {
+			akas := GetAkas()
+			g.Assert(len(akas)).Equal(3)
+			g.Assert(akas[0].Equal(main)).IsTrue()
+			g.Assert(akas[1].Equal(fido1)).IsTrue()
+		})
+		g.It("check GetOriginAddr() follows the destination zone", func() {
+			g.Assert(GetOriginAddr(types.AddrFromString("1:1/1")).Equal(fido1)).IsTrue()
+			g.Assert(GetOriginAddr(types.AddrFromString("2:5030/1")).Equal(main)).IsTrue()
+			g.Assert(GetOriginAddr(types.AddrFromString("3:633/1")).Equal(main)).IsTrue()
+			g.Assert(GetOriginAddr(nil).Equal(main)).IsTrue()
+		})
+		g.It("check IsAka()", func() {
+			g.Assert(IsAka(types.AddrFromString("21:1/100"))).IsTrue()
+			g.Assert(IsAka(types.AddrFromString("2:5020/9696"))).IsFalse()
+			g.Assert(IsAka(nil)).IsFalse()
+		})
+	})
+}
+
+func TestHeaderFields(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check GetHeaderFields()", func() {
+		g.After(func() {
+			Config.HeaderFields = nil
+		})
+		g.It("check default lines", func() {
+			Config.HeaderFields = nil
+			g.Assert(GetHeaderFields()).Equal([]string{"msg", "from", "to", "subj"})
+		})
+		g.It("check unknown and repeated names are left out", func() {
+			Config.HeaderFields = []string{"msg", "From", "cc", "from", "area"}
+			g.Assert(GetHeaderFields()).Equal([]string{"msg", "from", "area"})
+		})
+		g.It("check msg is put first if not listed", func() {
+			Config.HeaderFields = []string{"from", "date", "subj"}
+			g.Assert(GetHeaderFields()).Equal([]string{"msg", "from", "date", "subj"})
+			Config.HeaderFields = []string{"from", "msg"}
+			g.Assert(GetHeaderFields()).Equal([]string{"from", "msg"})
+		})
+	})
+}
+
+func TestReplyAttribution(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check reply_attribution validation", func() {
+		g.After(func() {
+			Config.ReplyAttribution = nil
+		})
+		set := func(tpl string) error {
+			Config.ReplyAttribution = &tpl
+			return validateReplyAttribution()
+		}
+		g.It("check unset and empty templates", func() {
+			Config.ReplyAttribution = nil
+			g.Assert(validateReplyAttribution()).IsNil()
+			_, ok := GetReplyAttribution()
+			g.Assert(ok).IsFalse()
+			g.Assert(set("")).IsNil()
+			tpl, ok := GetReplyAttribution()
+			g.Assert(ok).IsTrue()
+			g.Assert(tpl).Equal("")
+		})
+		g.It("check known macros", func() {
+			g.Assert(set("@date, @fromname wrote to @toname (@msgid):")).IsNil()
+		})
+		g.It("check unknown macros and line breaks are refused", func() {
+			g.Assert(set("@OName wrote:") == nil).IsFalse()
+			g.Assert(set("@fromname\nwrote:") == nil).IsFalse()
+		})
+	})
+}
+
+func TestPathList(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check nodelistpath", func() {
+		var c struct {
+			NodelistPath PathList
+		}
+		g.It("check a single path", func() {
+			g.Assert(yaml.Unmarshal([]byte("nodelistpath: nodelist.txt"), &c)).IsNil()
+			g.Assert(c.NodelistPath).Equal(PathList{"nodelist.txt"})
+			g.Assert(yaml.Unmarshal([]byte("nodelistpath: ''"), &c)).IsNil()
+			g.Assert(len(c.NodelistPath)).Equal(0)
+		})
+		g.It("check a list of paths", func() {
+			g.Assert(yaml.Unmarshal([]byte("nodelistpath: [NODELIST.299, PNT5020.299]"), &c)).IsNil()
+			g.Assert(c.NodelistPath).Equal(PathList{"NODELIST.299", "PNT5020.299"})
+		})
+	})
+}
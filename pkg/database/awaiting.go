@@ -0,0 +1,141 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// orphanedCondition returns the WHERE condition of the rows of an awaiting
+// table whose message, in msgTable by msgColumn, or link is gone
+func orphanedCondition(table, msgTable, msgColumn string) string {
+	return fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %[2]s WHERE %[2]s.id = %[1]s.%[3]s)"+
+		" OR NOT EXISTS (SELECT 1 FROM links WHERE links.id = %[1]s.link_id)", table, msgTable, msgColumn)
+}
+
+var (
+	orphanedEchomail = orphanedCondition(EchomailAwaiting{}.TableName(), Echomail{}.TableName(), "echomail_id")
+	orphanedNetmail  = orphanedCondition(NetmailAwaiting{}.TableName(), Netmail{}.TableName(), "netmail_id")
+	orphanedFilemail = orphanedCondition(FilemailAwaiting{}.TableName(), Filemail{}.TableName(), "filemail_id")
+)
+
+// OrphanedAwaiting counts the rows of the outbound queue pointing to
+// deleted messages or removed links
+type OrphanedAwaiting struct {
+	Echomail int64
+	Netmail  int64
+	Filemail int64
+}
+
+// Total returns the number of orphaned rows of all awaiting tables
+func (o OrphanedAwaiting) Total() int64 {
+	return o.Echomail + o.Netmail + o.Filemail
+}
+
+// FindOrphanedAwaiting returns the echomail awaiting rows without a
+// matching echomail or link
+func FindOrphanedAwaiting() ([]EchomailAwaiting, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	var rows []EchomailAwaiting
+	err := DB.Where(orphanedEchomail).Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find orphaned echomail awaiting: %w", err)
+	}
+
+	return rows, nil
+}
+
+// FindOrphanedNetmailAwaiting returns the netmail awaiting rows without a
+// matching netmail or link
+func FindOrphanedNetmailAwaiting() ([]NetmailAwaiting, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	var rows []NetmailAwaiting
+	err := DB.Where(orphanedNetmail).Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find orphaned netmail awaiting: %w", err)
+	}
+
+	return rows, nil
+}
+
+// FindOrphanedFilemailAwaiting returns the filemail awaiting rows without a
+// matching filemail or link
+func FindOrphanedFilemailAwaiting() ([]FilemailAwaiting, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	var rows []FilemailAwaiting
+	err := DB.Where(orphanedFilemail).Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find orphaned filemail awaiting: %w", err)
+	}
+
+	return rows, nil
+}
+
+// CountOrphanedAwaiting returns the number of orphaned rows per awaiting table
+func CountOrphanedAwaiting() (OrphanedAwaiting, error) {
+	var counts OrphanedAwaiting
+	if DB == nil {
+		return counts, fmt.Errorf("database connection is nil")
+	}
+
+	for _, c := range []struct {
+		model     any
+		condition string
+		count     *int64
+	}{
+		{&EchomailAwaiting{}, orphanedEchomail, &counts.Echomail},
+		{&NetmailAwaiting{}, orphanedNetmail, &counts.Netmail},
+		{&FilemailAwaiting{}, orphanedFilemail, &counts.Filemail},
+	} {
+		if err := DB.Model(c.model).Where(c.condition).Count(c.count).Error; err != nil {
+			return counts, fmt.Errorf("failed to count orphaned awaiting: %w", err)
+		}
+	}
+
+	return counts, nil
+}
+
+// DeleteOrphanedAwaiting deletes the orphaned rows of all awaiting tables in
+// one transaction and returns how many were deleted from each
+func DeleteOrphanedAwaiting() (OrphanedAwaiting, error) {
+	var deleted OrphanedAwaiting
+	if DB == nil {
+		return deleted, fmt.Errorf("database connection is nil")
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, d := range []struct {
+			model     any
+			condition string
+			deleted   *int64
+		}{
+			{&EchomailAwaiting{}, orphanedEchomail, &deleted.Echomail},
+			{&NetmailAwaiting{}, orphanedNetmail, &deleted.Netmail},
+			{&FilemailAwaiting{}, orphanedFilemail, &deleted.Filemail},
+		} {
+			res := tx.Where(d.condition).Delete(d.model)
+			if res.Error != nil {
+				return res.Error
+			}
+			*d.deleted = res.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return OrphanedAwaiting{}, fmt.Errorf("failed to delete orphaned awaiting: %w", err)
+	}
+
+	log.Printf("Deleted orphaned awaiting rows: %d echomail, %d netmail, %d filemail",
+		deleted.Echomail, deleted.Netmail, deleted.Filemail)
+	return deleted, nil
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"testing"
+
+	. "github.com/franela/goblin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestOrphanedAwaiting(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check orphaned awaiting rows", func() {
+		var link Link
+		var echomail Echomail
+		var netmail Netmail
+		var filemail Filemail
+		g.Before(func() {
+			db, err := gorm.Open(sqlite.Dialector{DriverName: "sqlite", DSN: "file::memory:"},
+				&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+			g.Assert(err).IsNil()
+			sqlDB, _ := db.DB()
+			sqlDB.SetMaxOpenConns(1)
+			g.Assert(db.AutoMigrate(&Link{}, &Echoarea{}, &Echomail{}, &EchomailAwaiting{}, &Netmail{},
+				&NetmailAwaiting{}, &Filearea{}, &Filemail{}, &FilemailAwaiting{})).IsNil()
+			link = Link{StationName: "2:5020/1", FtnAddress: "2:5020/1"}
+			g.Assert(db.Create(&link).Error).IsNil()
+			area := Echoarea{Name: "su.general"}
+			g.Assert(db.Create(&area).Error).IsNil()
+			echomail = Echomail{EchoareaID: area.ID, FromName: "Sysop", ToName: "All", FromFtnAddr: "2:5020/9696"}
+			g.Assert(db.Create(&echomail).Error).IsNil()
+			netmail = Netmail{FromAddress: "2:5020/9696", ToAddress: "2:5020/1"}
+			g.Assert(db.Create(&netmail).Error).IsNil()
+			fileArea := Filearea{Name: "nodelist"}
+			g.Assert(db.Create(&fileArea).Error).IsNil()
+			filemail = Filemail{FilearaID: fileArea.ID, Filename: "nodelist.001"}
+			g.Assert(db.Create(&filemail).Error).IsNil()
+			for _, row := range []any{
+				&EchomailAwaiting{LinkID: link.ID, EchomailID: echomail.ID},
+				&EchomailAwaiting{LinkID: link.ID, EchomailID: echomail.ID + 1},
+				&EchomailAwaiting{LinkID: link.ID + 1, EchomailID: echomail.ID},
+				&NetmailAwaiting{LinkID: link.ID, NetmailID: netmail.ID},
+				&NetmailAwaiting{LinkID: link.ID, NetmailID: netmail.ID + 1},
+				&FilemailAwaiting{LinkID: link.ID, FilemailID: filemail.ID},
+				&FilemailAwaiting{LinkID: link.ID + 1, FilemailID: filemail.ID},
+			} {
+				g.Assert(db.Omit("Link", "Echomail", "Netmail", "Filemail").Create(row).Error).IsNil()
+			}
+			DB = db
+		})
+		g.After(func() {
+			CloseDatabase()
+			DB = nil
+		})
+		g.It("check rows of deleted messages and removed links are found", func() {
+			echo, err := FindOrphanedAwaiting()
+			g.Assert(err).IsNil()
+			g.Assert(len(echo)).Equal(2)
+			for _, row := range echo {
+				g.Assert(row.LinkID == link.ID && row.EchomailID == echomail.ID).IsFalse()
+			}
+			net, err := FindOrphanedNetmailAwaiting()
+			g.Assert(err).IsNil()
+			g.Assert(len(net)).Equal(1)
+			g.Assert(net[0].NetmailID).Equal(netmail.ID + 1)
+			file, err := FindOrphanedFilemailAwaiting()
+			g.Assert(err).IsNil()
+			g.Assert(len(file)).Equal(1)
+			g.Assert(file[0].LinkID).Equal(link.ID + 1)
+			counts, err := CountOrphanedAwaiting()
+			g.Assert(err).IsNil()
+			g.Assert(counts).Equal(OrphanedAwaiting{Echomail: 2, Netmail: 1, Filemail: 1})
+			g.Assert(counts.Total()).Equal(int64(4))
+		})
+		g.It("check only orphaned rows are deleted", func() {
+			deleted, err := DeleteOrphanedAwaiting()
+			g.Assert(err).IsNil()
+			g.Assert(deleted.Total()).Equal(int64(4))
+			counts, err := CountOrphanedAwaiting()
+			g.Assert(err).IsNil()
+			g.Assert(counts.Total()).Equal(int64(0))
+			queued, err := GetQueuedLinks(echomail.ID)
+			g.Assert(err).IsNil()
+			g.Assert(len(queued)).Equal(1)
+			g.Assert(queued[link.ID]).IsTrue()
+			var left int64
+			DB.Model(&NetmailAwaiting{}).Count(&left)
+			g.Assert(left).Equal(int64(1))
+			DB.Model(&FilemailAwaiting{}).Count(&left)
+			g.Assert(left).Equal(int64(1))
+		})
+	})
+}
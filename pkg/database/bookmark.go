@@ -0,0 +1,73 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// Bookmark is a message a user marked to return to later, kept in the
+// lastread database
+type Bookmark struct {
+	ID       int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Username string `gorm:"column:username;not null;index" json:"username"`
+	AreaName string `gorm:"column:area_name;not null" json:"area_name"`
+	MsgID    int64  `gorm:"column:msg_id;not null;default:0" json:"msg_id"`   // database id of SQL messages, 0 in file bases
+	MsgNum   uint32 `gorm:"column:msg_num;not null;default:0" json:"msg_num"` // position when bookmarked
+	Note     string `gorm:"column:note;not null;default:''" json:"note"`
+	Created  int64  `gorm:"column:created;not null" json:"created"`
+}
+
+func (Bookmark) TableName() string {
+	return "bookmarks"
+}
+
+// AddBookmark bookmarks a message of an area for a user. msgID is the stable
+// database id of SQL messages, file bases pass 0 and are found by msgNum.
+func AddBookmark(username, areaName string, msgID int64, msgNum uint32, note string) (*Bookmark, error) {
+	if LastReadDB == nil {
+		return nil, fmt.Errorf("lastread database not initialized")
+	}
+
+	bm := &Bookmark{
+		Username: username,
+		AreaName: areaName,
+		MsgID:    msgID,
+		MsgNum:   msgNum,
+		Note:     note,
+		Created:  time.Now().Unix(),
+	}
+	if err := LastReadDB.Create(bm).Error; err != nil {
+		return nil, fmt.Errorf("failed to add bookmark for user %s in area %s: %w", username, areaName, err)
+	}
+
+	return bm, nil
+}
+
+// ListBookmarks retrieves the bookmarks of a user, newest first
+func ListBookmarks(username string) ([]Bookmark, error) {
+	if LastReadDB == nil {
+		return nil, fmt.Errorf("lastread database not initialized")
+	}
+
+	var bookmarks []Bookmark
+	err := LastReadDB.Where("username = ?", username).Order("created DESC, id DESC").Find(&bookmarks).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bookmarks for user %s: %w", username, err)
+	}
+
+	return bookmarks, nil
+}
+
+// DeleteBookmark removes a bookmark of a user
+func DeleteBookmark(username string, id int64) error {
+	if LastReadDB == nil {
+		return fmt.Errorf("lastread database not initialized")
+	}
+
+	result := LastReadDB.Where("username = ? AND id = ?", username, id).Delete(&Bookmark{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete bookmark %d for user %s: %w", id, username, result.Error)
+	}
+
+	return nil
+}
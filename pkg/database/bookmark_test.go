@@ -0,0 +1,54 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "github.com/franela/goblin"
+)
+
+func TestBookmarks(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check bookmarks", func() {
+		dbPath := filepath.Join(t.TempDir(), "lastread.db")
+		g.Before(func() {
+			g.Assert(InitLastReadDatabase(LastReadConfig{Enabled: true, DatabasePath: dbPath})).IsNil()
+		})
+		g.After(func() {
+			CloseLastReadDatabase()
+			LastReadDB = nil
+		})
+		g.It("add and list bookmarks per user", func() {
+			_, err := AddBookmark("sysop", "su.general", 42, 3, "golang thread")
+			g.Assert(err).IsNil()
+			_, err = AddBookmark("sysop", "netmail", 0, 7, "")
+			g.Assert(err).IsNil()
+			_, err = AddBookmark("guest", "su.general", 42, 3, "")
+			g.Assert(err).IsNil()
+
+			bookmarks, err := ListBookmarks("sysop")
+			g.Assert(err).IsNil()
+			g.Assert(len(bookmarks)).Equal(2)
+			g.Assert(bookmarks[0].AreaName).Equal("netmail")
+			g.Assert(bookmarks[1].MsgID).Equal(int64(42))
+			g.Assert(bookmarks[1].Note).Equal("golang thread")
+		})
+		g.It("survive reopening the database", func() {
+			CloseLastReadDatabase()
+			g.Assert(InitLastReadDatabase(LastReadConfig{Enabled: true, DatabasePath: dbPath})).IsNil()
+			bookmarks, err := ListBookmarks("sysop")
+			g.Assert(err).IsNil()
+			g.Assert(len(bookmarks)).Equal(2)
+		})
+		g.It("delete only own bookmarks", func() {
+			bookmarks, _ := ListBookmarks("sysop")
+			g.Assert(DeleteBookmark("guest", bookmarks[0].ID)).IsNil()
+			left, _ := ListBookmarks("sysop")
+			g.Assert(len(left)).Equal(2)
+			g.Assert(DeleteBookmark("sysop", bookmarks[0].ID)).IsNil()
+			left, _ = ListBookmarks("sysop")
+			g.Assert(len(left)).Equal(1)
+			g.Assert(left[0].AreaName).Equal("su.general")
+		})
+	})
+}
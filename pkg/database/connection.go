@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"time"
@@ -20,6 +21,10 @@ var (
 
 // InitDatabase initializes the database connection with the given configuration
 func InitDatabase(config DatabaseConfig) error {
+	if err := ValidateDSN(config.Driver, config.DSN); err != nil {
+		return err
+	}
+
 	var dialector gorm.Dialector
 
 	switch config.Driver {
@@ -64,13 +69,29 @@ func InitDatabase(config DatabaseConfig) error {
 	sqlDB.SetMaxIdleConns(config.MaxIdleConns)
 	sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
 
-	// Test the connection
+	SetRetryConfig(RetryConfig{
+		Retries:    max(config.Retries, 0),
+		Backoff:    config.RetryBackoff,
+		MaxBackoff: config.RetryMaxBackoff,
+	})
+	SetQueryTimeout(max(config.QueryTimeout, 0))
+	if err := RegisterTimeout(DB); err != nil {
+		return err
+	}
+
+	// Test the connection, leaving no connection behind for later queries
 	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		DB = nil
 		return fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	log.Printf("Connected to %s database successfully", config.Driver)
 
+	if err := ensureSchema(config.AutoMigrate); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -170,6 +191,51 @@ func GetAllEchoareaCounts() (map[int64]int64, error) {
 	return result, nil
 }
 
+// AreaLastDate represents the date of the newest message in an echoarea
+type AreaLastDate struct {
+	EchoareaID int64 `json:"echoarea_id"`
+	LastDate   int64 `json:"last_date"`
+}
+
+// GetAllEchoareaLastDates returns the newest message date for all echoareas in a single query
+func GetAllEchoareaLastDates() (map[int64]int64, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	var dates []AreaLastDate
+	err := DB.Model(&Echomail{}).
+		Select("echoarea_id, MAX(date) as last_date").
+		Group("echoarea_id").
+		Find(&dates).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get echoarea last dates: %w", err)
+	}
+
+	result := make(map[int64]int64)
+	for _, date := range dates {
+		result[date.EchoareaID] = date.LastDate
+	}
+
+	return result, nil
+}
+
+// GetNetmailLastDate returns the newest netmail date, 0 if there is no netmail
+func GetNetmailLastDate() (int64, error) {
+	if DB == nil {
+		return 0, fmt.Errorf("database connection is nil")
+	}
+
+	var last sql.NullInt64
+	err := DB.Model(&Netmail{}).Select("MAX(date)").Scan(&last).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to get netmail last date: %w", err)
+	}
+
+	return last.Int64, nil
+}
+
 // GetNetmailCount returns total netmail count
 func GetNetmailCount() (int64, error) {
 	if DB == nil {
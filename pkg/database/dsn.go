@@ -0,0 +1,166 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// DSNError is returned by ValidateDSN for a DSN which can not work, naming
+// the part of it to fix
+type DSNError struct {
+	Driver  string
+	Field   string
+	Problem string
+	Example string
+}
+
+func (e *DSNError) Error() string {
+	msg := fmt.Sprintf("invalid %s dsn: %s %s", e.Driver, e.Field, e.Problem)
+	if e.Example != "" {
+		msg += ", e.g. " + e.Example
+	}
+	return msg
+}
+
+// DSN examples shown with validation errors
+const (
+	mysqlDSNExample    = `"user:password@tcp(localhost:3306)/jnode"`
+	postgresDSNExample = `"host=localhost user=jnode password=pass dbname=jnode port=5432"`
+	sqliteDSNExample   = `"/path/to/jnode.db"`
+)
+
+// ValidateDSN checks the DSN of driver before connecting, so a typo is
+// reported as the field to fix instead of a driver error on the first query:
+// host and database name for mysql, database name for postgres, the file for
+// sqlite. Unix sockets are fine, given as unix(/path) for mysql and as a
+// host path for postgres.
+func ValidateDSN(driver, dsn string) error {
+	if strings.TrimSpace(dsn) == "" {
+		return &DSNError{Driver: driver, Field: "dsn", Problem: "is empty", Example: dsnExample(driver)}
+	}
+	switch driver {
+	case "mysql":
+		return validateMySQLDSN(dsn)
+	case "postgres", "postgresql":
+		return validatePostgresDSN(driver, dsn)
+	case "sqlite":
+		return validateSQLiteDSN(dsn)
+	}
+	return fmt.Errorf("unsupported database driver: %s", driver)
+}
+
+// dsnExample returns the DSN example of driver
+func dsnExample(driver string) string {
+	switch driver {
+	case "mysql":
+		return mysqlDSNExample
+	case "postgres", "postgresql":
+		return postgresDSNExample
+	case "sqlite":
+		return sqliteDSNExample
+	}
+	return ""
+}
+
+// validateMySQLDSN checks a go-sql-driver DSN,
+// [user[:password]@][net[(addr)]]/dbname[?param=value]
+func validateMySQLDSN(dsn string) error {
+	cfg, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return &DSNError{Driver: "mysql", Field: "dsn", Problem: "can not be parsed (" + err.Error() + ")", Example: mysqlDSNExample}
+	}
+	// the driver falls back to 127.0.0.1:3306 for a missing address
+	if !strings.Contains(dsn, cfg.Net+"(") || strings.Contains(dsn, cfg.Net+"()") {
+		return &DSNError{Driver: "mysql", Field: "host", Problem: "is missing", Example: mysqlDSNExample}
+	}
+	if cfg.DBName == "" {
+		return &DSNError{Driver: "mysql", Field: "database name", Problem: "is missing", Example: mysqlDSNExample}
+	}
+	return nil
+}
+
+// validatePostgresDSN checks a postgres:// URL or a key=value DSN. The host
+// may be a socket directory or missing, pgx then connects to the socket in
+// /var/run/postgresql or /tmp like libpq.
+func validatePostgresDSN(driver, dsn string) error {
+	settings := make(map[string]string)
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return &DSNError{Driver: driver, Field: "dsn", Problem: "is not a valid URL (" + err.Error() + ")", Example: postgresDSNExample}
+		}
+		settings["host"] = u.Hostname()
+		settings["dbname"] = strings.TrimPrefix(u.Path, "/")
+		for k, v := range u.Query() {
+			if len(v) > 0 && v[0] != "" {
+				settings[k] = v[0]
+			}
+		}
+	} else {
+		for _, field := range postgresFields(dsn) {
+			k, v, ok := strings.Cut(field, "=")
+			if !ok || k == "" {
+				return &DSNError{Driver: driver, Field: fmt.Sprintf("%q", field), Problem: "is not a key=value setting", Example: postgresDSNExample}
+			}
+			settings[k] = strings.ReplaceAll(strings.Trim(v, "'"), `\'`, "'")
+		}
+	}
+	if settings["dbname"] == "" && settings["database"] == "" {
+		return &DSNError{Driver: driver, Field: "dbname", Problem: "is missing", Example: postgresDSNExample}
+	}
+	return nil
+}
+
+// postgresFields splits a key=value DSN at spaces outside single quoted
+// values
+func postgresFields(dsn string) []string {
+	var fields []string
+	var sb strings.Builder
+	quoted, escaped := false, false
+	for _, r := range dsn {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '\'':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			if sb.Len() > 0 {
+				fields = append(fields, sb.String())
+				sb.Reset()
+			}
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	if sb.Len() > 0 {
+		fields = append(fields, sb.String())
+	}
+	return fields
+}
+
+// validateSQLiteDSN checks that the database file of a sqlite DSN, a path
+// or a file: URI, can be opened or created; in-memory databases are fine
+func validateSQLiteDSN(dsn string) error {
+	path := strings.TrimPrefix(dsn, "file:")
+	path, _, _ = strings.Cut(path, "?")
+	if path == ":memory:" || strings.Contains(dsn, "mode=memory") {
+		return nil
+	}
+	if path == "" {
+		return &DSNError{Driver: "sqlite", Field: "file path", Problem: "is missing", Example: sqliteDSNExample}
+	}
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		return &DSNError{Driver: "sqlite", Field: "file path", Problem: path + " is a directory", Example: sqliteDSNExample}
+	}
+	if fi, err := os.Stat(filepath.Dir(path)); err != nil || !fi.IsDir() {
+		return &DSNError{Driver: "sqlite", Field: "file path", Problem: "directory " + filepath.Dir(path) + " does not exist", Example: sqliteDSNExample}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LastArea is the area a user was reading when gossiped last quit, kept in
+// the lastread database
+type LastArea struct {
+	Username    string `gorm:"column:username;primaryKey" json:"username"`
+	AreaName    string `gorm:"column:area_name;not null" json:"area_name"`
+	LastUpdated int64  `gorm:"column:last_updated;not null" json:"last_updated"`
+}
+
+func (LastArea) TableName() string {
+	return "last_area"
+}
+
+// GetLastArea retrieves the area a user was last reading, "" if none was
+// stored yet
+func GetLastArea(username string) (string, error) {
+	if LastReadDB == nil {
+		return "", fmt.Errorf("lastread database not initialized")
+	}
+
+	var lastArea LastArea
+	err := LastReadDB.Where("username = ?", username).First(&lastArea).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get last area for user %s: %w", username, err)
+	}
+
+	return lastArea.AreaName, nil
+}
+
+// SetLastArea stores the area a user was last reading
+func SetLastArea(username, areaName string) error {
+	if LastReadDB == nil {
+		return fmt.Errorf("lastread database not initialized")
+	}
+
+	result := LastReadDB.Exec(`
+		INSERT INTO last_area (username, area_name, last_updated)
+		VALUES (?, ?, ?)
+		ON CONFLICT(username) DO UPDATE SET
+			area_name = excluded.area_name,
+			last_updated = excluded.last_updated
+	`, username, areaName, time.Now().Unix())
+	if result.Error != nil {
+		return fmt.Errorf("failed to set last area for user %s: %w", username, result.Error)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "github.com/franela/goblin"
+)
+
+func TestLastArea(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check last area", func() {
+		dbPath := filepath.Join(t.TempDir(), "lastread.db")
+		g.Before(func() {
+			g.Assert(InitLastReadDatabase(LastReadConfig{Enabled: true, DatabasePath: dbPath})).IsNil()
+		})
+		g.After(func() {
+			CloseLastReadDatabase()
+			LastReadDB = nil
+		})
+		g.It("check no area stored yet", func() {
+			name, err := GetLastArea("sysop")
+			g.Assert(err).IsNil()
+			g.Assert(name).Equal("")
+		})
+		g.It("check last area per user is replaced", func() {
+			g.Assert(SetLastArea("sysop", "su.general")).IsNil()
+			g.Assert(SetLastArea("guest", "netmail")).IsNil()
+			g.Assert(SetLastArea("sysop", "ru.golang")).IsNil()
+			name, err := GetLastArea("sysop")
+			g.Assert(err).IsNil()
+			g.Assert(name).Equal("ru.golang")
+			name, _ = GetLastArea("guest")
+			g.Assert(name).Equal("netmail")
+		})
+		g.It("check last area survives reopening the database", func() {
+			CloseLastReadDatabase()
+			g.Assert(InitLastReadDatabase(LastReadConfig{Enabled: true, DatabasePath: dbPath})).IsNil()
+			name, err := GetLastArea("sysop")
+			g.Assert(err).IsNil()
+			g.Assert(name).Equal("ru.golang")
+		})
+	})
+}
@@ -1,9 +1,13 @@
 package database
 
 import (
+	"encoding/binary"
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"gorm.io/driver/sqlite"
@@ -17,8 +21,21 @@ import (
 var (
 	// LastReadDB is the separate SQLite database for lastread values
 	LastReadDB *gorm.DB
+
+	// lastReadPath is the file LastReadDB was opened from, for reopening it
+	lastReadPath string
+
+	// lastReadMu guards the state of failing lastread writes below
+	lastReadMu       sync.Mutex
+	lastReadFailure  error
+	lastReadReopened time.Time
 )
 
+// lastReadReopenDelay is the least time between two attempts to reopen the
+// lastread database after a failed write, so a full disk does not cost an
+// attempt on every message read
+const lastReadReopenDelay = 30 * time.Second
+
 // LastRead represents a user's last read position in an area
 type LastRead struct {
 	ID           int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
@@ -40,6 +57,7 @@ func (LastRead) TableName() string {
 type LastReadConfig struct {
 	DatabasePath string `yaml:"database_path"`
 	Enabled      bool   `yaml:"enabled"`
+	ExportPath   string `yaml:"export_path"`
 }
 
 // InitLastReadDatabase initializes the separate SQLite database for lastread values
@@ -96,30 +114,90 @@ func InitLastReadDatabase(config LastReadConfig) error {
 		return fmt.Errorf("failed to ping lastread database: %w", err)
 	}
 
-	// Create table manually (no AutoMigrate)
-	if err := LastReadDB.Exec(`
-		CREATE TABLE IF NOT EXISTS lastread (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			username TEXT NOT NULL,
-			area_name TEXT NOT NULL,
-			last_read_msg INTEGER NOT NULL DEFAULT 0,
-			high_read_msg INTEGER NOT NULL DEFAULT 0,
-			last_updated INTEGER NOT NULL,
-			UNIQUE(username, area_name)
-		)
-	`).Error; err != nil {
-		return fmt.Errorf("failed to create lastread table: %w", err)
+	// Versioned schema (no AutoMigrate), upgrades existing files in place
+	if err := migrateLastRead(LastReadDB); err != nil {
+		return err
 	}
 
+	lastReadPath = dbPath
+	setLastReadFailure(nil)
 	log.Printf("Initialized lastread database at %s", dbPath)
 	return nil
 }
 
+// openLastReadFile opens the lastread database file at dbPath, pinging it
+// and keeping a single connection like InitLastReadDatabase
+func openLastReadFile(dbPath string) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Dialector{DriverName: "sqlite", DSN: dbPath},
+		&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetMaxIdleConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// reopenLastRead replaces LastReadDB with a new connection to its file
+// after a failed write, at most once per lastReadReopenDelay. It returns
+// true if there is a new connection to try the write again with.
+func reopenLastRead() bool {
+	lastReadMu.Lock()
+	defer lastReadMu.Unlock()
+	if lastReadPath == "" || time.Since(lastReadReopened) < lastReadReopenDelay {
+		return false
+	}
+	lastReadReopened = time.Now()
+	db, err := openLastReadFile(lastReadPath)
+	if err != nil {
+		log.Printf("Failed to reopen lastread database %s: %v", lastReadPath, err)
+		return false
+	}
+	if sqlDB, err := LastReadDB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	LastReadDB = db
+	log.Printf("Reopened lastread database %s", lastReadPath)
+	return true
+}
+
+// setLastReadFailure records the outcome of a lastread write, logging when
+// writes start failing and when they work again
+func setLastReadFailure(err error) {
+	lastReadMu.Lock()
+	defer lastReadMu.Unlock()
+	switch {
+	case err != nil && lastReadFailure == nil:
+		log.Printf("Lastread positions can not be saved, keeping them in memory: %v", err)
+	case err == nil && lastReadFailure != nil:
+		log.Printf("Lastread positions are saved again")
+	}
+	lastReadFailure = err
+}
+
+// LastReadFailure returns the error of the last lastread write if it
+// failed, nil while positions are saved
+func LastReadFailure() error {
+	lastReadMu.Lock()
+	defer lastReadMu.Unlock()
+	return lastReadFailure
+}
+
 // CloseLastReadDatabase closes the lastread database connection
 func CloseLastReadDatabase() error {
 	if LastReadDB == nil {
 		return nil
 	}
+	lastReadPath = ""
 
 	sqlDB, err := LastReadDB.DB()
 	if err != nil {
@@ -154,10 +232,25 @@ func SetLastRead(username, areaName string, position uint32) error {
 		return fmt.Errorf("lastread database not initialized")
 	}
 
+	err := upsertLastRead(username, areaName, position)
+	if err != nil && reopenLastRead() {
+		err = upsertLastRead(username, areaName, position)
+	}
+	setLastReadFailure(err)
+	if err != nil {
+		return fmt.Errorf("failed to set lastread for user %s in area %s: %w", username, areaName, err)
+	}
+
+	return nil
+}
+
+// upsertLastRead stores the position of a user in an area, raising the high
+// read mark
+func upsertLastRead(username, areaName string, position uint32) error {
 	now := time.Now().Unix()
-	
+
 	// Use UPSERT (INSERT OR REPLACE for SQLite)
-	result := LastReadDB.Exec(`
+	return LastReadDB.Exec(`
 		INSERT INTO lastread (username, area_name, last_read_msg, high_read_msg, last_updated)
 		VALUES (?, ?, ?, ?, ?)
 		ON CONFLICT(username, area_name) DO UPDATE SET
@@ -167,13 +260,7 @@ func SetLastRead(username, areaName string, position uint32) error {
 				ELSE high_read_msg
 			END,
 			last_updated = excluded.last_updated
-	`, username, areaName, position, position, now)
-
-	if result.Error != nil {
-		return fmt.Errorf("failed to set lastread for user %s in area %s: %w", username, areaName, result.Error)
-	}
-
-	return nil
+	`, username, areaName, position, position, now).Error
 }
 
 // GetHighRead retrieves the highest read message for a user in an area
@@ -195,6 +282,36 @@ func GetHighRead(username, areaName string) (uint32, error) {
 	return lastRead.HighReadMsg, nil
 }
 
+// SetHighRead sets the highest read message for a user in an area, lower
+// than the stored one too, leaving the last read position alone
+func SetHighRead(username, areaName string, position uint32) error {
+	if LastReadDB == nil {
+		return fmt.Errorf("lastread database not initialized")
+	}
+
+	err := upsertHighRead(username, areaName, position)
+	if err != nil && reopenLastRead() {
+		err = upsertHighRead(username, areaName, position)
+	}
+	setLastReadFailure(err)
+	if err != nil {
+		return fmt.Errorf("failed to set high read for user %s in area %s: %w", username, areaName, err)
+	}
+
+	return nil
+}
+
+// upsertHighRead stores the high read mark of a user in an area
+func upsertHighRead(username, areaName string, position uint32) error {
+	return LastReadDB.Exec(`
+		INSERT INTO lastread (username, area_name, last_read_msg, high_read_msg, last_updated)
+		VALUES (?, ?, 0, ?, ?)
+		ON CONFLICT(username, area_name) DO UPDATE SET
+			high_read_msg = excluded.high_read_msg,
+			last_updated = excluded.last_updated
+	`, username, areaName, position, time.Now().Unix()).Error
+}
+
 // GetAllLastReads retrieves all lastread records for a user
 func GetAllLastReads(username string) ([]LastRead, error) {
 	if LastReadDB == nil {
@@ -211,6 +328,22 @@ func GetAllLastReads(username string) ([]LastRead, error) {
 	return lastReads, nil
 }
 
+// GetLastReadUsers returns the names of the users with lastread records
+func GetLastReadUsers() ([]string, error) {
+	if LastReadDB == nil {
+		return nil, fmt.Errorf("lastread database not initialized")
+	}
+
+	var users []string
+	err := LastReadDB.Model(&LastRead{}).Distinct("username").Order("username").Pluck("username", &users).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lastread users: %w", err)
+	}
+
+	return users, nil
+}
+
 // GetLastReadsByArea retrieves all lastread records for an area
 func GetLastReadsByArea(areaName string) ([]LastRead, error) {
 	if LastReadDB == nil {
@@ -309,4 +442,35 @@ func GetLastReadStats() (map[string]interface{}, error) {
 // IsLastReadEnabled returns true if lastread database is available
 func IsLastReadEnabled() bool {
 	return LastReadDB != nil
-}
\ No newline at end of file
+}
+
+// ExportLastReads writes the lastread positions of a user as Squish style
+// lastread files, one <area>.sql per area in dir holding the message number
+// as a little-endian dword for user 0, so legacy readers can pick them up
+func ExportLastReads(username, dir string) (int, error) {
+	lastReads, err := GetAllLastReads(username)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create lastread export directory: %w", err)
+	}
+	for i, lr := range lastReads {
+		buf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(buf, lr.LastReadMsg)
+		if err := os.WriteFile(filepath.Join(dir, lastReadFileName(lr.AreaName)), buf, 0644); err != nil {
+			return i, fmt.Errorf("failed to export lastread for area %s: %w", lr.AreaName, err)
+		}
+	}
+	return len(lastReads), nil
+}
+
+// lastReadFileName maps an area name to its exported lastread file name
+func lastReadFileName(areaName string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.ToLower(areaName)) + ".sql"
+}
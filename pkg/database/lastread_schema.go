@@ -0,0 +1,87 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// lastReadMigration is one step of the lastread database schema
+type lastReadMigration struct {
+	version int
+	sql     string
+}
+
+// lastReadMigrations are applied in order to bring a lastread database to
+// the current schema. Append new steps, never change released ones.
+var lastReadMigrations = []lastReadMigration{
+	{1, `
+		CREATE TABLE IF NOT EXISTS lastread (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT NOT NULL,
+			area_name TEXT NOT NULL,
+			last_read_msg INTEGER NOT NULL DEFAULT 0,
+			high_read_msg INTEGER NOT NULL DEFAULT 0,
+			last_updated INTEGER NOT NULL,
+			UNIQUE(username, area_name)
+		)
+	`},
+	{2, `
+		CREATE TABLE IF NOT EXISTS bookmarks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT NOT NULL,
+			area_name TEXT NOT NULL,
+			msg_id INTEGER NOT NULL DEFAULT 0,
+			msg_num INTEGER NOT NULL DEFAULT 0,
+			note TEXT NOT NULL DEFAULT '',
+			created INTEGER NOT NULL
+		)
+	`},
+	{3, `CREATE INDEX IF NOT EXISTS idx_bookmarks_username ON bookmarks(username)`},
+	{4, `
+		CREATE TABLE IF NOT EXISTS last_area (
+			username TEXT NOT NULL PRIMARY KEY,
+			area_name TEXT NOT NULL,
+			last_updated INTEGER NOT NULL
+		)
+	`},
+}
+
+// lastReadSchemaVersion returns the schema version of the lastread database,
+// 0 for a database created before versioning or a new one
+func lastReadSchemaVersion(db *gorm.DB) (int, error) {
+	if err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`).Error; err != nil {
+		return 0, fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+	var version int
+	if err := db.Raw(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version).Error; err != nil {
+		return 0, fmt.Errorf("failed to read lastread schema version: %w", err)
+	}
+	return version, nil
+}
+
+// migrateLastRead applies the pending lastread migrations, each one in its
+// own transaction together with its version record
+func migrateLastRead(db *gorm.DB) error {
+	version, err := lastReadSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range lastReadMigrations {
+		if m.version <= version {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(m.sql).Error; err != nil {
+				return err
+			}
+			return tx.Exec(`INSERT INTO schema_version (version) VALUES (?)`, m.version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply lastread migration %d: %w", m.version, err)
+		}
+		log.Printf("Applied lastread schema migration %d", m.version)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "github.com/franela/goblin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestLastReadMigrations(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check lastread schema migrations", func() {
+		dbPath := filepath.Join(t.TempDir(), "lastread.db")
+		g.After(func() {
+			CloseLastReadDatabase()
+			LastReadDB = nil
+		})
+		g.It("upgrade a pre-versioning lastread file", func() {
+			// lastread file as created before schema versioning
+			old, err := gorm.Open(sqlite.Dialector{DriverName: "sqlite", DSN: dbPath},
+				&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+			g.Assert(err).IsNil()
+			g.Assert(old.Exec(lastReadMigrations[0].sql).Error).IsNil()
+			g.Assert(old.Exec(`INSERT INTO lastread (username, area_name, last_read_msg, high_read_msg, last_updated)
+				VALUES ('sysop', 'su.general', 12, 15, 0)`).Error).IsNil()
+			sqlDB, _ := old.DB()
+			sqlDB.Close()
+
+			g.Assert(InitLastReadDatabase(LastReadConfig{Enabled: true, DatabasePath: dbPath})).IsNil()
+			version, err := lastReadSchemaVersion(LastReadDB)
+			g.Assert(err).IsNil()
+			g.Assert(version).Equal(len(lastReadMigrations))
+			pos, err := GetLastRead("sysop", "su.general")
+			g.Assert(err).IsNil()
+			g.Assert(pos).Equal(uint32(12))
+		})
+		g.It("apply only pending migrations", func() {
+			saved := lastReadMigrations
+			defer func() { lastReadMigrations = saved }()
+			lastReadMigrations = append(saved[:len(saved):len(saved)],
+				lastReadMigration{len(saved) + 1, `ALTER TABLE lastread ADD COLUMN note TEXT`})
+
+			g.Assert(migrateLastRead(LastReadDB)).IsNil()
+			version, _ := lastReadSchemaVersion(LastReadDB)
+			g.Assert(version).Equal(len(saved) + 1)
+			g.Assert(LastReadDB.Migrator().HasColumn(&LastRead{}, "note")).IsTrue()
+			// a second run must not apply the ALTER TABLE again
+			g.Assert(migrateLastRead(LastReadDB)).IsNil()
+			high, err := GetHighRead("sysop", "su.general")
+			g.Assert(err).IsNil()
+			g.Assert(high).Equal(uint32(15))
+		})
+	})
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/franela/goblin"
+)
+
+func TestLastReadUsers(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check lastread positions of several users", func() {
+		g.Before(func() {
+			dbPath := filepath.Join(t.TempDir(), "lastread.db")
+			g.Assert(InitLastReadDatabase(LastReadConfig{Enabled: true, DatabasePath: dbPath})).IsNil()
+		})
+		g.After(func() {
+			CloseLastReadDatabase()
+			LastReadDB = nil
+		})
+		g.It("check users and areas are listed", func() {
+			g.Assert(SetLastRead("sysop", "su.general", 12)).IsNil()
+			g.Assert(SetLastRead("sysop", "ru.golang", 3)).IsNil()
+			g.Assert(SetLastRead("guest", "su.general", 7)).IsNil()
+			users, err := GetLastReadUsers()
+			g.Assert(err).IsNil()
+			g.Assert(users).Equal([]string{"guest", "sysop"})
+			byArea, err := GetLastReadsByArea("su.general")
+			g.Assert(err).IsNil()
+			g.Assert(len(byArea)).Equal(2)
+		})
+		g.It("check resetting a user keeps the others", func() {
+			g.Assert(DeleteAllLastReadsForUser("sysop")).IsNil()
+			users, _ := GetLastReadUsers()
+			g.Assert(users).Equal([]string{"guest"})
+			pos, err := GetLastRead("guest", "su.general")
+			g.Assert(err).IsNil()
+			g.Assert(pos).Equal(uint32(7))
+			pos, _ = GetLastRead("sysop", "su.general")
+			g.Assert(pos).Equal(uint32(0))
+		})
+	})
+}
+
+func TestLastReadFailure(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check failing lastread writes", func() {
+		g.Before(func() {
+			dbPath := filepath.Join(t.TempDir(), "lastread.db")
+			g.Assert(InitLastReadDatabase(LastReadConfig{Enabled: true, DatabasePath: dbPath})).IsNil()
+		})
+		g.After(func() {
+			CloseLastReadDatabase()
+			LastReadDB = nil
+			lastReadReopened = time.Time{}
+		})
+		g.It("check a failure is kept until reopening is due", func() {
+			g.Assert(SetLastRead("sysop", "su.general", 5)).IsNil()
+			g.Assert(LastReadFailure()).IsNil()
+			g.Assert(LastReadDB.Exec("PRAGMA query_only = ON").Error).IsNil()
+			lastReadReopened = time.Now()
+			g.Assert(SetLastRead("sysop", "su.general", 6) == nil).IsFalse()
+			g.Assert(LastReadFailure() == nil).IsFalse()
+			pos, err := GetLastRead("sysop", "su.general")
+			g.Assert(err).IsNil()
+			g.Assert(pos).Equal(uint32(5))
+		})
+		g.It("check the database is reopened and the failure cleared", func() {
+			lastReadReopened = time.Time{}
+			g.Assert(SetLastRead("sysop", "su.general", 7)).IsNil()
+			g.Assert(LastReadFailure()).IsNil()
+			pos, _ := GetLastRead("sysop", "su.general")
+			g.Assert(pos).Equal(uint32(7))
+		})
+	})
+}
+
+func TestExportLastReads(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check Squish style lastread export", func() {
+		g.Before(func() {
+			dbPath := filepath.Join(t.TempDir(), "lastread.db")
+			g.Assert(InitLastReadDatabase(LastReadConfig{Enabled: true, DatabasePath: dbPath})).IsNil()
+		})
+		g.After(func() {
+			CloseLastReadDatabase()
+			LastReadDB = nil
+		})
+		g.It("check one file per area of the user", func() {
+			g.Assert(SetLastRead("sysop", "SU.General", 12)).IsNil()
+			g.Assert(SetLastRead("sysop", "fido/local", 70000)).IsNil()
+			g.Assert(SetLastRead("guest", "ru.golang", 3)).IsNil()
+			dir := filepath.Join(t.TempDir(), "export")
+			n, err := ExportLastReads("sysop", dir)
+			g.Assert(err).IsNil()
+			g.Assert(n).Equal(2)
+			data, err := os.ReadFile(filepath.Join(dir, "su.general.sql"))
+			g.Assert(err).IsNil()
+			g.Assert(data).Equal([]byte{12, 0, 0, 0})
+			data, err = os.ReadFile(filepath.Join(dir, "fido_local.sql"))
+			g.Assert(err).IsNil()
+			g.Assert(binary.LittleEndian.Uint32(data)).Equal(uint32(70000))
+			_, err = os.Stat(filepath.Join(dir, "ru.golang.sql"))
+			g.Assert(os.IsNotExist(err)).IsTrue()
+		})
+		g.It("check a user without positions exports nothing", func() {
+			n, err := ExportLastReads("nobody", t.TempDir())
+			g.Assert(err).IsNil()
+			g.Assert(n).Equal(0)
+		})
+	})
+}
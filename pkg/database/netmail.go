@@ -0,0 +1,52 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// maxDeleteIDs bounds the id list of a single DELETE statement, below the
+// SQLite default limit of 999 bound parameters
+const maxDeleteIDs = 500
+
+// GetSentNetmail returns the netmail jnode has marked sent, oldest first
+func GetSentNetmail() ([]Netmail, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	var netmails []Netmail
+	err := DB.Where("send = ?", true).Order("id ASC").Find(&netmails).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sent netmail: %w", err)
+	}
+
+	return netmails, nil
+}
+
+// DeleteSentNetmail deletes the netmail with the given ids in one
+// transaction. Only sent netmail is deleted, ids of unsent mail are skipped.
+func DeleteSentNetmail(ids []int64) (int64, error) {
+	if DB == nil {
+		return 0, fmt.Errorf("database connection is nil")
+	}
+
+	var deleted int64
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for chunk := range slices.Chunk(ids, maxDeleteIDs) {
+			res := tx.Where("id IN ? AND send = ?", chunk, true).Delete(&Netmail{})
+			if res.Error != nil {
+				return res.Error
+			}
+			deleted += res.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sent netmail: %w", err)
+	}
+
+	return deleted, nil
+}
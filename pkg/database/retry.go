@@ -0,0 +1,78 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"log"
+	"net"
+	"syscall"
+	"time"
+)
+
+// RetryConfig controls how often queries failing with a transient error
+// are retried and how long to wait in between. Retries of 0 disables
+// retrying; the retries setting of the database configuration defaults to 3
+// when unset, so it is disabled there with a negative value.
+type RetryConfig struct {
+	Retries    int           // retries after the first attempt
+	Backoff    time.Duration // wait before the first retry, doubled for each next one
+	MaxBackoff time.Duration // upper bound of the wait
+}
+
+// retryConfig is set from the database configuration by InitDatabase
+var retryConfig = RetryConfig{Retries: 3, Backoff: 200 * time.Millisecond, MaxBackoff: 5 * time.Second}
+
+// SetRetryConfig replaces the retry settings used by WithRetry
+func SetRetryConfig(rc RetryConfig) {
+	retryConfig = rc
+}
+
+// IsTransient returns true for errors of a lost or refused connection where
+// the query did not reach the server, so running it again is safe. Errors
+// like record not found or constraint violations are not transient.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// IsUnsent returns true only for errors of a connection found broken before
+// the statement was sent. A connection reset may come after the server has
+// committed, so writes are retried on these alone.
+func IsUnsent(err error) bool {
+	return errors.Is(err, driver.ErrBadConn)
+}
+
+// WithRetry runs fn, running it again with exponential backoff while it
+// fails with a transient error. The pool replaces broken connections, so
+// each retry gets a fresh one. op names the operation in the log. Running
+// out of time is not transient and yields ErrTimeout.
+func WithRetry(op string, fn func() error) error {
+	return withRetry(op, fn, IsTransient)
+}
+
+// WithWriteRetry is WithRetry for statements which are not idempotent, like
+// an INSERT, retried only while they did not reach the server
+func WithWriteRetry(op string, fn func() error) error {
+	return withRetry(op, fn, IsUnsent)
+}
+
+func withRetry(op string, fn func() error, retryable func(error) bool) error {
+	rc := retryConfig
+	backoff := rc.Backoff
+	err := fn()
+	for attempt := 1; attempt <= rc.Retries && retryable(err); attempt++ {
+		log.Printf("Transient database error in %s, retry %d/%d in %v: %v", op, attempt, rc.Retries, backoff, err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, rc.MaxBackoff)
+		err = fn()
+	}
+	return timeoutError(err)
+}
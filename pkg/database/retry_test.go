@@ -0,0 +1,86 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/franela/goblin"
+	"gorm.io/gorm"
+)
+
+func TestWithRetry(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check WithRetry()", func() {
+		saved := retryConfig
+		g.Before(func() {
+			SetRetryConfig(RetryConfig{Retries: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond})
+		})
+		g.After(func() {
+			SetRetryConfig(saved)
+		})
+		g.It("check transient errors", func() {
+			g.Assert(IsTransient(nil)).IsFalse()
+			g.Assert(IsTransient(fmt.Errorf("error retrieving echomail message: %w", driver.ErrBadConn))).IsTrue()
+			g.Assert(IsTransient(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED})).IsTrue()
+			g.Assert(IsTransient(&net.OpError{Op: "read", Err: errors.New("i/o timeout")})).IsFalse()
+			g.Assert(IsTransient(gorm.ErrRecordNotFound)).IsFalse()
+			g.Assert(IsTransient(errors.New("UNIQUE constraint failed: echomail.id"))).IsFalse()
+		})
+		g.It("retry transient errors until success", func() {
+			calls := 0
+			err := WithRetry("test", func() error {
+				calls++
+				if calls < 3 {
+					return driver.ErrBadConn
+				}
+				return nil
+			})
+			g.Assert(err).IsNil()
+			g.Assert(calls).Equal(3)
+		})
+		g.It("give up after the configured retries", func() {
+			calls := 0
+			err := WithRetry("test", func() error {
+				calls++
+				return driver.ErrBadConn
+			})
+			g.Assert(errors.Is(err, driver.ErrBadConn)).IsTrue()
+			g.Assert(calls).Equal(4)
+		})
+		g.It("not retry other errors", func() {
+			calls := 0
+			err := WithRetry("test", func() error {
+				calls++
+				return gorm.ErrRecordNotFound
+			})
+			g.Assert(err).Equal(gorm.ErrRecordNotFound)
+			g.Assert(calls).Equal(1)
+		})
+		g.It("retry writes only when the statement was not sent", func() {
+			g.Assert(IsUnsent(driver.ErrBadConn)).IsTrue()
+			g.Assert(IsUnsent(syscall.ECONNRESET)).IsFalse()
+			calls := 0
+			err := WithWriteRetry("test", func() error {
+				calls++
+				return &net.OpError{Op: "read", Err: syscall.ECONNRESET}
+			})
+			g.Assert(errors.Is(err, syscall.ECONNRESET)).IsTrue()
+			g.Assert(calls).Equal(1)
+			calls = 0
+			err = WithWriteRetry("test", func() error {
+				calls++
+				if calls < 2 {
+					return driver.ErrBadConn
+				}
+				return nil
+			})
+			g.Assert(err).IsNil()
+			g.Assert(calls).Equal(2)
+		})
+	})
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// coreTables are the jnode tables gossiped can not work without
+var coreTables = []interface{}{
+	&Echoarea{},
+	&Echomail{},
+	&Netmail{},
+}
+
+// schemaModels are all jnode tables created when the schema is migrated
+var schemaModels = []interface{}{
+	&Link{},
+	&Echoarea{},
+	&Echomail{},
+	&Netmail{},
+	&Subscription{},
+	&EchomailAwaiting{},
+	&Filearea{},
+	&Filemail{},
+	&FileSubscription{},
+	&FilemailAwaiting{},
+	&LinkOption{},
+	&Route{},
+	&Jscript{},
+	&ScriptHelper{},
+	&Schedule{},
+	&Robot{},
+	&NetmailAwaiting{},
+}
+
+// missingCoreTables returns names of core jnode tables absent in the database
+func missingCoreTables() []string {
+	var missing []string
+	migrator := DB.Migrator()
+	for _, model := range coreTables {
+		if !migrator.HasTable(model) {
+			missing = append(missing, model.(interface{ TableName() string }).TableName())
+		}
+	}
+	return missing
+}
+
+// ensureSchema checks that the database contains the jnode schema. Missing
+// tables are created when autoMigrate is set, otherwise an error is returned.
+func ensureSchema(autoMigrate bool) error {
+	missing := missingCoreTables()
+	if len(missing) == 0 {
+		return nil
+	}
+	if !autoMigrate {
+		return fmt.Errorf("this does not look like a jnode database: missing tables %s (set database.auto_migrate to create them)",
+			strings.Join(missing, ", "))
+	}
+
+	log.Printf("Missing jnode tables %s, creating schema", strings.Join(missing, ", "))
+	if err := DB.AutoMigrate(schemaModels...); err != nil {
+		return fmt.Errorf("failed to create jnode schema: %w", err)
+	}
+	return nil
+}
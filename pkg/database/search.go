@@ -0,0 +1,57 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SearchHit is an echomail message matching a search term
+type SearchHit struct {
+	ID         int64  `json:"id"`
+	EchoareaID int64  `json:"echoarea_id"`
+	AreaName   string `json:"area_name"`
+	Position   int64  `json:"position"`
+	FromName   string `json:"from_name"`
+	Subject    string `json:"subject"`
+	Date       int64  `json:"date"`
+}
+
+// likeEscaper escapes LIKE wildcards, '!' is the ESCAPE character
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// SearchEchomail returns up to limit echomail messages of all echoareas whose
+// subject, sender, recipient or text contains term, newest first, and whether
+// more messages matched. Position is the 1-based message number in the area.
+func SearchEchomail(term string, limit int) ([]SearchHit, bool, error) {
+	if DB == nil {
+		return nil, false, fmt.Errorf("database connection is nil")
+	}
+	if term == "" || limit <= 0 {
+		return nil, false, nil
+	}
+
+	// only lowered, not case folded like in-memory searches: it has to match
+	// what LOWER() of the database makes of the text, which is ASCII only
+	// in sqlite
+	pattern := "%" + likeEscaper.Replace(strings.ToLower(term)) + "%"
+	var hits []SearchHit
+	err := DB.Table("echomail AS e").
+		Select(`e.id, e.echoarea_id, a.name AS area_name, e.from_name, e.subject, e.date,
+			(SELECT COUNT(*) FROM echomail p WHERE p.echoarea_id = e.echoarea_id AND p.id <= e.id) AS position`).
+		Joins("JOIN echoarea a ON a.id = e.echoarea_id").
+		Where(`LOWER(e.subject) LIKE ? ESCAPE '!' OR LOWER(e.from_name) LIKE ? ESCAPE '!'
+			OR LOWER(e.to_name) LIKE ? ESCAPE '!' OR LOWER(e.message) LIKE ? ESCAPE '!'`,
+			pattern, pattern, pattern, pattern).
+		Order("e.id DESC").
+		Limit(limit + 1).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to search echomail: %w", err)
+	}
+
+	more := len(hits) > limit
+	if more {
+		hits = hits[:limit]
+	}
+	return hits, more, nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/franela/goblin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestSearchEchomail(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SearchEchomail()", func() {
+		g.Before(func() {
+			db, err := gorm.Open(sqlite.Dialector{DriverName: "sqlite", DSN: "file::memory:"},
+				&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+			g.Assert(err).IsNil()
+			sqlDB, _ := db.DB()
+			sqlDB.SetMaxOpenConns(1)
+			g.Assert(db.AutoMigrate(&Echoarea{}, &Echomail{})).IsNil()
+			for _, name := range []string{"su.general", "ru.golang"} {
+				area := Echoarea{Name: name}
+				db.Create(&area)
+				for i := 1; i <= 3; i++ {
+					db.Create(&Echomail{
+						EchoareaID:  area.ID,
+						FromName:    "Sysop",
+						ToName:      "All",
+						FromFtnAddr: "2:5020/9696",
+						Subject:     fmt.Sprintf("%s 100%% topic %d", name, i),
+						Message:     "Hello Gopher\n",
+					})
+				}
+			}
+			DB = db
+		})
+		g.After(func() {
+			CloseDatabase()
+			DB = nil
+		})
+		g.It("check matches across areas", func() {
+			hits, more, err := SearchEchomail("topic 2", 10)
+			g.Assert(err).IsNil()
+			g.Assert(more).IsFalse()
+			g.Assert(len(hits)).Equal(2)
+			g.Assert(hits[0].AreaName).Equal("ru.golang")
+			g.Assert(hits[0].Position).Equal(int64(2))
+			g.Assert(hits[1].AreaName).Equal("su.general")
+			g.Assert(hits[1].Position).Equal(int64(2))
+		})
+		g.It("check case-insensitive text match", func() {
+			hits, _, err := SearchEchomail("gopher", 10)
+			g.Assert(err).IsNil()
+			g.Assert(len(hits)).Equal(6)
+		})
+		g.It("check limit", func() {
+			hits, more, err := SearchEchomail("hello", 4)
+			g.Assert(err).IsNil()
+			g.Assert(more).IsTrue()
+			g.Assert(len(hits)).Equal(4)
+		})
+		g.It("check wildcards are literal", func() {
+			hits, _, err := SearchEchomail("100%", 10)
+			g.Assert(err).IsNil()
+			g.Assert(len(hits)).Equal(6)
+			hits, _, err = SearchEchomail("0%_t", 10)
+			g.Assert(err).IsNil()
+			g.Assert(len(hits)).Equal(0)
+		})
+	})
+}
@@ -0,0 +1,212 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotSubscribed is returned when echomail is queued for a link which is
+// not subscribed to its echoarea
+var ErrNotSubscribed = errors.New("link not subscribed")
+
+// LinkSubscription represents a link together with its subscription state for an echoarea
+type LinkSubscription struct {
+	Link       Link
+	Subscribed bool
+}
+
+// GetAllLinks returns all links ordered by FTN address
+func GetAllLinks() ([]Link, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	var links []Link
+	err := DB.Order("ftn_address ASC").Find(&links).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get links: %w", err)
+	}
+
+	return links, nil
+}
+
+// ListSubscriptions returns all links with their subscription state for an echoarea
+func ListSubscriptions(areaID int64) ([]LinkSubscription, error) {
+	links, err := GetAllLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	var subscriptions []Subscription
+	err = DB.Where("echoarea_id = ?", areaID).Find(&subscriptions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions for echoarea %d: %w", areaID, err)
+	}
+
+	subscribed := make(map[int64]bool, len(subscriptions))
+	for _, subscription := range subscriptions {
+		subscribed[subscription.LinkID] = true
+	}
+
+	result := make([]LinkSubscription, 0, len(links))
+	for _, link := range links {
+		result = append(result, LinkSubscription{
+			Link:       link,
+			Subscribed: subscribed[link.ID],
+		})
+	}
+
+	return result, nil
+}
+
+// GetSubscriptionCounts returns the number of subscribed links per echoarea
+// id in one query, echoareas without subscriptions are left out
+func GetSubscriptionCounts() (map[int64]int, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	var rows []struct {
+		EchoareaID int64
+		Links      int
+	}
+	err := DB.Model(&Subscription{}).
+		Select("echoarea_id, COUNT(*) AS links").
+		Group("echoarea_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count subscriptions: %w", err)
+	}
+
+	counts := make(map[int64]int, len(rows))
+	for _, row := range rows {
+		counts[row.EchoareaID] = row.Links
+	}
+
+	return counts, nil
+}
+
+// SubscribeLink subscribes a link to an echoarea
+func SubscribeLink(linkID, areaID int64) error {
+	if DB == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	subscription := Subscription{
+		LinkID:     linkID,
+		EchoareaID: areaID,
+	}
+	err := DB.Omit(clause.Associations).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&subscription).Error
+	if err != nil {
+		return fmt.Errorf("failed to subscribe link %d to echoarea %d: %w", linkID, areaID, err)
+	}
+
+	log.Printf("Subscribed link %d to echoarea %d", linkID, areaID)
+	return nil
+}
+
+// UnsubscribeLink removes a link subscription from an echoarea
+func UnsubscribeLink(linkID, areaID int64) error {
+	if DB == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	result := DB.Where("link_id = ? AND echoarea_id = ?", linkID, areaID).Delete(&Subscription{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to unsubscribe link %d from echoarea %d: %w", linkID, areaID, result.Error)
+	}
+
+	log.Printf("Unsubscribed link %d from echoarea %d", linkID, areaID)
+	return nil
+}
+
+// GetQueuedLinks returns the ids of the links echomail is still waiting for
+func GetQueuedLinks(echomailID int64) (map[int64]bool, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	var linkIDs []int64
+	err := DB.Model(&EchomailAwaiting{}).
+		Where("echomail_id = ?", echomailID).
+		Pluck("link_id", &linkIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get links awaiting echomail %d: %w", echomailID, err)
+	}
+
+	queued := make(map[int64]bool, len(linkIDs))
+	for _, id := range linkIDs {
+		queued[id] = true
+	}
+
+	return queued, nil
+}
+
+// RequeueEchomail queues a saved echomail message for links subscribed after
+// it was posted. All links must be subscribed to its echoarea; ones which
+// still have the message queued are skipped.
+func RequeueEchomail(echomailID int64, linkIDs []int64) error {
+	if DB == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+	if len(linkIDs) == 0 {
+		return nil
+	}
+
+	var echomail Echomail
+	err := DB.Select("id", "echoarea_id").First(&echomail, echomailID).Error
+	if err != nil {
+		return fmt.Errorf("failed to get echomail %d: %w", echomailID, err)
+	}
+
+	var subscribed []int64
+	err = DB.Model(&Subscription{}).
+		Where("echoarea_id = ? AND link_id IN ?", echomail.EchoareaID, linkIDs).
+		Pluck("link_id", &subscribed).Error
+	if err != nil {
+		return fmt.Errorf("failed to get subscriptions for echoarea %d: %w", echomail.EchoareaID, err)
+	}
+	isSubscribed := make(map[int64]bool, len(subscribed))
+	for _, id := range subscribed {
+		isSubscribed[id] = true
+	}
+	for _, id := range linkIDs {
+		if !isSubscribed[id] {
+			return fmt.Errorf("link %d, echoarea %d: %w", id, echomail.EchoareaID, ErrNotSubscribed)
+		}
+	}
+
+	queued, err := GetQueuedLinks(echomailID)
+	if err != nil {
+		return err
+	}
+	var awaitingEntries []EchomailAwaiting
+	for _, id := range linkIDs {
+		if queued[id] {
+			continue
+		}
+		queued[id] = true
+		awaitingEntries = append(awaitingEntries, EchomailAwaiting{
+			LinkID:     id,
+			EchomailID: echomailID,
+		})
+	}
+	if len(awaitingEntries) == 0 {
+		return nil
+	}
+
+	err = DB.Omit(clause.Associations).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&awaitingEntries).Error
+	if err != nil {
+		return fmt.Errorf("failed to requeue echomail %d: %w", echomailID, err)
+	}
+
+	log.Printf("Requeued echomail %d for %d links", echomailID, len(awaitingEntries))
+	return nil
+}
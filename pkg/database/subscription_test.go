@@ -0,0 +1,118 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/franela/goblin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestGetSubscriptionCounts(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check GetSubscriptionCounts()", func() {
+		var areas []Echoarea
+		g.Before(func() {
+			db, err := gorm.Open(sqlite.Dialector{DriverName: "sqlite", DSN: "file::memory:"},
+				&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+			g.Assert(err).IsNil()
+			sqlDB, _ := db.DB()
+			sqlDB.SetMaxOpenConns(1)
+			g.Assert(db.AutoMigrate(&Link{}, &Echoarea{}, &Subscription{})).IsNil()
+			for _, addr := range []string{"2:5020/1", "2:5020/2"} {
+				g.Assert(db.Create(&Link{StationName: addr, FtnAddress: addr}).Error).IsNil()
+			}
+			for _, name := range []string{"su.general", "ru.golang", "r50.sysop"} {
+				area := Echoarea{Name: name}
+				g.Assert(db.Create(&area).Error).IsNil()
+				areas = append(areas, area)
+			}
+			DB = db
+		})
+		g.After(func() {
+			CloseDatabase()
+			DB = nil
+		})
+		g.It("check no subscriptions", func() {
+			counts, err := GetSubscriptionCounts()
+			g.Assert(err).IsNil()
+			g.Assert(len(counts)).Equal(0)
+		})
+		g.It("check counts follow subscribe and unsubscribe", func() {
+			links, err := GetAllLinks()
+			g.Assert(err).IsNil()
+			for _, link := range links {
+				g.Assert(SubscribeLink(link.ID, areas[0].ID)).IsNil()
+			}
+			g.Assert(SubscribeLink(links[1].ID, areas[1].ID)).IsNil()
+			counts, err := GetSubscriptionCounts()
+			g.Assert(err).IsNil()
+			g.Assert(counts[areas[0].ID]).Equal(2)
+			g.Assert(counts[areas[1].ID]).Equal(1)
+			_, ok := counts[areas[2].ID]
+			g.Assert(ok).IsFalse()
+
+			g.Assert(UnsubscribeLink(links[0].ID, areas[0].ID)).IsNil()
+			counts, err = GetSubscriptionCounts()
+			g.Assert(err).IsNil()
+			g.Assert(counts[areas[0].ID]).Equal(1)
+		})
+	})
+}
+
+func TestRequeueEchomail(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check RequeueEchomail()", func() {
+		var links []Link
+		var echomail Echomail
+		g.Before(func() {
+			db, err := gorm.Open(sqlite.Dialector{DriverName: "sqlite", DSN: "file::memory:"},
+				&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+			g.Assert(err).IsNil()
+			sqlDB, _ := db.DB()
+			sqlDB.SetMaxOpenConns(1)
+			g.Assert(db.AutoMigrate(&Link{}, &Echoarea{}, &Subscription{}, &Echomail{}, &EchomailAwaiting{})).IsNil()
+			for _, addr := range []string{"2:5020/1", "2:5020/2", "2:5020/3"} {
+				link := Link{StationName: addr, FtnAddress: addr}
+				g.Assert(db.Create(&link).Error).IsNil()
+				links = append(links, link)
+			}
+			area := Echoarea{Name: "su.general"}
+			g.Assert(db.Create(&area).Error).IsNil()
+			echomail = Echomail{EchoareaID: area.ID, FromName: "Sysop", ToName: "All", FromFtnAddr: "2:5020/9696"}
+			g.Assert(db.Create(&echomail).Error).IsNil()
+			DB = db
+			g.Assert(SubscribeLink(links[0].ID, area.ID)).IsNil()
+			g.Assert(SubscribeLink(links[1].ID, area.ID)).IsNil()
+			g.Assert(db.Omit("Link", "Echomail").Create(&EchomailAwaiting{LinkID: links[0].ID, EchomailID: echomail.ID}).Error).IsNil()
+		})
+		g.After(func() {
+			CloseDatabase()
+			DB = nil
+		})
+		g.It("check queueing a link subscribed later", func() {
+			g.Assert(RequeueEchomail(echomail.ID, []int64{links[1].ID})).IsNil()
+			queued, err := GetQueuedLinks(echomail.ID)
+			g.Assert(err).IsNil()
+			g.Assert(len(queued)).Equal(2)
+			g.Assert(queued[links[1].ID]).IsTrue()
+		})
+		g.It("check already queued links are skipped", func() {
+			g.Assert(RequeueEchomail(echomail.ID, []int64{links[0].ID, links[1].ID, links[1].ID})).IsNil()
+			var count int64
+			DB.Model(&EchomailAwaiting{}).Where("echomail_id = ?", echomail.ID).Count(&count)
+			g.Assert(count).Equal(int64(2))
+		})
+		g.It("check links not subscribed are refused", func() {
+			err := RequeueEchomail(echomail.ID, []int64{links[2].ID})
+			g.Assert(errors.Is(err, ErrNotSubscribed)).IsTrue()
+			queued, _ := GetQueuedLinks(echomail.ID)
+			g.Assert(queued[links[2].ID]).IsFalse()
+		})
+		g.It("check unknown echomail", func() {
+			g.Assert(RequeueEchomail(echomail.ID+1, []int64{links[0].ID}) == nil).IsFalse()
+		})
+	})
+}
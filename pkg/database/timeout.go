@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrTimeout is returned by statements which ran longer than the query
+// timeout, so the UI can tell a slow database from other errors
+var ErrTimeout = errors.New("database timeout")
+
+// queryTimeout bounds each statement, set from the database configuration
+// by InitDatabase; 0 disables it
+var queryTimeout time.Duration
+
+// timeoutKey stores the timeoutState of a statement in its settings
+const timeoutKey = "gossiped:timeout"
+
+// rowTimeoutKey stores the timeoutState of the last row statement of a
+// chain, released when the chain runs its next statement
+const rowTimeoutKey = "gossiped:row_timeout"
+
+// timeoutState is the context a statement ran with before its timeout
+type timeoutState struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// SetQueryTimeout replaces the timeout of the statements of connections set
+// up with RegisterTimeout
+func SetQueryTimeout(d time.Duration) {
+	queryTimeout = d
+}
+
+// IsTimeout returns true if err comes from a statement which ran out of time
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrTimeout) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// timeoutError wraps the error of a statement which ran out of time in
+// ErrTimeout, other errors are returned as they are
+func timeoutError(err error) error {
+	if err == nil || errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w after %v: %w", ErrTimeout, queryTimeout, err)
+}
+
+// RegisterTimeout runs every statement of db with a context expiring after
+// the query timeout, unless the caller gave it a deadline already. Create,
+// query, update, delete and raw statements release the context when done;
+// row statements keep it for their rows, which are read afterwards, until
+// the next statement of their chain or the timeout.
+func RegisterTimeout(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("*").Register("gossiped:timeout_start", startTimeout),
+		cb.Create().After("*").Register("gossiped:timeout_end", endTimeout),
+		cb.Query().Before("*").Register("gossiped:timeout_start", startTimeout),
+		cb.Query().After("*").Register("gossiped:timeout_end", endTimeout),
+		cb.Update().Before("*").Register("gossiped:timeout_start", startTimeout),
+		cb.Update().After("*").Register("gossiped:timeout_end", endTimeout),
+		cb.Delete().Before("*").Register("gossiped:timeout_start", startTimeout),
+		cb.Delete().After("*").Register("gossiped:timeout_end", endTimeout),
+		cb.Raw().Before("*").Register("gossiped:timeout_start", startTimeout),
+		cb.Raw().After("*").Register("gossiped:timeout_end", endTimeout),
+		cb.Row().Before("*").Register("gossiped:timeout_start", startTimeout),
+		cb.Row().After("*").Register("gossiped:timeout_end", endRowTimeout),
+	} {
+		if err != nil {
+			return fmt.Errorf("failed to register query timeout: %w", err)
+		}
+	}
+	return nil
+}
+
+// startTimeout gives the statement a context ending after the query timeout
+func startTimeout(db *gorm.DB) {
+	// the rows of the last row statement of the chain are read by now
+	if v, ok := db.Statement.Settings.LoadAndDelete(rowTimeoutKey); ok {
+		v.(timeoutState).cancel()
+	}
+	parent := db.Statement.Context
+	if queryTimeout <= 0 || parent == nil {
+		return
+	}
+	if _, ok := parent.Deadline(); ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(parent, queryTimeout)
+	db.Statement.Settings.Store(timeoutKey, timeoutState{parent: parent, ctx: ctx, cancel: cancel})
+	db.Statement.Context = ctx
+}
+
+// endTimeout releases the context of the statement, restoring the previous
+// one for further statements of a reused chain
+func endTimeout(db *gorm.DB) {
+	if st, ok := restoreContext(db); ok {
+		st.cancel()
+	}
+}
+
+// endRowTimeout restores the previous context of a row statement. Its rows
+// still use the timed one, kept to be released by the next statement of
+// the chain, see startTimeout, or right away if the statement failed.
+func endRowTimeout(db *gorm.DB) {
+	st, ok := restoreContext(db)
+	if !ok {
+		return
+	}
+	if db.Error != nil {
+		st.cancel()
+		return
+	}
+	db.Statement.Settings.Store(rowTimeoutKey, st)
+}
+
+// restoreContext puts back the context the statement had before its timeout
+// and wraps an error of running out of time in ErrTimeout
+func restoreContext(db *gorm.DB) (timeoutState, bool) {
+	v, ok := db.Statement.Settings.LoadAndDelete(timeoutKey)
+	if !ok {
+		return timeoutState{}, false
+	}
+	st := v.(timeoutState)
+	db.Statement.Context = st.parent
+	if db.Error != nil && !errors.Is(db.Error, ErrTimeout) && errors.Is(st.ctx.Err(), context.DeadlineExceeded) {
+		db.Error = fmt.Errorf("%w after %v: %w", ErrTimeout, queryTimeout, db.Error)
+	}
+	return st, true
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/franela/goblin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check query timeout", func() {
+		var db *gorm.DB
+		saved := queryTimeout
+		g.Before(func() {
+			var err error
+			// the driver drops a connection interrupted by the timeout, so
+			// the test needs a database file which outlives it
+			db, err = gorm.Open(sqlite.Dialector{DriverName: "sqlite", DSN: filepath.Join(t.TempDir(), "timeout.db")},
+				&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+			g.Assert(err).IsNil()
+			sqlDB, _ := db.DB()
+			sqlDB.SetMaxOpenConns(1)
+			g.Assert(RegisterTimeout(db)).IsNil()
+			g.Assert(db.AutoMigrate(&Echoarea{})).IsNil()
+			SetQueryTimeout(100 * time.Millisecond)
+		})
+		g.After(func() {
+			SetQueryTimeout(saved)
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		})
+		g.It("check slow statement times out", func() {
+			var n int64
+			err := db.Raw("WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM c) SELECT count(*) FROM c").Scan(&n).Error
+			g.Assert(IsTimeout(timeoutError(err))).IsTrue()
+			g.Assert(errors.Is(timeoutError(err), ErrTimeout)).IsTrue()
+		})
+		g.It("check the connection works after a timeout", func() {
+			g.Assert(db.Create(&Echoarea{Name: "su.general"}).Error).IsNil()
+			var areas []Echoarea
+			q := db.Where("name = ?", "su.general")
+			g.Assert(q.Find(&areas).Error).IsNil()
+			g.Assert(len(areas)).Equal(1)
+			// a reused chain does not keep the context of the last statement
+			var n int64
+			g.Assert(q.Model(&Echoarea{}).Count(&n).Error).IsNil()
+			g.Assert(n).Equal(int64(1))
+		})
+		g.It("check the context of a row statement is released by the next one", func() {
+			q := db.Model(&Echoarea{}).Where("name = ?", "su.general")
+			var n int64
+			g.Assert(q.Select("count(*)").Row().Scan(&n)).IsNil()
+			g.Assert(n).Equal(int64(1))
+			v, ok := q.Statement.Settings.Load(rowTimeoutKey)
+			g.Assert(ok).IsTrue()
+			rowCtx := v.(timeoutState).ctx
+			g.Assert(rowCtx.Err()).IsNil()
+			var areas []Echoarea
+			g.Assert(q.Select("*").Find(&areas).Error).IsNil()
+			g.Assert(errors.Is(rowCtx.Err(), context.Canceled)).IsTrue()
+			_, ok = q.Statement.Settings.Load(rowTimeoutKey)
+			g.Assert(ok).IsFalse()
+		})
+		g.It("check other errors are kept", func() {
+			g.Assert(IsTimeout(timeoutError(gorm.ErrRecordNotFound))).IsFalse()
+			g.Assert(timeoutError(nil)).IsNil()
+		})
+	})
+}
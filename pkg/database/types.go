@@ -52,6 +52,11 @@ type DatabaseConfig struct {
 	MaxOpenConns    int           `yaml:"max_open_conns"`    // Maximum open connections
 	MaxIdleConns    int           `yaml:"max_idle_conns"`    // Maximum idle connections
 	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"` // Connection max lifetime
+	AutoMigrate     bool          `yaml:"auto_migrate"`      // Create jnode schema if missing
+	Retries         int           `yaml:"retries"`           // Retries of queries failing with a transient error, negative disables
+	RetryBackoff    time.Duration `yaml:"retry_backoff"`     // Wait before the first retry, doubled for each next one
+	RetryMaxBackoff time.Duration `yaml:"retry_max_backoff"` // Upper bound of the wait between retries
+	QueryTimeout    time.Duration `yaml:"query_timeout"`     // Limit of each statement, negative disables
 }
 
 // DefaultDatabaseConfig returns default database configuration
@@ -62,5 +67,9 @@ func DefaultDatabaseConfig() DatabaseConfig {
 		MaxOpenConns:    25,
 		MaxIdleConns:    5,
 		ConnMaxLifetime: 5 * time.Minute,
+		Retries:         3,
+		RetryBackoff:    200 * time.Millisecond,
+		RetryMaxBackoff: 5 * time.Second,
+		QueryTimeout:    30 * time.Second,
 	}
 }
@@ -0,0 +1,91 @@
+package msgapi
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/database"
+)
+
+// sentNetmailBefore returns the netmail jnode has marked sent and which was
+// written more than days ago
+func sentNetmailBefore(days int) ([]database.Netmail, error) {
+	sent, err := database.GetSentNetmail()
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+	var old []database.Netmail
+	for _, nm := range sent {
+		if nm.Send && dateHelper.FromUnixTime(nm.Date).Before(cutoff) {
+			old = append(old, nm)
+		}
+	}
+	return old, nil
+}
+
+// CountArchivableNetmail returns the number of sent netmail messages older
+// than days, which ArchiveSentNetmail would archive
+func CountArchivableNetmail(days int) (int, error) {
+	old, err := sentNetmailBefore(days)
+	return len(old), err
+}
+
+// ArchiveSentNetmail appends the sent netmail older than days to the mbox
+// file at path and deletes it from the database once it is written. Unsent
+// netmail is never touched. It returns the number of archived messages.
+func (a *SQLArea) ArchiveSentNetmail(days int, path string) (int, error) {
+	if a.areaType != EchoAreaTypeNetmail {
+		return 0, fmt.Errorf("area %s is not netmail", a.areaName)
+	}
+	old, err := sentNetmailBefore(days)
+	if err != nil || len(old) == 0 {
+		return 0, err
+	}
+
+	var areaPtr AreaPrimitive = a
+	var buf bytes.Buffer
+	ids := make([]int64, 0, len(old))
+	for i := range old {
+		msg := a.netmailMessage(&old[i], 0, 0)
+		msg.AreaObject = &areaPtr
+		var text bytes.Buffer
+		if err := msg.ToRFC822(&text); err != nil {
+			return 0, err
+		}
+		fmt.Fprintf(&buf, "From %s %s\n", old[i].FromAddress, msg.DateWritten.UTC().Format(time.ANSIC))
+		// mboxrd quoting of body lines which would read as a separator
+		buf.Write(bytes.ReplaceAll(text.Bytes(), []byte("\nFrom "), []byte("\n>From ")))
+		buf.WriteString("\n")
+		ids = append(ids, old[i].ID)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("error opening netmail archive: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return 0, fmt.Errorf("error writing netmail archive: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return 0, fmt.Errorf("error writing netmail archive: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return 0, fmt.Errorf("error writing netmail archive: %w", err)
+	}
+
+	deleted, err := database.DeleteSentNetmail(ids)
+	if err != nil {
+		return 0, err
+	}
+	a.messageListValid = false
+	decrementMessageCountBy(0, true, deleted)
+
+	log.Printf("Archived %d sent netmail messages to %s", deleted, path)
+	return int(deleted), nil
+}
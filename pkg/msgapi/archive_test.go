@@ -0,0 +1,70 @@
+package msgapi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/database"
+	. "github.com/franela/goblin"
+)
+
+func TestArchiveSentNetmail(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check ArchiveSentNetmail()", func() {
+		echo := newTestSQLArea(t, 1)
+		netmail := NewSQLNetmailArea(echo.db)
+		path := filepath.Join(t.TempDir(), "netmail.mbox")
+		g.Before(func() {
+			g.Assert(echo.db.AutoMigrate(&database.Netmail{})).IsNil()
+			old := dateHelper.ToUnixTime(time.Now().AddDate(0, 0, -60))
+			recent := dateHelper.ToUnixTime(time.Now().AddDate(0, 0, -1))
+			for _, nm := range []database.Netmail{
+				{Subject: "old sent", Date: old, Send: true, Text: "From the archive\nbye\n"},
+				{Subject: "old unsent", Date: old, Send: false},
+				{Subject: "recent sent", Date: recent, Send: true},
+			} {
+				nm.FromName, nm.ToName = "Sysop", "Alexander Skovpen"
+				nm.FromAddress, nm.ToAddress = "2:5020/9696", "2:5020/9696.1"
+				g.Assert(echo.db.Create(&nm).Error).IsNil()
+			}
+			database.DB = echo.db
+		})
+		g.After(func() {
+			database.DB = nil
+		})
+		g.It("check only old sent netmail is counted", func() {
+			n, err := CountArchivableNetmail(30)
+			g.Assert(err).IsNil()
+			g.Assert(n).Equal(1)
+		})
+		g.It("check archive and delete", func() {
+			n, err := netmail.ArchiveSentNetmail(30, path)
+			g.Assert(err).IsNil()
+			g.Assert(n).Equal(1)
+
+			data, err := os.ReadFile(path)
+			g.Assert(err).IsNil()
+			g.Assert(strings.HasPrefix(string(data), "From 2:5020/9696 ")).IsTrue()
+			g.Assert(strings.Contains(string(data), "Subject: old sent")).IsTrue()
+			g.Assert(strings.Contains(string(data), "\n>From the archive")).IsTrue()
+
+			var left []database.Netmail
+			g.Assert(echo.db.Order("id ASC").Find(&left).Error).IsNil()
+			g.Assert(len(left)).Equal(2)
+			g.Assert(left[0].Subject).Equal("old unsent")
+			g.Assert(left[1].Subject).Equal("recent sent")
+		})
+		g.It("check nothing left to archive", func() {
+			n, err := netmail.ArchiveSentNetmail(30, path)
+			g.Assert(err).IsNil()
+			g.Assert(n).Equal(0)
+		})
+		g.It("check echo areas are refused", func() {
+			_, err := echo.ArchiveSentNetmail(30, path)
+			g.Assert(err == nil).IsFalse()
+		})
+	})
+}
@@ -2,12 +2,26 @@ package msgapi
 
 import (
 	"cmp"
+	"errors"
+	"fmt"
 	"slices"
 	"strings"
 
 	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/utils"
 )
 
+// ErrMsgOutOfRange is returned by GetMsg for a position past the last
+// message of the area, or for any position of an empty area
+var ErrMsgOutOfRange = errors.New("message position out of range")
+
+// ErrMsgNotFound is returned by GetMsgByID for an id not in the area
+var ErrMsgNotFound = errors.New("message not found")
+
+// ErrReadOnly is returned by CanPost for areas new messages can not be
+// posted to
+var ErrReadOnly = errors.New("read only")
+
 // EchoAreaMsgType Area msg base type
 type EchoAreaMsgType string
 
@@ -43,12 +57,59 @@ const (
 	EchoAreaTypeNone          EchoAreaType    = 5
 )
 
+// String returns the area type name
+func (t EchoAreaType) String() string {
+	switch t {
+	case EchoAreaTypeNetmail:
+		return "Netmail"
+	case EchoAreaTypeEcho:
+		return "Echo"
+	case EchoAreaTypeLocal:
+		return "Local"
+	case EchoAreaTypeDupe:
+		return "Dupe"
+	case EchoAreaTypeBad:
+		return "Bad"
+	}
+	return "None"
+}
+
+// Letter returns the one letter shown for the area type in the area list,
+// empty for EchoAreaTypeNone and unknown types
+func (t EchoAreaType) Letter() string {
+	name := t.String()
+	if name == "None" {
+		return ""
+	}
+	return name[:1]
+}
+
+// AreaCaps are the operations an area supports, as bit flags
+type AreaCaps uint8
+
+// area capabilities
+const (
+	AreaCanPost AreaCaps = 1 << iota
+	AreaCanDelete
+	AreaCanEdit
+	AreaIsNetmail
+	AreaReadOnly
+)
+
+// Has returns true if all of caps are set
+func (c AreaCaps) Has(caps AreaCaps) bool {
+	return c&caps == caps
+}
+
 // AreaPrimitive interface
 type AreaPrimitive interface {
 	Init()
 	GetCount() uint32
 	GetLast() uint32
 	GetMsg(position uint32) (*Message, error)
+	// GetMsgByID returns the message with a stable database id, nil
+	// without an error in areas which have no such ids
+	GetMsgByID(id int64) (*Message, error)
 	GetName() string
 	GetMsgType() EchoAreaMsgType
 	GetType() EchoAreaType
@@ -58,12 +119,44 @@ type AreaPrimitive interface {
 	DelMsg(uint32) error
 	SaveMsg(*Message) error
 	GetMessages() *[]MessageListItem
+	// Capabilities returns the operations the area supports
+	Capabilities() AreaCaps
 	// Line ending handling methods
 	GetStorageLineEnding() string
 	NormalizeForStorage(body string) string
 	NormalizeFromStorage(body string) string
 }
 
+// CanPost returns an error wrapping ErrReadOnly if new messages can not be
+// posted to area: bad and dupe areas, which only hold what the tosser put
+// aside, and jnode-sql areas needing a higher level than write_level
+func CanPost(area AreaPrimitive) error {
+	switch t := area.GetType(); t {
+	case EchoAreaTypeBad, EchoAreaTypeDupe:
+		return fmt.Errorf("%s is a %s area, %w", area.GetName(), t, ErrReadOnly)
+	}
+	if sqlArea, ok := area.(*SQLArea); ok {
+		if level, ok := config.GetWriteLevel(); ok && sqlArea.GetWriteLevel() > level {
+			return fmt.Errorf("%s needs write level %d, %w", area.GetName(), sqlArea.GetWriteLevel(), ErrReadOnly)
+		}
+	}
+	return nil
+}
+
+// areaCaps returns the capabilities all message bases share: posting unless
+// CanPost refuses it, which makes the area read only. Deleting and editing
+// are added by the message bases supporting them.
+func areaCaps(area AreaPrimitive) AreaCaps {
+	var caps AreaCaps
+	if area.GetType() == EchoAreaTypeNetmail {
+		caps |= AreaIsNetmail
+	}
+	if CanPost(area) != nil {
+		return caps | AreaReadOnly
+	}
+	return caps | AreaCanPost
+}
+
 func AreaHasUnreadMessages(area *AreaPrimitive) bool {
 	return (*area).GetCount()-(*area).GetLast() > 0
 }
@@ -128,10 +221,8 @@ func FilterAreas(searchText string) []FilteredArea {
 	}
 	
 	var filtered []FilteredArea
-	searchLower := strings.ToLower(searchText)
-	
 	for i, a := range Areas {
-		if strings.Contains(strings.ToLower(a.GetName()), searchLower) {
+		if utils.ContainsFold(a.GetName(), searchText) {
 			filtered = append(filtered, FilteredArea{a, i})
 		}
 	}
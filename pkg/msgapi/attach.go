@@ -0,0 +1,119 @@
+package msgapi
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/askovpen/gossiped/pkg/config"
+)
+
+// AttachAttr is the netmail attribute of a file attach (MSG_FILE), the
+// subject of such a message names the attached file
+const AttachAttr = "Att"
+
+// maxAttachPath is the longest path fitting the 72 byte FTN subject
+const maxAttachPath = 71
+
+// HasAttach reports whether the message is a file attach
+func (m *Message) HasAttach() bool {
+	return slices.Contains(m.Attrs, AttachAttr)
+}
+
+// SetAttach sets or clears the file attach attribute
+func (m *Message) SetAttach(on bool) {
+	m.Attrs = slices.DeleteFunc(m.Attrs, func(attr string) bool {
+		return attr == AttachAttr
+	})
+	if on {
+		m.Attrs = append(m.Attrs, AttachAttr)
+	}
+}
+
+// ResolveAttach checks that name is an existing regular file and returns
+// its absolute path, a leading ~ stands for the home directory
+func ResolveAttach(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("no file to attach")
+	}
+	if name == "~" || strings.HasPrefix(name, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand %s: %w", name, err)
+		}
+		name = filepath.Join(home, name[1:])
+	}
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("attached file: %w", err)
+	}
+	if !fi.Mode().IsRegular() {
+		return "", fmt.Errorf("attached file %s is not a regular file", path)
+	}
+	return path, nil
+}
+
+// AttachFile registers the file of a file attach before the message is
+// saved: the file is copied to netmail.attach_path when it is set, and the
+// subject is rewritten to the absolute path the mailer will send
+func AttachFile(m *Message) error {
+	if !m.HasAttach() {
+		return nil
+	}
+	path, err := ResolveAttach(m.Subject)
+	if err != nil {
+		return err
+	}
+	if spool := config.Config.Netmail.AttachPath; spool != "" {
+		if path, err = spoolAttach(path, spool); err != nil {
+			return err
+		}
+	}
+	if len(path) > maxAttachPath {
+		return fmt.Errorf("attached file path %s is longer than %d characters", path, maxAttachPath)
+	}
+	m.Subject = path
+	return nil
+}
+
+// spoolAttach copies the file at path into the spool directory, refusing
+// to overwrite an attach which is still waiting there
+func spoolAttach(path, spool string) (string, error) {
+	dst, err := filepath.Abs(filepath.Join(spool, filepath.Base(path)))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve attach path: %w", err)
+	}
+	if dst == path {
+		return path, nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open attached file: %w", err)
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", fmt.Errorf("failed to create attach path: %w", err)
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to spool attached file: %w", err)
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return "", fmt.Errorf("failed to spool attached file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return "", fmt.Errorf("failed to spool attached file: %w", err)
+	}
+	return dst, nil
+}
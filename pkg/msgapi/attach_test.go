@@ -0,0 +1,65 @@
+package msgapi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	. "github.com/franela/goblin"
+)
+
+func TestAttach(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check netmail file attach", func() {
+		dir := t.TempDir()
+		file := filepath.Join(dir, "nodelist.zip")
+		g.Before(func() {
+			g.Assert(os.WriteFile(file, []byte("PK"), 0644)).IsNil()
+		})
+		g.After(func() {
+			config.Config.Netmail.AttachPath = ""
+		})
+		g.It("check SetAttach()", func() {
+			m := &Message{Attrs: []string{"Pvt"}}
+			g.Assert(m.HasAttach()).IsFalse()
+			m.SetAttach(true)
+			m.SetAttach(true)
+			g.Assert(m.Attrs).Equal([]string{"Pvt", "Att"})
+			m.SetAttach(false)
+			g.Assert(m.Attrs).Equal([]string{"Pvt"})
+		})
+		g.It("check ResolveAttach()", func() {
+			path, err := ResolveAttach(" " + file + " ")
+			g.Assert(err).IsNil()
+			g.Assert(path).Equal(file)
+			_, err = ResolveAttach(filepath.Join(dir, "missing.zip"))
+			g.Assert(os.IsNotExist(errors.Unwrap(err))).IsTrue()
+			_, err = ResolveAttach(dir)
+			g.Assert(err == nil).IsFalse()
+			_, err = ResolveAttach("")
+			g.Assert(err == nil).IsFalse()
+		})
+		g.It("check AttachFile() keeps plain netmail", func() {
+			m := &Message{Subject: "Hello"}
+			g.Assert(AttachFile(m)).IsNil()
+			g.Assert(m.Subject).Equal("Hello")
+		})
+		g.It("check AttachFile() spools the file", func() {
+			spool := filepath.Join(dir, "spool")
+			config.Config.Netmail.AttachPath = spool
+			m := &Message{Subject: file, Attrs: []string{"Att"}}
+			g.Assert(AttachFile(m)).IsNil()
+			g.Assert(m.Subject).Equal(filepath.Join(spool, "nodelist.zip"))
+			data, err := os.ReadFile(m.Subject)
+			g.Assert(err).IsNil()
+			g.Assert(string(data)).Equal("PK")
+
+			// a waiting attach of the same name is not overwritten
+			m = &Message{Subject: file, Attrs: []string{"Att"}}
+			g.Assert(AttachFile(m) == nil).IsFalse()
+			g.Assert(m.Subject).Equal(file)
+		})
+	})
+}
@@ -0,0 +1,115 @@
+package msgapi
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/askovpen/gossiped/pkg/database"
+	"gorm.io/gorm"
+)
+
+// maxBatchDelete bounds the id list of a single DELETE statement, below the
+// SQLite default limit of 999 bound parameters
+const maxBatchDelete = 500
+
+// DelMsgs deletes the messages at positions from area. SQL areas delete them
+// in one statement per maxBatchDelete messages, other bases one by one from
+// the highest position down so the remaining positions stay valid.
+func DelMsgs(area AreaPrimitive, positions []uint32) error {
+	positions = sortedPositions(positions)
+	if sqlArea, ok := area.(*SQLArea); ok {
+		return sqlArea.delMsgs(positions)
+	}
+	for i := len(positions) - 1; i >= 0; i-- {
+		if err := area.DelMsg(positions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MoveMessages moves the messages at positions of src into dst one by one
+// with MoveMessage, which copies and deletes each in one step, so a failure
+// leaves no message in both areas. Moving into src itself is refused.
+func MoveMessages(src AreaPrimitive, positions []uint32, dst AreaPrimitive) error {
+	if src == dst || src.GetName() == dst.GetName() {
+		return fmt.Errorf("source and destination area are the same")
+	}
+	// Each move shifts the messages after it one position down
+	for i, pos := range sortedPositions(positions) {
+		if err := MoveMessage(src, pos-uint32(i), dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedPositions returns positions sorted ascending without duplicates
+// and the invalid position 0
+func sortedPositions(positions []uint32) []uint32 {
+	positions = slices.Clone(positions)
+	slices.Sort(positions)
+	positions = slices.Compact(positions)
+	if len(positions) > 0 && positions[0] == 0 {
+		positions = positions[1:]
+	}
+	return positions
+}
+
+// positionIDs maps sorted message positions of the area to row ids
+func (a *SQLArea) positionIDs(positions []uint32) ([]int64, error) {
+	var ids []int64
+	last := int(positions[len(positions)-1])
+	var err error
+	if a.areaType == EchoAreaTypeNetmail {
+		err = a.db.Raw("SELECT id FROM netmail ORDER BY id ASC LIMIT ?", last).Scan(&ids).Error
+	} else {
+		err = a.db.Raw("SELECT id FROM echomail WHERE echoarea_id = ? ORDER BY id ASC LIMIT ?",
+			a.areaID, last).Scan(&ids).Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error finding messages to delete: %w", err)
+	}
+	res := make([]int64, 0, len(positions))
+	for _, pos := range positions {
+		if int(pos) > len(ids) {
+			return nil, fmt.Errorf("message %d not found in area %s", pos, a.areaName)
+		}
+		res = append(res, ids[pos-1])
+	}
+	return res, nil
+}
+
+// delMsgs deletes the messages at sorted positions in one transaction and
+// updates the message list and count caches once
+func (a *SQLArea) delMsgs(positions []uint32) error {
+	if len(positions) == 0 {
+		return nil
+	}
+	ids, err := a.positionIDs(positions)
+	if err != nil {
+		return err
+	}
+	var model interface{} = &database.Echomail{}
+	if a.areaType == EchoAreaTypeNetmail {
+		model = &database.Netmail{}
+	}
+	err = a.db.Transaction(func(tx *gorm.DB) error {
+		for chunk := range slices.Chunk(ids, maxBatchDelete) {
+			if err := tx.Where("id IN ?", chunk).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting messages: %w", err)
+	}
+
+	a.messageListValid = false
+	decrementMessageCountBy(a.areaID, a.areaType == EchoAreaTypeNetmail, int64(len(ids)))
+
+	log.Printf("Deleted %d messages from area %s", len(ids), a.areaName)
+	return nil
+}
@@ -0,0 +1,79 @@
+package msgapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/config"
+)
+
+// Draft is an in-progress message saved per area
+type Draft struct {
+	Area     string
+	From     string
+	FromAddr string
+	To       string
+	ToAddr   string
+	Subject  string
+	Body     string
+	Saved    time.Time
+}
+
+// draftFile returns the draft file path for the area
+func draftFile(areaName string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.ToLower(areaName))
+	return filepath.Join(config.Config.Drafts.Path, name+".json")
+}
+
+// SaveDraft stores the draft for its area, replacing any previous one
+func SaveDraft(d *Draft) error {
+	if err := os.MkdirAll(config.Config.Drafts.Path, 0700); err != nil {
+		return fmt.Errorf("cannot create drafts directory: %w", err)
+	}
+	d.Saved = time.Now()
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	fn := draftFile(d.Area)
+	// Write to a temporary file first so a crash never leaves a partial draft
+	if err := os.WriteFile(fn+".tmp", b, 0600); err != nil {
+		return fmt.Errorf("cannot save draft for area %s: %w", d.Area, err)
+	}
+	return os.Rename(fn+".tmp", fn)
+}
+
+// LoadDraft returns the saved draft for the area or nil if there is none
+func LoadDraft(areaName string) (*Draft, error) {
+	b, err := os.ReadFile(draftFile(areaName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read draft for area %s: %w", areaName, err)
+	}
+	var d Draft
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, fmt.Errorf("cannot parse draft for area %s: %w", areaName, err)
+	}
+	return &d, nil
+}
+
+// DiscardDraft removes the saved draft for the area
+func DiscardDraft(areaName string) error {
+	err := os.Remove(draftFile(areaName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cannot discard draft for area %s: %w", areaName, err)
+	}
+	return nil
+}
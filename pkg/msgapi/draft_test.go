@@ -0,0 +1,41 @@
+package msgapi
+
+import (
+	"testing"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	. "github.com/franela/goblin"
+)
+
+func TestDraft(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check drafts", func() {
+		config.Config.Drafts.Path = t.TempDir()
+		g.It("check LoadDraft() without draft", func() {
+			d, err := LoadDraft("ru.golang")
+			g.Assert(err).IsNil()
+			g.Assert(d == nil).IsTrue()
+		})
+		g.It("check SaveDraft()/LoadDraft()", func() {
+			err := SaveDraft(&Draft{Area: "RU.GoLang", From: "Alexander", To: "All", Subject: "Привет", Body: "line1\nline2"})
+			g.Assert(err).IsNil()
+			d, err := LoadDraft("ru.golang")
+			g.Assert(err).IsNil()
+			g.Assert(d.Subject).Equal("Привет")
+			g.Assert(d.Body).Equal("line1\nline2")
+			g.Assert(d.Saved.IsZero()).IsFalse()
+		})
+		g.It("check DiscardDraft()", func() {
+			g.Assert(DiscardDraft("ru.golang")).IsNil()
+			d, _ := LoadDraft("ru.golang")
+			g.Assert(d == nil).IsTrue()
+			g.Assert(DiscardDraft("ru.golang")).IsNil()
+		})
+		g.It("check area names are safe file names", func() {
+			g.Assert(SaveDraft(&Draft{Area: "../netmail"})).IsNil()
+			d, err := LoadDraft("../netmail")
+			g.Assert(err).IsNil()
+			g.Assert(d.Area).Equal("../netmail")
+		})
+	})
+}
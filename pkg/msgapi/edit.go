@@ -0,0 +1,207 @@
+package msgapi
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/askovpen/gossiped/pkg/types"
+	"gorm.io/gorm/clause"
+)
+
+// splitStoredText splits stored message text into the kludge lines before
+// the text, the text and the kludge lines after it (e.g. Via)
+func (a *SQLArea) splitStoredText(text string) (head, body, tail []string) {
+	lines := strings.Split(strings.TrimRight(a.NormalizeFromStorage(text), ftnLineEnding), ftnLineEnding)
+	start := 0
+	for start < len(lines) && strings.HasPrefix(lines[start], "\x01") {
+		start++
+	}
+	end := len(lines)
+	for end > start && strings.HasPrefix(lines[end-1], "\x01") {
+		end--
+	}
+	return lines[:start], lines[start:end], lines[end:]
+}
+
+// GetStoredMsg returns the message at position as stored, for editing: the
+// header fields as UTF-8 and the text with \n line endings and without
+// kludge lines
+func (a *SQLArea) GetStoredMsg(position uint32) (*Message, error) {
+	if position == 0 {
+		position = 1
+	}
+	var areaPtr AreaPrimitive = a
+	msg := &Message{
+		Area:       a.areaName,
+		AreaObject: &areaPtr,
+		MsgNum:     position,
+		MaxNum:     a.GetCount(),
+		Kludges:    make(map[string]string),
+	}
+	var text string
+	if a.areaType == EchoAreaTypeNetmail {
+		var netmail database.Netmail
+		err := a.db.Order("id ASC").Offset(int(position - 1)).Limit(1).First(&netmail).Error
+		if err != nil {
+			return nil, fmt.Errorf("error finding netmail message to edit: %w", err)
+		}
+		msg.From, msg.To, msg.Subject = netmail.FromName, netmail.ToName, netmail.Subject
+		msg.FromAddr = types.AddrFromString(netmail.FromAddress)
+		msg.ToAddr = types.AddrFromString(netmail.ToAddress)
+		msg.DateWritten = dateHelper.FromUnixTime(netmail.Date)
+		msg.Attrs = a.parseNetmailAttrs(netmail.Attr)
+		text = netmail.Text
+	} else {
+		var echomail database.Echomail
+		err := a.db.Where("echoarea_id = ?", a.areaID).Order("id ASC").Offset(int(position - 1)).Limit(1).First(&echomail).Error
+		if err != nil {
+			return nil, fmt.Errorf("error finding echomail message to edit: %w", err)
+		}
+		msg.From, msg.To, msg.Subject = echomail.FromName, echomail.ToName, echomail.Subject
+		msg.FromAddr = types.AddrFromString(echomail.FromFtnAddr)
+		msg.DateWritten = dateHelper.FromUnixTime(echomail.Date)
+		if echomail.MsgID != "" {
+			msg.Kludges["MSGID:"] = echomail.MsgID
+		}
+		text = echomail.Message
+	}
+	if msg.FromAddr == nil {
+		msg.FromAddr = &types.FidoAddr{}
+	}
+	if msg.ToAddr == nil {
+		msg.ToAddr = &types.FidoAddr{}
+	}
+	msg.DateArrived = msg.DateWritten
+	_, body, _ := a.splitStoredText(text)
+	msg.Body = strings.Join(body, "\n")
+	return msg, nil
+}
+
+// UpdateMsg replaces From, To, Subject and text of the message at position
+// in place. The stored kludge lines, id, MSGID and date are kept unless msg
+// has a different MSGID or date. Echomail is queued for the area subscribers
+// again if edit.requeue is set.
+func (a *SQLArea) UpdateMsg(position uint32, msg *Message) error {
+	if position == 0 {
+		position = 1
+	}
+	if a.areaType == EchoAreaTypeNetmail {
+		return a.updateNetmailMessage(position, msg)
+	}
+	return a.updateEchomailMessage(position, msg)
+}
+
+// storedText rebuilds stored text around the edited body, keeping the
+// kludge lines of the old text
+func (a *SQLArea) storedText(old, body string) string {
+	head, _, tail := a.splitStoredText(old)
+	body = strings.TrimRight(a.NormalizeFromStorage(body), ftnLineEnding)
+	lines := append(append(head, strings.Split(body, ftnLineEnding)...), tail...)
+	return a.NormalizeForStorage(strings.Join(lines, ftnLineEnding))
+}
+
+// updateEchomailMessage updates an echomail message in place
+func (a *SQLArea) updateEchomailMessage(position uint32, msg *Message) error {
+	var echomail database.Echomail
+	err := a.db.Where("echoarea_id = ?", a.areaID).
+		Order("id ASC").
+		Offset(int(position - 1)).
+		Limit(1).
+		First(&echomail).Error
+	if err != nil {
+		return fmt.Errorf("error finding echomail message to update: %w", err)
+	}
+
+	updates := map[string]interface{}{
+		"from_name": msg.From,
+		"to_name":   msg.To,
+		"subject":   msg.Subject,
+		"message":   a.storedText(echomail.Message, msg.Body),
+	}
+	if msgid := msg.Kludges["MSGID:"]; msgid != "" && msgid != echomail.MsgID {
+		updates["msgid"] = msgid
+	}
+	if !msg.DateWritten.IsZero() && !msg.DateWritten.Equal(dateHelper.FromUnixTime(echomail.Date)) {
+		updates["date"] = dateHelper.ToUnixTime(msg.DateWritten)
+	}
+	if err := a.db.Model(&echomail).Omit(clause.Associations).Updates(updates).Error; err != nil {
+		return fmt.Errorf("error updating echomail message: %w", err)
+	}
+
+	if config.Config.Edit.Requeue {
+		if err := a.queueEchomailForSubscribers(a.db, echomail.ID); err != nil {
+			log.Printf("Warning: Failed to queue echomail for subscribers: %v", err)
+		}
+	}
+
+	a.messageListValid = false
+	log.Printf("Updated echomail message %d in area %s", position, a.areaName)
+	return nil
+}
+
+// updateNetmailMessage updates a netmail message in place
+func (a *SQLArea) updateNetmailMessage(position uint32, msg *Message) error {
+	var netmail database.Netmail
+	err := a.db.Order("id ASC").
+		Offset(int(position - 1)).
+		Limit(1).
+		First(&netmail).Error
+	if err != nil {
+		return fmt.Errorf("error finding netmail message to update: %w", err)
+	}
+
+	updates := map[string]interface{}{
+		"from_name":     msg.From,
+		"to_name":       msg.To,
+		"subject":       msg.Subject,
+		"text":          a.storedText(netmail.Text, msg.Body),
+		"last_modified": dateHelper.ToUnixTime(time.Now()),
+	}
+	if !msg.DateWritten.IsZero() && !msg.DateWritten.Equal(dateHelper.FromUnixTime(netmail.Date)) {
+		updates["date"] = dateHelper.ToUnixTime(msg.DateWritten)
+	}
+	// unsent netmail can be readdressed, e.g. when no route was found
+	readdressed := !netmail.Send && !msg.ToAddr.IsZero() &&
+		!msg.ToAddr.Equal(types.AddrFromString(netmail.ToAddress))
+	if readdressed {
+		updates["to_address"] = msg.ToAddr.String()
+		updates["text"] = a.readdressText(updates["text"].(string), msg.ToAddr, types.AddrFromString(netmail.FromAddress))
+	}
+	if err := a.db.Model(&netmail).Omit(clause.Associations).Updates(updates).Error; err != nil {
+		return fmt.Errorf("error updating netmail message: %w", err)
+	}
+
+	a.messageListValid = false
+	log.Printf("Updated netmail message %d", position)
+	if readdressed {
+		log.Printf("Netmail %d readdressed from %s to %s", netmail.ID, netmail.ToAddress, msg.ToAddr.String())
+		return a.RerouteNetmail(netmail.ID)
+	}
+	return nil
+}
+
+// readdressText replaces the INTL and TOPT kludges heading stored netmail
+// text with the ones of the new destination to
+func (a *SQLArea) readdressText(text string, to, from *types.FidoAddr) string {
+	if from == nil {
+		from = &types.FidoAddr{}
+	}
+	m := &Message{ToAddr: to, FromAddr: from, Kludges: make(map[string]string)}
+	m.setNetmailKludges()
+	head, body, tail := a.splitStoredText(text)
+	lines := []string{"\x01INTL " + m.Kludges["INTL"]}
+	if topt := m.Kludges["TOPT"]; topt != "" {
+		lines = append(lines, "\x01TOPT "+topt)
+	}
+	for _, l := range head {
+		if !strings.HasPrefix(l, "\x01INTL ") && !strings.HasPrefix(l, "\x01TOPT ") {
+			lines = append(lines, l)
+		}
+	}
+	lines = append(append(lines, body...), tail...)
+	return a.NormalizeForStorage(strings.Join(lines, ftnLineEnding))
+}
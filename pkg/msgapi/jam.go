@@ -7,7 +7,6 @@ import (
 	"errors"
 	"io"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/askovpen/gossiped/pkg/config"
@@ -89,15 +88,19 @@ func (j *JAM) getOffsetByNum(num uint32) (offset uint32) {
 	return 0
 }
 
+// GetMsgByID returns nil, messages of file bases have no database id
+func (j *JAM) GetMsgByID(id int64) (*Message, error) {
+	return nil, nil
+}
+
 // GetMsg return msg
 func (j *JAM) GetMsg(position uint32) (*Message, error) {
-	if len(j.indexStructure) == 0 {
-		//		return nil, errors.New("Empty Area")
-		return nil, nil
-	}
 	if position == 0 {
 		position = 1
 	}
+	if int(position) > len(j.indexStructure) {
+		return nil, ErrMsgOutOfRange
+	}
 	fJhr, err := os.Open(j.AreaPath + ".jhr")
 	if err != nil {
 		return nil, err
@@ -220,6 +223,7 @@ func (j *JAM) GetMsg(position uint32) (*Message, error) {
 	fJdt.Read(txt)
 	rm.Body += string(txt)
 	rm.Body += afterBody
+	rm.Body = j.NormalizeFromStorage(rm.Body)
 	err = rm.ParseRaw()
 	if err != nil {
 		return nil, err
@@ -566,6 +570,12 @@ func (j *JAM) GetChrs() string {
 	return j.Chrs
 }
 
+// Capabilities returns the operations the JAM base supports, deleting by
+// marking the message header deleted
+func (j *JAM) Capabilities() AreaCaps {
+	return areaCaps(j) | AreaCanDelete
+}
+
 // GetMessages get headers
 func (j *JAM) GetMessages() *[]MessageListItem {
 	if len(j.messages) > 0 || len(j.indexStructure) == 0 {
@@ -630,15 +640,14 @@ func (j *JAM) DelMsg(l uint32) error {
 
 // Line ending handling methods for JAM format
 func (ja *JAM) GetStorageLineEnding() string {
-	return "\r" // JAM stores FTN-style line endings
+	return ftnLineEnding // JAM stores FTN-style line endings
 }
 
 func (ja *JAM) NormalizeForStorage(body string) string {
-	// Convert Unix \n to FTN \r and ensure trailing \r for JAM format
-	return strings.Join(strings.Split(body, "\n"), "\x0d") + "\x0d"
+	return terminateLines(body, ja.GetStorageLineEnding())
 }
 
 func (ja *JAM) NormalizeFromStorage(body string) string {
-	// JAM already stores in FTN format, no conversion needed
-	return body
+	// lines written with \r\n or \n by other software end in \r as well
+	return convertLineEndings(body, ftnLineEnding)
 }
@@ -0,0 +1,26 @@
+package msgapi
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ftnLineEnding ends the lines of message bodies in memory, of packets and
+// of the MSG, JAM and Squish bases
+const ftnLineEnding = "\r"
+
+// lineEndings collapses \r\n, \n and \r line endings to a single one
+var lineEndings = regexp.MustCompile("\r\n|\n|\r")
+
+// convertLineEndings returns body with all its line endings turned into eol.
+// Body text is only converted here, through the NormalizeForStorage and
+// NormalizeFromStorage methods of the areas.
+func convertLineEndings(body, eol string) string {
+	return lineEndings.ReplaceAllLiteralString(body, eol)
+}
+
+// terminateLines returns body with all its line endings turned into eol and
+// ending in a single one, as stored by the bases
+func terminateLines(body, eol string) string {
+	return strings.TrimRight(convertLineEndings(body, eol), eol) + eol
+}
@@ -0,0 +1,197 @@
+package msgapi
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// EchoAreaMsgTypeMemory is the msg base type of MemoryArea
+const EchoAreaMsgTypeMemory EchoAreaMsgType = "Memory"
+
+// MemoryArea is an area kept in memory only, for tests of the area list,
+// reader and composer without a message base. Messages get database like
+// ids counting from 1 which stay with them when others are deleted.
+type MemoryArea struct {
+	AreaName string
+	AreaType EchoAreaType
+	Chrs     string
+	mu       sync.Mutex
+	msgs     []*Message
+	last     uint32
+	nextID   int64
+}
+
+// NewMemoryArea returns an empty area named name, messages are added with
+// SaveMsg
+func NewMemoryArea(name string, areaType EchoAreaType) *MemoryArea {
+	return &MemoryArea{AreaName: name, AreaType: areaType}
+}
+
+// Init for future
+func (m *MemoryArea) Init() {
+}
+
+// GetCount returns the number of messages
+func (m *MemoryArea) GetCount() uint32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return uint32(len(m.msgs))
+}
+
+// GetLast returns the position of the last read message
+func (m *MemoryArea) GetLast() uint32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last
+}
+
+// SetLast marks the message at position l as the last read one, clamped to
+// the messages of the area
+func (m *MemoryArea) SetLast(l uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.last = min(l, uint32(len(m.msgs)))
+}
+
+// copyAt returns a copy of the message at position, which callers may
+// change without touching the stored one
+func (m *MemoryArea) copyAt(position uint32) *Message {
+	msg := *m.msgs[position-1]
+	msg.MsgNum = position
+	msg.MaxNum = uint32(len(m.msgs))
+	msg.Body = m.NormalizeFromStorage(msg.Body)
+	msg.Attrs = slices.Clone(msg.Attrs)
+	msg.Kludges = maps.Clone(msg.Kludges)
+	if msg.Kludges == nil {
+		msg.Kludges = make(map[string]string)
+	}
+	return &msg
+}
+
+// GetMsg returns the message at position, counted from 1
+func (m *MemoryArea) GetMsg(position uint32) (*Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if position == 0 {
+		position = 1
+	}
+	if int(position) > len(m.msgs) {
+		return nil, fmt.Errorf("message %d of %d in %s: %w", position, len(m.msgs), m.AreaName, ErrMsgOutOfRange)
+	}
+	return m.copyAt(position), nil
+}
+
+// GetMsgByID returns the message with id
+func (m *MemoryArea) GetMsgByID(id int64) (*Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, msg := range m.msgs {
+		if msg.ID == id {
+			return m.copyAt(uint32(i + 1)), nil
+		}
+	}
+	return nil, fmt.Errorf("message id %d in %s: %w", id, m.AreaName, ErrMsgNotFound)
+}
+
+// GetName returns the area name
+func (m *MemoryArea) GetName() string {
+	return m.AreaName
+}
+
+// GetMsgType returns EchoAreaMsgTypeMemory
+func (m *MemoryArea) GetMsgType() EchoAreaMsgType {
+	return EchoAreaMsgTypeMemory
+}
+
+// GetType returns the area type
+func (m *MemoryArea) GetType() EchoAreaType {
+	return m.AreaType
+}
+
+// SetChrs sets the charset of the area
+func (m *MemoryArea) SetChrs(s string) {
+	m.Chrs = s
+}
+
+// GetChrs returns the charset of the area
+func (m *MemoryArea) GetChrs() string {
+	return m.Chrs
+}
+
+// Capabilities returns the operations of the area, as of a file base
+func (m *MemoryArea) Capabilities() AreaCaps {
+	return areaCaps(m) | AreaCanDelete
+}
+
+// SaveMsg appends a copy of msg with the next id, arrived now unless it
+// has an arrival date
+func (m *MemoryArea) SaveMsg(msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	saved := *msg
+	saved.ID = m.nextID
+	saved.Area = m.AreaName
+	saved.AreaObject = nil
+	saved.Body = m.NormalizeForStorage(msg.Body)
+	saved.Attrs = slices.Clone(msg.Attrs)
+	saved.Kludges = maps.Clone(msg.Kludges)
+	if saved.DateArrived.IsZero() {
+		saved.DateArrived = time.Now()
+	}
+	m.msgs = append(m.msgs, &saved)
+	return nil
+}
+
+// DelMsg removes the message at position l; the last read position moves
+// with the messages after it
+func (m *MemoryArea) DelMsg(l uint32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l == 0 {
+		l = 1
+	}
+	if int(l) > len(m.msgs) {
+		return fmt.Errorf("message %d of %d in %s: %w", l, len(m.msgs), m.AreaName, ErrMsgOutOfRange)
+	}
+	m.msgs = slices.Delete(m.msgs, int(l-1), int(l))
+	if m.last >= l && m.last > 0 {
+		m.last--
+	}
+	return nil
+}
+
+// GetMessages returns the message list of the area
+func (m *MemoryArea) GetMessages() *[]MessageListItem {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	items := make([]MessageListItem, 0, len(m.msgs))
+	for i, msg := range m.msgs {
+		items = append(items, MessageListItem{
+			ID:          msg.ID,
+			MsgNum:      uint32(i + 1),
+			From:        msg.From,
+			To:          msg.To,
+			Subject:     msg.Subject,
+			DateWritten: msg.DateWritten,
+		})
+	}
+	return &items
+}
+
+// Line ending handling methods for memory areas, which store FTN-style
+// line endings like the file bases
+func (m *MemoryArea) GetStorageLineEnding() string {
+	return ftnLineEnding
+}
+
+func (m *MemoryArea) NormalizeForStorage(body string) string {
+	return terminateLines(body, m.GetStorageLineEnding())
+}
+
+func (m *MemoryArea) NormalizeFromStorage(body string) string {
+	return convertLineEndings(body, ftnLineEnding)
+}
@@ -0,0 +1,71 @@
+package msgapi
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/askovpen/gossiped/pkg/types"
+	. "github.com/franela/goblin"
+)
+
+func TestMemoryArea(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check MemoryArea", func() {
+		var area AreaPrimitive = NewMemoryArea("memory.area", EchoAreaTypeEcho)
+		g.It("check empty area", func() {
+			g.Assert(area.GetCount()).Equal(uint32(0))
+			g.Assert(area.GetLast()).Equal(uint32(0))
+			_, err := area.GetMsg(1)
+			g.Assert(errors.Is(err, ErrMsgOutOfRange)).IsTrue()
+			g.Assert(len(*area.GetMessages())).Equal(0)
+		})
+		g.It("check SaveMsg() and GetMsg()", func() {
+			for i := 1; i <= 4; i++ {
+				m := &Message{AreaObject: &area, From: "SysOp", To: "All", Subject: fmt.Sprintf("Message %d", i),
+					FromAddr: types.AddrFromNum(2, 5020, 9696, 0), ToAddr: &types.FidoAddr{},
+					Body: "Hello\nWorld", Kludges: map[string]string{}}
+				g.Assert(area.SaveMsg(m.MakeBody())).IsNil()
+			}
+			g.Assert(area.GetCount()).Equal(uint32(4))
+			msg, err := area.GetMsg(2)
+			g.Assert(err).IsNil()
+			g.Assert(msg.ID).Equal(int64(2))
+			g.Assert(msg.MsgNum).Equal(uint32(2))
+			g.Assert(msg.MaxNum).Equal(uint32(4))
+			g.Assert(msg.Subject).Equal("Message 2")
+			g.Assert(msg.Body).Equal("Hello\rWorld\r")
+			g.Assert(msg.Kludges["MSGID:"] == "").IsFalse()
+			msg.Subject = "changed"
+			msg.Kludges["MSGID:"] = "changed"
+			again, _ := area.GetMsg(2)
+			g.Assert(again.Subject).Equal("Message 2")
+			g.Assert(again.Kludges["MSGID:"] == "changed").IsFalse()
+			items := *area.GetMessages()
+			g.Assert(len(items)).Equal(4)
+			g.Assert(items[3].MsgNum).Equal(uint32(4))
+			g.Assert(items[3].ID).Equal(int64(4))
+		})
+		g.It("check SetLast() is clamped", func() {
+			area.SetLast(9)
+			g.Assert(area.GetLast()).Equal(uint32(4))
+			area.SetLast(3)
+			g.Assert(area.GetLast()).Equal(uint32(3))
+			g.Assert(AreaHasUnreadMessages(&area)).IsTrue()
+		})
+		g.It("check DelMsg() keeps ids and moves the lastread", func() {
+			g.Assert(area.DelMsg(1)).IsNil()
+			g.Assert(area.GetCount()).Equal(uint32(3))
+			g.Assert(area.GetLast()).Equal(uint32(2))
+			msg, err := area.GetMsgByID(3)
+			g.Assert(err).IsNil()
+			g.Assert(msg.MsgNum).Equal(uint32(2))
+			_, err = area.GetMsgByID(1)
+			g.Assert(errors.Is(err, ErrMsgNotFound)).IsTrue()
+			g.Assert(area.DelMsg(4)).IsNotNil()
+			g.Assert(area.DelMsg(3)).IsNil()
+			g.Assert(area.GetLast()).Equal(uint32(2))
+			g.Assert(AreaHasUnreadMessages(&area)).IsFalse()
+		})
+	})
+}
@@ -1,8 +1,10 @@
 package msgapi
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,6 +16,7 @@ import (
 
 // MessageListItem struct
 type MessageListItem struct {
+	ID          int64 // database id of SQL messages, 0 in file bases
 	MsgNum      uint32
 	From        string
 	To          string
@@ -21,8 +24,19 @@ type MessageListItem struct {
 	DateWritten time.Time
 }
 
+// Matches returns true if From, To or Subject contains substr, case-insensitive
+func (mi MessageListItem) Matches(substr string) bool {
+	if substr == "" {
+		return true
+	}
+	return utils.ContainsFold(mi.From, substr) ||
+		utils.ContainsFold(mi.To, substr) ||
+		utils.ContainsFold(mi.Subject, substr)
+}
+
 // Message struct
 type Message struct {
+	ID          int64 // database id of SQL messages, 0 in file bases
 	Area        string
 	AreaObject  *AreaPrimitive
 	MsgNum      uint32
@@ -39,7 +53,11 @@ type Message struct {
 	To          string
 	Subject     string
 	Kludges     map[string]string
+	Via         []string
 	Corrupted   bool
+	AreaTag     string // AREA line of a stored echomail naming another area
+	Truncated   bool   // Body is a preview of a large jnode-sql message
+	FullSize    int    // characters of the whole stored text of a Truncated message
 }
 
 var (
@@ -49,6 +67,7 @@ var (
 // ParseRaw parse raw msg
 func (m *Message) ParseRaw() error {
 	m.Kludges = make(map[string]string)
+	m.Via = nil
 	for _, l := range strings.Split(m.Body, "\x0d") {
 		if len(l) > 5 && l[0:6] == "\x01INTL " {
 			m.Kludges["INTL"] = l[6:]
@@ -63,8 +82,18 @@ func (m *Message) ParseRaw() error {
 			if len(originRE.FindStringSubmatch(l)) > 0 {
 				m.Kludges["ORIGIN"] = originRE.FindStringSubmatch(l)[0]
 			}
+		} else if len(l) > 8 && l[0:9] == "\x01REPLYTO " {
+			m.Kludges["REPLYTO"] = strings.Trim(l[9:], " ")
+		} else if len(l) > 10 && l[0:11] == "\x01REPLYADDR " {
+			m.Kludges["REPLYADDR"] = strings.Trim(l[11:], " ")
+		} else if len(l) > 4 && l[0:5] == "\x01PID:" {
+			m.Kludges["PID:"] = strings.Trim(l[5:], " ")
+		} else if len(l) > 4 && l[0:5] == "\x01TID:" {
+			m.Kludges["TID:"] = strings.Trim(l[5:], " ")
 		} else if len(l) > 5 && l[0:6] == "\x01CHRS:" {
 			m.Kludges["CHRS"] = strings.ToUpper(strings.Split(strings.Trim(l[6:], " "), " ")[0])
+		} else if len(l) > 4 && l[0:5] == "\x01Via " {
+			m.Via = append(m.Via, strings.Trim(l[5:], " "))
 		}
 	}
 	//log.Printf("ParseRaw(): %#v", m.Kludges)
@@ -106,6 +135,7 @@ func (m *Message) ParseRaw() error {
 // ParseRawNoDecoding parse raw msg without automatic charset decoding (for jnode SQL)
 func (m *Message) ParseRawNoDecoding() error {
 	m.Kludges = make(map[string]string)
+	m.Via = nil
 	for _, l := range strings.Split(m.Body, "\x0d") {
 		if len(l) > 5 && l[0:6] == "\x01INTL " {
 			m.Kludges["INTL"] = l[6:]
@@ -120,8 +150,18 @@ func (m *Message) ParseRawNoDecoding() error {
 			if len(originRE.FindStringSubmatch(l)) > 0 {
 				m.Kludges["ORIGIN"] = originRE.FindStringSubmatch(l)[0]
 			}
+		} else if len(l) > 8 && l[0:9] == "\x01REPLYTO " {
+			m.Kludges["REPLYTO"] = strings.Trim(l[9:], " ")
+		} else if len(l) > 10 && l[0:11] == "\x01REPLYADDR " {
+			m.Kludges["REPLYADDR"] = strings.Trim(l[11:], " ")
+		} else if len(l) > 4 && l[0:5] == "\x01PID:" {
+			m.Kludges["PID:"] = strings.Trim(l[5:], " ")
+		} else if len(l) > 4 && l[0:5] == "\x01TID:" {
+			m.Kludges["TID:"] = strings.Trim(l[5:], " ")
 		} else if len(l) > 5 && l[0:6] == "\x01CHRS:" {
 			m.Kludges["CHRS"] = strings.ToUpper(strings.Split(strings.Trim(l[6:], " "), " ")[0])
+		} else if len(l) > 4 && l[0:5] == "\x01Via " {
+			m.Via = append(m.Via, strings.Trim(l[5:], " "))
 		}
 	}
 	//log.Printf("ParseRawNoDecoding(): %#v", m.Kludges)
@@ -179,12 +219,142 @@ func (m *Message) Encode() {
 	if (*m.AreaObject).GetChrs() != "" {
 		enc = strings.Split((*m.AreaObject).GetChrs(), " ")[0]
 	}
+	// a new message carries the charset to write in as "CHRS:", one read
+	// from an area, like an edited one, as "CHRS"
+	if chrs, ok := m.Kludges["CHRS:"]; ok && chrs != "" {
+		enc = strings.Split(chrs, " ")[0]
+	} else if chrs := m.GetChrsKludge(); chrs != "" {
+		enc = strings.Split(chrs, " ")[0]
+	}
 	m.Body = utils.EncodeCharmap(m.Body, enc)
 	m.From = utils.EncodeCharmap(m.From, enc)
 	m.To = utils.EncodeCharmap(m.To, enc)
 	m.Subject = utils.EncodeCharmap(m.Subject, enc)
 }
 
+// GetChrsKludge returns the CHRS kludge value of a read message, e.g. "CP866 2"
+func (m *Message) GetChrsKludge() string {
+	chrs, ok := m.Kludges["CHRS"]
+	if !ok || chrs == "" {
+		return ""
+	}
+	if chrs == "UTF-8" {
+		return chrs + " 4"
+	}
+	return chrs + " 2"
+}
+
+// GetKludge returns the value of kludge name from the parsed kludges or,
+// failing that, from the ^A lines of the body
+func (m *Message) GetKludge(name string) string {
+	for _, key := range []string{name, name + ":"} {
+		if v, ok := m.Kludges[key]; ok {
+			return v
+		}
+	}
+	prefix := "\x01" + name + ":"
+	for _, l := range strings.Split(m.Body, "\x0d") {
+		if strings.HasPrefix(l, prefix) {
+			return strings.Trim(l[len(prefix):], " ")
+		}
+	}
+	return ""
+}
+
+// ReplyTarget returns the name and address a reply goes to: those of the
+// REPLYTO kludge of gated mail, else the From of the message. A REPLYTO
+// without a name falls back to the REPLYADDR address, then to From.
+func (m *Message) ReplyTarget() (string, *types.FidoAddr) {
+	fields := strings.Fields(m.Kludges["REPLYTO"])
+	if len(fields) == 0 {
+		return m.From, m.FromAddr
+	}
+	addr := types.AddrFromString(fields[0])
+	if addr == nil {
+		return m.From, m.FromAddr
+	}
+	name := strings.Join(fields[1:], " ")
+	if name == "" {
+		name = m.Kludges["REPLYADDR"]
+	}
+	if name == "" {
+		name = m.From
+	}
+	return name, addr
+}
+
+// AuthorAddr returns the address a private answer to echomail goes to: that
+// of the MSGID, which keeps the point of the author, else FromAddr from the
+// origin line. It is false when the address may be incomplete, i.e. it is
+// unknown or only an origin line without a point gave it, which may be the
+// boss node of a point.
+func (m *Message) AuthorAddr() (*types.FidoAddr, bool) {
+	if fields := strings.Fields(m.Kludges["MSGID:"]); len(fields) > 0 {
+		if addr := types.AddrFromString(fields[0]); addr != nil && !addr.IsZero() {
+			return addr, true
+		}
+	}
+	if m.FromAddr.IsZero() {
+		return &types.FidoAddr{}, false
+	}
+	return m.FromAddr, m.FromAddr.GetPoint() != 0
+}
+
+// ReplyAddrLine returns the "To:" line starting a reply to gated mail, which
+// tells the gate the address of the original author, "" for other mail
+func (m *Message) ReplyAddrLine() string {
+	if m.Kludges["REPLYTO"] == "" || m.Kludges["REPLYADDR"] == "" {
+		return ""
+	}
+	return "To: " + m.Kludges["REPLYADDR"]
+}
+
+// Program returns the software which wrote the message, from the PID
+// kludge or, failing that, the TID kludge of the tosser
+func (m *Message) Program() string {
+	if pid := m.GetKludge("PID"); pid != "" {
+		return pid
+	}
+	return m.GetKludge("TID")
+}
+
+// Info returns a technical summary of the message: parsed addresses,
+// main kludges, area type, corruption flag and the raw kludge map
+func (m *Message) Info() string {
+	addr := func(a *types.FidoAddr) string {
+		if a.IsZero() {
+			return "(none)"
+		}
+		return fmt.Sprintf("%s (zone %d, net %d, node %d, point %d)", a.String(), a.GetZone(), a.GetNet(), a.GetNode(), a.GetPoint())
+	}
+	area, areaType := m.Area, "unknown"
+	if m.AreaObject != nil {
+		area = (*m.AreaObject).GetName()
+		areaType = (*m.AreaObject).GetType().String()
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From addr: %s\n", addr(m.FromAddr))
+	fmt.Fprintf(&sb, "To addr:   %s\n", addr(m.ToAddr))
+	fmt.Fprintf(&sb, "Area:      %s (%s)\n", area, areaType)
+	for _, name := range []string{"MSGID", "REPLY", "CHRS", "PID", "TID"} {
+		fmt.Fprintf(&sb, "%-10s %s\n", name+":", m.GetKludge(name))
+	}
+	fmt.Fprintf(&sb, "Corrupted: %t\n", m.Corrupted)
+	if m.AreaTag != "" {
+		fmt.Fprintf(&sb, "AREA tag:  %s, not %s (tosser or import bug)\n", m.AreaTag, area)
+	}
+	sb.WriteString("Kludges:\n")
+	keys := make([]string, 0, len(m.Kludges))
+	for k := range m.Kludges {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "  %-8s %q\n", k, m.Kludges[k])
+	}
+	return sb.String()
+}
+
 // Decode charset
 func (m *Message) Decode() {
 	enc := strings.Split(config.Config.Chrs.Default, " ")[0]
@@ -194,6 +364,10 @@ func (m *Message) Decode() {
 			enc = config.Config.Chrs.IBMPC
 		}
 	}
+	// a message labeled UTF-8 is UTF-8 whatever charset the area is read in
+	if chrs := GetReadChrs(m.Area); chrs != "" && m.Kludges["CHRS"] != "UTF-8" {
+		enc = chrs
+	}
 	//log.Printf("Decode(): %#v", m.Kludges)
 	m.Body = utils.DecodeCharmap(m.Body, enc)
 	m.From = utils.DecodeCharmap(m.From, enc)
@@ -203,27 +377,47 @@ func (m *Message) Decode() {
 
 // ToView export view
 func (m *Message) ToView(showKludges bool) string {
+	nm, _ := m.viewLines(showKludges)
+	return strings.Join(nm, "\n")
+}
+
+// viewLines returns the lines of the view with, for each, the index of the
+// body line it shows
+func (m *Message) viewLines(showKludges bool) ([]string, []int) {
 	var nm []string
+	var src []int
 	//re := regexp.MustCompile(">+")
 
 	// Split on \r to preserve empty lines like GetForward() does
 	lines := strings.Split(m.Body, "\x0d")
 
-	for _, l := range lines {
+	// stray controls like ANSI escapes garble the screen
+	controls := config.GetControlChars()
+	clean := func(l string) string {
+		if controls == "raw" {
+			return l
+		}
+		return utils.StripControls(l, controls == "show")
+	}
+
+	for i, l := range lines {
 		l = m.parseTabs(l)
 		if len(l) > 1 && l[0] == 1 {
-			if showKludges {
-				nm = append(nm, "@"+l[1:])
+			if showKludges || (config.Config.Netmail.ShowVia && strings.HasPrefix(l, "\x01Via ")) {
+				nm = append(nm, "@"+clean(l[1:]))
+				src = append(src, i)
 			}
 		} else if len(l) > 8 && l[0:9] == "SEEN-BY: " {
 			if showKludges {
-				nm = append(nm, ""+l)
+				nm = append(nm, clean(l))
+				src = append(src, i)
 			}
 		} else {
-			nm = append(nm, l)
+			nm = append(nm, clean(l))
+			src = append(src, i)
 		}
 	}
-	return strings.Join(nm, "\n")
+	return nm, src
 }
 
 // ToEditNewView export view
@@ -259,12 +453,26 @@ func (m *Message) ToEditNewView() string {
 			nm = append(nm, l)
 		}
 	}
+	nm = append(nm, signature(r)...)
 	nm = append(nm, "--- "+config.Config.Tearline)
 	nm = append(nm, " * Origin: "+config.Config.Origin+" ("+m.FromAddr.String()+")")
 	//log.Printf("pp: %d", p)
 	return strings.Join(nm, "\n")
 }
 
+// signature returns the configured signature lines with the template
+// macros of r expanded
+func signature(r *strings.Replacer) []string {
+	if config.Config.Signature == "" {
+		return nil
+	}
+	var nm []string
+	for _, l := range strings.Split(strings.TrimRight(config.Config.Signature, "\n"), "\n") {
+		nm = append(nm, r.Replace(l))
+	}
+	return nm
+}
+
 // GetForward get forward
 func (m *Message) GetForward() []string {
 	reO := regexp.MustCompile(`^ \* Origin: `)
@@ -296,18 +504,50 @@ func (m *Message) GetForward() []string {
 	return nm
 }
 
-// GetQuote get quote
+// isTearOrOrigin returns true for a tearline or origin line
+func isTearOrOrigin(l string) bool {
+	return l == "---" || strings.HasPrefix(l, "--- ") || strings.HasPrefix(l, " * Origin:")
+}
+
+// GetQuote get quote, without kludges and SEEN-BY, and without the
+// tearline and origin unless quote.keep_origin is set
 func (m *Message) GetQuote() []string {
+	return m.quoteLines(strings.Split(m.Body, "\x0d"))
+}
+
+// GetQuoteOfView quotes like GetQuote only the lines first to last of the
+// view of ToView(showKludges), e.g. a part marked by the reader
+func (m *Message) GetQuoteOfView(first, last int, showKludges bool) []string {
+	body := strings.Split(m.Body, "\x0d")
+	_, src := m.viewLines(showKludges)
+	var lines []string
+	for i := max(first, 0); i <= last && i < len(src); i++ {
+		lines = append(lines, body[src[i]])
+	}
+	return m.quoteLines(lines)
+}
+
+// quoteLines quotes the body lines with the initials of the author, adding
+// a level to lines which are quoted already
+func (m *Message) quoteLines(lines []string) []string {
 	var nm []string
 	re := regexp.MustCompile(">+")
 	from := ""
 	for _, l := range strings.Split(m.From, " ") {
 		from += string(l[0])
 	}
-	for _, l := range strings.Split(m.Body, "\x0d") {
+	raw := config.GetControlChars() == "raw"
+	for _, l := range lines {
 		if len(l) > 1 && l[0] == 1 {
 			continue
-		} else if len(l) > 8 && l[0:9] == "SEEN-BY: " {
+		}
+		if !raw {
+			// the reply gets no stray controls, not even shown ones
+			l = utils.StripControls(l, false)
+		}
+		if len(l) > 8 && l[0:9] == "SEEN-BY: " {
+			continue
+		} else if !config.Config.Quote.KeepOrigin && isTearOrOrigin(l) {
 			continue
 		} else if ind := re.FindStringIndex(l); ind != nil {
 			ind2 := strings.Index(l, "<")
@@ -330,6 +570,16 @@ func (m *Message) GetQuote() []string {
 
 // ToEditAnswerView export view
 func (m *Message) ToEditAnswerView(om *Message) string {
+	var quote []string
+	if config.GetAutoQuote() {
+		quote = om.GetQuote()
+	}
+	return m.ToEditAnswerQuoting(om, quote)
+}
+
+// ToEditAnswerQuoting returns the answer to om like ToEditAnswerView with
+// quote in place of @Quote, whatever auto_quote is set to
+func (m *Message) ToEditAnswerQuoting(om *Message, quote []string) string {
 	var nm []string
 	//p := 0
 	r := strings.NewReplacer(
@@ -348,6 +598,10 @@ func (m *Message) ToEditAnswerView(om *Message) string {
 				} else if len(l) > 6 && l[0:7] == "@Quoted" {
 					if len(l) == 7 {
 						nm = append(nm, "")
+					} else if tpl, ok := config.GetReplyAttribution(); ok {
+						if tpl != "" {
+							nm = append(nm, replyAttribution(tpl, om))
+						}
 					} else {
 						nm = append(nm, r.Replace(l[7:]))
 					}
@@ -359,7 +613,7 @@ func (m *Message) ToEditAnswerView(om *Message) string {
 						nm = append(nm, r.Replace(l[9:]))
 					}
 				} else if len(l) > 5 && l[0:6] == "@Quote" {
-					nm = append(nm, om.GetQuote()...)
+					nm = append(nm, quote...)
 				} else if len(l) > 6 && l[0:7] == "@CFName" {
 					nm = append(nm, r.Replace(l))
 				}
@@ -370,11 +624,22 @@ func (m *Message) ToEditAnswerView(om *Message) string {
 			nm = append(nm, l)
 		}
 	}
+	nm = append(nm, signature(r)...)
 	nm = append(nm, "--- "+config.Config.Tearline)
 	nm = append(nm, " * Origin: "+config.Config.Origin+" ("+m.FromAddr.String()+")")
 	return strings.Join(nm, "\n")
 }
 
+// replyAttribution expands the reply_attribution template tpl for a reply
+// to om
+func replyAttribution(tpl string, om *Message) string {
+	return strings.NewReplacer(
+		"@fromname", om.From,
+		"@toname", om.To,
+		"@date", om.DateWritten.Format("02 Jan 2006 15:04"),
+		"@msgid", om.Kludges["MSGID:"]).Replace(tpl)
+}
+
 // ToEditForwardView export view
 func (m *Message) ToEditForwardView(om *Message) string {
 	var nm []string
@@ -419,36 +684,93 @@ func (m *Message) ToEditForwardView(om *Message) string {
 			nm = append(nm, l)
 		}
 	}
+	nm = append(nm, signature(r)...)
 	nm = append(nm, "--- "+config.Config.Tearline)
 	nm = append(nm, " * Origin: "+config.Config.Origin+" ("+m.FromAddr.String()+")")
 	return strings.Join(nm, "\n")
 }
 
+// SetOriginAddr returns line with the address of an origin line replaced by
+// addr, false if line is not an origin line ending in an address
+func SetOriginAddr(line string, addr *types.FidoAddr) (string, bool) {
+	if !strings.HasPrefix(line, " * Origin: ") || !strings.HasSuffix(line, ")") {
+		return line, false
+	}
+	i := strings.LastIndex(line, "(")
+	if i < 0 {
+		return line, false
+	}
+	return line[:i] + "(" + addr.String() + ")", true
+}
+
+// ViaKludge returns a Via kludge line (without leading ^A) for addr at t
+func ViaKludge(addr *types.FidoAddr, t time.Time) string {
+	return fmt.Sprintf("Via %s @%s.UTC %s", addr.String(), t.UTC().Format("20060102.150405"), config.PID)
+}
+
+// setNetmailKludges sets INTL from FromAddr/ToAddr and FMPT/TOPT for
+// non-zero points, removing stale ones
+func (m *Message) setNetmailKludges() {
+	// Create copies of addresses to avoid modifying the original
+	to := types.AddrFromNum(m.ToAddr.GetZone(), m.ToAddr.GetNet(), m.ToAddr.GetNode(), 0)
+	top := m.ToAddr.GetPoint()
+	from := types.AddrFromNum(m.FromAddr.GetZone(), m.FromAddr.GetNet(), m.FromAddr.GetNode(), 0)
+	fromp := m.FromAddr.GetPoint()
+
+	m.Kludges["INTL"] = to.String() + " " + from.String()
+	delete(m.Kludges, "TOPT")
+	delete(m.Kludges, "FMPT")
+	if top > 0 {
+		m.Kludges["TOPT"] = strconv.FormatUint(uint64(top), 10)
+	}
+	if fromp > 0 {
+		m.Kludges["FMPT"] = strconv.FormatUint(uint64(fromp), 10)
+	}
+}
+
+// defaultFromAddr fills a blank FromAddr with the configured address, for
+// netmail the aka of the zone of the destination
+func (m *Message) defaultFromAddr() {
+	if !m.FromAddr.IsZero() {
+		return
+	}
+	if m.AreaObject != nil && (*m.AreaObject).GetType() == EchoAreaTypeNetmail {
+		m.FromAddr = config.GetOriginAddr(m.ToAddr)
+	} else {
+		m.FromAddr = config.Config.Address
+	}
+}
+
+// checkFromAddr fills a blank FromAddr, see defaultFromAddr, and refuses
+// one which is not among the configured addresses
+func (m *Message) checkFromAddr() error {
+	m.defaultFromAddr()
+	if m.FromAddr.IsZero() || m.FromAddr.GetZone() == 0 {
+		return errors.New("no From address, set address in the config")
+	}
+	if !config.IsAka(m.FromAddr) {
+		return fmt.Errorf("From address %s is not one of the configured addresses", m.FromAddr)
+	}
+	return nil
+}
+
 // MakeBody make body
 func (m *Message) MakeBody() *Message {
+	m.defaultFromAddr()
 	if (*m.AreaObject).GetType() == EchoAreaTypeNetmail {
-		// Create copies of addresses to avoid modifying the original
-		to := types.AddrFromNum(m.ToAddr.GetZone(), m.ToAddr.GetNet(), m.ToAddr.GetNode(), 0)
-		top := m.ToAddr.GetPoint()
-		from := types.AddrFromNum(m.FromAddr.GetZone(), m.FromAddr.GetNet(), m.FromAddr.GetNode(), 0)
-		fromp := m.FromAddr.GetPoint()
-		
-		m.Kludges["INTL"] = to.String() + " " + from.String()
-		if top > 0 {
-			m.Kludges["TOPT"] = strconv.FormatUint(uint64(top), 10)
-		}
-		if fromp > 0 {
-			m.Kludges["FMPT"] = strconv.FormatUint(uint64(fromp), 10)
-		}
+		m.setNetmailKludges()
 	}
 	m.Kludges["MSGID:"] = fmt.Sprintf("%s %08x", m.FromAddr.String(), uint32(time.Now().Unix()))
+	if _, ok := m.Kludges["PID:"]; !ok {
+		m.Kludges["PID:"] = config.PID
+	}
 	
 	// Use format-specific line ending normalization
 	if m.AreaObject != nil {
 		m.Body = (*m.AreaObject).NormalizeForStorage(m.Body)
 	} else {
 		// Fallback to traditional FTN format for backward compatibility
-		m.Body = strings.Join(strings.Split(m.Body, "\n"), "\x0d") + "\x0d"
+		m.Body = terminateLines(m.Body, ftnLineEnding)
 	}
 	
 	m.DateWritten = time.Now()
@@ -0,0 +1,457 @@
+package msgapi
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/types"
+	"github.com/askovpen/gossiped/pkg/utils"
+	. "github.com/franela/goblin"
+)
+
+func TestMessageListItem(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check MessageListItem", func() {
+		mi := MessageListItem{MsgNum: 42, From: "Alexander Skovpen", To: "All", Subject: "Привет, Мир"}
+		g.It("check Matches()", func() {
+			g.Assert(mi.Matches("")).IsTrue()
+			g.Assert(mi.Matches("skov")).IsTrue()
+			g.Assert(mi.Matches("ALL")).IsTrue()
+			g.Assert(mi.Matches("мир")).IsTrue()
+			g.Assert(mi.Matches("42")).IsFalse()
+			g.Assert(mi.Matches("nobody")).IsFalse()
+		})
+	})
+}
+
+func TestMessageEncode(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check Encode() charset", func() {
+		var area AreaPrimitive = NewMemoryArea("memory.area", EchoAreaTypeEcho)
+		savedDefault := config.Config.Chrs.Default
+		g.After(func() {
+			config.Config.Chrs.Default = savedDefault
+		})
+		g.It("check the CHRS of a new and of a read message", func() {
+			config.Config.Chrs.Default = "UTF-8 4"
+			m := &Message{AreaObject: &area, Body: "Привет", Kludges: map[string]string{"CHRS:": "CP866 2"}}
+			m.Encode()
+			g.Assert(m.Body).Equal(utils.EncodeCharmap("Привет", "CP866"))
+			m = &Message{AreaObject: &area, Body: "Привет", Kludges: map[string]string{"CHRS": "KOI8-R"}}
+			m.Encode()
+			g.Assert(m.Body).Equal(utils.EncodeCharmap("Привет", "KOI8-R"))
+			m = &Message{AreaObject: &area, Body: "Привет", Kludges: map[string]string{}}
+			m.Encode()
+			g.Assert(m.Body).Equal("Привет")
+		})
+	})
+}
+
+func TestMessageVia(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check Via kludge", func() {
+		g.It("check ViaKludge()", func() {
+			config.PID = "gossipEd+lin 2.1"
+			ts := time.Date(2024, 3, 5, 7, 8, 9, 0, time.FixedZone("MSK", 3*3600))
+			g.Assert(ViaKludge(types.AddrFromString("2:5020/9696.128"), ts)).
+				Equal("Via 2:5020/9696.128 @20240305.040809.UTC gossipEd+lin 2.1")
+		})
+		g.It("check Via trail parsing", func() {
+			m := &Message{Body: "\x01INTL 2:5020/1 2:5020/9696\x0dHello\x0d" +
+				"\x01Via 2:5020/9696 @20240305.040809.UTC gossipEd+lin 2.1\x0d" +
+				"\x01Via 2:5020/1 @20240305.041000.UTC jnode 1.0\x0d"}
+			g.Assert(m.ParseRawNoDecoding()).Equal(nil)
+			g.Assert(m.Via).Equal([]string{
+				"2:5020/9696 @20240305.040809.UTC gossipEd+lin 2.1",
+				"2:5020/1 @20240305.041000.UTC jnode 1.0",
+			})
+		})
+	})
+}
+
+func TestMessageInfo(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check message info", func() {
+		m := Message{
+			Body:     "\x01MSGID: 2:5020/9696 12345678\x0d\x01REPLY: 2:5020/1 87654321\x0d\x01PID: test 1.0\x0dHello\x0d",
+			FromAddr: types.AddrFromString("2:5020/9696.128"),
+			Kludges:  map[string]string{"MSGID:": "2:5020/9696 12345678", "CHRS": "CP866"},
+		}
+		g.It("check GetKludge()", func() {
+			g.Assert(m.GetKludge("MSGID")).Equal("2:5020/9696 12345678")
+			g.Assert(m.GetKludge("CHRS")).Equal("CP866")
+			g.Assert(m.GetKludge("REPLY")).Equal("2:5020/1 87654321")
+			g.Assert(m.GetKludge("PID")).Equal("test 1.0")
+			g.Assert(m.GetKludge("TZUTC")).Equal("")
+		})
+		g.It("check PID/TID are parsed", func() {
+			parsed := &Message{Body: "\x01TID: hpt/lnx 1.9\x0d\x01PID: GoldED+/LNX 1.1.5\x0dHello\x0d"}
+			parsed.ParseRawNoDecoding()
+			g.Assert(parsed.Kludges["PID:"]).Equal("GoldED+/LNX 1.1.5")
+			g.Assert(parsed.Kludges["TID:"]).Equal("hpt/lnx 1.9")
+			g.Assert(parsed.Program()).Equal("GoldED+/LNX 1.1.5")
+			parsed = &Message{Body: "\x01TID: hpt/lnx 1.9\x0dHello\x0d"}
+			parsed.ParseRawNoDecoding()
+			g.Assert(parsed.Program()).Equal("hpt/lnx 1.9")
+		})
+		g.It("check Info()", func() {
+			info := m.Info()
+			g.Assert(strings.Contains(info, "From addr: 2:5020/9696.128 (zone 2, net 5020, node 9696, point 128)")).IsTrue()
+			g.Assert(strings.Contains(info, "To addr:   (none)")).IsTrue()
+			g.Assert(strings.Contains(info, "REPLY:     2:5020/1 87654321")).IsTrue()
+			g.Assert(strings.Contains(info, "Corrupted: false")).IsTrue()
+			g.Assert(strings.Contains(info, "  CHRS     \"CP866\"")).IsTrue()
+		})
+	})
+}
+
+func TestNetmailKludges(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check netmail addressing kludges", func() {
+		var area AreaPrimitive = NewSQLNetmailArea(nil)
+		g.It("check INTL/FMPT/TOPT are stored and parsed back", func() {
+			msg := &Message{
+				AreaObject: &area,
+				FromAddr:   types.AddrFromString("2:5020/9696.128"),
+				ToAddr:     types.AddrFromString("1:100/200.5"),
+				Kludges:    map[string]string{"CHRS:": "UTF-8 4"},
+				Body:       "Hello",
+			}
+			msg.MakeBody()
+			text := area.(*SQLArea).netmailText(msg)
+			g.Assert(strings.HasPrefix(text, "\x01INTL 1:100/200 2:5020/9696\x0d\x01FMPT 128\x0d\x01TOPT 5\x0d")).IsTrue()
+			parsed := &Message{Body: area.(*SQLArea).NormalizeFromStorage(text)}
+			parsed.ParseRawNoDecoding()
+			g.Assert(parsed.Kludges["INTL"]).Equal("1:100/200 2:5020/9696")
+			g.Assert(parsed.FromAddr.String()).Equal("2:5020/9696.128")
+			g.Assert(parsed.ToAddr.String()).Equal("1:100/200.5")
+		})
+		g.It("check FMPT/TOPT are omitted for nodes", func() {
+			msg := &Message{
+				AreaObject: &area,
+				FromAddr:   types.AddrFromString("2:5020/9696"),
+				ToAddr:     types.AddrFromString("2:5020/1"),
+				Kludges:    map[string]string{"FMPT": "1", "TOPT": "2"},
+			}
+			msg.setNetmailKludges()
+			g.Assert(msg.Kludges["INTL"]).Equal("2:5020/1 2:5020/9696")
+			_, fmpt := msg.Kludges["FMPT"]
+			_, topt := msg.Kludges["TOPT"]
+			g.Assert(fmpt || topt).IsFalse()
+		})
+	})
+}
+
+func TestMessageRFC822(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check RFC822 export", func() {
+		g.It("check ToRFC822()", func() {
+			m := Message{
+				From:        "Alexander Skovpen",
+				FromAddr:    types.AddrFromString("2:5020/9696.128"),
+				To:          "Вася Пупкин",
+				ToAddr:      types.AddrFromString("2:5020/1"),
+				Subject:     "Привет",
+				DateWritten: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+				Body:        "\x01MSGID: 2:5020/9696.128 12345678\rHello\rМир\rSEEN-BY: 5020/1\r",
+			}
+			var sb strings.Builder
+			g.Assert(m.ToRFC822(&sb)).IsNil()
+			out := sb.String()
+			g.Assert(strings.Contains(out, "From: \"Alexander Skovpen\" <Alexander_Skovpen@p128.f9696.n5020.z2.fidonet.org>\r\n")).IsTrue()
+			g.Assert(strings.Contains(out, "@f1.n5020.z2.fidonet.org>\r\n")).IsTrue()
+			g.Assert(strings.Contains(out, "Subject: =?utf-8?q?")).IsTrue()
+			g.Assert(strings.Contains(out, "Date: Fri, 01 Mar 2024 12:30:00 +0000\r\n")).IsTrue()
+			g.Assert(strings.Contains(out, "X-FTN-MSGID: 2:5020/9696.128 12345678\r\n")).IsTrue()
+			g.Assert(strings.HasSuffix(out, "\r\n\r\nHello\r\nМир\r\n\r\n")).IsTrue()
+		})
+	})
+}
+
+func TestMessagePlainText(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check clipboard text", func() {
+		m := Message{
+			From: "Alexander Skovpen",
+			Body: "\x01MSGID: 2:5020/9696.128 12345678\rHello\rМир\rSEEN-BY: 5020/1\r",
+		}
+		g.It("check PlainText()", func() {
+			g.Assert(m.PlainText()).Equal("Hello\nМир")
+		})
+		g.It("check QuotedText()", func() {
+			g.Assert(m.QuotedText()).Equal(" AS> Hello\n AS> Мир")
+		})
+	})
+}
+
+func TestMessageSignature(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check auto quote and signature", func() {
+		om := &Message{From: "Alexander Skovpen", To: "All", Body: "Hello\rМир\r",
+			DateWritten: time.Date(2024, 3, 5, 7, 8, 9, 0, time.UTC)}
+		m := &Message{From: "Vasily Pupkin", To: "Alexander Skovpen", FromAddr: types.AddrFromNum(2, 5020, 9696, 1)}
+		g.Before(func() {
+			config.Template = []string{"Hello @pseudo!", "@Quoted@ODate, @OName wrote:", "@Quote", ""}
+			config.Config.Tearline = "gossipEd"
+			config.Config.Origin = "Test"
+			config.Config.Signature = "-- \n@CFName, replying to @OName\n"
+		})
+		g.After(func() {
+			config.Template = nil
+			config.Config.Signature = ""
+			config.Config.AutoQuote = nil
+		})
+		g.It("check quoted reply with signature above the tearline", func() {
+			lines := strings.Split(m.ToEditAnswerView(om), "\n")
+			g.Assert(lines).Equal([]string{
+				"Hello Alexander Skovpen!",
+				"05 Mar 2024, Alexander Skovpen wrote:",
+				" AS> Hello",
+				" AS> Мир",
+				" AS> ",
+				"",
+				"-- ",
+				"Vasily, replying to Alexander Skovpen",
+				"--- gossipEd",
+				" * Origin: Test (2:5020/9696.1)",
+			})
+		})
+		g.It("check auto_quote disabled", func() {
+			off := false
+			config.Config.AutoQuote = &off
+			view := m.ToEditAnswerView(om)
+			g.Assert(strings.Contains(view, "AS>")).IsFalse()
+			g.Assert(strings.Contains(view, "-- \nVasily, replying to Alexander Skovpen\n--- gossipEd")).IsTrue()
+		})
+		g.It("check signature in a new message", func() {
+			config.Template = []string{"Hello @pseudo!"}
+			config.Config.Signature = "@CFName"
+			g.Assert(m.ToEditNewView()).Equal("Hello Alexander Skovpen!\nVasily\n--- gossipEd\n * Origin: Test (2:5020/9696.1)")
+		})
+	})
+}
+
+func TestMessageReplyAttribution(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check reply attribution", func() {
+		om := &Message{From: "Alexander Skovpen", To: "All", Body: "Hello\r",
+			DateWritten: time.Date(2024, 3, 5, 7, 8, 9, 0, time.UTC),
+			Kludges:     map[string]string{"MSGID:": "2:5020/9696 12345678"}}
+		m := &Message{From: "Vasily Pupkin", To: "Alexander Skovpen", FromAddr: types.AddrFromNum(2, 5020, 9696, 1)}
+		attribution := func() []string {
+			return strings.Split(m.ToEditAnswerView(om), "\n")[:3]
+		}
+		g.Before(func() {
+			config.Template = []string{"Hello @pseudo!", "@Quoted@ODate, @OName wrote:", "@Quote"}
+		})
+		g.After(func() {
+			config.Template = nil
+			config.Config.ReplyAttribution = nil
+		})
+		g.It("check the template file line is kept by default", func() {
+			g.Assert(attribution()).Equal([]string{"Hello Alexander Skovpen!",
+				"05 Mar 2024, Alexander Skovpen wrote:", " AS> Hello"})
+		})
+		g.It("check reply_attribution macros", func() {
+			tpl := "In a message of @date (@msgid) @fromname wrote to @toname:"
+			config.Config.ReplyAttribution = &tpl
+			g.Assert(attribution()).Equal([]string{"Hello Alexander Skovpen!",
+				"In a message of 05 Mar 2024 07:08 (2:5020/9696 12345678) Alexander Skovpen wrote to All:",
+				" AS> Hello"})
+		})
+		g.It("check an empty reply_attribution leaves the line out", func() {
+			tpl := ""
+			config.Config.ReplyAttribution = &tpl
+			g.Assert(attribution()).Equal([]string{"Hello Alexander Skovpen!", " AS> Hello", " AS> "})
+		})
+	})
+}
+
+func TestMessageQuoteOrigin(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check tearline and origin in quotes", func() {
+		m := &Message{From: "Alexander Skovpen", Body: "\x01MSGID: 2:5020/9696 12345678\rHello\r--- GoldED+/LNX 1.1.5\r" +
+			" * Origin: Just Origin (2:5020/9696)\rSEEN-BY: 5020/9696\r\x01PATH: 5020/9696\r"}
+		g.After(func() {
+			config.Config.Quote.KeepOrigin = false
+		})
+		g.It("check they are left out", func() {
+			g.Assert(m.GetQuote()).Equal([]string{" AS> Hello", " AS> "})
+		})
+		g.It("check quote.keep_origin keeps them", func() {
+			config.Config.Quote.KeepOrigin = true
+			g.Assert(m.GetQuote()).Equal([]string{" AS> Hello", " AS> --- GoldED+/LNX 1.1.5",
+				" AS>  * Origin: Just Origin (2:5020/9696)", " AS> "})
+		})
+	})
+}
+
+func TestMessageViewKludges(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check kludges in the view", func() {
+		body := "\x01MSGID: 2:5020/9696 12345678\rHello\r\x01TZUTC: 0300\r> quoted\r--- GoldED+/LNX 1.1.5\r" +
+			"SEEN-BY: 5020/9696\r\x01PATH: 5020/9696\r"
+		m := &Message{From: "Alexander Skovpen", Body: body}
+		g.It("check they are hidden", func() {
+			g.Assert(m.ToView(false)).Equal("Hello\n> quoted\n--- GoldED+/LNX 1.1.5\n")
+		})
+		g.It("check they are shown where they are in the body", func() {
+			g.Assert(m.ToView(true)).Equal("@MSGID: 2:5020/9696 12345678\nHello\n@TZUTC: 0300\n> quoted\n" +
+				"--- GoldED+/LNX 1.1.5\nSEEN-BY: 5020/9696\n@PATH: 5020/9696\n")
+		})
+		g.It("check the body and quote are left alone", func() {
+			g.Assert(m.Body).Equal(body)
+			g.Assert(m.GetQuote()).Equal([]string{" AS> Hello", ">> quoted", " AS> "})
+		})
+	})
+}
+
+func TestMessageViewControls(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check control characters in the view", func() {
+		m := &Message{From: "Alexander Skovpen", Body: "\x01MSGID: 2:5020/9696 1\x07\r\x1b[1;33mHello\x1b[0m\x0c\r"}
+		g.After(func() {
+			config.Config.ControlChars = ""
+		})
+		g.It("check they are stripped by default", func() {
+			g.Assert(m.ToView(true)).Equal("@MSGID: 2:5020/9696 1\nHello\n")
+			g.Assert(m.GetQuote()).Equal([]string{" AS> Hello", " AS> "})
+		})
+		g.It("check control_chars show", func() {
+			config.Config.ControlChars = "show"
+			g.Assert(m.ToView(false)).Equal("^[[1;33mHello^[[0m^L\n")
+			g.Assert(m.GetQuote()).Equal([]string{" AS> Hello", " AS> "})
+		})
+		g.It("check control_chars raw", func() {
+			config.Config.ControlChars = "raw"
+			g.Assert(m.ToView(false)).Equal("\x1b[1;33mHello\x1b[0m\x0c\n")
+		})
+	})
+}
+
+func TestMessageQuoteOfView(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check quoting marked lines of the view", func() {
+		m := &Message{From: "Alexander Skovpen", To: "All", Body: "\x01MSGID: 2:5020/9696 12345678\rHello\r" +
+			" VP> first\r VP>> second\rBye\r--- GoldED+/LNX 1.1.5\rSEEN-BY: 5020/9696\r",
+			DateWritten: time.Date(2024, 3, 5, 7, 8, 9, 0, time.UTC)}
+		g.It("check lines of several quote levels", func() {
+			g.Assert(m.GetQuoteOfView(1, 3, false)).Equal([]string{" VP>> first", " VP>>> second", " AS> Bye"})
+		})
+		g.It("check kludges shown in the view are counted but not quoted", func() {
+			g.Assert(m.GetQuoteOfView(0, 1, true)).Equal([]string{" AS> Hello"})
+			g.Assert(m.GetQuoteOfView(4, 6, true)).Equal([]string{" AS> Bye"})
+		})
+		g.It("check lines past the view are ignored", func() {
+			g.Assert(m.GetQuoteOfView(3, 99, false)).Equal([]string{" AS> Bye", " AS> "})
+		})
+		g.It("check the attribution line is kept", func() {
+			config.Template = []string{"@Quoted@ODate, @OName wrote:", "@Quote"}
+			defer func() { config.Template = nil }()
+			reply := &Message{From: "Vasily Pupkin", FromAddr: types.AddrFromNum(2, 5020, 9696, 1)}
+			lines := strings.Split(reply.ToEditAnswerQuoting(m, m.GetQuoteOfView(3, 3, false)), "\n")
+			g.Assert(lines[:2]).Equal([]string{"05 Mar 2024, Alexander Skovpen wrote:", " AS> Bye"})
+		})
+	})
+}
+
+func TestSetOriginAddr(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SetOriginAddr()", func() {
+		aka := types.AddrFromNum(1, 123, 456, 0)
+		g.It("check origin line", func() {
+			line, ok := SetOriginAddr(" * Origin: Just Origin (2:5020/9696.128)", aka)
+			g.Assert(ok).IsTrue()
+			g.Assert(line).Equal(" * Origin: Just Origin (1:123/456)")
+			line, ok = SetOriginAddr(" * Origin: BBS (telnet) (2:5020/9696)", aka)
+			g.Assert(ok).IsTrue()
+			g.Assert(line).Equal(" * Origin: BBS (telnet) (1:123/456)")
+		})
+		g.It("check other lines", func() {
+			for _, l := range []string{"Hello (2:5020/9696)", " * Origin: no address", " + Origin: BBS (2:5020/9696)"} {
+				line, ok := SetOriginAddr(l, aka)
+				g.Assert(ok).IsFalse()
+				g.Assert(line).Equal(l)
+			}
+		})
+	})
+}
+
+func TestMessageAuthorAddr(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check AuthorAddr()", func() {
+		g.It("check the MSGID address keeps the point", func() {
+			m := &Message{FromAddr: types.AddrFromString("2:5020/9696"),
+				Kludges: map[string]string{"MSGID:": "2:5020/9696.128@fidonet 12345678"}}
+			addr, ok := m.AuthorAddr()
+			g.Assert(ok).IsTrue()
+			g.Assert(addr.String()).Equal("2:5020/9696.128")
+		})
+		g.It("check an origin address of a point", func() {
+			m := &Message{FromAddr: types.AddrFromString("2:5020/9696.128"),
+				Kludges: map[string]string{"MSGID:": "<1234@example.com> 12345678"}}
+			addr, ok := m.AuthorAddr()
+			g.Assert(ok).IsTrue()
+			g.Assert(addr.String()).Equal("2:5020/9696.128")
+		})
+		g.It("check an origin address of a node may be incomplete", func() {
+			m := &Message{FromAddr: types.AddrFromString("2:5020/9696"), Kludges: map[string]string{}}
+			addr, ok := m.AuthorAddr()
+			g.Assert(ok).IsFalse()
+			g.Assert(addr.String()).Equal("2:5020/9696")
+			m.FromAddr = &types.FidoAddr{}
+			addr, ok = m.AuthorAddr()
+			g.Assert(ok).IsFalse()
+			g.Assert(addr.IsZero()).IsTrue()
+		})
+	})
+}
+
+func TestMessageReplyTo(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check reply to gated mail", func() {
+		var area AreaPrimitive = NewSQLNetmailArea(nil)
+		g.It("check REPLYTO and REPLYADDR are followed", func() {
+			msg := &Message{
+				AreaObject: &area,
+				From:       "John Doe",
+				Body: "\x01INTL 2:5020/9696 2:5020/1234\x0d\x01REPLYADDR john@example.com\x0d" +
+					"\x01REPLYTO 2:5020/1234.5 UUCP\x0dHello\x0d",
+			}
+			g.Assert(msg.ParseRawNoDecoding()).IsNil()
+			g.Assert(msg.Kludges["REPLYTO"]).Equal("2:5020/1234.5 UUCP")
+			g.Assert(msg.Kludges["REPLYADDR"]).Equal("john@example.com")
+			name, addr := msg.ReplyTarget()
+			g.Assert(name).Equal("UUCP")
+			g.Assert(addr.String()).Equal("2:5020/1234.5")
+			g.Assert(msg.ReplyAddrLine()).Equal("To: john@example.com")
+		})
+		g.It("check REPLYTO without a name", func() {
+			msg := &Message{AreaObject: &area, From: "John Doe",
+				Body: "\x01INTL 2:5020/9696 2:5020/1234\x0d\x01REPLYADDR john@example.com\x0d\x01REPLYTO 2:5020/1234\x0dHello\x0d"}
+			msg.ParseRawNoDecoding()
+			name, addr := msg.ReplyTarget()
+			g.Assert(name).Equal("john@example.com")
+			g.Assert(addr.String()).Equal("2:5020/1234")
+		})
+		g.It("check plain netmail falls back to From", func() {
+			msg := &Message{AreaObject: &area, From: "John Doe",
+				Body: "\x01INTL 2:5020/9696 2:5020/1234\x0d\x01FMPT 7\x0dHello\x0d"}
+			msg.ParseRawNoDecoding()
+			name, addr := msg.ReplyTarget()
+			g.Assert(name).Equal("John Doe")
+			g.Assert(addr.String()).Equal("2:5020/1234.7")
+			g.Assert(msg.ReplyAddrLine()).Equal("")
+		})
+		g.It("check a bad REPLYTO address falls back to From", func() {
+			msg := &Message{AreaObject: &area, From: "John Doe",
+				Body: "\x01INTL 2:5020/9696 2:5020/1234\x0d\x01REPLYTO gate UUCP\x0dHello\x0d"}
+			msg.ParseRawNoDecoding()
+			name, addr := msg.ReplyTarget()
+			g.Assert(name).Equal("John Doe")
+			g.Assert(addr.String()).Equal("2:5020/1234")
+		})
+	})
+}
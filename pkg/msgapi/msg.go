@@ -77,7 +77,7 @@ func (m *MSG) getAttrs(a uint16) (attrs []string) {
 		"Pvt", "", "Rcv", "Snt",
 		"", "Trs", "", "K/s",
 		"Loc", "", "", "",
-		"Rrq", "", "Arq", "",
+		"Rrq", "Cpt", "Arq", "",
 	}
 	i := 0
 	for a > 0 {
@@ -97,14 +97,19 @@ func parseDate(date string) (ret time.Time) {
 	return ret
 }
 
+// GetMsgByID returns nil, messages of file bases have no database id
+func (m *MSG) GetMsgByID(id int64) (*Message, error) {
+	return nil, nil
+}
+
 // GetMsg getmsg
 func (m *MSG) GetMsg(position uint32) (*Message, error) {
-	if len(m.messageNums) == 0 {
-		return nil, nil
-	}
 	if position == 0 {
 		position = 1
 	}
+	if int(position) > len(m.messageNums) {
+		return nil, ErrMsgOutOfRange
+	}
 	f, err := os.Open(filepath.Join(m.AreaPath, strconv.FormatUint(uint64(m.messageNums[position-1]), 10)+".msg"))
 	if err != nil {
 		return nil, err
@@ -126,7 +131,7 @@ func (m *MSG) GetMsg(position uint32) (*Message, error) {
 		From:        strings.Trim(string(msgm.From[:]), "\x00"),
 		To:          strings.Trim(string(msgm.To[:]), "\x00"),
 		Subject:     strings.Trim(string(msgm.Subj[:]), "\x00"),
-		Body:        strings.Trim(msgm.Body, "\x00"),
+		Body:        m.NormalizeFromStorage(strings.Trim(msgm.Body, "\x00")),
 		DateWritten: parseDate(strings.Trim(string(msgm.Date[:]), "\x00")),
 		DateArrived: getTime(msgm.DateArrived),
 		Attrs:       m.getAttrs(uint16(msgm.Attr))}
@@ -281,6 +286,12 @@ func (m *MSG) GetChrs() string {
 	return m.Chrs
 }
 
+// Capabilities returns the operations of the *.msg directory, deleting by
+// removing the message file
+func (m *MSG) Capabilities() AreaCaps {
+	return areaCaps(m) | AreaCanDelete
+}
+
 // GetMessages get headers
 func (m *MSG) GetMessages() *[]MessageListItem {
 	if len(m.messages) > 0 || len(m.messageNums) == 0 {
@@ -320,15 +331,14 @@ func (m *MSG) DelMsg(l uint32) error {
 
 // Line ending handling methods for MSG format
 func (m *MSG) GetStorageLineEnding() string {
-	return "\r" // MSG stores FTN-style line endings
+	return ftnLineEnding // MSG stores FTN-style line endings
 }
 
 func (m *MSG) NormalizeForStorage(body string) string {
-	// Convert Unix \n to FTN \r and ensure trailing \r for MSG format
-	return strings.Join(strings.Split(body, "\n"), "\x0d") + "\x0d"
+	return terminateLines(body, m.GetStorageLineEnding())
 }
 
 func (m *MSG) NormalizeFromStorage(body string) string {
-	// MSG already stores in FTN format, no conversion needed
-	return body
+	// lines written with \r\n or \n by other software end in \r as well
+	return convertLineEndings(body, ftnLineEnding)
 }
@@ -1,6 +1,8 @@
 package msgapi
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -48,6 +50,11 @@ func TestMSG(t *testing.T) {
 			g.Assert(err).Equal(nil)
 			g.Assert(nm.FromAddr).Equal(types.AddrFromNum(2, 5020, 9696, 1))
 		})
+		g.It("read msg past the end", func() {
+			nm, err := Area.GetMsg(3)
+			g.Assert(nm == nil).IsTrue()
+			g.Assert(errors.Is(err, ErrMsgOutOfRange)).IsTrue()
+		})
 		g.It("get/set last", func() {
 			Area.SetLast(1)
 			g.Assert(Area.GetLast()).Equal(uint32(1))
@@ -109,3 +116,24 @@ func BenchmarkMSGGetMessages(b *testing.B) {
 	os.RemoveAll("../../testdata/test")
 
 }
+
+func TestFileAreaLineEndings(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check MSG, JAM and Squish line ending normalization", func() {
+		for _, area := range []AreaPrimitive{&MSG{}, &JAM{}, &Squish{}} {
+			area := area
+			name := fmt.Sprintf("%T", area)
+			g.It("check GetStorageLineEnding() of "+name, func() {
+				g.Assert(area.GetStorageLineEnding()).Equal("\r")
+			})
+			g.It("check NormalizeForStorage() of "+name, func() {
+				g.Assert(area.NormalizeForStorage("one\ntwo\n\nfour")).Equal("one\rtwo\r\rfour\r")
+				g.Assert(area.NormalizeForStorage("one\r\ntwo\n")).Equal("one\rtwo\r")
+			})
+			g.It("check NormalizeFromStorage() of "+name, func() {
+				g.Assert(area.NormalizeFromStorage("one\rtwo\r")).Equal("one\rtwo\r")
+				g.Assert(area.NormalizeFromStorage("one\r\ntwo\n")).Equal("one\rtwo\r")
+			})
+		}
+	})
+}
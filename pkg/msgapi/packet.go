@@ -0,0 +1,267 @@
+package msgapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/askovpen/gossiped/pkg/types"
+	"github.com/askovpen/gossiped/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// pktHeader is the FSC-0048 type 2+ packet header
+type pktHeader struct {
+	OrigNode  uint16
+	DestNode  uint16
+	Year      uint16
+	Month     uint16
+	Day       uint16
+	Hour      uint16
+	Minute    uint16
+	Second    uint16
+	Baud      uint16
+	PktVer    uint16
+	OrigNet   uint16
+	DestNet   uint16
+	ProdCodeL uint8
+	Revision  uint8
+	Password  [8]byte
+	QOrigZone uint16
+	QDestZone uint16
+	AuxNet    uint16
+	CWCopy    uint16
+	ProdCodeH uint8
+	RevMinor  uint8
+	CapWord   uint16
+	OrigZone  uint16
+	DestZone  uint16
+	OrigPoint uint16
+	DestPoint uint16
+	ProdData  [4]byte
+}
+
+// pktMessage is a packed message of a type 2 packet, its strings NUL
+// terminated
+type pktMessage struct {
+	MsgType  uint16
+	OrigNode uint16
+	DestNode uint16
+	OrigNet  uint16
+	DestNet  uint16
+	Attr     uint16
+	Cost     uint16
+	DateTime [20]byte
+	To       string
+	From     string
+	Subject  string
+	Text     string
+}
+
+// pktProductCode is the FTSC product code for programs without one
+const pktProductCode = 0xfe
+
+// ExportAreaToPacket writes the echomail of the area as a type 2+ packet
+// from origin to dest, each message with its AREA line and the SEEN-BY and
+// PATH stored by the tosser. Messages not tossed yet get the SEEN-BY of
+// origin and dest and the PATH of origin. An empty area gives a packet
+// with the header only.
+func (a *SQLArea) ExportAreaToPacket(w io.Writer, origin, dest *types.FidoAddr) error {
+	if a.areaType == EchoAreaTypeNetmail {
+		return fmt.Errorf("area %s is not an echo area", a.areaName)
+	}
+	if origin.IsZero() || origin.GetZone() == 0 {
+		return errors.New("packet origin address not valid")
+	}
+	if dest.IsZero() || dest.GetZone() == 0 {
+		return errors.New("packet destination address not valid")
+	}
+
+	var buf bytes.Buffer
+	if err := utils.WriteStructToBuffer(&buf, newPktHeader(origin, dest, time.Now())); err != nil {
+		return err
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("error writing packet: %w", err)
+	}
+
+	var batch []database.Echomail
+	res := a.db.Where("echoarea_id = ?", a.areaID).Order("id ASC").
+		FindInBatches(&batch, 100, func(tx *gorm.DB, n int) error {
+			for i := range batch {
+				buf.Reset()
+				if err := utils.WriteStructToBuffer(&buf, a.packMessage(&batch[i], origin, dest)); err != nil {
+					return err
+				}
+				if _, err := w.Write(buf.Bytes()); err != nil {
+					return fmt.Errorf("error writing packet: %w", err)
+				}
+			}
+			return nil
+		})
+	if res.Error != nil {
+		return fmt.Errorf("error exporting area %s: %w", a.areaName, res.Error)
+	}
+
+	// a zero message type ends the packet
+	if _, err := w.Write([]byte{0, 0}); err != nil {
+		return fmt.Errorf("error writing packet: %w", err)
+	}
+	return nil
+}
+
+// newPktHeader returns the header of a packet from origin to dest made at t
+func newPktHeader(origin, dest *types.FidoAddr, t time.Time) *pktHeader {
+	h := &pktHeader{
+		OrigNode:  origin.GetNode(),
+		DestNode:  dest.GetNode(),
+		Year:      uint16(t.Year()),
+		Month:     uint16(t.Month()) - 1,
+		Day:       uint16(t.Day()),
+		Hour:      uint16(t.Hour()),
+		Minute:    uint16(t.Minute()),
+		Second:    uint16(t.Second()),
+		PktVer:    2,
+		OrigNet:   origin.GetNet(),
+		DestNet:   dest.GetNet(),
+		ProdCodeL: pktProductCode,
+		QOrigZone: origin.GetZone(),
+		QDestZone: dest.GetZone(),
+		CWCopy:    0x0100,
+		CapWord:   0x0001,
+		OrigZone:  origin.GetZone(),
+		DestZone:  dest.GetZone(),
+		OrigPoint: origin.GetPoint(),
+		DestPoint: dest.GetPoint(),
+	}
+	if origin.GetPoint() > 0 {
+		// type 2+ packets of points carry the net in AuxNet
+		h.OrigNet, h.AuxNet = 0xffff, origin.GetNet()
+	}
+	return h
+}
+
+// packMessage returns the stored echomail as a packed message from origin
+// to dest, its text encoded to its CHRS
+func (a *SQLArea) packMessage(echomail *database.Echomail, origin, dest *types.FidoAddr) *pktMessage {
+	text := a.NormalizeFromStorage(echomail.Message)
+	if text != "" && !strings.HasSuffix(text, "\r") {
+		text += "\r"
+	}
+	var kludges string
+	if echomail.MsgID != "" && !strings.Contains(text, "\x01MSGID:") {
+		kludges += "\x01MSGID: " + echomail.MsgID + "\r"
+	}
+	chrs := "UTF-8"
+	if i := strings.Index(text, "\x01CHRS:"); i >= 0 {
+		chrs = strings.ToUpper(strings.Fields(text[i+6:] + " UTF-8")[0])
+	} else if stored := a.chrsFor(config.Config.Chrs.Default); stored != "" {
+		kludges += "\x01CHRS: " + stored + "\r"
+		chrs = strings.ToUpper(strings.Fields(stored)[0])
+	}
+	text = "AREA:" + a.areaName + "\r" + kludges + text
+	if !strings.Contains(text, "\rSEEN-BY:") {
+		text += pktControlLines("SEEN-BY:", a.NormalizeFromStorage(echomail.SeenBy), seenByOf(origin, dest)...) +
+			pktControlLines("\x01PATH:", a.NormalizeFromStorage(echomail.Path), origin.GetNet(), origin.GetNode())
+	}
+
+	m := &pktMessage{
+		MsgType:  2,
+		OrigNode: origin.GetNode(),
+		DestNode: dest.GetNode(),
+		OrigNet:  origin.GetNet(),
+		DestNet:  dest.GetNet(),
+		To:       pktString(utils.EncodeCharmap(echomail.ToName, chrs), 35),
+		From:     pktString(utils.EncodeCharmap(echomail.FromName, chrs), 35),
+		Subject:  pktString(utils.EncodeCharmap(echomail.Subject, chrs), 71),
+		Text:     utils.EncodeCharmapChunked(text, chrs) + "\x00",
+	}
+	copy(m.DateTime[:], dateHelper.FromUnixTime(echomail.Date).Format("02 Jan 06  15:04:05"))
+	return m
+}
+
+// pktString cuts s to max bytes and terminates it with NUL
+func pktString(s string, max int) string {
+	if len(s) > max {
+		s = s[:max]
+	}
+	return s + "\x00"
+}
+
+// seenByOf returns the net/node pairs of origin and dest, sorted
+func seenByOf(origin, dest *types.FidoAddr) []uint16 {
+	nodes := [][2]uint16{{origin.GetNet(), origin.GetNode()}, {dest.GetNet(), dest.GetNode()}}
+	slices.SortFunc(nodes, func(x, y [2]uint16) int {
+		if x[0] != y[0] {
+			return int(x[0]) - int(y[0])
+		}
+		return int(x[1]) - int(y[1])
+	})
+	nodes = slices.Compact(nodes)
+	var pairs []uint16
+	for _, n := range nodes {
+		pairs = append(pairs, n[0], n[1])
+	}
+	return pairs
+}
+
+// pktControlLines returns the SEEN-BY or PATH lines of a packed message:
+// the stored ones, as lines already or as a list of 2D addresses, else
+// ones made of the net/node pairs. stored has FTN line endings, as returned
+// by NormalizeFromStorage.
+func pktControlLines(prefix, stored string, pairs ...uint16) string {
+	stored = strings.TrimSpace(stored)
+	if strings.Contains(stored, strings.TrimPrefix(prefix, "\x01")) {
+		var sb strings.Builder
+		for _, l := range strings.Split(stored, ftnLineEnding) {
+			if l == "" {
+				continue
+			}
+			if strings.HasPrefix(l, "PATH:") {
+				l = "\x01" + l
+			}
+			sb.WriteString(l + ftnLineEnding)
+		}
+		return sb.String()
+	}
+	addrs := strings.Fields(stored)
+	if len(addrs) == 0 {
+		for i := 0; i+1 < len(pairs); i += 2 {
+			addrs = append(addrs, fmt.Sprintf("%d/%d", pairs[i], pairs[i+1]))
+		}
+	}
+	return wrap2D(prefix, addrs)
+}
+
+// wrap2D writes 2D addresses after prefix in lines of at most 79
+// characters, leaving out the net of an address in the net before it. A
+// node without a net is in the net of the address before it.
+func wrap2D(prefix string, addrs []string) string {
+	var sb strings.Builder
+	line, net := prefix, ""
+	for _, addr := range addrs {
+		item := addr
+		if n, node, ok := strings.Cut(addr, "/"); !ok {
+			addr = net + "/" + addr
+		} else if n == net {
+			item = node
+		} else {
+			net = n
+		}
+		if len(line)+1+len(item) > 79 && line != prefix {
+			sb.WriteString(line + "\r")
+			line, item = prefix, addr
+		}
+		line += " " + item
+	}
+	if line != prefix {
+		sb.WriteString(line + "\r")
+	}
+	return sb.String()
+}
@@ -0,0 +1,89 @@
+package msgapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/askovpen/gossiped/pkg/types"
+	. "github.com/franela/goblin"
+)
+
+func TestExportAreaToPacket(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check ExportAreaToPacket()", func() {
+		origin := types.AddrFromString("2:5020/9696")
+		dest := types.AddrFromString("2:5020/1")
+		g.It("check addresses are validated", func() {
+			area := newTestSQLArea(t, 0)
+			var buf bytes.Buffer
+			g.Assert(area.ExportAreaToPacket(&buf, nil, dest) == nil).IsFalse()
+			g.Assert(area.ExportAreaToPacket(&buf, origin, &types.FidoAddr{}) == nil).IsFalse()
+			g.Assert(buf.Len()).Equal(0)
+		})
+		g.It("check empty area gives the header only", func() {
+			area := newTestSQLArea(t, 0)
+			var buf bytes.Buffer
+			g.Assert(area.ExportAreaToPacket(&buf, origin, dest)).IsNil()
+			pkt := buf.Bytes()
+			g.Assert(len(pkt)).Equal(60)
+			g.Assert(binary.LittleEndian.Uint16(pkt[0:])).Equal(uint16(9696))
+			g.Assert(binary.LittleEndian.Uint16(pkt[2:])).Equal(uint16(1))
+			g.Assert(binary.LittleEndian.Uint16(pkt[18:])).Equal(uint16(2))
+			g.Assert(binary.LittleEndian.Uint16(pkt[20:])).Equal(uint16(5020))
+			g.Assert(binary.LittleEndian.Uint16(pkt[44:])).Equal(uint16(1))
+			g.Assert(binary.LittleEndian.Uint16(pkt[46:])).Equal(uint16(2))
+			g.Assert(pkt[58:]).Equal([]byte{0, 0})
+		})
+		g.It("check point origin uses AuxNet", func() {
+			h := newPktHeader(types.AddrFromString("2:5020/9696.1"), dest, dateHelper.FromUnixTime(0))
+			g.Assert(h.OrigNet).Equal(uint16(0xffff))
+			g.Assert(h.AuxNet).Equal(uint16(5020))
+			g.Assert(h.OrigPoint).Equal(uint16(1))
+		})
+		g.It("check messages carry AREA, kludges, SEEN-BY and PATH", func() {
+			area := newTestSQLArea(t, 1)
+			area.SetChrs("CP866 2")
+			area.db.Create(&database.Echomail{
+				EchoareaID:  area.areaID,
+				FromName:    "Alexander Skovpen",
+				ToName:      "All",
+				FromFtnAddr: "2:5020/9696",
+				Subject:     "Тест",
+				Message:     "\x01CHRS: UTF-8 4\nПривет\n",
+				SeenBy:      "5020/1 5020/2 5030/1",
+				Path:        "5020/9696",
+				MsgID:       "2:5020/9696 12345678",
+			})
+			var buf bytes.Buffer
+			g.Assert(area.ExportAreaToPacket(&buf, origin, dest)).IsNil()
+			pkt := buf.String()
+			g.Assert(strings.HasSuffix(pkt, "\x00\x00\x00")).IsTrue()
+			msgs := strings.Split(pkt[58:len(pkt)-2], "\x02\x00\xe0\x25\x01\x00")
+			g.Assert(len(msgs)).Equal(3)
+
+			// stored without CHRS, written in the area charset
+			g.Assert(strings.Contains(msgs[1], "AREA:test.area\r\x01CHRS: CP866 2\rHello\r")).IsTrue()
+			g.Assert(strings.Contains(msgs[1], "\rSEEN-BY: 5020/1 9696\r\x01PATH: 5020/9696\r\x00")).IsTrue()
+
+			// stored CHRS, SEEN-BY and PATH are kept
+			g.Assert(strings.Contains(msgs[2], "All\x00Alexander Skovpen\x00Тест\x00")).IsTrue()
+			g.Assert(strings.Contains(msgs[2], "AREA:test.area\r\x01MSGID: 2:5020/9696 12345678\r\x01CHRS: UTF-8 4\rПривет\r")).IsTrue()
+			g.Assert(strings.Contains(msgs[2], "\rSEEN-BY: 5020/1 2 5030/1\r\x01PATH: 5020/9696\r\x00")).IsTrue()
+		})
+		g.It("check long SEEN-BY lines are wrapped", func() {
+			var addrs []string
+			for i := 1; i <= 30; i++ {
+				addrs = append(addrs, "5020/"+strings.Repeat("1", 3)+string(rune('0'+i%10)))
+			}
+			lines := strings.Split(strings.TrimSuffix(wrap2D("SEEN-BY:", addrs), "\r"), "\r")
+			g.Assert(len(lines) > 1).IsTrue()
+			for _, l := range lines {
+				g.Assert(len(l) <= 79).IsTrue()
+				g.Assert(strings.HasPrefix(l, "SEEN-BY: 5020/")).IsTrue()
+			}
+		})
+	})
+}
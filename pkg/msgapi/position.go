@@ -0,0 +1,99 @@
+package msgapi
+
+import (
+	"log"
+
+	"github.com/askovpen/gossiped/pkg/database"
+	"gorm.io/gorm"
+)
+
+// positionQuery builds the statement reading the message at a position of
+// an SQL area, the position being its row number in id order
+type positionQuery interface {
+	// SQL returns the statement selecting columns of the row at a position
+	// of table, rows filtered by where unless it is empty. The position
+	// is its last placeholder, after those of where.
+	SQL(columns, table, where string) string
+	// Arg returns the value of the position placeholder for position,
+	// counted from 1. Positions are not clamped: one past the last row
+	// selects nothing, callers check it against the count.
+	Arg(position uint32) int
+}
+
+// offsetPosition skips the rows before the position, which reads all of
+// them; the strategy of SQLite and the fallback of servers without window
+// functions
+type offsetPosition struct{}
+
+func (offsetPosition) SQL(columns, table, where string) string {
+	return "SELECT " + columns + " FROM " + table + whereClause(where) + " ORDER BY id ASC LIMIT 1 OFFSET ?"
+}
+
+func (offsetPosition) Arg(position uint32) int {
+	return int(position - 1)
+}
+
+// windowPosition numbers the ids of the area with ROW_NUMBER() and reads
+// the row of the id at the position by its primary key, the numbering
+// needing only the id index on MySQL 8 and Postgres. No id is numbered
+// with a position past the last row, which then selects nothing.
+type windowPosition struct{}
+
+func (windowPosition) SQL(columns, table, where string) string {
+	return "SELECT " + columns + " FROM " + table + " WHERE id = (" +
+		"SELECT id FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY id) AS rn FROM " + table + whereClause(where) + ") numbered" +
+		" WHERE rn = ?)"
+}
+
+func (windowPosition) Arg(position uint32) int {
+	return int(position)
+}
+
+// whereClause returns where as a WHERE clause, nothing if it is empty
+func whereClause(where string) string {
+	if where == "" {
+		return ""
+	}
+	return " WHERE " + where
+}
+
+// positionQueryFor returns the position strategy of the gorm dialect
+func positionQueryFor(dialect string) positionQuery {
+	switch dialect {
+	case "mysql", "postgres":
+		return windowPosition{}
+	}
+	return offsetPosition{}
+}
+
+// positionStrategy returns the position query of the area, picked by the
+// driver on first use
+func (a *SQLArea) positionStrategy() positionQuery {
+	a.stmtMu.Lock()
+	defer a.stmtMu.Unlock()
+	if a.positions == nil {
+		a.positions = positionQueryFor(a.db.Dialector.Name())
+	}
+	return a.positions
+}
+
+// scanAt scans columns of the message at position into dest. A server
+// refusing the window function query, like MySQL before 8.0, makes the
+// area fall back to OFFSET.
+func (a *SQLArea) scanAt(dest interface{}, columns string, position uint32) *gorm.DB {
+	table, where, args := "echomail", "echoarea_id = ?", []interface{}{a.areaID}
+	if a.areaType == EchoAreaTypeNetmail {
+		table, where, args = "netmail", "", nil
+	}
+	pq := a.positionStrategy()
+	res := a.stmtQuery().Raw(pq.SQL(columns, table, where), append(args, pq.Arg(position))...).Scan(dest)
+	if _, ok := pq.(windowPosition); ok && res.Error != nil &&
+		!database.IsTransient(res.Error) && !database.IsTimeout(res.Error) {
+		log.Printf("Window function position query failed in %s, using OFFSET: %v", a.areaName, res.Error)
+		a.stmtMu.Lock()
+		a.positions = offsetPosition{}
+		a.stmtMu.Unlock()
+		return a.scanAt(dest, columns, position)
+	}
+	return res
+}
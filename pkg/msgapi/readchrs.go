@@ -0,0 +1,32 @@
+package msgapi
+
+import (
+	"strings"
+	"sync"
+)
+
+var (
+	readChrsMu sync.Mutex
+	readChrs   = map[string]string{}
+)
+
+// SetReadChrs makes messages of the named area read in chrs for the rest of
+// the session, whatever their CHRS kludge says unless it is UTF-8; "" reads
+// them as before
+func SetReadChrs(areaName string, chrs string) {
+	readChrsMu.Lock()
+	defer readChrsMu.Unlock()
+	if chrs == "" {
+		delete(readChrs, areaName)
+		return
+	}
+	readChrs[areaName] = strings.ToUpper(chrs)
+}
+
+// GetReadChrs returns the charset messages of the named area are read in,
+// "" if it was not changed
+func GetReadChrs(areaName string) string {
+	readChrsMu.Lock()
+	defer readChrsMu.Unlock()
+	return readChrs[areaName]
+}
@@ -0,0 +1,147 @@
+package msgapi
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"gorm.io/gorm/clause"
+)
+
+// Netmail attributes of return receipts: ReceiptReqAttr (MSG_RRQ) asks
+// the addressee for a receipt, ReceiptAttr (MSG_CPT) marks the receipt
+const (
+	ReceiptReqAttr = "Rrq"
+	ReceiptAttr    = "Cpt"
+)
+
+// WantsReceipt reports whether the netmail asks for a return receipt, by
+// its attribute or the RRQ flag of a FLAGS kludge
+func (m *Message) WantsReceipt() bool {
+	if slices.Contains(m.Attrs, ReceiptReqAttr) {
+		return true
+	}
+	for _, l := range strings.Split(m.Body, "\x0d") {
+		if flags, ok := strings.CutPrefix(l, "\x01FLAGS "); ok {
+			if slices.Contains(strings.Fields(strings.ToUpper(flags)), "RRQ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetReceiptRequest sets or clears the return receipt request attribute
+func (m *Message) SetReceiptRequest(on bool) {
+	m.Attrs = slices.DeleteFunc(m.Attrs, func(attr string) bool {
+		return attr == ReceiptReqAttr
+	})
+	if on {
+		m.Attrs = append(m.Attrs, ReceiptReqAttr)
+	}
+}
+
+// Receipt returns the return receipt of the netmail m received at t, a
+// private netmail to its sender from the aka of the sender's zone
+// answering its MSGID, to be saved to area
+func (m *Message) Receipt(area *AreaPrimitive, t time.Time) *Message {
+	r := &Message{
+		AreaObject: area,
+		From:       config.GetFromName((*area).GetName()),
+		FromAddr:   config.GetOriginAddr(m.FromAddr),
+		To:         m.From,
+		ToAddr:     m.FromAddr,
+		Subject:    "Return receipt",
+		Attrs:      []string{"Pvt", ReceiptAttr},
+		Kludges:    map[string]string{"PID:": config.PID, "CHRS:": config.Config.Chrs.Default},
+	}
+	if chrs := (*area).GetChrs(); chrs != "" {
+		r.Kludges["CHRS:"] = chrs
+	}
+	if msgid := m.GetKludge("MSGID"); msgid != "" {
+		r.Kludges["REPLY:"] = msgid
+	}
+	r.Body = fmt.Sprintf("Your message to %s\nwritten %s\nsubject: %s\nwas received by %s at %s on %s.\n",
+		m.To,
+		m.DateWritten.Format("02 Jan 06 15:04:05"),
+		m.Subject,
+		m.To,
+		m.ToAddr.String(),
+		t.Format("02 Jan 06 15:04:05"),
+	)
+	return r
+}
+
+// SendReceipt saves the return receipt of the netmail msg, received at t,
+// to area and clears the request of msg, so opening it again does not
+// answer it twice. Only netmail to one of our addresses asking for a
+// receipt is answered, and only in jnode-sql netmail where the answered
+// request can be recorded.
+func SendReceipt(area *AreaPrimitive, msg *Message, t time.Time) (*Message, error) {
+	sqlArea, ok := (*area).(*SQLArea)
+	if !ok || sqlArea.GetType() != EchoAreaTypeNetmail {
+		return nil, fmt.Errorf("return receipts are only sent from jnode-sql netmail")
+	}
+	if !msg.WantsReceipt() {
+		return nil, fmt.Errorf("message does not request a return receipt")
+	}
+	if msg.ToAddr == nil || !config.IsAka(msg.ToAddr) {
+		return nil, fmt.Errorf("message is not addressed to one of our addresses")
+	}
+	if msg.FromAddr.IsZero() {
+		return nil, fmt.Errorf("message has no sender address for a return receipt")
+	}
+	receipt := msg.Receipt(area, t)
+	if err := sqlArea.SaveMsg(receipt.MakeBody()); err != nil {
+		return nil, err
+	}
+	if err := sqlArea.clearReceiptRequest(msg.ID); err != nil {
+		return receipt, fmt.Errorf("return receipt sent but not recorded: %w", err)
+	}
+	msg.SetReceiptRequest(false)
+	msg.Body = withoutReceiptFlag(msg.Body)
+	return receipt, nil
+}
+
+// clearReceiptRequest removes the return receipt request attribute and RRQ
+// flag of the netmail with id
+func (a *SQLArea) clearReceiptRequest(id int64) error {
+	var netmail database.Netmail
+	if err := a.db.Where("id = ?", id).First(&netmail).Error; err != nil {
+		return fmt.Errorf("error finding netmail message %d: %w", id, err)
+	}
+	text := a.NormalizeForStorage(withoutReceiptFlag(a.NormalizeFromStorage(netmail.Text)))
+	err := a.db.Model(&netmail).Omit(clause.Associations).Updates(map[string]interface{}{
+		"attr":          netmail.Attr &^ 4096, // MSG_RRQ
+		"text":          text,
+		"last_modified": dateHelper.ToUnixTime(time.Now()),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("error updating netmail message %d: %w", id, err)
+	}
+	a.messageListValid = false
+	return nil
+}
+
+// withoutReceiptFlag drops RRQ from the FLAGS kludge of body, and the
+// kludge itself when no other flag is left
+func withoutReceiptFlag(body string) string {
+	lines := strings.Split(body, ftnLineEnding)
+	kept := lines[:0]
+	for _, l := range lines {
+		if flags, ok := strings.CutPrefix(l, "\x01FLAGS "); ok {
+			fields := slices.DeleteFunc(strings.Fields(flags), func(f string) bool {
+				return strings.EqualFold(f, "RRQ")
+			})
+			if len(fields) == 0 {
+				continue
+			}
+			l = "\x01FLAGS " + strings.Join(fields, " ")
+		}
+		kept = append(kept, l)
+	}
+	return strings.Join(kept, ftnLineEnding)
+}
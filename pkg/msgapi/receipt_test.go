@@ -0,0 +1,123 @@
+package msgapi
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/askovpen/gossiped/pkg/types"
+	. "github.com/franela/goblin"
+)
+
+func TestReceipt(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check netmail return receipts", func() {
+		echo := newTestSQLArea(t, 0)
+		var netmail AreaPrimitive = NewSQLNetmailArea(echo.db)
+		var linkID int64
+		savedAddress, savedAkas, savedUser := config.Config.Address, config.Config.Akas, config.Config.Username
+		g.Before(func() {
+			g.Assert(echo.db.AutoMigrate(&database.Netmail{}, &database.Link{}, &database.LinkOption{}, &database.Route{})).IsNil()
+			link := database.Link{StationName: "2:5030/100", FtnAddress: "2:5030/100"}
+			g.Assert(echo.db.Create(&link).Error).IsNil()
+			linkID = link.ID
+			config.Config.Address = types.AddrFromString("2:5020/9696")
+			config.Config.Akas = []*types.FidoAddr{types.AddrFromString("1:123/45")}
+			config.Config.Username = "Sysop"
+		})
+		g.After(func() {
+			config.Config.Address, config.Config.Akas, config.Config.Username = savedAddress, savedAkas, savedUser
+		})
+		original := func() *Message {
+			return &Message{
+				From:        "Alexander Skovpen",
+				FromAddr:    types.AddrFromString("2:5030/100.5"),
+				To:          "Sysop",
+				ToAddr:      types.AddrFromString("2:5020/9696"),
+				Subject:     "Hello",
+				DateWritten: time.Date(2026, 10, 1, 12, 30, 0, 0, time.UTC),
+				Kludges:     map[string]string{"MSGID:": "2:5030/100.5 12345678"},
+			}
+		}
+		g.It("check WantsReceipt() and SetReceiptRequest()", func() {
+			m := &Message{Attrs: []string{"Pvt"}, Body: "\x01FLAGS DIR\x0dHello\x0d"}
+			g.Assert(m.WantsReceipt()).IsFalse()
+			m.SetReceiptRequest(true)
+			m.SetReceiptRequest(true)
+			g.Assert(m.Attrs).Equal([]string{"Pvt", "Rrq"})
+			g.Assert(m.WantsReceipt()).IsTrue()
+			m.SetReceiptRequest(false)
+			g.Assert(m.Attrs).Equal([]string{"Pvt"})
+			m.Body = "\x01FLAGS DIR rrq\x0dHello\x0d"
+			g.Assert(m.WantsReceipt()).IsTrue()
+		})
+		g.It("check receipt addressing and kludges", func() {
+			r := original().Receipt(&netmail, time.Date(2026, 10, 2, 8, 0, 0, 0, time.UTC))
+			g.Assert(r.To).Equal("Alexander Skovpen")
+			g.Assert(r.ToAddr.String()).Equal("2:5030/100.5")
+			g.Assert(r.From).Equal("Sysop")
+			g.Assert(r.FromAddr.String()).Equal("2:5020/9696")
+			g.Assert(r.Attrs).Equal([]string{"Pvt", "Cpt"})
+			g.Assert(r.Kludges["REPLY:"]).Equal("2:5030/100.5 12345678")
+			g.Assert(strings.Contains(r.Body, "written 01 Oct 26 12:30:00")).IsTrue()
+			g.Assert(strings.Contains(r.Body, "subject: Hello")).IsTrue()
+			g.Assert(strings.Contains(r.Body, "at 2:5020/9696 on 02 Oct 26 08:00:00")).IsTrue()
+			r.MakeBody()
+			g.Assert(r.Kludges["INTL"]).Equal("2:5030/100 2:5020/9696")
+			g.Assert(r.Kludges["TOPT"]).Equal("5")
+			_, ok := r.Kludges["FMPT"]
+			g.Assert(ok).IsFalse()
+		})
+		g.It("check receipt from the aka of the sender's zone", func() {
+			m := original()
+			m.FromAddr = types.AddrFromString("1:234/5")
+			r := m.Receipt(&netmail, time.Now())
+			g.Assert(r.FromAddr.String()).Equal("1:123/45")
+		})
+		g.It("check saved receipt is routed back with its attributes", func() {
+			g.Assert(netmail.SaveMsg(original().Receipt(&netmail, time.Now()).MakeBody())).IsNil()
+			var stored database.Netmail
+			g.Assert(echo.db.Last(&stored).Error).IsNil()
+			g.Assert(stored.ToAddress).Equal("2:5030/100.5")
+			g.Assert(stored.RouteVia != nil && *stored.RouteVia == linkID).IsTrue()
+			g.Assert(stored.Attr & 8192).Equal(8192)
+			g.Assert(strings.Contains(stored.Text, "\x01REPLY: 2:5030/100.5 12345678")).IsTrue()
+			msg, err := netmail.GetMsg(netmail.GetCount())
+			g.Assert(err).IsNil()
+			g.Assert(msg.Attrs).Equal([]string{"Pvt", "Cpt"})
+		})
+		g.It("check SendReceipt() answers a request once", func() {
+			// Received netmail is stored by the tosser, not saved from here
+			g.Assert(echo.db.Create(&database.Netmail{FromName: "Alexander Skovpen", ToName: "Sysop",
+				FromAddress: "2:5030/100.5", ToAddress: "2:5020/9696", Subject: "Hello", Attr: 4096 | 1,
+				Text: "\x01MSGID: 2:5030/100.5 12345678\n\x01FLAGS DIR RRQ\nHello\n"}).Error).IsNil()
+			count := netmail.GetCount()
+			msg, err := netmail.GetMsg(count)
+			g.Assert(err).IsNil()
+			g.Assert(msg.WantsReceipt()).IsTrue()
+			r, err := SendReceipt(&netmail, msg, time.Now())
+			g.Assert(err).IsNil()
+			g.Assert(r.To).Equal("Alexander Skovpen")
+			g.Assert(netmail.GetCount()).Equal(count + 1)
+			g.Assert(msg.WantsReceipt()).IsFalse()
+			msg, err = netmail.GetMsg(count)
+			g.Assert(err).IsNil()
+			g.Assert(msg.WantsReceipt()).IsFalse()
+			g.Assert(strings.Contains(msg.Body, "\x01FLAGS DIR\x0d")).IsTrue()
+			_, err = SendReceipt(&netmail, msg, time.Now())
+			g.Assert(err == nil).IsFalse()
+			g.Assert(netmail.GetCount()).Equal(count + 1)
+		})
+		g.It("check SendReceipt() refuses netmail to others and without a request", func() {
+			m := original()
+			m.Attrs = []string{ReceiptReqAttr}
+			m.ToAddr = types.AddrFromString("2:5030/200")
+			_, err := SendReceipt(&netmail, m, time.Now())
+			g.Assert(err == nil).IsFalse()
+			_, err = SendReceipt(&netmail, original(), time.Now())
+			g.Assert(err == nil).IsFalse()
+		})
+	})
+}
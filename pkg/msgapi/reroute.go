@@ -0,0 +1,130 @@
+package msgapi
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/askovpen/gossiped/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// netmailHold is the jnode attribute bit of held netmail (MSG_HOLD)
+const netmailHold = 512
+
+// UnroutedNetmail is an unsent netmail findNetmailRoute finds no link for
+type UnroutedNetmail struct {
+	MessageListItem
+	ToAddr  string
+	Problem string // why routing failed
+}
+
+// UnroutedNetmail returns the unsent netmail without a route which can not
+// be routed now either, in id order, so the address can be fixed before
+// RerouteNetmail
+func (a *SQLArea) UnroutedNetmail() ([]UnroutedNetmail, error) {
+	if a.areaType != EchoAreaTypeNetmail {
+		return nil, fmt.Errorf("area %s is not netmail", a.areaName)
+	}
+	var ids []int64
+	if err := a.db.Model(&database.Netmail{}).Order("id ASC").Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("error listing netmail: %w", err)
+	}
+	var stuck []database.Netmail
+	err := a.db.Where("send = ? AND route_via IS NULL", false).Order("id ASC").
+		Omit("text").Find(&stuck).Error
+	if err != nil {
+		return nil, fmt.Errorf("error listing unsent netmail: %w", err)
+	}
+	var unrouted []UnroutedNetmail
+	for _, nm := range stuck {
+		_, err := a.findNetmailRoute(routedMessage(&nm))
+		if err == nil {
+			// direct links are stored without route_via too
+			continue
+		}
+		pos, _ := slices.BinarySearch(ids, nm.ID)
+		unrouted = append(unrouted, UnroutedNetmail{
+			MessageListItem: MessageListItem{
+				ID:          nm.ID,
+				MsgNum:      uint32(pos + 1),
+				From:        nm.FromName,
+				To:          nm.ToName,
+				Subject:     nm.Subject,
+				DateWritten: dateHelper.FromUnixTime(nm.Date),
+			},
+			ToAddr:  nm.ToAddress,
+			Problem: err.Error(),
+		})
+	}
+	return unrouted, nil
+}
+
+// RerouteNetmail runs routing again for the unsent netmail with database id
+// netmailID, e.g. after its address was corrected, and stores the new route.
+// The hold set by netmail.hold_unroutable is lifted once a route is found.
+func (a *SQLArea) RerouteNetmail(netmailID int64) error {
+	if a.areaType != EchoAreaTypeNetmail {
+		return fmt.Errorf("area %s is not netmail", a.areaName)
+	}
+	var nm database.Netmail
+	if err := a.db.First(&nm, netmailID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("netmail id %d: %w", netmailID, ErrMsgNotFound)
+	} else if err != nil {
+		return fmt.Errorf("error finding netmail to reroute: %w", err)
+	}
+	if nm.Send {
+		return fmt.Errorf("netmail to %s is already sent", nm.ToAddress)
+	}
+	routeVia, routeErr := a.findNetmailRoute(routedMessage(&nm))
+	updates := map[string]interface{}{
+		"route_via":     routeVia,
+		"last_modified": dateHelper.ToUnixTime(time.Now()),
+	}
+	if config.Config.Netmail.HoldUnroutable {
+		if routeErr != nil {
+			updates["attr"] = nm.Attr | netmailHold
+		} else {
+			updates["attr"] = nm.Attr &^ netmailHold
+		}
+	}
+	// a readdressed netmail no route is found for loses its old route
+	if err := a.db.Model(&nm).Omit(clause.Associations).Updates(updates).Error; err != nil {
+		return fmt.Errorf("error storing netmail route: %w", err)
+	}
+	a.messageListValid = false
+	if routeErr != nil {
+		log.Printf("Reroute of netmail %d to %s failed: %v", nm.ID, nm.ToAddress, routeErr)
+		return fmt.Errorf("netmail to %s: %w", nm.ToAddress, routeErr)
+	}
+	if routeVia != nil {
+		log.Printf("Rerouted netmail %d to %s via link %d", nm.ID, nm.ToAddress, *routeVia)
+	} else {
+		log.Printf("Rerouted netmail %d to %s directly", nm.ID, nm.ToAddress)
+	}
+	return nil
+}
+
+// routedMessage returns the header of the stored netmail findNetmailRoute
+// matches routes against
+func routedMessage(nm *database.Netmail) *Message {
+	msg := &Message{
+		From:     nm.FromName,
+		To:       nm.ToName,
+		Subject:  nm.Subject,
+		FromAddr: types.AddrFromString(nm.FromAddress),
+		ToAddr:   types.AddrFromString(nm.ToAddress),
+	}
+	if msg.FromAddr == nil {
+		msg.FromAddr = &types.FidoAddr{}
+	}
+	if msg.ToAddr == nil {
+		msg.ToAddr = &types.FidoAddr{}
+	}
+	return msg
+}
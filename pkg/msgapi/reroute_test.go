@@ -0,0 +1,89 @@
+package msgapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/askovpen/gossiped/pkg/types"
+	. "github.com/franela/goblin"
+)
+
+func TestRerouteNetmail(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check unrouted netmail", func() {
+		echo := newTestSQLArea(t, 0)
+		netmail := NewSQLNetmailArea(echo.db)
+		var linkID int64
+		savedAddress, savedHold := config.Config.Address, config.Config.Netmail.HoldUnroutable
+		g.Before(func() {
+			g.Assert(echo.db.AutoMigrate(&database.Netmail{}, &database.Link{}, &database.LinkOption{}, &database.Route{})).IsNil()
+			link := database.Link{StationName: "2:5030/100", FtnAddress: "2:5030/100"}
+			g.Assert(echo.db.Create(&link).Error).IsNil()
+			linkID = link.ID
+			config.Config.Address = types.AddrFromString("2:5020/9696")
+			config.Config.Netmail.HoldUnroutable = true
+		})
+		g.After(func() {
+			config.Config.Address, config.Config.Netmail.HoldUnroutable = savedAddress, savedHold
+		})
+		save := func(to string) {
+			var areaPtr AreaPrimitive = netmail
+			g.Assert(netmail.SaveMsg(&Message{
+				AreaObject: &areaPtr,
+				From:       "Sysop",
+				FromAddr:   types.AddrFromString("2:5020/9696"),
+				To:         "Alexander Skovpen",
+				ToAddr:     types.AddrFromString(to),
+				Subject:    "Hello " + to,
+				Body:       "Hello\n",
+				Attrs:      []string{"Pvt"},
+				Kludges:    map[string]string{},
+			})).IsNil()
+		}
+		g.It("check unroutable netmail is held and listed", func() {
+			save("2:5030/100")
+			save("2:9999/1.2")
+			var stored database.Netmail
+			g.Assert(echo.db.Last(&stored).Error).IsNil()
+			g.Assert(stored.RouteVia == nil).IsTrue()
+			g.Assert(stored.Attr & netmailHold).Equal(netmailHold)
+			unrouted, err := netmail.UnroutedNetmail()
+			g.Assert(err).IsNil()
+			g.Assert(len(unrouted)).Equal(1)
+			g.Assert(unrouted[0].ID).Equal(stored.ID)
+			g.Assert(unrouted[0].MsgNum).Equal(uint32(2))
+			g.Assert(unrouted[0].ToAddr).Equal("2:9999/1.2")
+			g.Assert(unrouted[0].Problem == "").IsFalse()
+		})
+		g.It("check RerouteNetmail() keeps failing for the same address", func() {
+			unrouted, _ := netmail.UnroutedNetmail()
+			g.Assert(netmail.RerouteNetmail(unrouted[0].ID) == nil).IsFalse()
+		})
+		g.It("check fixed address is routed and the hold lifted", func() {
+			unrouted, _ := netmail.UnroutedNetmail()
+			msg, err := netmail.GetStoredMsg(unrouted[0].MsgNum)
+			g.Assert(err).IsNil()
+			msg.ToAddr = types.AddrFromString("2:5030/100.7")
+			g.Assert(netmail.UpdateMsg(unrouted[0].MsgNum, msg)).IsNil()
+			var stored database.Netmail
+			g.Assert(echo.db.First(&stored, unrouted[0].ID).Error).IsNil()
+			g.Assert(stored.ToAddress).Equal("2:5030/100.7")
+			g.Assert(stored.RouteVia != nil && *stored.RouteVia == linkID).IsTrue()
+			g.Assert(stored.Attr&netmailHold == 0).IsTrue()
+			g.Assert(strings.HasPrefix(stored.Text, "\x01INTL 2:5030/100 2:5020/9696\n\x01TOPT 7\n")).IsTrue()
+			g.Assert(strings.Count(stored.Text, "\x01INTL")).Equal(1)
+			g.Assert(strings.Contains(stored.Text, "\nHello\n")).IsTrue()
+			unrouted, err = netmail.UnroutedNetmail()
+			g.Assert(err).IsNil()
+			g.Assert(len(unrouted)).Equal(0)
+		})
+		g.It("check sent netmail is not rerouted", func() {
+			var stored database.Netmail
+			g.Assert(echo.db.First(&stored).Error).IsNil()
+			g.Assert(echo.db.Model(&stored).Update("send", true).Error).IsNil()
+			g.Assert(netmail.RerouteNetmail(stored.ID) == nil).IsFalse()
+		})
+	})
+}
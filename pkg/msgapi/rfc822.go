@@ -0,0 +1,101 @@
+package msgapi
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/types"
+	"github.com/askovpen/gossiped/pkg/utils"
+)
+
+// rfc822Address maps an FTN name and address to a gateway style e-mail
+// address: "Name" <First_Last@p1.f2.n3.z4.fidonet.org>
+func rfc822Address(name string, addr *types.FidoAddr) string {
+	local := strings.Join(strings.Fields(name), "_")
+	if local == "" {
+		local = "UUCP"
+	}
+	domain := "fidonet.org"
+	if !addr.IsZero() {
+		domain = fmt.Sprintf("f%d.n%d.z%d.%s", addr.GetNode(), addr.GetNet(), addr.GetZone(), domain)
+		if addr.GetPoint() > 0 {
+			domain = fmt.Sprintf("p%d.%s", addr.GetPoint(), domain)
+		}
+	}
+	return (&mail.Address{Name: name, Address: local + "@" + domain}).String()
+}
+
+// toUTF8 converts a message field to UTF-8. File based areas are decoded
+// to UTF-8 on read, SQL areas hold text in the display charset.
+func (m *Message) toUTF8(s string) string {
+	if m.AreaObject != nil && (*m.AreaObject).GetMsgType() == EchoAreaMsgTypeSQL {
+		s = utils.DecodeCharmap(s, strings.Split(config.Config.Chrs.Default, " ")[0])
+	}
+	return strings.ToValidUTF8(s, "�")
+}
+
+// textLines returns body lines without kludges and SEEN-BY, of a body with
+// the FTN line endings the areas read it with
+func (m *Message) textLines() []string {
+	var lines []string
+	for _, l := range strings.Split(m.Body, ftnLineEnding) {
+		if strings.HasPrefix(l, "\x01") || strings.HasPrefix(l, "SEEN-BY:") {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// PlainText returns the UTF-8 body without kludges and SEEN-BY
+func (m *Message) PlainText() string {
+	return m.toUTF8(strings.TrimRight(strings.Join(m.textLines(), "\n"), "\n"))
+}
+
+// QuotedText returns the UTF-8 body with quote prefixes, as used for replies
+func (m *Message) QuotedText() string {
+	lines := m.GetQuote()
+	// drop quoted empty lines at the end of the body
+	for len(lines) > 0 && strings.HasSuffix(strings.TrimSpace(lines[len(lines)-1]), ">") {
+		lines = lines[:len(lines)-1]
+	}
+	return m.toUTF8(strings.Join(lines, "\n"))
+}
+
+// ToRFC822 writes the message as RFC822 style text with From/To/Subject/Date
+// headers mapped from FTN fields and an UTF-8 body without kludges, ready to
+// be piped to a mailer
+func (m *Message) ToRFC822(w io.Writer) error {
+	var sb strings.Builder
+	header := func(name, value string) {
+		sb.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", rfc822Address(m.toUTF8(m.From), m.FromAddr))
+	header("To", rfc822Address(m.toUTF8(m.To), m.ToAddr))
+	header("Subject", mime.QEncoding.Encode("utf-8", m.toUTF8(m.Subject)))
+	date := m.DateWritten
+	if date.IsZero() {
+		date = time.Now()
+	}
+	header("Date", date.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+	if m.AreaObject != nil && (*m.AreaObject).GetType() != EchoAreaTypeNetmail {
+		header("X-FTN-Area", (*m.AreaObject).GetName())
+	}
+	if msgid := m.GetKludge("MSGID"); msgid != "" {
+		header("X-FTN-MSGID", msgid)
+	}
+	sb.WriteString("\r\n")
+	for _, l := range m.textLines() {
+		sb.WriteString(m.toUTF8(l) + "\r\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
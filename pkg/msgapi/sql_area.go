@@ -1,51 +1,102 @@
 package msgapi
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/askovpen/gossiped/pkg/config"
 	"github.com/askovpen/gossiped/pkg/database"
 	"github.com/askovpen/gossiped/pkg/types"
 	"github.com/askovpen/gossiped/pkg/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // DateHelper for time conversions
 var dateHelper = database.DateHelper{}
 
-// Global cache for message counts
+// Global cache for message counts, guarded by countMu as it is rebuilt
+// while areas read it
 var (
+	countMu           sync.Mutex
 	messageCountCache map[int64]int64
 	netmailCountCache int64
 	countCacheValid   bool
+
+	lastDateCache        map[int64]int64
+	netmailLastDateCache int64
 )
 
 // SQLArea implements AreaPrimitive interface for jnode SQL database
 type SQLArea struct {
-	db       *gorm.DB
-	areaID   int64
-	areaName string
-	areaType EchoAreaType
-	chrs     string
+	db          *gorm.DB
+	areaID      int64
+	areaName    string
+	areaType    EchoAreaType
+	chrs        string
+	description string
+	writeLevel  int64
 
 	// Cache for message list
 	messageListCache []MessageListItem
 	messageListValid bool
 
-	// Last read tracking
+	// Last read tracking, lastReadUnsaved while the position could not be
+	// written to the lastread database
 	lastReadPosition uint32
+	lastReadUnsaved  bool
+
+	// Prepared statements for navigation queries
+	stmtMu sync.Mutex
+	stmts  *gorm.PreparedStmtDB
+	stmtDB *gorm.DB
+
+	// Position to row strategy of the driver, see positionQuery
+	positions positionQuery
+}
+
+// stmtQuery returns a session reusing prepared statements of this area.
+// The cache is per area instead of gorm's shared PrepareStmt session cache,
+// so that Close releases only the statements of this area.
+func (a *SQLArea) stmtQuery() *gorm.DB {
+	a.stmtMu.Lock()
+	defer a.stmtMu.Unlock()
+	if a.stmtDB == nil {
+		a.stmts = gorm.NewPreparedStmtDB(a.db.ConnPool)
+		tx := a.db.Session(&gorm.Session{Context: context.Background()})
+		tx.Statement.ConnPool = a.stmts
+		a.stmtDB = tx
+	}
+	return a.stmtDB
+}
+
+// Close releases the prepared statements of the area
+func (a *SQLArea) Close() {
+	a.stmtMu.Lock()
+	defer a.stmtMu.Unlock()
+	if a.stmts != nil {
+		a.stmts.Close()
+	}
+	a.stmts = nil
+	a.stmtDB = nil
 }
 
 // NewSQLArea creates a new SQL area instance
 func NewSQLArea(db *gorm.DB, echoarea database.Echoarea) *SQLArea {
 	area := &SQLArea{
-		db:       db,
-		areaID:   echoarea.ID,
-		areaName: echoarea.Name,
-		chrs:     "", // Will be set from configuration
+		db:          db,
+		areaID:      echoarea.ID,
+		areaName:    echoarea.Name,
+		chrs:        "", // Will be set from configuration
+		description: echoarea.Description,
+		writeLevel:  echoarea.WLevel,
 	}
 
 	// Map jnode area type to gossiped area type
@@ -57,11 +108,12 @@ func NewSQLArea(db *gorm.DB, echoarea database.Echoarea) *SQLArea {
 // NewSQLNetmailArea creates a new SQL netmail area instance
 func NewSQLNetmailArea(db *gorm.DB) *SQLArea {
 	return &SQLArea{
-		db:       db,
-		areaID:   0, // Netmail doesn't have echoarea_id
-		areaName: "Netmail",
-		areaType: EchoAreaTypeNetmail,
-		chrs:     "",
+		db:          db,
+		areaID:      0, // Netmail doesn't have echoarea_id
+		areaName:    "Netmail",
+		areaType:    EchoAreaTypeNetmail,
+		chrs:        "",
+		description: "Private messages to and from this node",
 	}
 }
 
@@ -102,23 +154,100 @@ func RefreshMessageCounts() error {
 		return fmt.Errorf("failed to get netmail count: %w", err)
 	}
 
+	lastDates, err := database.GetAllEchoareaLastDates()
+	if err != nil {
+		return fmt.Errorf("failed to get echoarea last dates: %w", err)
+	}
+
+	netmailLastDate, err := database.GetNetmailLastDate()
+	if err != nil {
+		return fmt.Errorf("failed to get netmail last date: %w", err)
+	}
+
+	countMu.Lock()
 	messageCountCache = counts
 	netmailCountCache = netmailCount
+	lastDateCache = lastDates
+	netmailLastDateCache = netmailLastDate
 	countCacheValid = true
+	countMu.Unlock()
 
 	log.Printf("Loaded message counts for %d echoareas and %d netmail messages", len(counts), netmailCount)
 	return nil
 }
 
+// VerifyMessageCounts compares the cached message counts with fresh ones
+// from the database, logging each area which drifted, e.g. as jnode tossed
+// or purged messages meanwhile. It returns the number of stale counts and
+// leaves the cache as it is.
+func VerifyMessageCounts() (int, error) {
+	counts, err := database.GetAllEchoareaCounts()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get echoarea counts: %w", err)
+	}
+	netmailCount, err := database.GetNetmailCount()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get netmail count: %w", err)
+	}
+
+	countMu.Lock()
+	defer countMu.Unlock()
+	if !countCacheValid {
+		return 0, nil
+	}
+	stale := 0
+	for id := range counts {
+		if counts[id] != messageCountCache[id] {
+			log.Printf("Message count of echoarea %d: cached %d, database %d", id, messageCountCache[id], counts[id])
+			stale++
+		}
+	}
+	for id, cached := range messageCountCache {
+		if _, ok := counts[id]; !ok && cached != 0 {
+			log.Printf("Message count of echoarea %d: cached %d, database 0", id, cached)
+			stale++
+		}
+	}
+	if netmailCount != netmailCountCache {
+		log.Printf("Message count of netmail: cached %d, database %d", netmailCountCache, netmailCount)
+		stale++
+	}
+	return stale, nil
+}
+
 // InvalidateMessageCounts clears the message count cache
 func InvalidateMessageCounts() {
+	countMu.Lock()
+	defer countMu.Unlock()
 	countCacheValid = false
 	messageCountCache = nil
 	netmailCountCache = 0
+	lastDateCache = nil
+	netmailLastDateCache = 0
+}
+
+// touchLastDate advances the cached newest message date of an area
+func touchLastDate(areaID int64, isNetmail bool, date int64) {
+	countMu.Lock()
+	defer countMu.Unlock()
+	if !countCacheValid {
+		return // No cache to update
+	}
+
+	if isNetmail {
+		netmailLastDateCache = max(netmailLastDateCache, date)
+	} else {
+		if lastDateCache == nil {
+			lastDateCache = make(map[int64]int64)
+		}
+		lastDateCache[areaID] = max(lastDateCache[areaID], date)
+	}
 }
 
 // IncrementMessageCount increments the cached count for a specific area
 func IncrementMessageCount(areaID int64, isNetmail bool) {
+	countMu.Lock()
+	defer countMu.Unlock()
 	if !countCacheValid {
 		return // No cache to update
 	}
@@ -133,18 +262,37 @@ func IncrementMessageCount(areaID int64, isNetmail bool) {
 	}
 }
 
+// DecrementMessageCount decrements the cached count for a specific area
+func DecrementMessageCount(areaID int64, isNetmail bool) {
+	decrementMessageCountBy(areaID, isNetmail, 1)
+}
+
+// decrementMessageCountBy subtracts n from the cached count of an area
+func decrementMessageCountBy(areaID int64, isNetmail bool, n int64) {
+	countMu.Lock()
+	defer countMu.Unlock()
+	if !countCacheValid {
+		return // No cache to update
+	}
+
+	if isNetmail {
+		netmailCountCache = max(netmailCountCache-n, 0)
+	} else if messageCountCache[areaID] > 0 {
+		messageCountCache[areaID] = max(messageCountCache[areaID]-n, 0)
+	}
+}
+
 // GetCount returns the total number of messages in the area
 func (a *SQLArea) GetCount() uint32 {
-	// Use cached count if available
-	if countCacheValid {
-		if a.areaType == EchoAreaTypeNetmail {
-			return uint32(netmailCountCache)
-		} else {
-			if count, exists := messageCountCache[a.areaID]; exists {
-				return uint32(count)
-			}
-			return 0 // Area has no messages
-		}
+	// Use cached count if available, an area without messages has none
+	countMu.Lock()
+	valid, cached := countCacheValid, messageCountCache[a.areaID]
+	if a.areaType == EchoAreaTypeNetmail {
+		cached = netmailCountCache
+	}
+	countMu.Unlock()
+	if valid {
+		return uint32(cached)
 	}
 
 	// Fallback to individual query if cache is not valid
@@ -152,13 +300,13 @@ func (a *SQLArea) GetCount() uint32 {
 
 	if a.areaType == EchoAreaTypeNetmail {
 		// Count netmail messages
-		if err := a.db.Model(&database.Netmail{}).Count(&count).Error; err != nil {
+		if err := a.stmtQuery().Model(&database.Netmail{}).Count(&count).Error; err != nil {
 			log.Printf("Error counting netmail messages: %v", err)
 			return 0
 		}
 	} else {
 		// Count echomail messages for this area
-		if err := a.db.Model(&database.Echomail{}).Where("echoarea_id = ?", a.areaID).Count(&count).Error; err != nil {
+		if err := a.stmtQuery().Model(&database.Echomail{}).Where("echoarea_id = ?", a.areaID).Count(&count).Error; err != nil {
 			log.Printf("Error counting echomail messages for area %s: %v", a.areaName, err)
 			return 0
 		}
@@ -167,16 +315,63 @@ func (a *SQLArea) GetCount() uint32 {
 	return uint32(count)
 }
 
+// CountUnreadTo counts the messages after the lastread of the area whose
+// To is name, compared like utils.NamesEqual, with a COUNT query instead of
+// loading the message list
+func (a *SQLArea) CountUnreadTo(name string) (uint32, error) {
+	var lastID int64
+	if last := a.GetLast(); last > 0 {
+		res := a.scanAt(&lastID, "id", last)
+		if res.Error != nil {
+			return 0, fmt.Errorf("error counting unread messages of %s: %w", a.areaName, res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return 0, nil
+		}
+	}
+	var count int64
+	err := a.withRetry("CountUnreadTo", func() error {
+		q := a.stmtQuery().Table("netmail")
+		if a.areaType != EchoAreaTypeNetmail {
+			q = a.stmtQuery().Table("echomail").Where("echoarea_id = ?", a.areaID)
+		}
+		return q.Where("id > ? AND REPLACE(TRIM(to_name), '.', '') = ?",
+			lastID, strings.ReplaceAll(strings.Trim(name, " "), ".", "")).Count(&count).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error counting unread messages of %s: %w", a.areaName, err)
+	}
+	return uint32(count), nil
+}
+
+// GetLastDate returns the date of the newest message in the area,
+// zero time if the area is empty or counts are not loaded
+func (a *SQLArea) GetLastDate() time.Time {
+	countMu.Lock()
+	defer countMu.Unlock()
+	var last int64
+	if a.areaType == EchoAreaTypeNetmail {
+		last = netmailLastDateCache
+	} else {
+		last = lastDateCache[a.areaID]
+	}
+	if !countCacheValid || last == 0 {
+		return time.Time{}
+	}
+	return dateHelper.FromUnixTime(last)
+}
+
 // GetLast returns the last read message position
 func (a *SQLArea) GetLast() uint32 {
-	// First try to get from local SQLite database if enabled
-	if database.IsLastReadEnabled() {
+	// A position the lastread database failed to store is newer than its own
+	if database.IsLastReadEnabled() && !a.lastReadUnsaved {
 		position, err := database.GetLastRead(config.Config.Username, a.areaName)
 		if err != nil {
 			log.Printf("Error getting lastread from SQLite for area %s: %v", a.areaName, err)
 			// Fall back to memory cache
 			return a.lastReadPosition
 		}
+		a.lastReadPosition = position
 		return position
 	}
 	
@@ -192,46 +387,191 @@ func (a *SQLArea) SetLast(position uint32) {
 	// Save to local SQLite database if enabled
 	if database.IsLastReadEnabled() {
 		err := database.SetLastRead(config.Config.Username, a.areaName, position)
-		if err != nil {
+		if err != nil && !a.lastReadUnsaved {
 			log.Printf("Error saving lastread to SQLite for area %s: %v", a.areaName, err)
-			// Don't fail the operation if lastread save fails
 		}
+		// Don't fail the operation if lastread save fails, the memory
+		// cache serves GetLast until a save works again
+		a.lastReadUnsaved = err != nil
 	}
 }
 
-// GetMsg retrieves a message at the specified position
+// GetMsg retrieves a message at the specified position, 0 meaning the first
+// one; a position past the last message yields ErrMsgOutOfRange
 func (a *SQLArea) GetMsg(position uint32) (*Message, error) {
 	if position == 0 {
 		position = 1
 	}
 
+	var msg *Message
+	err := a.withRetry("GetMsg", func() error {
+		var err error
+		if a.areaType == EchoAreaTypeNetmail {
+			msg, err = a.getNetmailMessage(position)
+		} else {
+			msg, err = a.getEchomailMessage(position)
+		}
+		return err
+	})
+	return msg, err
+}
+
+// withRetry runs a query of the area with database.WithRetry, dropping the
+// prepared statements of a broken connection before the next attempt
+func (a *SQLArea) withRetry(op string, fn func() error) error {
+	return database.WithRetry(op+" "+a.areaName, a.closeOnTransient(fn))
+}
+
+// withWriteRetry runs statements of the area which must not run twice, like
+// the INSERT of a new message, with database.WithWriteRetry
+func (a *SQLArea) withWriteRetry(op string, fn func() error) error {
+	return database.WithWriteRetry(op+" "+a.areaName, a.closeOnTransient(fn))
+}
+
+// closeOnTransient wraps fn to close the prepared statements of the area
+// when it fails with a transient error
+func (a *SQLArea) closeOnTransient(fn func() error) func() error {
+	return func() error {
+		err := fn()
+		if database.IsTransient(err) {
+			a.Close()
+		}
+		return err
+	}
+}
+
+// GetMsgByID retrieves a message by its database id, which unlike the
+// position does not change when other messages are deleted
+func (a *SQLArea) GetMsgByID(id int64) (*Message, error) {
+	var msg *Message
+	err := a.withRetry("GetMsgByID", func() error {
+		var err error
+		msg, err = a.getMsgByID(id, false)
+		return err
+	})
+	return msg, err
+}
+
+// GetFullMsg retrieves the message with database id like GetMsgByID, with
+// the whole text of a large message instead of its preview
+func (a *SQLArea) GetFullMsg(id int64) (*Message, error) {
+	var msg *Message
+	err := a.withRetry("GetFullMsg", func() error {
+		var err error
+		msg, err = a.getMsgByID(id, true)
+		return err
+	})
+	return msg, err
+}
+
+// getMsgByID retrieves a message by its database id, see GetMsgByID; full
+// skips the large message preview
+func (a *SQLArea) getMsgByID(id int64, full bool) (*Message, error) {
+	table := "echomail"
+	where, args := "echoarea_id = ? AND id <= ?", []interface{}{a.areaID, id}
+	if a.areaType == EchoAreaTypeNetmail {
+		table, where, args = "netmail", "id <= ?", []interface{}{id}
+	}
+
+	var position int64
+	if err := a.stmtQuery().Table(table).Where(where, args...).Count(&position).Error; err != nil {
+		return nil, fmt.Errorf("error retrieving %s message %d: %w", table, id, err)
+	}
+
 	if a.areaType == EchoAreaTypeNetmail {
-		return a.getNetmailMessage(position)
-	} else {
-		return a.getEchomailMessage(position)
+		var netmail netmailRow
+		res := a.stmtQuery().Raw("SELECT "+a.messageColumns(full)+" FROM netmail WHERE id = ? LIMIT 1", id).Scan(&netmail)
+		if res.Error != nil {
+			return nil, fmt.Errorf("error retrieving netmail message %d: %w", id, res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return nil, fmt.Errorf("message id %d in %s: %w", id, a.areaName, ErrMsgNotFound)
+		}
+		return a.netmailMessage(&netmail.Netmail, uint32(position), previewSize(netmail.TextSize, full)), nil
 	}
+
+	var echomail echomailRow
+	res := a.stmtQuery().Raw("SELECT "+a.messageColumns(full)+" FROM echomail WHERE echoarea_id = ? AND id = ? LIMIT 1",
+		a.areaID, id).Scan(&echomail)
+	if res.Error != nil {
+		return nil, fmt.Errorf("error retrieving echomail message %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("message id %d in %s: %w", id, a.areaName, ErrMsgNotFound)
+	}
+	return a.echomailMessage(&echomail.Echomail, uint32(position), previewSize(echomail.TextSize, full)), nil
+}
+
+// echomailRow and netmailRow are messages read with messageColumns
+type echomailRow struct {
+	database.Echomail
+	TextSize int `gorm:"column:text_size"`
+}
+
+type netmailRow struct {
+	database.Netmail
+	TextSize int `gorm:"column:text_size"`
+}
+
+// Columns of a message besides its text, see messageColumns
+const (
+	echomailColumns = "id, echoarea_id, from_name, to_name, from_ftn_addr, date, subject, seen_by, path, msgid"
+	netmailColumns  = "id, from_name, to_name, from_address, to_address, subject, date, route_via, send, attr, last_modified"
+)
+
+// messageColumns returns the columns to read a message of the area with,
+// the length of its text in characters as text_size and, unless full is
+// set, only the first large_message_size characters of the text
+func (a *SQLArea) messageColumns(full bool) string {
+	columns, text := echomailColumns, "message"
+	if a.areaType == EchoAreaTypeNetmail {
+		columns, text = netmailColumns, "text"
+	}
+	length := "LENGTH"
+	if a.db.Dialector.Name() == "mysql" {
+		// LENGTH counts bytes in MySQL
+		length = "CHAR_LENGTH"
+	}
+	value := text
+	if limit := config.GetLargeMessageSize(); !full && limit > 0 {
+		value = fmt.Sprintf("SUBSTR(%s, 1, %d)", text, limit)
+	}
+	return fmt.Sprintf("%s, %s AS %s, %s(%s) AS text_size", columns, value, text, length, text)
+}
+
+// previewSize returns size, the text_size of a message read with
+// messageColumns, if only a preview of its text was read, 0 otherwise
+func previewSize(size int, full bool) int {
+	if limit := config.GetLargeMessageSize(); full || limit == 0 || size <= limit {
+		return 0
+	}
+	return size
 }
 
 // getEchomailMessage retrieves an echomail message
 func (a *SQLArea) getEchomailMessage(position uint32) (*Message, error) {
-	var echomail database.Echomail
+	var echomail echomailRow
 
-	// Get message by position (offset)
-	err := a.db.Where("echoarea_id = ?", a.areaID).
-		Order("id ASC").
-		Offset(int(position - 1)).
-		Limit(1).
-		First(&echomail).Error
-
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("error retrieving echomail message: %w", err)
+	// Get message by position, bound as a parameter so the prepared
+	// statement is the same for every position
+	res := a.scanAt(&echomail, a.messageColumns(false), position)
+	if res.Error != nil {
+		return nil, fmt.Errorf("error retrieving echomail message: %w", res.Error)
 	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("message %d in %s: %w", position, a.areaName, ErrMsgOutOfRange)
+	}
+
+	return a.echomailMessage(&echomail.Echomail, position, previewSize(echomail.TextSize, false)), nil
+}
 
-	// Convert database record to Message struct
+// echomailMessage converts a database record at position to a Message,
+// a preview of a large message if its text was read cut to a preview of
+// the whole size characters, see previewSize
+func (a *SQLArea) echomailMessage(echomail *database.Echomail, position uint32, size int) *Message {
+	text := previewText(echomail.Message, size)
 	msg := &Message{
+		ID:          echomail.ID,
 		Area:        a.areaName,
 		AreaObject:  nil, // Will be set by caller if needed
 		MsgNum:      position,
@@ -239,13 +579,17 @@ func (a *SQLArea) getEchomailMessage(position uint32) (*Message, error) {
 		From:        echomail.FromName,
 		To:          echomail.ToName,
 		Subject:     echomail.Subject,
-		Body:        a.NormalizeFromStorage(echomail.Message), // Convert \n to \r for FTN processing
+		Body:        a.NormalizeFromStorage(text), // Convert \n to \r for FTN processing
 		DateWritten: dateHelper.FromUnixTime(echomail.Date),
 		DateArrived: dateHelper.FromUnixTime(echomail.Date),
 		Attrs:       []string{}, // Parse attributes if needed
 		Kludges:     make(map[string]string),
 		Corrupted:   false,
 	}
+	if size > 0 {
+		msg.Truncated = true
+		msg.FullSize = size
+	}
 
 	// Parse FTN address
 	msg.FromAddr = types.AddrFromString(echomail.FromFtnAddr)
@@ -258,43 +602,65 @@ func (a *SQLArea) getEchomailMessage(position uint32) (*Message, error) {
 	msg.ToAddr = &types.FidoAddr{}
 
 	// Parse message for kludges and other FTN-specific content (jnode SQL specific - no auto-decode)
-	err = msg.ParseRawNoDecoding()
+	err := msg.ParseRawNoDecoding()
 	if err != nil {
 		log.Printf("Error parsing message %d: %v", position, err)
 	}
+	decodeUnlabeled(msg)
+	a.setReadChrs(msg)
+	if tag := embeddedAreaTag(msg.Body); tag != "" && !strings.EqualFold(tag, a.areaName) {
+		log.Printf("Warning: echomail %d of area %s has AREA:%s", echomail.ID, a.areaName, tag)
+		msg.AreaTag = tag
+		msg.Corrupted = true
+	}
 	
 	// For jnode SQL: Override charset behavior
 	// Database always stores UTF-8, convert to display charset from config
 	displayCharset := strings.Split(config.Config.Chrs.Default, " ")[0]
 	if displayCharset != "UTF-8" {
-		msg.Body = utils.EncodeCharmap(msg.Body, displayCharset)
+		msg.Body = utils.EncodeCharmapChunked(msg.Body, displayCharset)
 		msg.From = utils.EncodeCharmap(msg.From, displayCharset)
 		msg.To = utils.EncodeCharmap(msg.To, displayCharset)
 		msg.Subject = utils.EncodeCharmap(msg.Subject, displayCharset)
 	}
 
-	return msg, nil
+	return msg
+}
+
+// previewText cuts the preview of a message of size characters at its last
+// line break, leaving the text of a message read whole as it is
+func previewText(text string, size int) string {
+	if size == 0 {
+		return text
+	}
+	if i := strings.LastIndexAny(text, "\r\n"); i > 0 {
+		return text[:i+1]
+	}
+	return text
 }
 
 // getNetmailMessage retrieves a netmail message
 func (a *SQLArea) getNetmailMessage(position uint32) (*Message, error) {
-	var netmail database.Netmail
+	var netmail netmailRow
 
-	// Get message by position (offset)
-	err := a.db.Order("id ASC").
-		Offset(int(position - 1)).
-		Limit(1).
-		First(&netmail).Error
-
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("error retrieving netmail message: %w", err)
+	// Get message by position, see getEchomailMessage
+	res := a.scanAt(&netmail, a.messageColumns(false), position)
+	if res.Error != nil {
+		return nil, fmt.Errorf("error retrieving netmail message: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("message %d in %s: %w", position, a.areaName, ErrMsgOutOfRange)
 	}
 
-	// Convert database record to Message struct
+	return a.netmailMessage(&netmail.Netmail, position, previewSize(netmail.TextSize, false)), nil
+}
+
+// netmailMessage converts a database record at position to a Message, see
+// echomailMessage
+func (a *SQLArea) netmailMessage(netmail *database.Netmail, position uint32, size int) *Message {
+	text := previewText(netmail.Text, size)
 	msg := &Message{
+		ID:          netmail.ID,
 		Area:        a.areaName,
 		AreaObject:  nil,
 		MsgNum:      position,
@@ -302,13 +668,17 @@ func (a *SQLArea) getNetmailMessage(position uint32) (*Message, error) {
 		From:        netmail.FromName,
 		To:          netmail.ToName,
 		Subject:     netmail.Subject,
-		Body:        a.NormalizeFromStorage(netmail.Text), // Convert \n to \r for FTN processing
+		Body:        a.NormalizeFromStorage(text), // Convert \n to \r for FTN processing
 		DateWritten: dateHelper.FromUnixTime(netmail.Date),
 		DateArrived: dateHelper.FromUnixTime(netmail.Date),
 		Attrs:       a.parseNetmailAttrs(netmail.Attr),
 		Kludges:     make(map[string]string),
 		Corrupted:   false,
 	}
+	if size > 0 {
+		msg.Truncated = true
+		msg.FullSize = size
+	}
 
 	// Parse FTN addresses
 	msg.FromAddr = types.AddrFromString(netmail.FromAddress)
@@ -324,22 +694,45 @@ func (a *SQLArea) getNetmailMessage(position uint32) (*Message, error) {
 	}
 
 	// Parse message for kludges (jnode SQL specific - no auto-decode)
-	err = msg.ParseRawNoDecoding()
+	err := msg.ParseRawNoDecoding()
 	if err != nil {
 		log.Printf("Error parsing netmail %d: %v", position, err)
 	}
+	decodeUnlabeled(msg)
+	a.setReadChrs(msg)
 	
 	// For jnode SQL: Override charset behavior - same as echomail
 	// Database always stores UTF-8, convert to display charset from config
 	displayCharset := strings.Split(config.Config.Chrs.Default, " ")[0]
 	if displayCharset != "UTF-8" {
-		msg.Body = utils.EncodeCharmap(msg.Body, displayCharset)
+		msg.Body = utils.EncodeCharmapChunked(msg.Body, displayCharset)
 		msg.From = utils.EncodeCharmap(msg.From, displayCharset)
 		msg.To = utils.EncodeCharmap(msg.To, displayCharset)
 		msg.Subject = utils.EncodeCharmap(msg.Subject, displayCharset)
 	}
 
-	return msg, nil
+	return msg
+}
+
+// GetRawMsg returns the stored text of the message at position as is,
+// without NormalizeFromStorage, kludge parsing or charset conversion
+func (a *SQLArea) GetRawMsg(position uint32) (string, error) {
+	if position == 0 {
+		position = 1
+	}
+	var text string
+	column := "message"
+	if a.areaType == EchoAreaTypeNetmail {
+		column = "text"
+	}
+	res := a.scanAt(&text, column, position)
+	if res.Error != nil {
+		return "", fmt.Errorf("error retrieving raw message: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return "", fmt.Errorf("message %d not found in area %s", position, a.areaName)
+	}
+	return text, nil
 }
 
 // parseNetmailAttrs converts jnode integer attributes to gossiped string attributes
@@ -375,6 +768,12 @@ func (a *SQLArea) parseNetmailAttrs(attr int) []string {
 	if attr&512 != 0 { // MSG_HOLD
 		attrs = append(attrs, "Hld")
 	}
+	if attr&4096 != 0 { // MSG_RRQ
+		attrs = append(attrs, ReceiptReqAttr)
+	}
+	if attr&8192 != 0 { // MSG_CPT
+		attrs = append(attrs, ReceiptAttr)
+	}
 
 	return attrs
 }
@@ -384,6 +783,31 @@ func (a *SQLArea) GetName() string {
 	return a.areaName
 }
 
+// GetDescription returns the echoarea description loaded with the area,
+// a fixed one for bad and dupe areas without a description
+func (a *SQLArea) GetDescription() string {
+	if a.description != "" {
+		return a.description
+	}
+	switch a.areaType {
+	case EchoAreaTypeBad:
+		return "Messages rejected by the tosser"
+	case EchoAreaTypeDupe:
+		return "Duplicate messages"
+	}
+	return ""
+}
+
+// GetWriteLevel returns the level needed to post to the echoarea
+func (a *SQLArea) GetWriteLevel() int64 {
+	return a.writeLevel
+}
+
+// GetAreaID returns the echoarea database ID (0 for netmail)
+func (a *SQLArea) GetAreaID() int64 {
+	return a.areaID
+}
+
 // GetMsgType returns the message base type
 func (a *SQLArea) GetMsgType() EchoAreaMsgType {
 	// For SQL areas, we use a custom type
@@ -405,6 +829,97 @@ func (a *SQLArea) GetChrs() string {
 	return a.chrs
 }
 
+// Capabilities returns what jnode-sql areas support: posting up to the
+// write level, deleting and editing messages in place
+func (a *SQLArea) Capabilities() AreaCaps {
+	return areaCaps(a) | AreaCanDelete | AreaCanEdit
+}
+
+// chrsFor returns the CHRS for messages of the area: the area's chrs from
+// the config, then chrs.jnode_default, then fallback
+func (a *SQLArea) chrsFor(fallback string) string {
+	if a.chrs != "" {
+		return a.chrs
+	}
+	if config.Config.Chrs.JnodeDefault != "" {
+		return config.Config.Chrs.JnodeDefault
+	}
+	return fallback
+}
+
+// embeddedAreaTag returns the area tag of an AREA line or kludge among the
+// kludges heading body, as left in the stored text by some tossers, or ""
+func embeddedAreaTag(body string) string {
+	for _, l := range strings.Split(body, "\r") {
+		if tag, ok := strings.CutPrefix(strings.TrimPrefix(l, "\x01"), "AREA:"); ok {
+			return strings.TrimSpace(tag)
+		}
+		if !strings.HasPrefix(l, "\x01") {
+			break
+		}
+	}
+	return ""
+}
+
+// saveChrs returns the CHRS a composed message is stored with: one chosen
+// for the message, i.e. other than chrs.default the composer starts with,
+// then the area's chrs, then chrs.jnode_default, then the composer's one
+func (a *SQLArea) saveChrs(msg *Message) string {
+	if chrs := msg.Kludges["CHRS:"]; chrs != "" && chrs != config.Config.Chrs.Default {
+		return chrs
+	}
+	return a.chrsFor(msg.Kludges["CHRS:"])
+}
+
+// setSaveChrs leaves the CHRS of saveChrs as the only CHRS kludge of msg,
+// dropping the one parsed from a read message
+func (a *SQLArea) setSaveChrs(msg *Message) {
+	chrs := a.saveChrs(msg)
+	delete(msg.Kludges, "CHRS")
+	if chrs == "" {
+		delete(msg.Kludges, "CHRS:")
+		return
+	}
+	msg.Kludges["CHRS:"] = chrs
+}
+
+// decodeUnlabeled converts a stored text without a CHRS kludge which is not
+// UTF-8, as left by tossers writing the packet text as is, from the charset
+// DetectCharset guesses for its body. Text which is not UTF-8 is converted
+// from the charset picked with SetReadChrs instead, labeled or not.
+func decodeUnlabeled(msg *Message) {
+	if chrs := GetReadChrs(msg.Area); chrs != "" {
+		// text stored as UTF-8 is shown as is, whatever the charset picked
+		for _, s := range []*string{&msg.Body, &msg.From, &msg.To, &msg.Subject} {
+			if !utf8.ValidString(*s) {
+				*s = utils.DecodeCharmap(*s, chrs)
+			}
+		}
+		return
+	}
+	if _, ok := msg.Kludges["CHRS"]; ok {
+		return
+	}
+	chrs := utils.DetectCharset(msg.Body, config.GetDetectCharsets(), config.GetDetectDefault())
+	if chrs == "UTF-8" {
+		return
+	}
+	for _, s := range []*string{&msg.Body, &msg.From, &msg.To, &msg.Subject} {
+		if !utf8.ValidString(*s) {
+			*s = utils.DecodeCharmap(*s, chrs)
+		}
+	}
+}
+
+// setReadChrs sets the CHRS of a read message with the precedence of
+// chrsFor, the stored CHRS kludge being the last resort. The database holds
+// UTF-8 text, so this only affects the charset replies are written in.
+func (a *SQLArea) setReadChrs(msg *Message) {
+	if chrs := a.chrsFor(""); chrs != "" {
+		msg.Kludges["CHRS"] = strings.ToUpper(strings.Split(chrs, " ")[0])
+	}
+}
+
 // GetMessages returns a list of message headers
 func (a *SQLArea) GetMessages() *[]MessageListItem {
 	if a.messageListValid {
@@ -440,6 +955,7 @@ func (a *SQLArea) loadEchomailList() {
 
 	for i, echomail := range echomails {
 		item := MessageListItem{
+			ID:          echomail.ID,
 			MsgNum:      uint32(i + 1),
 			From:        echomail.FromName,
 			To:          echomail.ToName,
@@ -465,6 +981,7 @@ func (a *SQLArea) loadNetmailList() {
 
 	for i, netmail := range netmails {
 		item := MessageListItem{
+			ID:          netmail.ID,
 			MsgNum:      uint32(i + 1),
 			From:        netmail.FromName,
 			To:          netmail.ToName,
@@ -475,8 +992,16 @@ func (a *SQLArea) loadNetmailList() {
 	}
 }
 
-// SaveMsg saves a new message to the database
+// SaveMsg saves a new message to the database. It changes msg like the
+// MakeBody it runs: the AreaObject of msg is set to this area, whichever
+// area it was made for, and a blank FromAddr is filled in.
 func (a *SQLArea) SaveMsg(msg *Message) error {
+	// the line endings of the area are used by MakeBody
+	var areaPtr AreaPrimitive = a
+	msg.AreaObject = &areaPtr
+	if err := msg.checkFromAddr(); err != nil {
+		return fmt.Errorf("error saving message to %s: %w", a.areaName, err)
+	}
 	if a.areaType == EchoAreaTypeNetmail {
 		return a.saveNetmailMessage(msg)
 	} else {
@@ -486,21 +1011,10 @@ func (a *SQLArea) SaveMsg(msg *Message) error {
 
 // saveEchomailMessage saves an echomail message
 func (a *SQLArea) saveEchomailMessage(msg *Message) error {
-	// Set area object for proper line ending handling
-	var areaPtr AreaPrimitive = a
-	msg.AreaObject = &areaPtr
-	
 	// Ensure message body is processed
 	msg.MakeBody()
 	
-	// For jnode SQL: Override CHRS kludge with jnode_default if configured
-	if config.Config.Chrs.JnodeDefault != "" {
-		// Remove any existing CHRS kludge variants
-		delete(msg.Kludges, "CHRS:")
-		delete(msg.Kludges, "CHRS")
-		// Set jnode default CHRS
-		msg.Kludges["CHRS:"] = config.Config.Chrs.JnodeDefault
-	}
+	a.setSaveChrs(msg)
 
 	// Build message with kludges included in text (jnode style)
 	messageText := ""
@@ -525,32 +1039,37 @@ func (a *SQLArea) saveEchomailMessage(msg *Message) error {
 		MsgID:       msg.Kludges["MSGID:"],
 	}
 
-	err := a.db.Create(&echomail).Error
+	// The message is only saved queued for all subscribed links
+	err := a.withWriteRetry("SaveMsg", func() error {
+		echomail.ID = 0
+		return a.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&echomail).Error; err != nil {
+				return err
+			}
+			return a.queueEchomailForSubscribers(tx, echomail.ID)
+		})
+	})
 	if err != nil {
 		return fmt.Errorf("error saving echomail message: %w", err)
 	}
 
-	// Queue message for all subscribed links
-	if err := a.queueEchomailForSubscribers(echomail.ID); err != nil {
-		log.Printf("Warning: Failed to queue echomail for subscribers: %v", err)
-		// Don't fail the entire operation if queueing fails
-	}
-
 	// Invalidate message list cache
 	a.messageListValid = false
 
 	// Increment message count cache when new messages are added
 	IncrementMessageCount(a.areaID, false)
+	touchLastDate(a.areaID, false, echomail.Date)
 
 	log.Printf("Saved echomail message to area %s", a.areaName)
 	return nil
 }
 
-// queueEchomailForSubscribers queues echomail message for all subscribed links
-func (a *SQLArea) queueEchomailForSubscribers(echomailID int64) error {
+// queueEchomailForSubscribers queues echomail message for all subscribed
+// links, in the transaction tx of the caller if it has one
+func (a *SQLArea) queueEchomailForSubscribers(tx *gorm.DB, echomailID int64) error {
 	// Get all subscribed links for this echoarea
 	var subscriptions []database.Subscription
-	err := a.db.Where("echoarea_id = ?", a.areaID).Find(&subscriptions).Error
+	err := tx.Where("echoarea_id = ?", a.areaID).Find(&subscriptions).Error
 	if err != nil {
 		return fmt.Errorf("error getting subscriptions for area %s: %w", a.areaName, err)
 	}
@@ -566,7 +1085,10 @@ func (a *SQLArea) queueEchomailForSubscribers(echomailID int64) error {
 
 	// Batch insert all awaiting entries
 	if len(awaitingEntries) > 0 {
-		err = a.db.Create(&awaitingEntries).Error
+		// Entries still waiting from an earlier queueing are kept
+		err = tx.Omit(clause.Associations).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&awaitingEntries).Error
 		if err != nil {
 			return fmt.Errorf("error creating echomail awaiting entries: %w", err)
 		}
@@ -582,59 +1104,55 @@ func (a *SQLArea) saveNetmailMessage(msg *Message) error {
 	log.Printf("DEBUG: saveNetmailMessage called - ToAddr: %s (Zone:%d Net:%d Node:%d Point:%d)", 
 		msg.ToAddr.String(), msg.ToAddr.GetZone(), msg.ToAddr.GetNet(), msg.ToAddr.GetNode(), msg.ToAddr.GetPoint())
 	
-	// Set area object for proper line ending handling
-	var areaPtr AreaPrimitive = a
-	msg.AreaObject = &areaPtr
-	
 	// Ensure message body is processed
 	msg.MakeBody()
 	
-	// For jnode SQL: Override CHRS kludge with jnode_default if configured
-	if config.Config.Chrs.JnodeDefault != "" {
-		// Remove any existing CHRS kludge variants
-		delete(msg.Kludges, "CHRS:")
-		delete(msg.Kludges, "CHRS")
-		// Set jnode default CHRS
-		msg.Kludges["CHRS:"] = config.Config.Chrs.JnodeDefault
-	}
+	a.setSaveChrs(msg)
 
-	// Build message with kludges included in text (jnode style)
-	messageText := ""
-	for kl, v := range msg.Kludges {
-		// Skip MSGID since it's stored in dedicated msgid field
-		if kl != "MSGID:" {
-			messageText += "\x01" + kl + " " + v + "\x0d"
-		}
-	}
-	messageText += msg.Body
+	messageText := a.netmailText(msg)
 
 	// Convert attributes back to integer format
-	attr := a.convertAttrsToInt(msg.Attrs)
+	msgAttr := a.convertAttrsToInt(msg.Attrs)
 
-	// Find routing for this netmail
+	// The route is looked up and the netmail saved in one transaction, a
+	// failing lookup saves nothing while no route found saves it unrouted
 	log.Printf("DEBUG: Before findNetmailRoute - ToAddr: %s (Zone:%d Net:%d Node:%d Point:%d)", 
 		msg.ToAddr.String(), msg.ToAddr.GetZone(), msg.ToAddr.GetNet(), msg.ToAddr.GetNode(), msg.ToAddr.GetPoint())
-	routeVia, err := a.findNetmailRoute(msg)
-	if err != nil {
-		log.Printf("Warning: Failed to find route for netmail: %v", err)
-		// Continue without routing - might be handled later
-	}
-
-	netmail := database.Netmail{
-		FromName:     msg.From,
-		ToName:       msg.To,
-		FromAddress:  msg.FromAddr.String(),
-		ToAddress:    msg.ToAddr.String(),
-		Subject:      msg.Subject,
-		Text:         messageText,
-		Date:         dateHelper.ToUnixTime(msg.DateWritten),
-		Send:         false, // Always false for unsent mail (jnode will set to true after sending)
-		Attr:         attr,
-		LastModified: dateHelper.ToUnixTime(time.Now()),
-		RouteVia:     routeVia, // This should be nil for direct routing or Link ID for routing via link
-	}
+	var netmail database.Netmail
+	var routeVia *int64
+	err := a.withWriteRetry("SaveMsg", func() error {
+		return a.db.Transaction(func(tx *gorm.DB) error {
+			var routeErr error
+			routeVia, routeErr = a.findNetmailRouteIn(tx, msg)
+			attr := msgAttr
+			if errors.Is(routeErr, errNoRoute) {
+				log.Printf("Warning: Failed to find route for netmail: %v", routeErr)
+				// Continue without routing, RerouteNetmail retries once the
+				// address is fixed
+				if config.Config.Netmail.HoldUnroutable {
+					attr |= netmailHold
+					log.Printf("Netmail to %s held until rerouted", msg.ToAddr.String())
+				}
+			} else if routeErr != nil {
+				return routeErr
+			}
 
-	err = a.db.Create(&netmail).Error
+			netmail = database.Netmail{
+				FromName:     msg.From,
+				ToName:       msg.To,
+				FromAddress:  msg.FromAddr.String(),
+				ToAddress:    msg.ToAddr.String(),
+				Subject:      msg.Subject,
+				Text:         messageText,
+				Date:         dateHelper.ToUnixTime(msg.DateWritten),
+				Send:         false, // Always false for unsent mail (jnode will set to true after sending)
+				Attr:         attr,
+				LastModified: dateHelper.ToUnixTime(time.Now()),
+				RouteVia:     routeVia, // This should be nil for direct routing or Link ID for routing via link
+			}
+			return tx.Create(&netmail).Error
+		})
+	})
 	if err != nil {
 		return fmt.Errorf("error saving netmail message: %w", err)
 	}
@@ -650,66 +1168,187 @@ func (a *SQLArea) saveNetmailMessage(msg *Message) error {
 
 	// Increment message count cache when new messages are added
 	IncrementMessageCount(0, true)
+	touchLastDate(0, true, netmail.Date)
 
 	log.Printf("Saved netmail message")
 	return nil
 }
 
+// netmailText builds the stored netmail text with kludges included (jnode
+// style): INTL, FMPT and TOPT first, then the rest in name order
+func (a *SQLArea) netmailText(msg *Message) string {
+	msg.setNetmailKludges()
+	names := []string{"INTL", "FMPT", "TOPT"}
+	var rest []string
+	for kl := range msg.Kludges {
+		// Skip MSGID since it's stored in dedicated msgid field
+		if kl != "MSGID:" && kl != "INTL" && kl != "FMPT" && kl != "TOPT" {
+			rest = append(rest, kl)
+		}
+	}
+	sort.Strings(rest)
+	messageText := ""
+	for _, kl := range append(names, rest...) {
+		if v, ok := msg.Kludges[kl]; ok {
+			messageText += "\x01" + kl + " " + v + "\x0d"
+		}
+	}
+	messageText += msg.Body
+	if config.GetNetmailVia() {
+		messageText += "\x01" + ViaKludge(config.Config.Address, time.Now()) + a.GetStorageLineEnding()
+	}
+	return messageText
+}
+
+// errNoRoute is returned by findNetmailRoute when no link is found for the
+// destination, other errors are database failures
+var errNoRoute = errors.New("no route found")
+
 // findNetmailRoute implements complex netmail routing logic
 func (a *SQLArea) findNetmailRoute(msg *Message) (*int64, error) {
+	return a.findNetmailRouteIn(a.db, msg)
+}
+
+// findNetmailRouteIn looks up the route of msg like findNetmailRoute, in the
+// transaction tx
+func (a *SQLArea) findNetmailRouteIn(tx *gorm.DB, msg *Message) (*int64, error) {
 	destAddr := msg.ToAddr.String()
 	log.Printf("DEBUG: findNetmailRoute called for destination: %s", destAddr)
 	log.Printf("DEBUG: ToAddr details - Zone:%d Net:%d Node:%d Point:%d", 
 		msg.ToAddr.GetZone(), msg.ToAddr.GetNet(), msg.ToAddr.GetNode(), msg.ToAddr.GetPoint())
 
-	// Step 1: Try direct link
-	var link database.Link
-	log.Printf("DEBUG: Step 1 - Looking for direct link to: %s", destAddr)
-	err := a.db.Where("ftn_address = ?", destAddr).First(&link).Error
-	if err == nil {
-		log.Printf("Found direct link for %s: %s", destAddr, link.StationName)
-		// For direct links, jnode uses route_via = null (direct routing)
-		return nil, nil
-	}
-	log.Printf("DEBUG: Step 1 failed - %v", err)
-
-	// Step 2: If not found, try without point
-	if msg.ToAddr.GetPoint() != 0 {
-		// Create address without point using existing FidoAddr functions
-		addrWithoutPoint := types.AddrFromNum(
-			msg.ToAddr.GetZone(),
-			msg.ToAddr.GetNet(),
-			msg.ToAddr.GetNode(),
-			0, // point = 0
-		).String()
-
-		log.Printf("DEBUG: Step 2 - Looking for boss node: %s", addrWithoutPoint)
-		err = a.db.Where("ftn_address = ?", addrWithoutPoint).First(&link).Error
-		if err == nil {
-			log.Printf("Found link without point for %s: %s", addrWithoutPoint, link.StationName)
-			return &link.ID, nil
-		}
-		log.Printf("DEBUG: Step 2 failed - %v", err)
+	var links []database.Link
+	if err := tx.Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to load links: %w", err)
+	}
+
+	// Step 1: Try direct link, also under the pointnet form of the address
+	candidates := append([]*types.FidoAddr{msg.ToAddr}, pointnetAddrs(msg.ToAddr)...)
+	for _, addr := range candidates {
+		log.Printf("Routing %s: trying direct link to %s", destAddr, addr)
+		for _, link := range links {
+			if addr.Equal(types.AddrFromString(link.FtnAddress)) {
+				log.Printf("Found direct link for %s: %s", addr, link.StationName)
+				// For direct links, jnode uses route_via = null (direct routing)
+				return nil, nil
+			}
+		}
+	}
+
+	// Step 2: If not found, try boss node of the point, then links with
+	// point routing in their options
+	var point *types.FidoAddr
+	for _, addr := range candidates {
+		if addr.GetPoint() != 0 {
+			point = addr
+			break
+		}
+	}
+	if point != nil {
+		boss := types.AddrFromNum(point.GetZone(), point.GetNet(), point.GetNode(), 0)
+		log.Printf("Routing %s: trying boss node %s", destAddr, boss)
+		for i, link := range links {
+			if boss.Equal(types.AddrFromString(link.FtnAddress)) {
+				log.Printf("Found link without point for %s: %s", link.FtnAddress, link.StationName)
+				return &links[i].ID, nil
+			}
+		}
+		var options []database.LinkOption
+		if err := tx.Where("name = ?", linkOptionRoutePoints).Order("link_id ASC").Find(&options).Error; err != nil {
+			return nil, fmt.Errorf("failed to load link options: %w", err)
+		}
+		for i, option := range options {
+			log.Printf("Routing %s: trying %s of link %d: %s", point, linkOptionRoutePoints, option.LinkID, option.Value)
+			if routePointsMatch(option.Value, point) {
+				log.Printf("Found point route for %s: link %d", point, option.LinkID)
+				return &options[i].LinkID, nil
+			}
+		}
 	}
 
 	// Step 3: Process routing table
-	var route database.Route
-	err = a.db.Where(
-		"(from_address = ? OR from_address = '*') AND "+
-			"(to_address = ? OR to_address = '*') AND "+
-			"(from_name = ? OR from_name = '*') AND "+
-			"(to_name = ? OR to_name = '*') AND "+
-			"(subject = ? OR subject = '*')",
-		msg.FromAddr.String(), destAddr, msg.From, msg.To, msg.Subject).
-		Order("nice ASC").
-		First(&route).Error
+	var routes []database.Route
+	if err := tx.Order("nice ASC").Find(&routes).Error; err != nil {
+		return nil, fmt.Errorf("failed to load routing table: %w", err)
+	}
+	log.Printf("Routing %s: trying %d routing table rules", destAddr, len(routes))
+	for _, route := range routes {
+		if routeAddrMatch(route.FromAddress, msg.FromAddr) &&
+			routeAddrMatch(route.ToAddress, msg.ToAddr) &&
+			routeTextMatch(route.FromName, msg.From) &&
+			routeTextMatch(route.ToName, msg.To) &&
+			routeTextMatch(route.Subject, msg.Subject) {
+			log.Printf("Found route via routing table for %s: link %d", destAddr, route.RouteVia)
+			return &route.RouteVia, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w for netmail to %s", errNoRoute, destAddr)
+}
+
+// RoutePreview describes how netmail msg would be routed on save
+func (a *SQLArea) RoutePreview(msg *Message) string {
+	routeVia, err := a.findNetmailRoute(msg)
+	if err != nil {
+		return "no route found"
+	}
+	if routeVia == nil {
+		return "direct"
+	}
+	var link database.Link
+	if err := a.db.First(&link, *routeVia).Error; err != nil {
+		return fmt.Sprintf("via link %d", *routeVia)
+	}
+	return fmt.Sprintf("via %s (%s)", link.StationName, link.FtnAddress)
+}
+
+// routeAddrMatch reports whether a routing table address pattern matches addr
+func routeAddrMatch(pattern string, addr *types.FidoAddr) bool {
+	return pattern == "*" || addr.Equal(types.AddrFromString(pattern))
+}
+
+// linkOptionRoutePoints names the link option listing the points netmail is
+// routed to through the link, separated by commas or spaces, where
+// zone:net/node.* stands for all points of a node
+const linkOptionRoutePoints = "route_points"
+
+// routePointsMatch reports whether the route_points value list matches the
+// point address addr
+func routePointsMatch(list string, addr *types.FidoAddr) bool {
+	for _, pattern := range strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}) {
+		if node, ok := strings.CutSuffix(pattern, ".*"); ok {
+			if addr.SameNode(types.AddrFromString(node)) {
+				return true
+			}
+		} else if addr.Equal(types.AddrFromString(pattern)) {
+			return true
+		}
+	}
+	return false
+}
 
-	if err == nil {
-		log.Printf("Found route via routing table for %s: link %d", destAddr, route.RouteVia)
-		return &route.RouteVia, nil
+// pointnetAddrs returns the other form of addr under the netmail.pointnet
+// translation: a point of this node also has the 2D fakenet address
+// zone:pointnet/point, and a fakenet address stands for that point
+func pointnetAddrs(addr *types.FidoAddr) []*types.FidoAddr {
+	pointnet, own := config.Config.Netmail.Pointnet, config.Config.Address
+	if pointnet == 0 || own == nil || addr == nil {
+		return nil
+	}
+	if addr.GetPoint() != 0 && addr.SameNode(own) {
+		return []*types.FidoAddr{types.AddrFromNum(own.GetZone(), pointnet, addr.GetPoint(), 0)}
 	}
+	if addr.GetPoint() == 0 && addr.GetZone() == own.GetZone() && addr.GetNet() == pointnet {
+		return []*types.FidoAddr{types.AddrFromNum(own.GetZone(), own.GetNet(), own.GetNode(), addr.GetNode())}
+	}
+	return nil
+}
 
-	return nil, fmt.Errorf("no route found for netmail to %s", destAddr)
+// routeTextMatch reports whether a routing table text pattern matches s
+func routeTextMatch(pattern, s string) bool {
+	return pattern == "*" || pattern == s
 }
 
 // convertAttrsToInt converts string attributes back to integer format
@@ -736,6 +1375,10 @@ func (a *SQLArea) convertAttrsToInt(attrs []string) int {
 			result |= 128 // MSG_KILL
 		case "Hld":
 			result |= 512 // MSG_HOLD
+		case ReceiptReqAttr:
+			result |= 4096 // MSG_RRQ
+		case ReceiptAttr:
+			result |= 8192 // MSG_CPT
 		}
 	}
 
@@ -810,21 +1453,77 @@ func (a *SQLArea) deleteNetmailMessage(position uint32) error {
 	return nil
 }
 
+// copyEchomailTo copies echomail message at position into dst, keeping the
+// stored text (kludges included), addresses and MSGID, and queues it for
+// dst subscribers. With move the source message is deleted in the same
+// transaction, so a failure neither loses nor duplicates it.
+func (a *SQLArea) copyEchomailTo(position uint32, dst *SQLArea, move bool) error {
+	if a.areaType == EchoAreaTypeNetmail || dst.areaType == EchoAreaTypeNetmail {
+		return fmt.Errorf("only echomail can be copied between areas")
+	}
+	if a.areaID == dst.areaID {
+		return fmt.Errorf("source and destination area are the same")
+	}
+	if position == 0 {
+		position = 1
+	}
+
+	var echomail database.Echomail
+	res := a.scanAt(&echomail, "*", position)
+	if res.Error != nil {
+		return fmt.Errorf("error finding echomail message to copy: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("message %d in %s: %w", position, a.areaName, ErrMsgOutOfRange)
+	}
+
+	copied := echomail
+	copied.ID = 0
+	copied.EchoareaID = dst.areaID
+	copied.SeenBy = "" // Will be filled by tosser
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&copied).Error; err != nil {
+			return fmt.Errorf("error copying echomail message to area %s: %w", dst.areaName, err)
+		}
+		if err := dst.queueEchomailForSubscribers(tx, copied.ID); err != nil {
+			return err
+		}
+		if move {
+			if err := tx.Delete(&echomail).Error; err != nil {
+				return fmt.Errorf("error deleting echomail message from area %s: %w", a.areaName, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	dst.messageListValid = false
+	IncrementMessageCount(dst.areaID, false)
+	touchLastDate(dst.areaID, false, copied.Date)
+	if move {
+		a.messageListValid = false
+		DecrementMessageCount(a.areaID, false)
+		log.Printf("Moved echomail message %d from area %s to area %s", position, a.areaName, dst.areaName)
+	} else {
+		log.Printf("Copied echomail message %d from area %s to area %s", position, a.areaName, dst.areaName)
+	}
+	return nil
+}
+
 // Line ending handling methods for jnode SQL format
 func (a *SQLArea) GetStorageLineEnding() string {
 	return "\n" // jnode SQL stores Unix-style line endings
 }
 
 func (a *SQLArea) NormalizeForStorage(body string) string {
-	// Convert FTN \r line endings to Unix \n for database storage
-	// Remove any trailing \r to avoid double line endings
-	result := strings.ReplaceAll(body, "\r", "\n")
-	// Ensure single trailing newline for database consistency
-	result = strings.TrimRight(result, "\n") + "\n"
-	return result
+	// Convert FTN \r (or mixed) line endings to Unix \n for database storage
+	return terminateLines(body, a.GetStorageLineEnding())
 }
 
 func (a *SQLArea) NormalizeFromStorage(body string) string {
-	// Convert Unix \n line endings from database to FTN \r for internal processing
-	return strings.ReplaceAll(body, "\n", "\r")
+	// Convert Unix \n line endings from database to FTN \r for internal
+	// processing, imported text may carry \r\n or \r already
+	return convertLineEndings(body, ftnLineEnding)
 }
@@ -0,0 +1,976 @@
+package msgapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/askovpen/gossiped/pkg/types"
+	. "github.com/franela/goblin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestSQLArea returns an echo area in a single connection in-memory
+// SQLite database with n messages
+func newTestSQLArea(tb testing.TB, n int) *SQLArea {
+	db, err := gorm.Open(sqlite.Dialector{DriverName: "sqlite", DSN: "file::memory:"},
+		&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	tb.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&database.Echoarea{}, &database.Echomail{}, &database.Link{},
+		&database.Subscription{}, &database.EchomailAwaiting{}); err != nil {
+		tb.Fatal(err)
+	}
+	echoarea := database.Echoarea{Name: "test.area"}
+	db.Create(&echoarea)
+	for i := 1; i <= n; i++ {
+		db.Create(&database.Echomail{
+			EchoareaID:  echoarea.ID,
+			FromName:    "Alexander Skovpen",
+			ToName:      "All",
+			FromFtnAddr: "2:5020/9696",
+			Subject:     fmt.Sprintf("Message %d", i),
+			Message:     "Hello\n",
+		})
+	}
+	config.Config.Chrs.Default = "UTF-8 4"
+	InvalidateMessageCounts()
+	return NewSQLArea(db, echoarea)
+}
+
+func TestSQLAreaPreparedStatements(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea prepared statements", func() {
+		area := newTestSQLArea(t, 3)
+		g.It("check GetMsg() reuses statements", func() {
+			for i := uint32(1); i <= 3; i++ {
+				msg, err := area.GetMsg(i)
+				g.Assert(err).IsNil()
+				g.Assert(msg.Subject).Equal(fmt.Sprintf("Message %d", i))
+			}
+			g.Assert(area.GetCount()).Equal(uint32(3))
+			g.Assert(len(area.stmts.Stmts)).Equal(2)
+		})
+		g.It("check Close() releases statements", func() {
+			area.Close()
+			g.Assert(area.stmts == nil).IsTrue()
+			msg, err := area.GetMsg(2)
+			g.Assert(err).IsNil()
+			g.Assert(msg.Subject).Equal("Message 2")
+		})
+	})
+}
+
+func TestSQLAreaPositionQuery(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea position queries", func() {
+		g.It("check the strategy of each driver", func() {
+			g.Assert(positionQueryFor("mysql")).Equal(positionQuery(windowPosition{}))
+			g.Assert(positionQueryFor("postgres")).Equal(positionQuery(windowPosition{}))
+			g.Assert(positionQueryFor("sqlite")).Equal(positionQuery(offsetPosition{}))
+		})
+		g.It("check the statements", func() {
+			g.Assert(offsetPosition{}.SQL("*", "echomail", "echoarea_id = ?")).
+				Equal("SELECT * FROM echomail WHERE echoarea_id = ? ORDER BY id ASC LIMIT 1 OFFSET ?")
+			g.Assert(offsetPosition{}.Arg(3)).Equal(2)
+			g.Assert(windowPosition{}.SQL("text", "netmail", "")).
+				Equal("SELECT text FROM netmail WHERE id = (SELECT id FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY id) AS rn FROM netmail) numbered WHERE rn = ?)")
+			g.Assert(windowPosition{}.Arg(3)).Equal(3)
+		})
+		g.It("check both strategies read the same messages", func() {
+			area := newTestSQLArea(t, 5)
+			g.Assert(area.positionStrategy()).Equal(positionQuery(offsetPosition{}))
+			// sqlite supports window functions as well
+			for _, pq := range []positionQuery{offsetPosition{}, windowPosition{}} {
+				area.positions = pq
+				for i := uint32(1); i <= 5; i++ {
+					msg, err := area.GetMsg(i)
+					g.Assert(err).IsNil()
+					g.Assert(msg.Subject).Equal(fmt.Sprintf("Message %d", i))
+				}
+				_, err := area.GetMsg(6)
+				g.Assert(errors.Is(err, ErrMsgOutOfRange)).IsTrue()
+				text, err := area.GetRawMsg(2)
+				g.Assert(err).IsNil()
+				g.Assert(text).Equal("Hello\n")
+			}
+		})
+	})
+}
+
+func BenchmarkSQLAreaGetMsg(b *testing.B) {
+	navigate := func(b *testing.B, area *SQLArea) {
+		for i := 0; i < b.N; i++ {
+			if _, err := area.GetMsg(uint32(i%100) + 1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.Run("plain", func(b *testing.B) {
+		area := newTestSQLArea(b, 100)
+		// Bypass the statement cache
+		area.stmtDB = area.db
+		navigate(b, area)
+	})
+	b.Run("prepared", func(b *testing.B) {
+		area := newTestSQLArea(b, 100)
+		defer area.Close()
+		navigate(b, area)
+	})
+}
+
+func TestSQLAreaChrs(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea CHRS precedence", func() {
+		area := newTestSQLArea(t, 1)
+		savedAddress := config.Config.Address
+		g.Before(func() {
+			config.Config.Address = types.AddrFromString("2:5020/9696")
+		})
+		g.After(func() {
+			config.Config.Chrs.JnodeDefault = ""
+			config.Config.Address = savedAddress
+		})
+		g.It("check composer CHRS is kept", func() {
+			config.Config.Chrs.JnodeDefault = ""
+			g.Assert(area.chrsFor("LATIN-1 2")).Equal("LATIN-1 2")
+			msg, _ := area.GetMsg(1)
+			g.Assert(msg.GetChrsKludge()).Equal("")
+		})
+		g.It("check jnode_default overrides composer CHRS", func() {
+			config.Config.Chrs.JnodeDefault = "UTF-8 4"
+			g.Assert(area.chrsFor("LATIN-1 2")).Equal("UTF-8 4")
+			msg, _ := area.GetMsg(1)
+			g.Assert(msg.GetChrsKludge()).Equal("UTF-8 4")
+		})
+		g.It("check area CHRS overrides jnode_default", func() {
+			config.Config.Chrs.JnodeDefault = "UTF-8 4"
+			area.SetChrs("CP866 2")
+			g.Assert(area.chrsFor("LATIN-1 2")).Equal("CP866 2")
+			msg, _ := area.GetMsg(1)
+			g.Assert(msg.GetChrsKludge()).Equal("CP866 2")
+		})
+		g.It("check saved message carries area CHRS", func() {
+			msg := &Message{
+				From:     "Alexander Skovpen",
+				FromAddr: types.AddrFromString("2:5020/9696"),
+				To:       "All",
+				Subject:  "Charset",
+				Body:     "Hello",
+				Kludges:  map[string]string{"CHRS:": config.Config.Chrs.Default},
+			}
+			g.Assert(area.SaveMsg(msg)).IsNil()
+			var echomail database.Echomail
+			area.db.Last(&echomail)
+			g.Assert(strings.Contains(echomail.Message, "\x01CHRS: CP866 2\r")).IsTrue()
+		})
+		g.It("check CHRS chosen for the message wins on save", func() {
+			msg := &Message{Kludges: map[string]string{"CHRS:": "LATIN-1 2", "CHRS": "UTF-8"}}
+			area.setSaveChrs(msg)
+			g.Assert(msg.Kludges).Equal(map[string]string{"CHRS:": "LATIN-1 2"})
+		})
+		g.It("check jnode_default is only a fallback on save", func() {
+			area.SetChrs("")
+			defer area.SetChrs("CP866 2")
+			config.Config.Chrs.JnodeDefault = "CP866 2"
+			msg := &Message{Kludges: map[string]string{"CHRS:": config.Config.Chrs.Default}}
+			g.Assert(area.saveChrs(msg)).Equal("CP866 2")
+			msg = &Message{Kludges: map[string]string{}}
+			g.Assert(area.saveChrs(msg)).Equal("CP866 2")
+			msg = &Message{Kludges: map[string]string{"CHRS:": "LATIN-1 2"}}
+			g.Assert(area.saveChrs(msg)).Equal("LATIN-1 2")
+		})
+		g.It("check no CHRS leaves no stale kludge", func() {
+			area.SetChrs("")
+			defer area.SetChrs("CP866 2")
+			config.Config.Chrs.JnodeDefault = ""
+			msg := &Message{Kludges: map[string]string{"CHRS": "CP866"}}
+			area.setSaveChrs(msg)
+			g.Assert(len(msg.Kludges)).Equal(0)
+		})
+		g.It("check PID round-trips through save and read", func() {
+			config.PID = "gossipEd+lin 2.1"
+			msg := &Message{
+				From:     "Alexander Skovpen",
+				FromAddr: types.AddrFromString("2:5020/9696"),
+				To:       "All",
+				Subject:  "Program",
+				Body:     "Hello",
+				Kludges:  map[string]string{},
+			}
+			g.Assert(area.SaveMsg(msg)).IsNil()
+			saved, err := area.GetMsg(area.GetCount())
+			g.Assert(err).IsNil()
+			g.Assert(saved.Kludges["PID:"]).Equal("gossipEd+lin 2.1")
+			g.Assert(saved.Program()).Equal("gossipEd+lin 2.1")
+
+			// a PID set by the composer is kept
+			msg = &Message{
+				From:     "Alexander Skovpen",
+				FromAddr: types.AddrFromString("2:5020/9696"),
+				To:       "All",
+				Subject:  "Program",
+				Body:     "Hello",
+				Kludges:  map[string]string{"PID:": "test 1.0"},
+			}
+			g.Assert(area.SaveMsg(msg)).IsNil()
+			saved, _ = area.GetMsg(area.GetCount())
+			g.Assert(saved.Kludges["PID:"]).Equal("test 1.0")
+		})
+	})
+}
+
+func TestSQLAreaRawMsg(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea raw message", func() {
+		area := newTestSQLArea(t, 2)
+		g.It("check GetRawMsg() returns stored text", func() {
+			text, err := area.GetRawMsg(2)
+			g.Assert(err).IsNil()
+			g.Assert(text).Equal("Hello\n")
+		})
+		g.It("check GetRawMsg() past the end", func() {
+			_, err := area.GetRawMsg(3)
+			g.Assert(err == nil).IsFalse()
+		})
+	})
+}
+
+func TestSQLAreaAreaTag(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea AREA line verification", func() {
+		area := newTestSQLArea(t, 1)
+		for _, text := range []string{
+			"AREA:OTHER.AREA\n\x01MSGID: 2:5020/9696 1\nHello\n",
+			"\x01MSGID: 2:5020/9696 2\n\x01AREA:TEST.AREA\nHello\n",
+			"Hello\nAREA:OTHER.AREA\n",
+		} {
+			area.db.Create(&database.Echomail{EchoareaID: area.areaID, FromName: "Alexander Skovpen",
+				ToName: "All", FromFtnAddr: "2:5020/9696", Subject: "Area", Message: text})
+		}
+		InvalidateMessageCounts()
+		g.It("check a message without an AREA line", func() {
+			msg, err := area.GetMsg(1)
+			g.Assert(err).IsNil()
+			g.Assert(msg.AreaTag).Equal("")
+			g.Assert(msg.Corrupted).IsFalse()
+		})
+		g.It("check an AREA line of another area is flagged", func() {
+			msg, err := area.GetMsg(2)
+			g.Assert(err).IsNil()
+			g.Assert(msg.AreaTag).Equal("OTHER.AREA")
+			g.Assert(msg.Corrupted).IsTrue()
+			g.Assert(msg.Body).Equal("AREA:OTHER.AREA\r\x01MSGID: 2:5020/9696 1\rHello\r")
+			g.Assert(strings.Contains(msg.Info(), "AREA tag:  OTHER.AREA, not test.area")).IsTrue()
+		})
+		g.It("check the AREA kludge of the area itself", func() {
+			msg, _ := area.GetMsg(3)
+			g.Assert(msg.AreaTag).Equal("")
+			g.Assert(msg.Corrupted).IsFalse()
+		})
+		g.It("check AREA in the text is not a tag", func() {
+			msg, _ := area.GetMsg(4)
+			g.Assert(msg.AreaTag).Equal("")
+		})
+	})
+}
+
+func TestSQLAreaUnlabeledChrs(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea text without CHRS", func() {
+		area := newTestSQLArea(t, 0)
+		for _, text := range []string{
+			"\x8f\xe0\xa8\xa2\xa5\xe2 \xa2\xe1\xa5\xac!\n",
+			"\x01CHRS: LATIN-1 2\n\x8f\xe0\xa8\xa2\xa5\xe2\n",
+			"Привет всем!\n",
+		} {
+			area.db.Create(&database.Echomail{
+				EchoareaID:  area.areaID,
+				FromName:    "Alexander Skovpen",
+				ToName:      "All",
+				FromFtnAddr: "2:5020/9696",
+				Subject:     "\x92\xa5\xe1\xe2",
+				Message:     text,
+			})
+		}
+		InvalidateMessageCounts()
+		g.It("check unlabeled cp866 is decoded", func() {
+			msg, err := area.GetMsg(1)
+			g.Assert(err).IsNil()
+			g.Assert(strings.HasPrefix(msg.Body, "Привет всем!")).IsTrue()
+			g.Assert(msg.Subject).Equal("Тест")
+		})
+		g.It("check labeled text is left as is", func() {
+			msg, err := area.GetMsg(2)
+			g.Assert(err).IsNil()
+			g.Assert(strings.Contains(msg.Body, "\x8f\xe0")).IsTrue()
+		})
+		g.It("check unlabeled utf-8 is left as is", func() {
+			msg, err := area.GetMsg(3)
+			g.Assert(err).IsNil()
+			g.Assert(strings.HasPrefix(msg.Body, "Привет всем!")).IsTrue()
+		})
+		g.It("check the charset picked for the area wins", func() {
+			defer SetReadChrs(area.GetName(), "")
+			SetReadChrs(area.GetName(), "cp866")
+			g.Assert(GetReadChrs(area.GetName())).Equal("CP866")
+			msg, err := area.GetMsg(2)
+			g.Assert(err).IsNil()
+			g.Assert(strings.Contains(msg.Body, "Привет")).IsTrue()
+			msg, _ = area.GetMsg(3)
+			g.Assert(strings.HasPrefix(msg.Body, "Привет всем!")).IsTrue()
+			SetReadChrs(area.GetName(), "UTF-8")
+			msg, _ = area.GetMsg(1)
+			g.Assert(strings.HasPrefix(msg.Body, "\x8f\xe0")).IsTrue()
+		})
+		g.It("check the picked charset decodes file base messages", func() {
+			defer SetReadChrs("test.area", "")
+			SetReadChrs("test.area", "KOI8-R")
+			msg := &Message{Area: "test.area", Body: "\xf0\xd2\xc9\xd7\xc5\xd4",
+				Kludges: map[string]string{"CHRS": "CP866"}}
+			msg.Decode()
+			g.Assert(msg.Body).Equal("Привет")
+		})
+		g.It("check file base messages labeled UTF-8 keep their charset", func() {
+			defer SetReadChrs("test.area", "")
+			SetReadChrs("test.area", "KOI8-R")
+			msg := &Message{Area: "test.area", Body: "Привет",
+				Kludges: map[string]string{"CHRS": "UTF-8"}}
+			msg.Decode()
+			g.Assert(msg.Body).Equal("Привет")
+		})
+	})
+}
+
+func TestCanPost(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check CanPost()", func() {
+		g.After(func() {
+			config.Config.WriteLevel = nil
+		})
+		g.It("check bad and dupe areas are read only", func() {
+			for _, areaType := range []EchoAreaType{EchoAreaTypeBad, EchoAreaTypeDupe} {
+				err := CanPost(&MSG{AreaName: "bad", AreaType: areaType})
+				g.Assert(errors.Is(err, ErrReadOnly)).IsTrue()
+			}
+			g.Assert(CanPost(&MSG{AreaName: "netmail", AreaType: EchoAreaTypeNetmail})).IsNil()
+			g.Assert(CanPost(&MSG{AreaName: "local", AreaType: EchoAreaTypeLocal})).IsNil()
+		})
+		g.It("check the write level of jnode-sql areas", func() {
+			area := newTestSQLArea(t, 0)
+			area.writeLevel = 5
+			g.Assert(CanPost(area)).IsNil()
+			level := int64(2)
+			config.Config.WriteLevel = &level
+			g.Assert(errors.Is(CanPost(area), ErrReadOnly)).IsTrue()
+			level = 5
+			g.Assert(CanPost(area)).IsNil()
+			g.Assert(CanPost(NewSQLNetmailArea(area.db))).IsNil()
+		})
+		g.It("check Capabilities()", func() {
+			area := newTestSQLArea(t, 0)
+			g.Assert(area.Capabilities()).Equal(AreaCanPost | AreaCanDelete | AreaCanEdit)
+			g.Assert(NewSQLNetmailArea(area.db).Capabilities().Has(AreaIsNetmail | AreaCanPost)).IsTrue()
+			area.writeLevel = 5
+			level := int64(2)
+			config.Config.WriteLevel = &level
+			caps := area.Capabilities()
+			g.Assert(caps.Has(AreaCanPost)).IsFalse()
+			g.Assert(caps.Has(AreaReadOnly | AreaCanDelete | AreaCanEdit)).IsTrue()
+			bad := &MSG{AreaName: "bad", AreaType: EchoAreaTypeBad}
+			g.Assert(bad.Capabilities()).Equal(AreaCanDelete | AreaReadOnly)
+			g.Assert(NewMemoryArea("memory.area", EchoAreaTypeEcho).Capabilities()).Equal(AreaCanPost | AreaCanDelete)
+		})
+		g.It("check moving needs AreaCanDelete", func() {
+			area := newTestSQLArea(t, 1)
+			var src AreaPrimitive = noDeleteArea{area}
+			err := MoveMessage(src, 1, NewMemoryArea("memory.area", EchoAreaTypeEcho))
+			g.Assert(errors.Is(err, ErrReadOnly)).IsTrue()
+			g.Assert(area.GetCount()).Equal(uint32(1))
+		})
+	})
+}
+
+// noDeleteArea is an SQL area whose messages can not be deleted
+type noDeleteArea struct {
+	*SQLArea
+}
+
+func (a noDeleteArea) Capabilities() AreaCaps {
+	return a.SQLArea.Capabilities() &^ AreaCanDelete
+}
+
+func TestSQLAreaLineEndings(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea line ending normalization", func() {
+		area := &SQLArea{}
+		for _, c := range []struct{ name, in string }{
+			{"LF", "one\ntwo\n\nfour\n"},
+			{"CRLF", "one\r\ntwo\r\n\r\nfour\r\n"},
+			{"CR", "one\rtwo\r\rfour\r"},
+			{"mixed", "one\r\ntwo\n\rfour\r\n"},
+		} {
+			c := c
+			g.It("check NormalizeFromStorage() with "+c.name, func() {
+				g.Assert(area.NormalizeFromStorage(c.in)).Equal("one\rtwo\r\rfour\r")
+			})
+			g.It("check NormalizeForStorage() with "+c.name, func() {
+				g.Assert(area.NormalizeForStorage(c.in)).Equal("one\ntwo\n\nfour\n")
+			})
+			g.It("check round trip with "+c.name, func() {
+				stored := area.NormalizeForStorage(c.in)
+				g.Assert(area.NormalizeForStorage(area.NormalizeFromStorage(stored))).Equal(stored)
+			})
+		}
+	})
+}
+
+func TestSQLAreaDelMsgs(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea batch delete", func() {
+		g.It("check DelMsgs() removes the tagged messages only", func() {
+			area := newTestSQLArea(t, 6)
+			g.Assert(DelMsgs(area, []uint32{5, 2, 2, 0, 3})).IsNil()
+			g.Assert(area.GetCount()).Equal(uint32(3))
+			var subjects []string
+			for _, mi := range *area.GetMessages() {
+				subjects = append(subjects, mi.Subject)
+			}
+			g.Assert(subjects).Equal([]string{"Message 1", "Message 4", "Message 6"})
+		})
+		g.It("check DelMsgs() updates the count cache once", func() {
+			area := newTestSQLArea(t, 4)
+			countCacheValid = true
+			messageCountCache = map[int64]int64{area.areaID: 4}
+			defer InvalidateMessageCounts()
+			g.Assert(DelMsgs(area, []uint32{1, 4})).IsNil()
+			g.Assert(area.GetCount()).Equal(uint32(2))
+		})
+		g.It("check DelMsgs() past the end", func() {
+			area := newTestSQLArea(t, 2)
+			g.Assert(DelMsgs(area, []uint32{1, 3}) == nil).IsFalse()
+			g.Assert(area.GetCount()).Equal(uint32(2))
+		})
+	})
+}
+
+func TestSQLAreaTransfer(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea copy and move", func() {
+		var src, dst *SQLArea
+		g.BeforeEach(func() {
+			src = newTestSQLArea(t, 3)
+			echoarea := database.Echoarea{Name: "dst.area"}
+			g.Assert(src.db.Create(&echoarea).Error).IsNil()
+			dst = NewSQLArea(src.db, echoarea)
+		})
+		g.It("check CopyMessage() keeps the source message", func() {
+			g.Assert(CopyMessage(src, 2, dst)).IsNil()
+			g.Assert(src.GetCount()).Equal(uint32(3))
+			g.Assert(dst.GetCount()).Equal(uint32(1))
+			msg, err := dst.GetMsg(1)
+			g.Assert(err).IsNil()
+			g.Assert(msg.Subject).Equal("Message 2")
+		})
+		g.It("check MoveMessage() deletes the source message", func() {
+			g.Assert(MoveMessage(src, 2, dst)).IsNil()
+			g.Assert(src.GetCount()).Equal(uint32(2))
+			g.Assert(dst.GetCount()).Equal(uint32(1))
+			msg, err := src.GetMsg(2)
+			g.Assert(err).IsNil()
+			g.Assert(msg.Subject).Equal("Message 3")
+		})
+		g.It("check a failing move keeps the source message only", func() {
+			g.Assert(src.db.Migrator().DropTable(&database.Subscription{})).IsNil()
+			g.Assert(MoveMessage(src, 1, dst) == nil).IsFalse()
+			InvalidateMessageCounts()
+			g.Assert(src.GetCount()).Equal(uint32(3))
+			g.Assert(dst.GetCount()).Equal(uint32(0))
+		})
+		g.It("check MoveMessages() keeps the order", func() {
+			g.Assert(MoveMessages(src, []uint32{3, 1}, dst)).IsNil()
+			g.Assert(src.GetCount()).Equal(uint32(1))
+			var subjects []string
+			for _, mi := range *dst.GetMessages() {
+				subjects = append(subjects, mi.Subject)
+			}
+			g.Assert(subjects).Equal([]string{"Message 1", "Message 3"})
+		})
+		g.It("check MoveMessages() into the source area is refused", func() {
+			g.Assert(MoveMessages(src, []uint32{1}, src) == nil).IsFalse()
+			g.Assert(src.GetCount()).Equal(uint32(3))
+		})
+		g.It("check a message past the end", func() {
+			err := CopyMessage(src, 4, dst)
+			g.Assert(errors.Is(err, ErrMsgOutOfRange)).IsTrue()
+		})
+	})
+}
+
+func TestSQLAreaUpdateMsg(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea in place update", func() {
+		area := newTestSQLArea(t, 3)
+		var before database.Echomail
+		g.Before(func() {
+			area.db.Model(&database.Echomail{}).Where("subject = ?", "Message 2").
+				Updates(map[string]interface{}{
+					"message": "\x01PID: test\rHello\nWorld\n\x01Via 2:5020/9696\n",
+					"msgid":   "2:5020/9696 12345678",
+					"date":    int64(1700000000000),
+				})
+			area.db.Where("subject = ?", "Message 2").First(&before)
+		})
+		g.It("check GetStoredMsg() strips kludge lines", func() {
+			msg, err := area.GetStoredMsg(2)
+			g.Assert(err).IsNil()
+			g.Assert(msg.Subject).Equal("Message 2")
+			g.Assert(msg.Body).Equal("Hello\nWorld")
+			g.Assert(msg.Kludges["MSGID:"]).Equal("2:5020/9696 12345678")
+		})
+		g.It("check UpdateMsg() keeps id, MSGID, date and kludges", func() {
+			msg, _ := area.GetStoredMsg(2)
+			msg.Subject = "Message 2, corrected"
+			msg.Body = "Hello\nGopher"
+			g.Assert(area.UpdateMsg(2, msg)).IsNil()
+			var after database.Echomail
+			area.db.First(&after, before.ID)
+			g.Assert(after.Subject).Equal("Message 2, corrected")
+			g.Assert(after.Message).Equal("\x01PID: test\nHello\nGopher\n\x01Via 2:5020/9696\n")
+			g.Assert(after.MsgID).Equal(before.MsgID)
+			g.Assert(after.Date).Equal(before.Date)
+			g.Assert(area.GetCount()).Equal(uint32(3))
+		})
+		g.It("check UpdateMsg() past the end", func() {
+			msg, _ := area.GetStoredMsg(1)
+			g.Assert(area.UpdateMsg(4, msg) == nil).IsFalse()
+		})
+	})
+}
+
+func TestSQLAreaLastDate(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea last message date", func() {
+		area := newTestSQLArea(t, 3)
+		g.Before(func() {
+			g.Assert(area.db.AutoMigrate(&database.Netmail{})).IsNil()
+			area.db.Model(&database.Echomail{}).Where("subject = ?", "Message 2").
+				Update("date", int64(1700000000000))
+			database.DB = area.db
+		})
+		g.After(func() {
+			database.DB = nil
+			InvalidateMessageCounts()
+		})
+		g.It("check zero time without cache", func() {
+			g.Assert(area.GetLastDate().IsZero()).IsTrue()
+		})
+		g.It("check RefreshMessageCounts() loads MAX(date)", func() {
+			g.Assert(RefreshMessageCounts()).IsNil()
+			g.Assert(area.GetLastDate().Unix()).Equal(int64(1700000000))
+			g.Assert(area.GetCount()).Equal(uint32(3))
+		})
+		g.It("check touchLastDate() only moves forward", func() {
+			touchLastDate(area.areaID, false, 1600000000000)
+			g.Assert(area.GetLastDate().Unix()).Equal(int64(1700000000))
+			touchLastDate(area.areaID, false, 1800000000000)
+			g.Assert(area.GetLastDate().Unix()).Equal(int64(1800000000))
+		})
+		g.It("check VerifyMessageCounts() finds drifted counts", func() {
+			g.Assert(RefreshMessageCounts()).IsNil()
+			stale, err := VerifyMessageCounts()
+			g.Assert(err).IsNil()
+			g.Assert(stale).Equal(0)
+			// jnode tosses a message behind our back
+			area.db.Create(&database.Echomail{EchoareaID: area.areaID, Subject: "Tossed", Message: "Hello\n"})
+			stale, err = VerifyMessageCounts()
+			g.Assert(err).IsNil()
+			g.Assert(stale).Equal(1)
+			g.Assert(area.GetCount()).Equal(uint32(3))
+			g.Assert(RefreshMessageCounts()).IsNil()
+			g.Assert(area.GetCount()).Equal(uint32(4))
+			stale, _ = VerifyMessageCounts()
+			g.Assert(stale).Equal(0)
+		})
+	})
+}
+
+func TestSQLAreaGetMsgRange(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea GetMsg() bounds", func() {
+		g.It("check position 0 is the first message", func() {
+			area := newTestSQLArea(t, 3)
+			msg, err := area.GetMsg(0)
+			g.Assert(err).IsNil()
+			g.Assert(msg.Subject).Equal("Message 1")
+		})
+		g.It("check position past the count", func() {
+			area := newTestSQLArea(t, 3)
+			msg, err := area.GetMsg(4)
+			g.Assert(msg == nil).IsTrue()
+			g.Assert(errors.Is(err, ErrMsgOutOfRange)).IsTrue()
+		})
+		g.It("check empty area", func() {
+			area := newTestSQLArea(t, 0)
+			for _, position := range []uint32{0, 1} {
+				msg, err := area.GetMsg(position)
+				g.Assert(msg == nil).IsTrue()
+				g.Assert(errors.Is(err, ErrMsgOutOfRange)).IsTrue()
+			}
+		})
+	})
+}
+
+func TestSQLAreaGetMsgByID(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea GetMsgByID()", func() {
+		g.It("check id survives deletion of earlier messages", func() {
+			area := newTestSQLArea(t, 4)
+			items := *area.GetMessages()
+			g.Assert(len(items)).Equal(4)
+			id := items[2].ID
+			g.Assert(id == 0).IsFalse()
+
+			msg, err := area.GetMsgByID(id)
+			g.Assert(err).IsNil()
+			g.Assert(msg.ID).Equal(id)
+			g.Assert(msg.MsgNum).Equal(uint32(3))
+			g.Assert(msg.Subject).Equal("Message 3")
+
+			g.Assert(area.DelMsg(1)).IsNil()
+			msg, err = area.GetMsgByID(id)
+			g.Assert(err).IsNil()
+			g.Assert(msg.MsgNum).Equal(uint32(2))
+			g.Assert(msg.Subject).Equal("Message 3")
+			byPos, err := area.GetMsg(2)
+			g.Assert(err).IsNil()
+			g.Assert(byPos.ID).Equal(id)
+		})
+		g.It("check unknown id", func() {
+			area := newTestSQLArea(t, 1)
+			msg, err := area.GetMsgByID(1000)
+			g.Assert(msg == nil).IsTrue()
+			g.Assert(errors.Is(err, ErrMsgNotFound)).IsTrue()
+		})
+	})
+}
+
+func TestSQLAreaGetDescription(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea GetDescription()", func() {
+		g.It("check description from the echoarea", func() {
+			area := NewSQLArea(nil, database.Echoarea{Name: "ru.golang", Description: "Go programming"})
+			g.Assert(area.GetDescription()).Equal("Go programming")
+		})
+		g.It("check fixed descriptions", func() {
+			g.Assert(NewSQLNetmailArea(nil).GetDescription() == "").IsFalse()
+			g.Assert(NewSQLArea(nil, database.Echoarea{Name: "BadMail"}).GetDescription() == "").IsFalse()
+			g.Assert(NewSQLArea(nil, database.Echoarea{Name: "su.general"}).GetDescription()).Equal("")
+		})
+	})
+}
+
+func TestSQLAreaLargeMessage(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea large message preview", func() {
+		saved := config.Config.LargeMessageSize
+		g.After(func() {
+			config.Config.LargeMessageSize = saved
+		})
+		g.It("check preview and full text", func() {
+			area := newTestSQLArea(t, 1)
+			text := strings.Repeat("line of a file listing\n", 100)
+			g.Assert(area.db.Model(&database.Echomail{}).Where("echoarea_id = ?", area.areaID).
+				Update("message", text).Error).IsNil()
+
+			config.Config.LargeMessageSize = 100
+			msg, err := area.GetMsg(1)
+			g.Assert(err).IsNil()
+			g.Assert(msg.Truncated).IsTrue()
+			g.Assert(msg.FullSize).Equal(len(text))
+			g.Assert(len(msg.Body) <= 100).IsTrue()
+			g.Assert(strings.HasSuffix(msg.Body, "listing\r")).IsTrue()
+
+			byID, err := area.GetMsgByID(msg.ID)
+			g.Assert(err).IsNil()
+			g.Assert(byID.Truncated).IsTrue()
+			g.Assert(byID.Body).Equal(msg.Body)
+
+			full, err := area.GetFullMsg(msg.ID)
+			g.Assert(err).IsNil()
+			g.Assert(full.Truncated).IsFalse()
+			g.Assert(strings.Count(full.Body, "\r")).Equal(100)
+
+			config.Config.LargeMessageSize = -1
+			msg, err = area.GetMsg(1)
+			g.Assert(err).IsNil()
+			g.Assert(msg.Truncated).IsFalse()
+
+			config.Config.LargeMessageSize = len(text)
+			msg, err = area.GetMsg(1)
+			g.Assert(err).IsNil()
+			g.Assert(msg.Truncated).IsFalse()
+		})
+	})
+}
+
+func TestSQLAreaFromAddr(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check From address of saved messages", func() {
+		echo := newTestSQLArea(t, 0)
+		netmail := NewSQLNetmailArea(echo.db)
+		savedAddress, savedAkas := config.Config.Address, config.Config.Akas
+		g.Before(func() {
+			g.Assert(echo.db.AutoMigrate(&database.Netmail{}, &database.Link{}, &database.LinkOption{}, &database.Route{})).IsNil()
+			config.Config.Address = types.AddrFromString("2:5020/9696")
+			config.Config.Akas = []*types.FidoAddr{types.AddrFromString("1:123/45")}
+		})
+		g.After(func() {
+			config.Config.Address, config.Config.Akas = savedAddress, savedAkas
+		})
+		g.It("check blank echomail From gets the configured address", func() {
+			g.Assert(echo.SaveMsg(&Message{From: "Sysop", To: "All", Subject: "Hello", Body: "Hello",
+				Kludges: map[string]string{}})).IsNil()
+			var echomail database.Echomail
+			g.Assert(echo.db.Last(&echomail).Error).IsNil()
+			g.Assert(echomail.FromFtnAddr).Equal("2:5020/9696")
+			g.Assert(strings.HasPrefix(echomail.MsgID, "2:5020/9696 ")).IsTrue()
+		})
+		g.It("check the saved message is set to the area", func() {
+			var other AreaPrimitive = NewMemoryArea("memory.area", EchoAreaTypeEcho)
+			msg := &Message{AreaObject: &other, From: "Sysop", To: "All", Subject: "Hello", Body: "Hello",
+				Kludges: map[string]string{}}
+			g.Assert(echo.SaveMsg(msg)).IsNil()
+			g.Assert((*msg.AreaObject).GetName()).Equal(echo.GetName())
+			g.Assert(msg.FromAddr.String()).Equal("2:5020/9696")
+		})
+		g.It("check blank netmail From gets the aka of the destination zone", func() {
+			g.Assert(netmail.SaveMsg(&Message{From: "Sysop", To: "Alexander Skovpen", ToAddr: types.AddrFromString("1:234/5"),
+				Subject: "Hello", Body: "Hello", Kludges: map[string]string{}, FromAddr: &types.FidoAddr{}})).IsNil()
+			var stored database.Netmail
+			g.Assert(echo.db.Last(&stored).Error).IsNil()
+			g.Assert(stored.FromAddress).Equal("1:123/45")
+			g.Assert(strings.Contains(stored.Text, "\x01INTL 1:234/5 1:123/45\r")).IsTrue()
+		})
+		g.It("check From addresses which are not ours are refused", func() {
+			err := echo.SaveMsg(&Message{From: "Sysop", FromAddr: types.AddrFromString("2:5030/100"),
+				To: "All", Subject: "Hello", Body: "Hello", Kludges: map[string]string{}})
+			g.Assert(err == nil).IsFalse()
+			config.Config.Address, config.Config.Akas = nil, nil
+			defer func() {
+				config.Config.Address = types.AddrFromString("2:5020/9696")
+				config.Config.Akas = []*types.FidoAddr{types.AddrFromString("1:123/45")}
+			}()
+			err = echo.SaveMsg(&Message{From: "Sysop", To: "All", Subject: "Hello", Body: "Hello",
+				Kludges: map[string]string{}})
+			g.Assert(err == nil).IsFalse()
+		})
+	})
+}
+
+func TestSQLAreaSaveTransaction(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check saved messages and their queueing", func() {
+		echo := newTestSQLArea(t, 0)
+		netmail := NewSQLNetmailArea(echo.db)
+		savedAddress, savedHold := config.Config.Address, config.Config.Netmail.HoldUnroutable
+		g.Before(func() {
+			g.Assert(echo.db.AutoMigrate(&database.Netmail{}, &database.LinkOption{}, &database.Route{})).IsNil()
+			link := database.Link{StationName: "2:5030/100", FtnAddress: "2:5030/100"}
+			g.Assert(echo.db.Create(&link).Error).IsNil()
+			g.Assert(echo.db.Create(&database.Subscription{LinkID: link.ID, EchoareaID: echo.areaID}).Error).IsNil()
+			config.Config.Address = types.AddrFromString("2:5020/9696")
+			config.Config.Netmail.HoldUnroutable = true
+		})
+		g.After(func() {
+			config.Config.Address, config.Config.Netmail.HoldUnroutable = savedAddress, savedHold
+		})
+		saveEcho := func() error {
+			return echo.SaveMsg(&Message{From: "Sysop", To: "All", Subject: "Hello", Body: "Hello",
+				Kludges: map[string]string{}})
+		}
+		saveNetmail := func() error {
+			return netmail.SaveMsg(&Message{From: "Sysop", To: "Alexander Skovpen", ToAddr: types.AddrFromString("2:9999/1"),
+				Subject: "Hello", Body: "Hello", Kludges: map[string]string{}, FromAddr: types.AddrFromString("2:5020/9696")})
+		}
+		count := func(model interface{}) int64 {
+			var n int64
+			g.Assert(echo.db.Model(model).Count(&n).Error).IsNil()
+			return n
+		}
+		g.It("check saved echomail is queued", func() {
+			g.Assert(saveEcho()).IsNil()
+			g.Assert(count(&database.Echomail{})).Equal(int64(1))
+			g.Assert(count(&database.EchomailAwaiting{})).Equal(int64(1))
+		})
+		g.It("check echomail is not saved when queueing fails", func() {
+			g.Assert(echo.db.Migrator().DropTable(&database.EchomailAwaiting{})).IsNil()
+			defer func() {
+				g.Assert(echo.db.AutoMigrate(&database.EchomailAwaiting{})).IsNil()
+			}()
+			g.Assert(saveEcho() == nil).IsFalse()
+			g.Assert(count(&database.Echomail{})).Equal(int64(1))
+			g.Assert(echo.GetCount()).Equal(uint32(1))
+		})
+		g.It("check netmail without a route is saved held", func() {
+			g.Assert(saveNetmail()).IsNil()
+			var stored database.Netmail
+			g.Assert(echo.db.Last(&stored).Error).IsNil()
+			g.Assert(stored.RouteVia == nil).IsTrue()
+			g.Assert(stored.Attr & netmailHold).Equal(netmailHold)
+		})
+		g.It("check netmail is not saved when routing fails", func() {
+			g.Assert(echo.db.Migrator().DropTable(&database.Route{})).IsNil()
+			defer func() {
+				g.Assert(echo.db.AutoMigrate(&database.Route{})).IsNil()
+			}()
+			g.Assert(saveNetmail() == nil).IsFalse()
+			g.Assert(count(&database.Netmail{})).Equal(int64(1))
+		})
+	})
+}
+
+func TestSQLAreaNetmailRoute(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check findNetmailRoute()", func() {
+		echo := newTestSQLArea(t, 0)
+		netmail := NewSQLNetmailArea(echo.db)
+		ids := make(map[string]int64)
+		savedAddress, savedPointnet := config.Config.Address, config.Config.Netmail.Pointnet
+		g.Before(func() {
+			g.Assert(echo.db.AutoMigrate(&database.Link{}, &database.LinkOption{}, &database.Route{})).IsNil()
+			for _, addr := range []string{"2:5030/100", "2:5020/1", "2:5020/2", "2:20999/3"} {
+				link := database.Link{StationName: addr, FtnAddress: addr}
+				g.Assert(echo.db.Create(&link).Error).IsNil()
+				ids[addr] = link.ID
+			}
+			g.Assert(echo.db.Create(&database.Route{Nice: 10, FromName: "*", ToName: "*", FromAddress: "*",
+				ToAddress: "2:5040/1.7", Subject: "*", RouteVia: ids["2:5020/2"]}).Error).IsNil()
+			g.Assert(echo.db.Create(&database.LinkOption{LinkID: ids["2:5020/1"], Name: linkOptionRoutePoints,
+				Value: "2:5060/6.1, 2:5050/50.*"}).Error).IsNil()
+			config.Config.Address = types.AddrFromString("2:5020/9696")
+			config.Config.Netmail.Pointnet = 20999
+		})
+		g.After(func() {
+			config.Config.Address, config.Config.Netmail.Pointnet = savedAddress, savedPointnet
+		})
+		route := func(to string) (*int64, error) {
+			return netmail.findNetmailRoute(&Message{
+				FromAddr: types.AddrFromString("2:5020/9696"),
+				ToAddr:   types.AddrFromString(to),
+				From:     "Sysop",
+				To:       "Alexander Skovpen",
+			})
+		}
+		g.It("check point routes to its boss node", func() {
+			via, err := route("2:5030/100.5")
+			g.Assert(err).IsNil()
+			g.Assert(*via).Equal(ids["2:5030/100"])
+		})
+		g.It("check point routes by routing table rule", func() {
+			via, err := route("2:5040/1.7")
+			g.Assert(err).IsNil()
+			g.Assert(*via).Equal(ids["2:5020/2"])
+			_, err = route("2:5040/1.8")
+			g.Assert(err == nil).IsFalse()
+		})
+		g.It("check own point through pointnet", func() {
+			via, err := route("2:5020/9696.3")
+			g.Assert(err).IsNil()
+			g.Assert(via == nil).IsTrue()
+		})
+		g.It("check point routing link option", func() {
+			via, err := route("2:5050/50.2")
+			g.Assert(err).IsNil()
+			g.Assert(*via).Equal(ids["2:5020/1"])
+			via, err = route("2:5060/6.1")
+			g.Assert(err).IsNil()
+			g.Assert(*via).Equal(ids["2:5020/1"])
+			_, err = route("2:5060/6.2")
+			g.Assert(err == nil).IsFalse()
+		})
+	})
+}
+
+func TestPointnetAddrs(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check pointnetAddrs()", func() {
+		savedAddress, savedPointnet := config.Config.Address, config.Config.Netmail.Pointnet
+		g.After(func() {
+			config.Config.Address, config.Config.Netmail.Pointnet = savedAddress, savedPointnet
+		})
+		g.It("check translation both ways", func() {
+			config.Config.Address = types.AddrFromString("2:5020/9696")
+			config.Config.Netmail.Pointnet = 0
+			g.Assert(len(pointnetAddrs(types.AddrFromString("2:5020/9696.3")))).Equal(0)
+			config.Config.Netmail.Pointnet = 20999
+			addrs := pointnetAddrs(types.AddrFromString("2:5020/9696.3"))
+			g.Assert(len(addrs)).Equal(1)
+			g.Assert(addrs[0].String()).Equal("2:20999/3")
+			addrs = pointnetAddrs(types.AddrFromString("2:20999/3"))
+			g.Assert(len(addrs)).Equal(1)
+			g.Assert(addrs[0].String()).Equal("2:5020/9696.3")
+			g.Assert(len(pointnetAddrs(types.AddrFromString("2:5030/100.5")))).Equal(0)
+		})
+	})
+}
+
+func TestSQLAreaTypeLetter(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check area type letters", func() {
+		echo := newTestSQLArea(t, 0)
+		g.It("check the synthetic netmail area", func() {
+			g.Assert(NewSQLNetmailArea(echo.db).GetType().Letter()).Equal("N")
+		})
+		g.It("check mapped jnode areas", func() {
+			g.Assert(echo.GetType().Letter()).Equal("E")
+			for name, letter := range map[string]string{"Netmail": "N", "BadMail": "B", "Bad": "B",
+				"DupeMail": "D", "Dupe": "D", "ru.golded": "E"} {
+				g.Assert(mapJnodeAreaType(name).Letter()).Equal(letter)
+			}
+		})
+		g.It("check local and unknown types", func() {
+			g.Assert(EchoAreaTypeLocal.Letter()).Equal("L")
+			g.Assert(EchoAreaTypeNone.Letter()).Equal("")
+			g.Assert(EchoAreaType(9).Letter()).Equal("")
+		})
+	})
+}
+
+func TestSQLAreaCountUnreadTo(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check SQLArea unread messages to a user", func() {
+		area := newTestSQLArea(t, 0)
+		netmail := NewSQLNetmailArea(area.db)
+		g.Before(func() {
+			g.Assert(area.db.AutoMigrate(&database.Netmail{})).IsNil()
+			for _, to := range []string{"Alexander Skovpen", "All", "Alexander. Skovpen ", "Someone Else"} {
+				g.Assert(area.db.Create(&database.Netmail{FromName: "Sysop", ToName: to,
+					FromAddress: "2:5020/1", ToAddress: "2:5020/9696", Text: "Hello\n"}).Error).IsNil()
+			}
+		})
+		g.It("check names are compared like NamesEqual", func() {
+			n, err := netmail.CountUnreadTo("Alexander Skovpen")
+			g.Assert(err).IsNil()
+			g.Assert(n).Equal(uint32(2))
+		})
+		g.It("check messages up to the lastread are not counted", func() {
+			netmail.SetLast(1)
+			n, err := netmail.CountUnreadTo("Alexander Skovpen")
+			g.Assert(err).IsNil()
+			g.Assert(n).Equal(uint32(1))
+			netmail.SetLast(4)
+			n, _ = netmail.CountUnreadTo("Alexander Skovpen")
+			g.Assert(n).Equal(uint32(0))
+		})
+	})
+}
@@ -90,7 +90,7 @@ func (s *Squish) getAttrs(a uint32) (attrs []string) {
 		"Pvt", "", "Rcv", "Snt",
 		"", "Trs", "", "K/s",
 		"Loc", "", "", "",
-		"Rrq", "", "Arq", "",
+		"Rrq", "Cpt", "Arq", "",
 		"Scn", "", "", "",
 		"", "", "", "",
 		"", "", "", "",
@@ -129,15 +129,19 @@ func readSQDH(headerb *bytes.Buffer) (sqdH, error) {
 	return sqdh, nil
 }
 
+// GetMsgByID returns nil, messages of file bases have no database id
+func (s *Squish) GetMsgByID(id int64) (*Message, error) {
+	return nil, nil
+}
+
 // GetMsg return message
 func (s *Squish) GetMsg(position uint32) (*Message, error) {
-	if len(s.indexStructure) == 0 {
-		//		return nil, errors.New("Empty Area")
-		return nil, nil
-	}
 	if position == 0 {
 		position = 1
 	}
+	if int(position) > len(s.indexStructure) {
+		return nil, ErrMsgOutOfRange
+	}
 	f, err := os.Open(s.AreaPath + ".sqd")
 	if err != nil {
 		return nil, err
@@ -194,6 +198,7 @@ func (s *Squish) GetMsg(position uint32) (*Message, error) {
 	if strings.Contains(rm.Body, "\x00") {
 		rm.Body = rm.Body[0:strings.Index(rm.Body, "\x00")]
 	}
+	rm.Body = s.NormalizeFromStorage(rm.Body)
 	err = rm.ParseRaw()
 	if err != nil {
 		return nil, err
@@ -478,6 +483,12 @@ func (s *Squish) GetChrs() string {
 	return s.Chrs
 }
 
+// Capabilities returns posting and deleting, Squish messages are not edited
+// in place
+func (s *Squish) Capabilities() AreaCaps {
+	return areaCaps(s) | AreaCanDelete
+}
+
 // GetMessages get headers
 func (s *Squish) GetMessages() *[]MessageListItem {
 	if len(s.messages) > 0 || len(s.indexStructure) == 0 {
@@ -597,15 +608,14 @@ func (s *Squish) DelMsg(l uint32) error {
 
 // Line ending handling methods for Squish format
 func (s *Squish) GetStorageLineEnding() string {
-	return "\r" // Squish stores FTN-style line endings
+	return ftnLineEnding // Squish stores FTN-style line endings
 }
 
 func (s *Squish) NormalizeForStorage(body string) string {
-	// Convert Unix \n to FTN \r and ensure trailing \r for Squish format
-	return strings.Join(strings.Split(body, "\n"), "\x0d") + "\x0d"
+	return terminateLines(body, s.GetStorageLineEnding())
 }
 
 func (s *Squish) NormalizeFromStorage(body string) string {
-	// Squish already stores in FTN format, no conversion needed
-	return body
+	// lines written with \r\n or \n by other software end in \r as well
+	return convertLineEndings(body, ftnLineEnding)
 }
@@ -0,0 +1,31 @@
+package msgapi
+
+import (
+	"fmt"
+)
+
+// CopyMessage copies message pos of src into dst, preserving addresses,
+// MSGID and CHRS. Only SQL echomail areas are supported.
+func CopyMessage(src AreaPrimitive, pos uint32, dst AreaPrimitive) error {
+	return transferMessage(src, pos, dst, false)
+}
+
+// MoveMessage moves message pos of src into dst, see CopyMessage
+func MoveMessage(src AreaPrimitive, pos uint32, dst AreaPrimitive) error {
+	return transferMessage(src, pos, dst, true)
+}
+
+func transferMessage(src AreaPrimitive, pos uint32, dst AreaPrimitive, move bool) error {
+	if move && !src.Capabilities().Has(AreaCanDelete) {
+		return fmt.Errorf("messages of %s can not be deleted, %w", src.GetName(), ErrReadOnly)
+	}
+	srcArea, ok := src.(*SQLArea)
+	if !ok {
+		return fmt.Errorf("copying messages from %s areas is not supported", src.GetMsgType())
+	}
+	dstArea, ok := dst.(*SQLArea)
+	if !ok {
+		return fmt.Errorf("copying messages to %s areas is not supported", dst.GetMsgType())
+	}
+	return srcArea.copyEchomailTo(pos, dstArea, move)
+}
@@ -2,16 +2,21 @@ package nodelist
 
 import (
 	"bufio"
+	"compress/gzip"
+	"errors"
 	"fmt"
 	"github.com/askovpen/gossiped/pkg/types"
 	"io"
+	"log"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 )
 
 type (
-	nodelineS struct {
+	// Node is a single nodelist entry
+	Node struct {
 		Address types.FidoAddr
 		BBS     string
 		City    string
@@ -20,52 +25,188 @@ type (
 )
 
 // Nodelist contains NodeList
-var Nodelist []nodelineS
+var Nodelist []Node
 
-// Read reads NodeList from the file
+var (
+	sysopIndex   []string
+	addressIndex map[types.FidoAddr]int
+)
+
+// Read reads a nodelist, pointlist or nodelist segment into Nodelist,
+// merging it with the files read before: a node whose address is already
+// known replaces the earlier entry. Pointlists list the points of the node
+// of a Boss line, or of the node above their Point lines.
 func Read(fn string) error {
 	file, err := os.Open(fn)
 	if err != nil {
 		return fmt.Errorf("cannot open nodelist file '%s': %w", fn, err)
 	}
 	defer file.Close()
-	b, err := io.ReadAll(file)
+	br := bufio.NewReader(file)
+	var r io.Reader = br
+	if isGzip(fn, br) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			log.Printf("cannot decompress nodelist file '%s': %v", fn, err)
+			return fmt.Errorf("cannot decompress nodelist file '%s': %w", fn, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	b, err := io.ReadAll(r)
 	if err != nil {
+		log.Printf("cannot read nodelist file '%s': %v", fn, err)
 		return err
 	}
+	// the index follows Nodelist, which may have been reset
+	buildIndex()
 	scanner := bufio.NewScanner(strings.NewReader(string(b)))
 	re := regexp.MustCompile(",")
 	var z, n, f string
+	var boss *types.FidoAddr
+	read, replaced := 0, 0
 	for scanner.Scan() {
-		if scanner.Text()[0] == ';' {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || line[0] == ';' {
+			continue
+		}
+		res := re.Split(line, -1)
+		if strings.EqualFold(res[0], "boss") && len(res) > 1 {
+			boss = types.AddrFromString(res[1])
 			continue
 		}
-		res := re.Split(scanner.Text(), -1)
 		if len(res) < 5 {
 			continue
 		}
+		var address *types.FidoAddr
 		switch strings.ToLower(res[0]) {
 		case "zone":
 			z = res[1]
 			n = "0"
 			f = "0"
+			boss = nil
 		case "region":
 			n = res[1]
 			f = "0"
+			boss = nil
 		case "host":
 			n = res[1]
 			f = "0"
+			boss = nil
+		case "point":
+			if boss != nil {
+				address = types.AddrFromString(boss.String() + "." + res[1])
+			} else {
+				address = types.AddrFromString(z + ":" + n + "/" + f + "." + res[1])
+			}
 		default:
-			f = res[1]
+			if boss != nil {
+				address = types.AddrFromString(boss.String() + "." + res[1])
+			} else {
+				f = res[1]
+			}
+		}
+		if address == nil {
+			address = types.AddrFromString(z + ":" + n + "/" + f)
 		}
-		address := types.AddrFromString(z + ":" + n + "/" + f)
-		node := nodelineS{
+		if address == nil {
+			continue
+		}
+		node := Node{
 			Address: *address,
 			BBS:     res[2],
 			City:    res[3],
 			Sysop:   res[4],
 		}
+		read++
+		if i, ok := addressIndex[node.Address]; ok {
+			Nodelist[i] = node
+			replaced++
+			continue
+		}
+		addressIndex[node.Address] = len(Nodelist)
 		Nodelist = append(Nodelist, node)
 	}
+	buildIndex()
+	log.Printf("Read %d nodes from nodelist file '%s', %d of them replacing earlier entries", read, fn, replaced)
+	return nil
+}
+
+// ReadAll reads the nodelist files fns in order, later files overriding and
+// adding to earlier ones. A file which can not be read is skipped, the
+// errors of all of them are returned.
+func ReadAll(fns []string) error {
+	var errs []error
+	for _, fn := range fns {
+		if err := Read(fn); err != nil {
+			log.Printf("%v", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isGzip reports whether the nodelist is gzip-compressed, by extension or magic bytes
+func isGzip(fn string, r *bufio.Reader) bool {
+	if strings.EqualFold(filepath.Ext(fn), ".gz") {
+		return true
+	}
+	magic, err := r.Peek(2)
+	return err == nil && magic[0] == 0x1f && magic[1] == 0x8b
+}
+
+// normalizeName lowercases name and replaces nodelist underscores with spaces
+func normalizeName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", " "))
+}
+
+// buildIndex builds lookup indexes for the loaded nodelist
+func buildIndex() {
+	sysopIndex = make([]string, len(Nodelist))
+	addressIndex = make(map[types.FidoAddr]int, len(Nodelist))
+	for i, node := range Nodelist {
+		sysopIndex[i] = normalizeName(node.Sysop)
+		if _, ok := addressIndex[node.Address]; !ok {
+			addressIndex[node.Address] = i
+		}
+	}
+}
+
+// FindBySysop returns nodes whose sysop name contains substr, case-insensitive
+func FindBySysop(substr string) []Node {
+	substr = normalizeName(substr)
+	var nodes []Node
+	for i, sysop := range sysopIndex {
+		if strings.Contains(sysop, substr) {
+			nodes = append(nodes, Nodelist[i])
+		}
+	}
+	return nodes
+}
+
+// FindByAddress returns the node with the given address or nil
+func FindByAddress(addr *types.FidoAddr) *Node {
+	if addr == nil {
+		return nil
+	}
+	if i, ok := addressIndex[*addr]; ok {
+		return &Nodelist[i]
+	}
 	return nil
 }
+
+// Search returns nodes whose sysop name contains s or whose address starts with s
+func Search(s string) []Node {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Nodelist
+	}
+	name := normalizeName(s)
+	var nodes []Node
+	for i, node := range Nodelist {
+		if strings.Contains(sysopIndex[i], name) || strings.HasPrefix(node.Address.String(), s) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
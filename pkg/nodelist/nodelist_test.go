@@ -1,8 +1,11 @@
 package nodelist
 
 import (
+	"compress/gzip"
 	"github.com/askovpen/gossiped/pkg/types"
 	. "github.com/franela/goblin"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -21,5 +24,94 @@ func TestNodelist(t *testing.T) {
 			g.Assert(Nodelist[len(Nodelist)-1].BBS).Equal("El_Gato_De_Fuego_BBS_II")
 			g.Assert(Nodelist[len(Nodelist)-1].City).Equal("Pedasi_Panama")
 		})
+		g.It("check FindBySysop()", func() {
+			nodes := FindBySysop("nick andre")
+			g.Assert(len(nodes) > 0).IsTrue()
+			g.Assert(nodes[0].Address).Equal(*types.AddrFromString("1:0/0"))
+			g.Assert(len(FindBySysop("JOHN_DOVEY")) > 0).IsTrue()
+			g.Assert(len(FindBySysop("no such sysop here"))).Equal(0)
+		})
+		g.It("check FindByAddress()", func() {
+			node := FindByAddress(types.AddrFromString("4:920/69"))
+			g.Assert(node != nil).IsTrue()
+			g.Assert(node.Sysop).Equal("John_Dovey")
+			g.Assert(FindByAddress(types.AddrFromString("4:920/69.1")) == nil).IsTrue()
+			g.Assert(FindByAddress(nil) == nil).IsTrue()
+		})
+		g.It("check Search()", func() {
+			g.Assert(len(Search(""))).Equal(len(Nodelist))
+			nodes := Search("4:920/69")
+			g.Assert(len(nodes) > 0).IsTrue()
+			g.Assert(nodes[0].Address).Equal(*types.AddrFromString("4:920/69"))
+			g.Assert(len(Search("dovey")) > 0).IsTrue()
+		})
+		g.It("check nodelist.Read() of gzip file", func() {
+			plain, err := os.ReadFile("../../testdata/NODELIST.299")
+			g.Assert(err).IsNil()
+			fn := filepath.Join(t.TempDir(), "NODELIST.Z99")
+			f, err := os.Create(fn)
+			g.Assert(err).IsNil()
+			zw := gzip.NewWriter(f)
+			zw.Write(plain)
+			zw.Close()
+			f.Close()
+			Nodelist = nil
+			g.Assert(Read(fn)).IsNil()
+			g.Assert(len(Nodelist)).Equal(1216)
+			g.Assert(Nodelist[0].Sysop).Equal("Nick_Andre")
+		})
+		g.It("check nodelist.Read() of broken gzip file", func() {
+			fn := filepath.Join(t.TempDir(), "NODELIST.gz")
+			os.WriteFile(fn, []byte("not gzipped"), 0644)
+			Nodelist = nil
+			g.Assert(Read(fn) != nil).IsTrue()
+			g.Assert(len(Nodelist)).Equal(0)
+		})
+	})
+}
+
+func TestNodelistMerge(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check merging nodelists", func() {
+		dir := t.TempDir()
+		write := func(name, text string) string {
+			fn := filepath.Join(dir, name)
+			g.Assert(os.WriteFile(fn, []byte(text), 0644)).IsNil()
+			return fn
+		}
+		nodes := write("NODELIST.001", ";A test nodelist\r\n"+
+			"Zone,2,Europe,Moscow,Sysop_One,-Unpublished-,300\r\n"+
+			"Host,5020,Moscow_Net,Moscow,Sysop_Two,-Unpublished-,300\r\n"+
+			",9696,Old_BBS,Moscow,Alexander_Skovpen,-Unpublished-,300\r\n"+
+			"\r\n"+
+			",1,First_BBS,Moscow,Ivan_Petrov,-Unpublished-,300\r\n"+
+			"Point,3,Point_Three,Moscow,Petr_Ivanov,-Unpublished-,300\r\n")
+		segment := write("SEGMENT.001", "Zone,2,Europe,Moscow,Sysop_One,-Unpublished-,300\r\n"+
+			"Host,5020,Moscow_Net,Moscow,Sysop_Two,-Unpublished-,300\r\n"+
+			",9696,New_BBS,Moscow,Alexander_Skovpen,-Unpublished-,300\r\n")
+		points := write("PNT5020.001", "Boss,2:5020/9696\r\n"+
+			",128,Point_BBS,Moscow,Sergey_Sidorov,-Unpublished-,300\r\n")
+		g.It("check later files override and add nodes", func() {
+			Nodelist = nil
+			g.Assert(ReadAll([]string{nodes, segment, points, filepath.Join(dir, "missing")}) != nil).IsTrue()
+			g.Assert(len(Nodelist)).Equal(6)
+			g.Assert(FindByAddress(types.AddrFromString("2:5020/9696")).BBS).Equal("New_BBS")
+			g.Assert(FindByAddress(types.AddrFromString("2:5020/1.3")).Sysop).Equal("Petr_Ivanov")
+			g.Assert(FindByAddress(types.AddrFromString("2:5020/9696.128")).Sysop).Equal("Sergey_Sidorov")
+			g.Assert(len(FindBySysop("sergey"))).Equal(1)
+			g.Assert(len(Search("2:5020/9696"))).Equal(2)
+		})
+		g.It("check Point lines of a Boss-format pointlist", func() {
+			Nodelist = nil
+			boss := write("PNT5030.001", "Boss,2:5030/100\r\n"+
+				"Point,1,First_Point,Spb,Oleg_Orlov,-Unpublished-,300\r\n"+
+				"Point,2,Second_Point,Spb,Anna_Orlova,-Unpublished-,300\r\n"+
+				"Boss,2:5030/200\r\n"+
+				"Point,1,Other_Point,Spb,Boris_Belov,-Unpublished-,300\r\n")
+			g.Assert(Read(boss)).IsNil()
+			g.Assert(len(Nodelist)).Equal(3)
+			g.Assert(FindByAddress(types.AddrFromString("2:5030/100.2")).Sysop).Equal("Anna_Orlova")
+			g.Assert(FindByAddress(types.AddrFromString("2:5030/200.1")).Sysop).Equal("Boris_Belov")
+		})
 	})
 }
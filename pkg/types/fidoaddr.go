@@ -15,17 +15,40 @@ type FidoAddr struct {
 }
 
 var (
-	fidoAddrRE = regexp.MustCompile(`(\d+):(\d+)/(\d+)\.?(\d+)?(@.*)?`)
+	fidoAddrRE      = regexp.MustCompile(`(\d+):(\d+)/(\d+)\.?(\d+)?(@.*)?`)
+	fidoShortAddrRE = regexp.MustCompile(`^\s*(\d+)/(\d+)(?:\.(\d+))?\s*$`)
+	defaultZone     uint16
 )
 
+// SetDefaultZone set zone used for addresses given without zone (net/node)
+func SetDefaultZone(zone uint16) {
+	defaultZone = zone
+}
+
 // Equal compare two *FidoAddr
 func (f *FidoAddr) Equal(fn *FidoAddr) bool {
+	if f == nil || fn == nil {
+		return f == nil && fn == nil
+	}
 	if f.zone == fn.zone && f.net == fn.net && f.node == fn.node && f.point == fn.point {
 		return true
 	}
 	return false
 }
 
+// SameNode compare two *FidoAddr ignoring point
+func (f *FidoAddr) SameNode(fn *FidoAddr) bool {
+	if f == nil || fn == nil {
+		return false
+	}
+	return f.zone == fn.zone && f.net == fn.net && f.node == fn.node
+}
+
+// IsZero return true for nil or empty address
+func (f *FidoAddr) IsZero() bool {
+	return f == nil || (f.zone == 0 && f.net == 0 && f.node == 0 && f.point == 0)
+}
+
 func (f *FidoAddr) String() string {
 	if f == nil {
 		return ""
@@ -56,11 +79,12 @@ func (f *FidoAddr) FQDN() (string, error) {
 }
 
 // AddrFromString return FidoAddr from string
+// net/node[.point] form inherits zone set by SetDefaultZone
 func AddrFromString(s string) *FidoAddr {
 	f := &FidoAddr{}
 	res := fidoAddrRE.FindStringSubmatch(s)
 	if len(res) == 0 {
-		return nil
+		return addrFromShortString(s)
 	}
 	if len(res[1]) > 0 {
 		zone, _ := strconv.Atoi(res[1])
@@ -81,6 +105,24 @@ func AddrFromString(s string) *FidoAddr {
 	return f
 }
 
+// addrFromShortString return FidoAddr from net/node[.point] string
+func addrFromShortString(s string) *FidoAddr {
+	if defaultZone == 0 {
+		return nil
+	}
+	res := fidoShortAddrRE.FindStringSubmatch(s)
+	if len(res) == 0 {
+		return nil
+	}
+	net, _ := strconv.Atoi(res[1])
+	node, _ := strconv.Atoi(res[2])
+	point := 0
+	if len(res[3]) > 0 {
+		point, _ = strconv.Atoi(res[3])
+	}
+	return AddrFromNum(defaultZone, uint16(net), uint16(node), uint16(point))
+}
+
 // AddrFromNum return FidoAddr from digits
 func AddrFromNum(zone uint16, net uint16, node uint16, point uint16) *FidoAddr {
 	f := &FidoAddr{}
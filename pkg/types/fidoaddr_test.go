@@ -22,6 +22,43 @@ func TestFidoAddr(t *testing.T) {
 			g.Assert(AddrFromString("2:5020/9696").Equal(AddrFromNum(2, 5020, 9696, 0))).Equal(true)
 			g.Assert(AddrFromString("2:5020/9696.5").Equal(AddrFromNum(2, 5020, 9696, 0))).Equal(false)
 		})
+		g.It("check Equal()", func() {
+			var n *FidoAddr
+			g.Assert(n.Equal(nil)).Equal(true)
+			g.Assert(n.Equal(AddrFromNum(2, 5020, 9696, 0))).Equal(false)
+			g.Assert(AddrFromNum(2, 5020, 9696, 0).Equal(nil)).Equal(false)
+			g.Assert(AddrFromString("2:5020/1042.0").Equal(AddrFromString("2:5020/1042"))).Equal(true)
+			g.Assert(AddrFromString("2:5020/1042@fidonet").Equal(AddrFromString("2:5020/1042"))).Equal(true)
+			g.Assert(AddrFromString("1:5020/1042").Equal(AddrFromString("2:5020/1042"))).Equal(false)
+		})
+		g.It("check SameNode()", func() {
+			g.Assert(AddrFromString("2:5020/1042.5").SameNode(AddrFromString("2:5020/1042"))).Equal(true)
+			g.Assert(AddrFromString("2:5020/1042.5").SameNode(AddrFromString("2:5020/1042.7"))).Equal(true)
+			g.Assert(AddrFromString("2:5020/1042").SameNode(AddrFromString("2:5020/1043"))).Equal(false)
+			g.Assert(AddrFromString("2:5020/1042").SameNode(AddrFromString("2:5021/1042"))).Equal(false)
+			g.Assert(AddrFromString("2:5020/1042").SameNode(AddrFromString("1:5020/1042"))).Equal(false)
+			g.Assert(AddrFromString("2:5020/1042").SameNode(nil)).Equal(false)
+		})
+		g.It("check IsZero()", func() {
+			var n *FidoAddr
+			g.Assert(n.IsZero()).Equal(true)
+			g.Assert((&FidoAddr{}).IsZero()).Equal(true)
+			g.Assert(AddrFromNum(0, 0, 0, 1).IsZero()).Equal(false)
+			g.Assert(AddrFromString("2:5020/1042").IsZero()).Equal(false)
+		})
+		g.It("check AddrFromString() without zone", func() {
+			SetDefaultZone(0)
+			g.Assert(AddrFromString("5020/1042") == nil).Equal(true)
+			SetDefaultZone(2)
+			defer SetDefaultZone(0)
+			g.Assert(AddrFromString("5020/1042").Equal(AddrFromNum(2, 5020, 1042, 0))).Equal(true)
+			g.Assert(AddrFromString(" 5020/1042.7 ").Equal(AddrFromNum(2, 5020, 1042, 7))).Equal(true)
+			g.Assert(AddrFromString("5020/1042").Equal(AddrFromString("2:5020/1042.0"))).Equal(true)
+			g.Assert(AddrFromString("1:5020/1042").Equal(AddrFromNum(1, 5020, 1042, 0))).Equal(true)
+			g.Assert(AddrFromString("2:5020") == nil).Equal(true)
+			g.Assert(AddrFromString("5020") == nil).Equal(true)
+			g.Assert(AddrFromString("5020/abc") == nil).Equal(true)
+		})
 		g.It("check GetZone()", func() {
 			g.Assert((&FidoAddr{2, 5020, 9696, 0}).GetZone()).Equal(uint16(2))
 		})
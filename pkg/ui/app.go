@@ -1,32 +1,47 @@
 package ui
 
 import (
+	"github.com/askovpen/gossiped/pkg/config"
 	"github.com/askovpen/gossiped/pkg/msgapi"
+	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
 // App ui struct
 type App struct {
-	App         *tview.Application
-	Layout      *tview.Flex
-	Pages       *tview.Pages
-	sb          *StatusBar
-	al          *tview.Table
-	im          IM
-	showKludges bool
-	CurrentArea *msgapi.AreaPrimitive
+	App            *tview.Application
+	Layout         *tview.Flex
+	Pages          *tview.Pages
+	sb             *StatusBar
+	al             *tview.Table
+	im             IM
+	showKludges    bool
+	showAreaTypes  bool
+	showLinkCounts bool
+	lastReadWarned bool
+	unread         unreadMail
+	pollStop       chan struct{}
+	bells          int
+	CurrentArea    *msgapi.AreaPrimitive
+	tags           map[uint32]bool
+	tagsArea       string
+	history        navHistory
 }
 
 // NewApp return new App
 func NewApp() *App {
-	a := &App{}
+	a := &App{showKludges: config.Config.ShowKludges, showAreaTypes: config.Config.ShowAreaTypes}
 	a.App = tview.NewApplication()
+	a.App.SetAfterDrawFunc(a.ringBells)
+	a.App.SetInputCapture(a.confirmInterrupt)
 	a.sb = NewStatusBar(a)
 	a.Pages = tview.NewPages()
 	a.Pages.AddPage(a.AreaList())
 	a.Pages.AddPage(a.AreaListQuit())
 	a.Pages.AddPage(a.AreaListHelp())
+	a.restoreLastArea()
 	a.sb.Run()
+	a.runMailPoller()
 	a.Layout = tview.NewFlex().
 		SetDirection(tview.FlexRow).
 		AddItem(a.Pages, 0, 1, true).
@@ -34,7 +49,27 @@ func NewApp() *App {
 	return a
 }
 
+// confirmInterrupt asks before Ctrl-C stops the app while a message is
+// still open in the editor; Ctrl-C again quits anyway
+func (a *App) confirmInterrupt(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() != tcell.KeyCtrlC || !config.GetConfirmQuitUnsaved() || a.Pages.HasPage("QuitUnsaved") {
+		return event
+	}
+	// checked while the editor is still open, Run keeps the draft on stop
+	area := a.unsavedMsgArea()
+	if area == "" {
+		return event
+	}
+	a.Pages.AddPage(a.QuitUnsaved(area))
+	return nil
+}
+
 // Run run App
 func (a *App) Run() error {
-	return a.App.SetRoot(a.Layout, true).Run()
+	defer a.sb.Stop()
+	defer a.stopMailPoller()
+	err := a.App.SetRoot(a.Layout, true).Run()
+	a.keepUnsavedMsg()
+	a.saveLastArea()
+	return err
 }
@@ -2,9 +2,13 @@ package ui
 
 import (
 	"fmt"
+	"log"
 	"strconv"
+	"time"
 
+	"github.com/askovpen/gossiped/pkg/areasconfig"
 	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
 	"github.com/askovpen/gossiped/pkg/msgapi"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -30,6 +34,34 @@ func (a *App) AreaListQuit() (string, tview.Primitive, bool, bool) {
 	return "AreaListQuit", modal, false, false
 }
 
+// QuitUnsaved asks before quitting with a message of areaName still open in
+// the editor, which is kept as a draft if drafts are enabled
+func (a *App) QuitUnsaved(areaName string) (string, tview.Primitive, bool, bool) {
+	focus := a.App.GetFocus()
+	fate := "It will be lost, enable drafts to keep it."
+	if config.Config.Drafts.Enabled {
+		fate = "It will be kept as a draft."
+	}
+	modal := NewModalMenu().
+		SetText(fmt.Sprintf("You have an unsaved message in %s - quit anyway?", tview.Escape(areaName))).
+		AddText(fate).
+		AddButtons([]string{
+			"    Quit   ",
+			"   Cancel  ",
+		}).
+		SetDoneFunc(func(buttonIndex int) {
+			if buttonIndex == 0 {
+				// Run keeps the draft once the app has stopped
+				a.App.Stop()
+			} else {
+				a.Pages.HidePage("QuitUnsaved")
+				a.Pages.RemovePage("QuitUnsaved")
+				a.App.SetFocus(focus)
+			}
+		})
+	return "QuitUnsaved", modal, true, true
+}
+
 func initAreaListHeader(a *App) {
 	borderStyle := config.GetElementStyle(config.ColorAreaAreaList, config.ColorElementBorder)
 	headerStyle := config.GetElementStyle(config.ColorAreaAreaList, config.ColorElementHeader)
@@ -57,6 +89,77 @@ func initAreaListHeader(a *App) {
 			SetTextColor(fgHeader).SetBackgroundColor(bgHeader).SetAttributes(attrHeader).
 			SetSelectable(false).
 			SetAlign(tview.AlignRight))
+	a.al.SetCell(
+		0, 4, tview.NewTableCell("  Activity").
+			SetTextColor(fgHeader).SetBackgroundColor(bgHeader).SetAttributes(attrHeader).
+			SetSelectable(false).
+			SetAlign(tview.AlignRight))
+	if a.showLinkCounts {
+		a.al.SetCell(
+			0, 5, tview.NewTableCell(" Links").
+				SetTextColor(fgHeader).SetBackgroundColor(bgHeader).SetAttributes(attrHeader).
+				SetSelectable(false).
+				SetAlign(tview.AlignRight))
+	}
+	if a.showAreaTypes {
+		a.al.SetCell(
+			0, areaTypeColumn(a), tview.NewTableCell(" Type").
+				SetTextColor(fgHeader).SetBackgroundColor(bgHeader).SetAttributes(attrHeader).
+				SetSelectable(false).
+				SetAlign(tview.AlignCenter))
+	}
+}
+
+// areaTypeColumn returns the area list column of the area type, after the
+// links column when that is shown
+func areaTypeColumn(a *App) int {
+	if a.showLinkCounts {
+		return 6
+	}
+	return 5
+}
+
+// loadLinkCounts returns the subscribed links per echoarea id, nil if they
+// could not be loaded
+func loadLinkCounts() map[int64]int {
+	counts, err := database.GetSubscriptionCounts()
+	if err != nil {
+		log.Printf("Error loading subscription counts: %v", err)
+		return nil
+	}
+	return counts
+}
+
+// linkCount returns the links column text of an area, empty for areas which
+// have no links like netmail or file bases
+func linkCount(ar msgapi.AreaPrimitive, counts map[int64]int) string {
+	sqlArea, ok := ar.(*msgapi.SQLArea)
+	if !ok || counts == nil || sqlArea.GetType() != msgapi.EchoAreaTypeEcho {
+		return ""
+	}
+	return strconv.Itoa(counts[sqlArea.GetAreaID()])
+}
+
+// areaActivity describes how recent the newest message of an area is,
+// comparing calendar days in the location of now
+func areaActivity(last, now time.Time) string {
+	if last.IsZero() {
+		return ""
+	}
+	y, m, d := now.Date()
+	if !last.Before(time.Date(y, m, d, 0, 0, 0, 0, now.Location())) {
+		return "new today"
+	}
+	days := int(now.Sub(last).Hours() / 24)
+	switch {
+	case days < 7:
+		return "this week"
+	case days < 60:
+		return fmt.Sprintf("%dd", days)
+	case days < 730:
+		return fmt.Sprintf("%dmo", days/30)
+	}
+	return fmt.Sprintf("%dy", days/365)
 }
 
 func (a *App) RefreshAreaList() {
@@ -112,7 +215,13 @@ func refreshAreaListWithFilter(a *App, currentArea string, searchText string) {
 	styleHighligt := config.GetElementStyle(config.ColorAreaAreaList, config.ColorElementHighlight)
 	fgItem, bgItem, attrItem := styleItem.Decompose()
 	fgHigh, bgHigh, attrHigh := styleHighligt.Decompose()
+	fgType, _, attrType := config.GetElementStyle(config.ColorAreaAreaList, config.ColorElementType).Decompose()
 	var selectIndex = -1
+	now := time.Now()
+	var linkCounts map[int64]int
+	if a.showLinkCounts {
+		linkCounts = loadLinkCounts()
+	}
 	
 	// Get filtered areas based on search text
 	filteredAreas := msgapi.FilterAreas(searchText)
@@ -137,6 +246,27 @@ func refreshAreaListWithFilter(a *App, currentArea string, searchText string) {
 		a.al.SetCell(i+1, 3, tview.NewTableCell(strconv.FormatInt(int64(ar.GetCount()-ar.GetLast()), 10)).
 			SetTextColor(fg).SetBackgroundColor(bg).SetAttributes(attr).
 			SetAlign(tview.AlignRight))
+		activity := ""
+		if sqlArea, ok := ar.(*msgapi.SQLArea); ok {
+			activity = areaActivity(sqlArea.GetLastDate(), now)
+		}
+		fgAct, bgAct, attrAct := fg, bg, attr
+		if activity == "new today" {
+			fgAct, bgAct, attrAct = fgHigh, bgHigh, attrHigh
+		}
+		a.al.SetCell(i+1, 4, tview.NewTableCell(activity).
+			SetTextColor(fgAct).SetBackgroundColor(bgAct).SetAttributes(attrAct).
+			SetAlign(tview.AlignRight))
+		if a.showLinkCounts {
+			a.al.SetCell(i+1, 5, tview.NewTableCell(linkCount(ar, linkCounts)).
+				SetTextColor(fg).SetBackgroundColor(bg).SetAttributes(attr).
+				SetAlign(tview.AlignRight))
+		}
+		if a.showAreaTypes {
+			a.al.SetCell(i+1, areaTypeColumn(a), tview.NewTableCell(ar.GetType().Letter()).
+				SetTextColor(fgType).SetBackgroundColor(bg).SetAttributes(attrType).
+				SetAlign(tview.AlignCenter))
+		}
 		if currentArea != "" && currentArea == ar.GetName() {
 			selectIndex = i + 1
 		}
@@ -171,11 +301,15 @@ func (a *App) AreaList() (string, tview.Primitive, bool, bool) {
 			
 			if row-1 < len(areas) {
 				var area = areas[row-1].AreaPrimitive
-				a.sb.SetStatus(fmt.Sprintf("%s: %d msgs, %d unread",
+				status := fmt.Sprintf("%s: %d msgs, %d unread",
 					area.GetName(),
 					area.GetCount(),
 					area.GetCount()-area.GetLast(),
-				))
+				)
+				if desc := areaDescription(area); desc != "" {
+					status += " - " + tview.Escape(desc)
+				}
+				a.sb.SetStatus(status)
 			}
 		})
 	_, defBg, _ := config.StyleDefault.Decompose()
@@ -190,16 +324,93 @@ func (a *App) AreaList() (string, tview.Primitive, bool, bool) {
 		}
 	})
 	a.al.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch key := event.Key(); key {
-		case tcell.KeyEsc:
+		switch {
+		case keymap.Match(KeyActionQuit, event):
 			searchString.Clear()
 			currentSearchText = ""
 			disableSetSelectedFunc = false // Re-enable when returning to area list
 			refreshAreaList(a, "")
 			a.Pages.ShowPage("AreaListQuit")
-		case tcell.KeyF1:
+		case keymap.Match(KeyActionHelp, event):
 			a.Pages.ShowPage("AreaListHelp")
-		case tcell.KeyRight, tcell.KeyEnter:
+		case keymap.Match(KeyActionSubscriptions, event):
+			row, _ := a.al.GetSelection()
+			areas := getAreasForSelection(currentSearchText)
+			if row > 0 && row-1 < len(areas) {
+				if sqlArea, ok := areas[row-1].AreaPrimitive.(*msgapi.SQLArea); ok && sqlArea.GetType() != msgapi.EchoAreaTypeNetmail {
+					a.Pages.AddPage(a.showSubscriptions(sqlArea, row))
+					a.Pages.ShowPage("SubscriptionsModal")
+				}
+			}
+			return nil
+		case keymap.Match(KeyActionLinkCounts, event):
+			if database.DB == nil {
+				a.sb.SetStatus("Link counts need the jnode-sql database")
+				return nil
+			}
+			var selected string
+			row, _ := a.al.GetSelection()
+			if areas := getAreasForSelection(currentSearchText); row > 0 && row-1 < len(areas) {
+				selected = areas[row-1].AreaPrimitive.GetName()
+			}
+			a.showLinkCounts = !a.showLinkCounts
+			refreshAreaListWithFilter(a, selected, currentSearchText)
+			return nil
+		case keymap.Match(KeyActionAreaTypes, event):
+			var selected string
+			row, _ := a.al.GetSelection()
+			if areas := getAreasForSelection(currentSearchText); row > 0 && row-1 < len(areas) {
+				selected = areas[row-1].AreaPrimitive.GetName()
+			}
+			a.showAreaTypes = !a.showAreaTypes
+			refreshAreaListWithFilter(a, selected, currentSearchText)
+			return nil
+		case keymap.Match(KeyActionSyncAreas, event):
+			a.syncAreas(currentSearchText)
+			return nil
+		case keymap.Match(KeyActionColorPreview, event):
+			a.Pages.AddPage(a.ColorPreview())
+			a.Pages.ShowPage("ColorPreview")
+			return nil
+		case keymap.Match(KeyActionRebuildCounts, event):
+			a.rebuildCounts(currentSearchText)
+			return nil
+		case keymap.Match(KeyActionGlobalSearch, event):
+			if database.DB == nil {
+				a.sb.SetStatus("Search in all areas needs the jnode-sql database")
+				return nil
+			}
+			a.Pages.AddPage(a.showGlobalSearch())
+			a.Pages.ShowPage("GlobalSearchModal")
+			return nil
+		case keymap.Match(KeyActionArchiveSent, event):
+			a.archiveSentNetmail()
+			return nil
+		case keymap.Match(KeyActionCleanQueue, event):
+			a.cleanOrphanedAwaiting()
+			return nil
+		case keymap.Match(KeyActionUnrouted, event):
+			a.showUnrouted()
+			return nil
+		case keymap.Match(KeyActionLastReads, event):
+			a.showLastReads("", func() {
+				a.RefreshAreaList()
+				a.App.SetFocus(a.al)
+			})
+			return nil
+		case keymap.Match(KeyActionBookmarks, event):
+			if !database.IsLastReadEnabled() {
+				a.sb.SetStatus("Bookmarks need the lastread database")
+				return nil
+			}
+			a.Pages.AddPage(a.showBookmarks(func(page string) {
+				if page == "" {
+					a.App.SetFocus(a.al)
+				}
+			}))
+			a.Pages.ShowPage("BookmarksModal")
+			return nil
+		case keymap.Match(KeyActionOpenArea, event):
 			// Disable SetSelectedFunc during our manual selection
 			disableSetSelectedFunc = true
 			
@@ -215,18 +426,7 @@ func (a *App) AreaList() (string, tview.Primitive, bool, bool) {
 			if a.CurrentArea != nil {
 				// Initialize area before first access
 				(*a.CurrentArea).Init()
-				lastMsg := (*a.CurrentArea).GetLast()
-				countMsg := (*a.CurrentArea).GetCount()
-				
-				// Handle empty areas properly - allow access but use special message number
-				var msgNum uint32
-				if countMsg == 0 {
-					msgNum = 1 // ViewMsg will handle this case properly
-				} else if lastMsg == 0 && countMsg > 0 {
-					msgNum = 1
-				} else {
-					msgNum = lastMsg
-				}
+				msgNum := openMsgNum((*a.CurrentArea).GetLast(), (*a.CurrentArea).GetCount())
 				
 				pageName := fmt.Sprintf("ViewMsg-%s-%d", (*a.CurrentArea).GetName(), msgNum)
 				
@@ -241,14 +441,14 @@ func (a *App) AreaList() (string, tview.Primitive, bool, bool) {
 				searchString.Clear()
 				currentSearchText = ""
 			}
-		case tcell.KeyDown, tcell.KeyUp:
+		case event.Key() == tcell.KeyDown, event.Key() == tcell.KeyUp:
 			// Allow navigation within filtered list - don't clear search
 			return event
-		case tcell.KeyBackspace, tcell.KeyBackspace2:
+		case event.Key() == tcell.KeyBackspace, event.Key() == tcell.KeyBackspace2:
 			searchString.RemoveChar()
 			currentSearchText = searchString.GetText()
 			refreshAreaListWithFilter(a, "", currentSearchText)
-		case tcell.KeyRune:
+		case event.Key() == tcell.KeyRune:
 			searchString.AddChar(event.Rune())
 			currentSearchText = searchString.GetText()
 			refreshAreaListWithFilter(a, "", currentSearchText)
@@ -262,6 +462,237 @@ func (a *App) AreaList() (string, tview.Primitive, bool, bool) {
 		AddItem(a.al, 0, 1, true)
 	return "AreaList", layout, true, true
 }
+// syncAreas picks up echoareas added or removed in the database, keeping
+// the selected area
+func (a *App) syncAreas(searchText string) {
+	var selected string
+	row, _ := a.al.GetSelection()
+	if areas := getAreasForSelection(searchText); row > 0 && row-1 < len(areas) {
+		selected = areas[row-1].AreaPrimitive.GetName()
+	}
+	var current string
+	if a.CurrentArea != nil {
+		current = (*a.CurrentArea).GetName()
+	}
+	added, removed, err := areasconfig.SyncAreas()
+	if err != nil {
+		a.sb.SetStatus(fmt.Sprintf("Sync failed: %v", err))
+		return
+	}
+	refreshAreaListWithFilter(a, selected, searchText)
+	// Areas slice was rebuilt and sorted, re-point current area
+	a.CurrentArea = nil
+	for i := range msgapi.Areas {
+		if msgapi.Areas[i].GetName() == current {
+			a.CurrentArea = &msgapi.Areas[i]
+			break
+		}
+	}
+	a.sb.SetStatus(fmt.Sprintf("Areas synced: %d added, %d removed", added, removed))
+}
+
+// rebuildCounts reloads the message counts of all areas from the database,
+// logging the ones which drifted, keeping the selected area
+func (a *App) rebuildCounts(searchText string) {
+	if database.DB == nil {
+		a.sb.SetStatus("Rebuilding counts needs the jnode-sql database")
+		return
+	}
+	var selected string
+	row, _ := a.al.GetSelection()
+	if areas := getAreasForSelection(searchText); row > 0 && row-1 < len(areas) {
+		selected = areas[row-1].AreaPrimitive.GetName()
+	}
+	stale, err := msgapi.VerifyMessageCounts()
+	if err != nil {
+		a.sb.SetStatus(fmt.Sprintf("Rebuilding counts failed: %v", err))
+		return
+	}
+	if err := msgapi.RefreshMessageCounts(); err != nil {
+		a.sb.SetStatus(fmt.Sprintf("Rebuilding counts failed: %v", err))
+		return
+	}
+	refreshAreaListWithFilter(a, selected, searchText)
+	a.sb.SetStatus(fmt.Sprintf("Message counts rebuilt, %d were stale", stale))
+}
+
+// showSubscriptions manages the links of the area shown in row of the area
+// list, updating its links column when the window is closed
+func (a *App) showSubscriptions(area *msgapi.SQLArea, row int) (string, tview.Primitive, bool, bool) {
+	modal := NewModalSubscriptions(area.GetName(), area.GetAreaID()).
+		SetDoneFunc(func() {
+			a.Pages.HidePage("SubscriptionsModal")
+			a.Pages.RemovePage("SubscriptionsModal")
+			if a.showLinkCounts {
+				if cell := a.al.GetCell(row, 5); cell != nil {
+					cell.SetText(linkCount(area, loadLinkCounts()))
+				}
+			}
+			a.App.SetFocus(a.al)
+		})
+	return "SubscriptionsModal", modal, true, true
+}
+
+// showUnrouted lists the unsent netmail no route is found for, Enter opens
+// one to fix its address
+func (a *App) showUnrouted() {
+	var area *msgapi.AreaPrimitive
+	for i := range msgapi.Areas {
+		if sqlArea, ok := msgapi.Areas[i].(*msgapi.SQLArea); ok && sqlArea.GetType() == msgapi.EchoAreaTypeNetmail {
+			area = &msgapi.Areas[i]
+			break
+		}
+	}
+	if area == nil {
+		a.sb.SetStatus("Unrouted netmail needs the jnode-sql database")
+		return
+	}
+	modal := NewModalUnrouted((*area).(*msgapi.SQLArea))
+	if modal.Len() == 0 {
+		a.sb.SetStatus("No unrouted netmail")
+		return
+	}
+	modal.SetStatusFunc(a.sb.SetStatus).
+		SetDoneFunc(func(msgNum uint32) {
+			a.Pages.HidePage("UnroutedModal")
+			a.Pages.RemovePage("UnroutedModal")
+			if msgNum == 0 {
+				a.App.SetFocus(a.al)
+				return
+			}
+			(*area).Init()
+			a.clearTags()
+			a.CurrentArea = area
+			a.showViewMsg(area, msgNum)
+		})
+	a.Pages.AddPage("UnroutedModal", modal, true, true)
+	a.Pages.ShowPage("UnroutedModal")
+}
+
+// archiveSentNetmail asks before archiving and deleting sent netmail older
+// than netmail.archive.days, opt-in with netmail.archive.enabled
+func (a *App) archiveSentNetmail() {
+	archive := config.Config.Netmail.Archive
+	if !archive.Enabled {
+		a.sb.SetStatus("Archiving sent netmail is disabled, see netmail.archive in the config")
+		return
+	}
+	var netmail *msgapi.SQLArea
+	for _, ar := range msgapi.Areas {
+		if sqlArea, ok := ar.(*msgapi.SQLArea); ok && sqlArea.GetType() == msgapi.EchoAreaTypeNetmail {
+			netmail = sqlArea
+			break
+		}
+	}
+	if netmail == nil {
+		a.sb.SetStatus("Archiving sent netmail needs the jnode-sql database")
+		return
+	}
+	n, err := msgapi.CountArchivableNetmail(archive.Days)
+	if err != nil {
+		a.sb.SetStatus(err.Error())
+		return
+	}
+	if n == 0 {
+		a.sb.SetStatus(fmt.Sprintf("No sent netmail older than %d days", archive.Days))
+		return
+	}
+	modal := NewModalMenu().
+		SetText(fmt.Sprintf("Archive %d sent netmails older than %d days?", n, archive.Days)).
+		AddText("Appended to " + tview.Escape(archive.Path) + ", then deleted").
+		AddButtons([]string{"Yes", "No"}).
+		SetDoneFunc(func(buttonIndex int) {
+			a.Pages.HidePage("ArchiveNetmailModal")
+			a.Pages.RemovePage("ArchiveNetmailModal")
+			if buttonIndex == 0 {
+				archived, err := netmail.ArchiveSentNetmail(archive.Days, archive.Path)
+				if err != nil {
+					a.sb.SetStatus(err.Error())
+				} else {
+					a.sb.SetStatus(fmt.Sprintf("Archived %d sent netmails to %s", archived, archive.Path))
+				}
+				a.RefreshAreaList()
+			}
+			a.App.SetFocus(a.al)
+		})
+	a.Pages.AddPage("ArchiveNetmailModal", modal, true, true)
+	a.Pages.ShowPage("ArchiveNetmailModal")
+}
+
+// cleanOrphanedAwaiting deletes, after asking, the rows of the outbound
+// queue of jnode pointing to deleted messages or removed links
+func (a *App) cleanOrphanedAwaiting() {
+	if database.DB == nil {
+		a.sb.SetStatus("Cleaning the outbound queue needs the jnode-sql database")
+		return
+	}
+	orphaned, err := database.CountOrphanedAwaiting()
+	if err != nil {
+		a.sb.SetStatus(err.Error())
+		return
+	}
+	if orphaned.Total() == 0 {
+		a.sb.SetStatus("No orphaned entries in the outbound queue")
+		return
+	}
+	modal := NewModalMenu().
+		SetText(fmt.Sprintf("Delete %d orphaned outbound queue entries?", orphaned.Total())).
+		AddText(fmt.Sprintf("Echomail %d, netmail %d, files %d, their message or link is gone",
+			orphaned.Echomail, orphaned.Netmail, orphaned.Filemail)).
+		AddButtons([]string{"Yes", "No"}).
+		SetDoneFunc(func(buttonIndex int) {
+			a.Pages.HidePage("CleanQueueModal")
+			a.Pages.RemovePage("CleanQueueModal")
+			if buttonIndex == 0 {
+				deleted, err := database.DeleteOrphanedAwaiting()
+				if err != nil {
+					a.sb.SetStatus(err.Error())
+				} else {
+					a.sb.SetStatus(fmt.Sprintf("Deleted %d orphaned outbound queue entries", deleted.Total()))
+				}
+			}
+			a.App.SetFocus(a.al)
+		})
+	a.Pages.AddPage("CleanQueueModal", modal, true, true)
+	a.Pages.ShowPage("CleanQueueModal")
+}
+
+// showGlobalSearch searches echomail of all areas, opening the selected result
+func (a *App) showGlobalSearch() (string, tview.Primitive, bool, bool) {
+	modal := NewModalSearch().
+		SetDoneFunc(func(hit *database.SearchHit) {
+			a.Pages.HidePage("GlobalSearchModal")
+			a.Pages.RemovePage("GlobalSearchModal")
+			if hit == nil {
+				a.App.SetFocus(a.al)
+				return
+			}
+			a.openSearchHit(hit)
+		})
+	return "GlobalSearchModal", modal, true, true
+}
+
+// openSearchHit opens the area of a search result at the found message
+func (a *App) openSearchHit(hit *database.SearchHit) {
+	for i := range msgapi.Areas {
+		if msgapi.Areas[i].GetName() != hit.AreaName {
+			continue
+		}
+		a.CurrentArea = &msgapi.Areas[i]
+		(*a.CurrentArea).Init()
+		msgNum := uint32(hit.Position)
+		pageName := fmt.Sprintf("ViewMsg-%s-%d", hit.AreaName, msgNum)
+		if a.Pages.HasPage(pageName) {
+			a.Pages.SwitchToPage(pageName)
+		} else {
+			a.showViewMsg(a.CurrentArea, msgNum)
+		}
+		return
+	}
+	a.sb.SetStatus(fmt.Sprintf("Area %s is not loaded", hit.AreaName))
+	a.App.SetFocus(a.al)
+}
+
 func (a *App) onSelected(row int, column int) {
 	if row < 1 {
 		row = 1
@@ -270,8 +701,65 @@ func (a *App) onSelected(row int, column int) {
 	if a.Pages.HasPage(fmt.Sprintf("ViewMsg-%s-%d", (*a.CurrentArea).GetName(), (*a.CurrentArea).GetLast())) {
 		a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*a.CurrentArea).GetName(), (*a.CurrentArea).GetLast()))
 	} else {
-		a.Pages.AddPage(a.ViewMsg(a.CurrentArea, (*a.CurrentArea).GetLast()))
-		a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*a.CurrentArea).GetName(), (*a.CurrentArea).GetLast()))
+		a.showViewMsg(a.CurrentArea, (*a.CurrentArea).GetLast())
 	}
 }
 
+// restoreLastArea selects the area read when gossiped last quit, opening
+// its last read message for start_area resume
+func (a *App) restoreLastArea() {
+	mode := config.GetStartArea()
+	if mode == "top" || !database.IsLastReadEnabled() {
+		return
+	}
+	name, err := database.GetLastArea(config.Config.Username)
+	if err != nil {
+		log.Printf("%v", err)
+		return
+	}
+	for i := range msgapi.Areas {
+		if msgapi.Areas[i].GetName() != name {
+			continue
+		}
+		refreshAreaList(a, name)
+		if mode == "resume" {
+			a.CurrentArea = &msgapi.Areas[i]
+			(*a.CurrentArea).Init()
+			a.showViewMsg(a.CurrentArea, openMsgNum((*a.CurrentArea).GetLast(), (*a.CurrentArea).GetCount()))
+		}
+		return
+	}
+}
+
+// saveLastArea remembers the area being read for the next start
+func (a *App) saveLastArea() {
+	if a.CurrentArea == nil || config.GetStartArea() == "top" || !database.IsLastReadEnabled() {
+		return
+	}
+	if err := database.SetLastArea(config.Config.Username, (*a.CurrentArea).GetName()); err != nil {
+		log.Printf("%v", err)
+	}
+}
+
+// areaDescription returns the description of jnode-sql areas, "" for
+// file bases which have none
+func areaDescription(area msgapi.AreaPrimitive) string {
+	if sqlArea, ok := area.(*msgapi.SQLArea); ok {
+		return sqlArea.GetDescription()
+	}
+	return ""
+}
+
+// openMsgNum returns the message to open an area at: the last read one,
+// the first one if nothing was read yet, or 0 for the empty area view
+func openMsgNum(lastMsg, countMsg uint32) uint32 {
+	switch {
+	case countMsg == 0:
+		return 0
+	case lastMsg == 0:
+		return 1
+	case lastMsg > countMsg:
+		return countMsg
+	}
+	return lastMsg
+}
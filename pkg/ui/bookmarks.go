@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/askovpen/gossiped/pkg/msgapi"
+	"github.com/rivo/tview"
+)
+
+// bookmarkMatches returns true if bm points at the message with database id
+// msgID at position msgNum; file bases have no ids and match by position
+func bookmarkMatches(bm *database.Bookmark, msgID int64, msgNum uint32) bool {
+	if bm.MsgID != 0 || msgID != 0 {
+		return bm.MsgID == msgID
+	}
+	return bm.MsgNum == msgNum
+}
+
+// areaBookmarks returns the bookmarks of the user in area
+func areaBookmarks(area *msgapi.AreaPrimitive) []database.Bookmark {
+	if !database.IsLastReadEnabled() {
+		return nil
+	}
+	bookmarks, err := database.ListBookmarks(config.Config.Username)
+	if err != nil {
+		return nil
+	}
+	var res []database.Bookmark
+	for _, bm := range bookmarks {
+		if bm.AreaName == (*area).GetName() {
+			res = append(res, bm)
+		}
+	}
+	return res
+}
+
+// bookmarkedMsgs returns the positions of the bookmarked messages of area
+func bookmarkedMsgs(area *msgapi.AreaPrimitive) map[uint32]bool {
+	bookmarks := areaBookmarks(area)
+	if len(bookmarks) == 0 {
+		return nil
+	}
+	marked := make(map[uint32]bool)
+	for i, mi := range *(*area).GetMessages() {
+		for j := range bookmarks {
+			if bookmarkMatches(&bookmarks[j], mi.ID, uint32(i+1)) {
+				marked[uint32(i+1)] = true
+			}
+		}
+	}
+	return marked
+}
+
+// toggleBookmark bookmarks msg of area with its subject as the note, or
+// removes the bookmark if it was already set
+func (a *App) toggleBookmark(area *msgapi.AreaPrimitive, msg *msgapi.Message) {
+	if !database.IsLastReadEnabled() {
+		a.sb.SetStatus("Bookmarks need the lastread database")
+		return
+	}
+	for _, bm := range areaBookmarks(area) {
+		if bookmarkMatches(&bm, msg.ID, msg.MsgNum) {
+			if err := database.DeleteBookmark(config.Config.Username, bm.ID); err != nil {
+				a.sb.SetStatus(err.Error())
+			} else {
+				a.sb.SetStatus(fmt.Sprintf("Bookmark on message %d removed", msg.MsgNum))
+			}
+			return
+		}
+	}
+	if _, err := database.AddBookmark(config.Config.Username, (*area).GetName(), msg.ID, msg.MsgNum, msg.Subject); err != nil {
+		a.sb.SetStatus(err.Error())
+		return
+	}
+	a.sb.SetStatus(fmt.Sprintf("Message %d bookmarked", msg.MsgNum))
+}
+
+// showBookmarks lists the bookmarks of the user, leave is called when one
+// was opened with the name of its page, or with "" on cancel
+func (a *App) showBookmarks(leave func(page string)) (string, tview.Primitive, bool, bool) {
+	modal := NewModalBookmarks().
+		SetDoneFunc(func(bm *database.Bookmark) {
+			a.Pages.HidePage("BookmarksModal")
+			a.Pages.RemovePage("BookmarksModal")
+			page := ""
+			if bm != nil {
+				page = a.openBookmark(bm)
+			}
+			leave(page)
+		})
+	return "BookmarksModal", modal, true, true
+}
+
+// openBookmark opens the area of a bookmark at the bookmarked message,
+// returning the name of the shown page or "" if it could not be opened
+func (a *App) openBookmark(bm *database.Bookmark) string {
+	var area *msgapi.AreaPrimitive
+	for i := range msgapi.Areas {
+		if msgapi.Areas[i].GetName() == bm.AreaName {
+			area = &msgapi.Areas[i]
+			break
+		}
+	}
+	if area == nil {
+		a.sb.SetStatus(fmt.Sprintf("Area %s is not loaded", bm.AreaName))
+		return ""
+	}
+	(*area).Init()
+	msgNum := bm.MsgNum
+	if bm.MsgID != 0 {
+		// positions of SQL messages shift when others are deleted
+		msg, err := (*area).GetMsgByID(bm.MsgID)
+		if errors.Is(err, msgapi.ErrMsgNotFound) {
+			a.sb.SetStatus("Bookmarked message no longer exists")
+			return ""
+		} else if err != nil {
+			a.sb.SetStatus(err.Error())
+			return ""
+		} else if msg != nil {
+			msgNum = msg.MsgNum
+		}
+	}
+	a.clearTags()
+	a.CurrentArea = area
+	pageName := fmt.Sprintf("ViewMsg-%s-%d", bm.AreaName, openMsgNum(msgNum, (*area).GetCount()))
+	if a.Pages.HasPage(pageName) {
+		a.Pages.SwitchToPage(pageName)
+		return pageName
+	}
+	return a.showViewMsg(area, msgNum)
+}
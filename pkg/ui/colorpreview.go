@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ColorPreview shows every element of the loaded colorscheme in its style
+func (a *App) ColorPreview() (string, tview.Primitive, bool, bool) {
+	var sb strings.Builder
+	for _, section := range config.GetColorSections() {
+		fmt.Fprintf(&sb, "\n%s\n", section)
+		for _, element := range config.GetColorElements(section) {
+			style := config.GetElementStyle(section, element)
+			fmt.Fprintf(&sb, "  %-16s %s[-:-:-]\n", element, config.FormatTextWithStyle(" Sample text ", style))
+		}
+	}
+	modal := NewModalHelp().
+		SetTitle("Colorscheme").
+		SetColoredText(sb.String()).
+		SetDoneFunc(func() {
+			a.Pages.HidePage("ColorPreview")
+			a.Pages.RemovePage("ColorPreview")
+			a.App.SetFocus(a.al)
+		})
+	return "ColorPreview", modal, true, true
+}
+
+// PrintColorPreview writes every element of the loaded colorscheme to w,
+// styled with ANSI escapes, for --preview-colors
+func PrintColorPreview(w io.Writer) {
+	for _, section := range config.GetColorSections() {
+		fmt.Fprintf(w, "%s\n", section)
+		for _, element := range config.GetColorElements(section) {
+			style := config.GetElementStyle(section, element)
+			fmt.Fprintf(w, "  %-16s %s Sample text \x1b[0m\n", element, ansiStyle(style))
+		}
+	}
+}
+
+// ansiStyle returns the ANSI SGR escape selecting style, colors in 24 bit
+func ansiStyle(style tcell.Style) string {
+	fg, bg, attrs := style.Decompose()
+	params := []string{"0"}
+	for _, a := range []struct {
+		mask tcell.AttrMask
+		sgr  string
+	}{
+		{tcell.AttrBold, "1"},
+		{tcell.AttrDim, "2"},
+		{tcell.AttrItalic, "3"},
+		{tcell.AttrUnderline, "4"},
+		{tcell.AttrBlink, "5"},
+		{tcell.AttrReverse, "7"},
+		{tcell.AttrStrikeThrough, "9"},
+	} {
+		if attrs&a.mask != 0 {
+			params = append(params, a.sgr)
+		}
+	}
+	if fg != tcell.ColorDefault && fg.Valid() {
+		r, g, b := fg.RGB()
+		params = append(params, "38;2;"+strconv.Itoa(int(r))+";"+strconv.Itoa(int(g))+";"+strconv.Itoa(int(b)))
+	}
+	if bg != tcell.ColorDefault && bg.Valid() {
+		r, g, b := bg.RGB()
+		params = append(params, "48;2;"+strconv.Itoa(int(r))+";"+strconv.Itoa(int(g))+";"+strconv.Itoa(int(b)))
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
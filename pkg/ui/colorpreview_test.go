@@ -0,0 +1,22 @@
+package ui
+
+import (
+	"testing"
+
+	. "github.com/franela/goblin"
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestAnsiStyle(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check ansiStyle()", func() {
+		g.It("check default style", func() {
+			g.Assert(ansiStyle(tcell.StyleDefault)).Equal("\x1b[0m")
+		})
+		g.It("check colors and attributes", func() {
+			style := tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorNavy).Bold(true)
+			g.Assert(ansiStyle(style)).Equal("\x1b[0;1;38;2;255;255;0;48;2;0;0;128m")
+			g.Assert(ansiStyle(tcell.StyleDefault.Reverse(true).Underline(true))).Equal("\x1b[0;4;7m")
+		})
+	})
+}
@@ -5,8 +5,10 @@ import (
 	"github.com/askovpen/gossiped/pkg/config"
 	"github.com/askovpen/gossiped/pkg/msgapi"
 	"github.com/askovpen/gossiped/pkg/nodelist"
+	"github.com/askovpen/gossiped/pkg/types"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"strings"
 )
 
 type coords struct {
@@ -25,6 +27,8 @@ type EditHeader struct {
 	done      func([5][]rune)
 	msg       *msgapi.Message
 	app       *App
+	// originSet keeps the origin address from following the destination
+	originSet bool
 }
 
 // NewEditHeader create new EditHeader
@@ -45,10 +49,13 @@ func NewEditHeader(a *App, msg *msgapi.Message) *EditHeader {
 			[]rune(msg.ToAddr.String()),
 			[]rune(msg.Subject),
 		},
-		sPosition: [5]int{stringWidth(msg.From), stringWidth(msg.FromAddr.String()), stringWidth(msg.To), stringWidth(msg.ToAddr.String()), stringWidth(msg.Subject)},
-		sIndex:    0,
-		msg:       msg,
-		app:       a,
+		sIndex: 0,
+		msg:    msg,
+		app:    a,
+	}
+	// the caret is a rune index, starting at the end of each field
+	for i := range eh.sInputs {
+		eh.sPosition[i] = len(eh.sInputs[i])
 	}
 	return eh
 }
@@ -68,55 +75,122 @@ func (e *EditHeader) Draw(screen tcell.Screen) {
 	tview.Print(screen, config.FormatTextWithStyle("Msg  :", headerStyle), x+1, y, 6, 0, boxBg)
 	tview.Print(screen, config.FormatTextWithStyle("From :", headerStyle), x+1, y+1, 6, 0, boxBg)
 	tview.Print(screen, config.FormatTextWithStyle("To   :", headerStyle), x+1, y+2, 6, 0, boxBg)
-	tview.Print(screen, config.FormatTextWithStyle("Subj :", headerStyle), x+1, y+3, 6, 0, boxBg)
+	tview.Print(screen, config.FormatTextWithStyle(subjectLabel(e.msg), headerStyle), x+1, y+3, 6, 0, boxBg)
 
 	if e.HasFocus() {
 		for i := e.sCoords[e.sIndex].f; i < e.sCoords[e.sIndex].t; i++ {
 			screen.SetContent(x+i, y+e.sCoords[e.sIndex].y, ' ', nil, selectionStyle)
 		}
 	}
+	cursorX := 0
 	for i := 0; i < 5; i++ {
-		tview.Print(screen, config.FormatTextWithStyle(string(e.sInputs[i]), itemStyle), x+e.sCoords[i].f, y+e.sCoords[i].y, len(e.sInputs[i]), 0, boxFg)
+		width := e.sCoords[i].t - e.sCoords[i].f
+		text, caret := fieldView(e.sInputs[i], e.sPosition[i], width)
+		tview.Print(screen, config.FormatTextWithStyle(text, itemStyle), x+e.sCoords[i].f, y+e.sCoords[i].y, width, 0, boxFg)
+		if i == e.sIndex {
+			cursorX = caret
+		}
 	}
 	if e.HasFocus() {
-		screen.ShowCursor(x+e.sCoords[e.sIndex].f+len(e.sInputs[e.sIndex][:e.sPosition[e.sIndex]]), y+e.sCoords[e.sIndex].y)
+		screen.ShowCursor(x+e.sCoords[e.sIndex].f+cursorX, y+e.sCoords[e.sIndex].y)
+	}
+}
+
+// fieldView returns the part of input which fits in width cells and keeps
+// the caret at rune position pos in view, scrolling the field to the left
+// as needed, and the cell offset of the caret in it. A wide character which
+// would straddle the right edge is left out instead of being cut.
+func fieldView(input []rune, pos, width int) (string, int) {
+	if width <= 0 {
+		return "", 0
+	}
+	pos = max(0, min(pos, len(input)))
+	start := 0
+	// the caret needs a cell of its own after the text before it
+	for start < pos && stringWidth(string(input[start:pos])) >= width {
+		start++
+	}
+	end := pos
+	for end < len(input) && stringWidth(string(input[start:end+1])) <= width {
+		end++
 	}
+	return string(input[start:end]), stringWidth(string(input[start:pos]))
 }
 
 // InputHandler event handler
 func (e *EditHeader) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
 	return e.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		prevIndex := e.sIndex
 		add := func(r rune) {
 			e.sInputs[e.sIndex] = append(e.sInputs[e.sIndex], ' ')
 			copy(e.sInputs[e.sIndex][e.sPosition[e.sIndex]+1:], e.sInputs[e.sIndex][e.sPosition[e.sIndex]:])
 			e.sInputs[e.sIndex][e.sPosition[e.sIndex]] = r
 			e.sPosition[e.sIndex]++
 		}
-		switch key := event.Key(); key {
-		case tcell.KeyTab:
-			if e.sIndex == 2 || e.sIndex == 3 {
-				e.app.Pages.AddPage(e.showNodeList())
-				e.app.Pages.ShowPage("NodeListModal")
-			} else {
-				e.sIndex++
+		switch key := event.Key(); {
+		case e.nodelistKey(event):
+			e.app.Pages.AddPage(e.showNodeList())
+			e.app.Pages.ShowPage("NodeListModal")
+		case keymap.Match(KeyActionAttach, event):
+			e.toggleAttach()
+		case keymap.Match(KeyActionReceipt, event):
+			e.toggleReceipt()
+		case keymap.Match(KeyActionMessageInfo, event):
+			e.app.Pages.AddPage(e.app.MessageInfo(e.msg))
+		case keymap.Match(KeyActionNextAka, event):
+			e.app.nextOrigin()
+		case keymap.Match(KeyActionCancel, event):
+			// Cancel message creation - keep a draft, remove pages and return to ViewMsg
+			if config.Config.Drafts.Enabled {
+				e.app.saveDraft()
+				e.app.sb.SetStatus("Draft saved")
 			}
+			e.app.stopDraftAutosave()
+			insertPageName := fmt.Sprintf("InsertMsg-%s", (*e.app.im.curArea).GetName())
+			viewPageName := fmt.Sprintf("ViewMsg-%s-%d", (*e.app.im.curArea).GetName(), (*e.app.im.curArea).GetLast())
+			e.app.Pages.RemovePage(insertPageName)
+			e.app.Pages.SwitchToPage(viewPageName)
+			e.app.App.SetFocus(e.app.Pages)
+		case key == tcell.KeyTab:
+			e.sIndex++
 			if e.sIndex == 5 {
 				e.sIndex = 0
 			} else if (*e.msg.AreaObject).GetType() != msgapi.EchoAreaTypeNetmail && e.sIndex == 3 {
 				e.sIndex = 4
 			}
-		case tcell.KeyRight:
+		case key == tcell.KeyRight:
 			if e.sPosition[e.sIndex] < len(e.sInputs[e.sIndex]) {
 				e.sPosition[e.sIndex]++
 			}
-		case tcell.KeyLeft:
+		case key == tcell.KeyLeft:
 			if e.sPosition[e.sIndex] > 0 {
 				e.sPosition[e.sIndex]--
 			}
-		case tcell.KeyEnter:
+		case key == tcell.KeyEnter:
 			if e.sIndex == 4 {
 				if e.done != nil {
+					if len(strings.TrimSpace(string(e.sInputs[0]))) == 0 {
+						e.app.sb.SetStatus("From is empty")
+						e.sIndex = 0
+						return
+					}
+					if len(strings.TrimSpace(string(e.sInputs[2]))) == 0 {
+						e.app.sb.SetStatus("To is empty")
+						e.sIndex = 2
+						return
+					}
 					if len(e.sInputs[0]) > 0 && len(e.sInputs[1]) > 0 && len(e.sInputs[2]) > 0 {
+						if (*e.msg.AreaObject).GetType() == msgapi.EchoAreaTypeNetmail && types.AddrFromString(string(e.sInputs[3])) == nil {
+							e.app.sb.SetStatus("Invalid destination address")
+							e.sIndex = 3
+							return
+						}
+						if e.msg.HasAttach() {
+							if _, err := msgapi.ResolveAttach(string(e.sInputs[4])); err != nil {
+								e.app.sb.SetStatus(err.Error())
+								return
+							}
+						}
 						e.done(e.sInputs)
 					}
 				}
@@ -126,8 +200,9 @@ func (e *EditHeader) InputHandler() func(event *tcell.EventKey, setFocus func(p
 					e.sIndex = 4
 				}
 			}
-		case tcell.KeyBackspace, tcell.KeyBackspace2:
+		case key == tcell.KeyBackspace, key == tcell.KeyBackspace2:
 			if e.sPosition[e.sIndex] > 0 {
+				e.originSet = e.originSet || e.sIndex == 1
 				if e.sPosition[e.sIndex] < len(e.sInputs[e.sIndex]) {
 					e.sInputs[e.sIndex] = append(e.sInputs[e.sIndex][:(e.sPosition[e.sIndex]-1)], e.sInputs[e.sIndex][e.sPosition[e.sIndex]:]...)
 				} else {
@@ -135,38 +210,140 @@ func (e *EditHeader) InputHandler() func(event *tcell.EventKey, setFocus func(p
 				}
 				e.sPosition[e.sIndex]--
 			}
-		case tcell.KeyEscape:
-			// Cancel message creation - remove pages and return to ViewMsg
-			insertPageName := fmt.Sprintf("InsertMsg-%s", (*e.app.im.curArea).GetName())
-			viewPageName := fmt.Sprintf("ViewMsg-%s-%d", (*e.app.im.curArea).GetName(), (*e.app.im.curArea).GetLast())
-			e.app.Pages.RemovePage(insertPageName)
-			e.app.Pages.SwitchToPage(viewPageName)
-			e.app.App.SetFocus(e.app.Pages)
-		case tcell.KeyRune:
+		case key == tcell.KeyRune:
+			e.originSet = e.originSet || e.sIndex == 1
 			add(event.Rune())
 		}
+		if prevIndex == 3 && e.sIndex != 3 {
+			e.defaultOrigin()
+		}
 	})
 }
 
+// setOrigin puts addr in the origin address field
+func (e *EditHeader) setOrigin(addr *types.FidoAddr) {
+	e.sInputs[1] = []rune(addr.String())
+	e.sPosition[1] = len(e.sInputs[1])
+}
+
+// defaultOrigin picks the origin address for the destination address,
+// unless it was chosen by hand
+func (e *EditHeader) defaultOrigin() {
+	to := types.AddrFromString(string(e.sInputs[3]))
+	if e.originSet || to == nil {
+		return
+	}
+	if addr := config.GetOriginAddr(to); string(e.sInputs[1]) != addr.String() {
+		e.setOrigin(addr)
+		e.app.setOriginAddr(addr)
+	}
+}
+
+// SetInputs replaces the header fields, e.g. from a restored draft
+func (e *EditHeader) SetInputs(from, fromAddr, to, toAddr, subject string) *EditHeader {
+	for i, v := range [5]string{from, fromAddr, to, toAddr, subject} {
+		e.sInputs[i] = []rune(v)
+		e.sPosition[i] = len(e.sInputs[i])
+	}
+	e.originSet = true
+	return e
+}
+
 // SetDoneFunc callback
 func (e *EditHeader) SetDoneFunc(handler func([5][]rune)) *EditHeader {
 	e.done = handler
 	return e
 }
 
+// nodelistKey returns true if event opens the node list on the To fields:
+// any nodelist key in netmail; in echomail, where To is mostly "All", Tab
+// moves on to the next field unless edit.echo_nodelist is set
+func (e *EditHeader) nodelistKey(event *tcell.EventKey) bool {
+	if (e.sIndex != 2 && e.sIndex != 3) || !keymap.Match(KeyActionNodelist, event) {
+		return false
+	}
+	return (*e.msg.AreaObject).GetType() == msgapi.EchoAreaTypeNetmail ||
+		config.Config.Edit.EchoNodelist || event.Key() != tcell.KeyTab
+}
+
 func (e *EditHeader) showNodeList() (string, tview.Primitive, bool, bool) {
 	modal := NewModalNodeList().
-		SetDoneFunc(func(buttonIndex int) {
-			if (buttonIndex > 0) && (len(nodelist.Nodelist) > 0) {
-				e.sInputs[2] = []rune(nodelist.Nodelist[buttonIndex-1].Sysop)
+		SetText(" Nodelist ").
+		SetFilter(string(e.sInputs[e.sIndex])).
+		SetDoneFunc(func(node *nodelist.Node) {
+			if node != nil {
+				e.sInputs[2] = []rune(node.Sysop)
 				if (*e.msg.AreaObject).GetType() == msgapi.EchoAreaTypeNetmail {
-					e.sInputs[3] = []rune(nodelist.Nodelist[buttonIndex-1].Address.String())
+					e.sInputs[3] = []rune(node.Address.String())
 				}
 				e.sIndex = 4
+				e.app.sb.SetStatus(fmt.Sprintf("%s, %s (%s)",
+					strings.ReplaceAll(node.Sysop, "_", " "),
+					strings.ReplaceAll(node.City, "_", " "),
+					node.Address.String()))
 			}
 			e.app.Pages.HidePage("NodeListModal")
 			e.app.Pages.RemovePage("NodeListModal")
 			e.app.App.SetFocus(e.app.Pages)
+		}).
+		SetCopyFunc(func(node *nodelist.Node) {
+			e.app.copyToClipboard(nodeText(node), "Copied to clipboard: "+node.Address.String())
 		})
 	return "NodeListModal", modal, true, true
 }
+
+// toggleAttach turns a netmail into a file attach, picking the file named by
+// the subject, or turns a file attach back into a plain netmail
+func (e *EditHeader) toggleAttach() {
+	if _, ok := (*e.msg.AreaObject).(*msgapi.SQLArea); !ok || (*e.msg.AreaObject).GetType() != msgapi.EchoAreaTypeNetmail {
+		e.app.sb.SetStatus("File attaches are only supported in jnode SQL netmail")
+		return
+	}
+	if e.msg.HasAttach() {
+		e.msg.SetAttach(false)
+		e.app.sb.SetStatus("File attach removed")
+		return
+	}
+	e.app.Pages.AddPage(e.showAttach())
+	e.app.Pages.ShowPage("AttachModal")
+}
+
+// toggleReceipt requests a return receipt for the netmail, or no longer
+func (e *EditHeader) toggleReceipt() {
+	if _, ok := (*e.msg.AreaObject).(*msgapi.SQLArea); !ok || (*e.msg.AreaObject).GetType() != msgapi.EchoAreaTypeNetmail {
+		e.app.sb.SetStatus("Return receipts can only be requested in jnode SQL netmail")
+		return
+	}
+	on := !e.msg.WantsReceipt()
+	e.msg.SetReceiptRequest(on)
+	if on {
+		e.app.sb.SetStatus("Return receipt requested")
+	} else {
+		e.app.sb.SetStatus("Return receipt request removed")
+	}
+}
+
+func (e *EditHeader) showAttach() (string, tview.Primitive, bool, bool) {
+	modal := NewModalAttach(string(e.sInputs[4])).
+		SetDoneFunc(func(path string) {
+			if path != "" {
+				e.msg.SetAttach(true)
+				e.sInputs[4] = []rune(path)
+				e.sPosition[4] = len(e.sInputs[4])
+				e.sIndex = 4
+				e.app.sb.SetStatus("File attach: " + path)
+			}
+			e.app.Pages.HidePage("AttachModal")
+			e.app.Pages.RemovePage("AttachModal")
+			e.app.App.SetFocus(e.app.Pages)
+		})
+	return "AttachModal", modal, true, true
+}
+
+// subjectLabel labels the subject line, which names the attached file of a file attach
+func subjectLabel(msg *msgapi.Message) string {
+	if msg != nil && msg.HasAttach() {
+		return "File :"
+	}
+	return "Subj :"
+}
@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/msgapi"
+	"github.com/askovpen/gossiped/pkg/nodelist"
+	"github.com/askovpen/gossiped/pkg/types"
+	. "github.com/franela/goblin"
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestFieldView(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check fieldView()", func() {
+		g.It("check short input", func() {
+			text, caret := fieldView([]rune("Sysop"), 5, 10)
+			g.Assert(text).Equal("Sysop")
+			g.Assert(caret).Equal(5)
+			text, caret = fieldView([]rune("Sysop"), 2, 10)
+			g.Assert(text).Equal("Sysop")
+			g.Assert(caret).Equal(2)
+		})
+		g.It("check caret is counted in cells", func() {
+			text, caret := fieldView([]rune("漢字ab"), 2, 10)
+			g.Assert(text).Equal("漢字ab")
+			g.Assert(caret).Equal(4)
+		})
+		g.It("check long input scrolls to the caret", func() {
+			text, caret := fieldView([]rune("Hello world"), 11, 6)
+			g.Assert(text).Equal("world")
+			g.Assert(caret).Equal(5)
+			text, caret = fieldView([]rune("Hello world"), 0, 6)
+			g.Assert(text).Equal("Hello ")
+			g.Assert(caret).Equal(0)
+		})
+		g.It("check wide characters at the edge", func() {
+			// the third ideograph would take cells 4 and 5 of a 5 cell field
+			text, caret := fieldView([]rune("漢字漢字"), 0, 5)
+			g.Assert(text).Equal("漢字")
+			g.Assert(caret).Equal(0)
+			text, caret = fieldView([]rune("漢字漢字"), 4, 5)
+			g.Assert(text).Equal("漢字")
+			g.Assert(caret).Equal(4)
+		})
+	})
+}
+
+func TestNodeText(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check nodeText()", func() {
+		g.It("check address, sysop and city", func() {
+			node := &nodelist.Node{Address: *types.AddrFromString("2:5020/1"), Sysop: "Ivan_Petrov", City: "Moscow"}
+			g.Assert(nodeText(node)).Equal("2:5020/1 Ivan Petrov, Moscow")
+		})
+		g.It("check a node without a city", func() {
+			node := &nodelist.Node{Address: *types.AddrFromString("2:5020/1.5"), Sysop: "Point"}
+			g.Assert(nodeText(node)).Equal("2:5020/1.5 Point")
+		})
+	})
+}
+
+func TestEditHeaderNodelistKey(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check the node list key on the To field", func() {
+		tab := tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone)
+		f2 := tcell.NewEventKey(tcell.KeyF2, 0, tcell.ModNone)
+		header := func(areaType msgapi.EchoAreaType) *EditHeader {
+			var area msgapi.AreaPrimitive = msgapi.NewMemoryArea("test", areaType)
+			return &EditHeader{sIndex: 2, msg: &msgapi.Message{AreaObject: &area}}
+		}
+		g.After(func() {
+			config.Config.Edit.EchoNodelist = false
+		})
+		g.It("check Tab opens it in netmail", func() {
+			g.Assert(header(msgapi.EchoAreaTypeNetmail).nodelistKey(tab)).IsTrue()
+		})
+		g.It("check Tab moves on in echomail", func() {
+			e := header(msgapi.EchoAreaTypeEcho)
+			g.Assert(e.nodelistKey(tab)).IsFalse()
+			g.Assert(e.nodelistKey(f2)).IsTrue()
+			e.sIndex = 4
+			g.Assert(e.nodelistKey(f2)).IsFalse()
+		})
+		g.It("check edit.echo_nodelist restores Tab in echomail", func() {
+			config.Config.Edit.EchoNodelist = true
+			g.Assert(header(msgapi.EchoAreaTypeEcho).nodelistKey(tab)).IsTrue()
+		})
+	})
+}
@@ -193,7 +193,22 @@ func (v *View) InsertNewline() bool {
 	
 	// Extract quote string from current line
 	quoteStr, quoteLen := GetQuoteString(currentLine)
+	quoted := quoteLen > 0
 	
+	// Nothing typed after the quote string before the cursor: drop the
+	// dangling quote string rather than leave it on a line of its own
+	if quoted {
+		runes := []rune(currentLine)
+		ql := utf8.RuneCountInString(quoteStr)
+		if cx >= ql && cx <= len(runes) && strings.TrimSpace(string(runes[ql:cx])) == "" {
+			v.Buf.Remove(Loc{0, v.Cursor.Y}, Loc{cx, v.Cursor.Y})
+		} else if cx > 0 && cx < ql {
+			// Enter inside the quote string must not split it, the empty
+			// line goes before the quoted one
+			v.Cursor.X = 0
+		}
+	}
+
 	// Check if quote string should be eliminated based on cursor position
 	// This follows GoldED+ logic: eliminate quote when at end of line, at linefeed, or inside quote string
 	if quoteLen > 0 && ShouldEliminateQuote(currentLine, cx) {
@@ -226,7 +241,9 @@ func (v *View) InsertNewline() bool {
 				wsAfterQuote := ws[quoteLen:]
 				newLineContent += wsAfterQuote
 			}
-		} else {
+		} else if !quoted {
+			// the leading whitespace of an eliminated quote string is not
+			// an indentation
 			newLineContent = ws
 		}
 	}
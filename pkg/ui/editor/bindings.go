@@ -347,6 +347,11 @@ modSearch:
 		}, true
 	}
 
+	// The space rune can't be written as is in comma separated key lists
+	if k == "Space" {
+		k = " "
+	}
+
 	// If we were given one character, then we've got a rune.
 	if len(k) == 1 {
 		return keyDesc{
@@ -360,6 +365,12 @@ modSearch:
 	return keyDesc{}, false
 }
 
+// FindKey parses a key description such as "CtrlQ", "Alt-k" or "F3"
+func FindKey(k string) (key tcell.Key, modifiers tcell.ModMask, r rune, ok bool) {
+	b, ok := findKey(k)
+	return b.keyCode, b.modifiers, b.r, ok
+}
+
 // findAction will find 'action' using string 'v'
 func findAction(v string) (action func(*View) bool) {
 	action = bindingActions[v]
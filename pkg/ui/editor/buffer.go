@@ -5,6 +5,7 @@ import (
 	"strings"
 	"unicode/utf8"
 
+	"github.com/askovpen/gossiped/pkg/config"
 	"github.com/askovpen/gossiped/pkg/highlight"
 )
 
@@ -61,6 +62,7 @@ func NewBuffer(reader io.Reader, size int64, path string, cursorPosition []strin
 	b.LineArray = NewLineArray(size, reader)
 
 	b.Settings = DefaultLocalSettings()
+	b.Settings["scrollbar"] = config.Config.Scrollbar
 	//	for k, v := range globalSettings {
 	//		if _, ok := b.Settings[k]; ok {
 	//			b.Settings[k] = v
@@ -105,10 +107,6 @@ filetype: msg
 detect:
   filename: "\\.msg$"
 rules:
-- comment: ".*\\>+.*$"
-- comment2: ".*\\>{2}.*$"
-- comment3: ".*\\>{3}.*$"
-- comment4: ".*\\>{4}.*$"
 - tagline: "^\\.\\.\\..*$"
 - origin: "^ \\* Origin:.*$"
 - tearline: "^--- .*$"
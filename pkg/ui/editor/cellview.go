@@ -119,6 +119,12 @@ func (c *CellView) Draw(buf *Buffer, colorscheme *config.ColorScheme, top, heigh
 		if startStyle != nil {
 			curStyle = *startStyle
 		}
+		// Quoted lines, prefix included, take the color of their quote level
+		quoted := false
+		if level := GetQuoteLevel(lineStr); level > 0 {
+			quoted = true
+			curStyle = (*colorscheme).GetColor(config.GetQuoteColor(level))
+		}
 
 		// We'll either draw the length of the line, or the width of the screen
 		// whichever is smaller
@@ -137,7 +143,7 @@ func (c *CellView) Draw(buf *Buffer, colorscheme *config.ColorScheme, top, heigh
 			if colN >= len(line) {
 				break
 			}
-			if group, ok := buf.Match(lineN)[colN]; ok {
+			if group, ok := buf.Match(lineN)[colN]; ok && !quoted {
 				curStyle = (*colorscheme).GetColor(group.String())
 			}
 
@@ -205,6 +211,8 @@ func (c *CellView) Draw(buf *Buffer, colorscheme *config.ColorScheme, top, heigh
 		}
 		if group, ok := buf.Match(lineN)[len(line)]; ok {
 			curStyle = (*colorscheme).GetColor(group.String())
+		} else if quoted {
+			curStyle = config.StyleDefault
 		}
 
 		// newline
@@ -3,11 +3,9 @@ package editor
 import (
 	"strings"
 	"unicode"
-)
+	"unicode/utf8"
 
-const (
-	QuoteStops  = "<\"'-"
-	MaxQuoteLen = 40
+	"github.com/askovpen/gossiped/pkg/config"
 )
 
 // IsQuoteChar checks if the given character is a quote character
@@ -18,10 +16,12 @@ func IsQuoteChar(char rune) bool {
 
 // IsQuoteEnhanced performs enhanced quote detection based on GoldED+ is_quote2() algorithm
 // This is the only quote detection method we use (no basic detection)
+// The quote stop characters are set with quote.stops
 func IsQuoteEnhanced(line string, prevLines []string) bool {
 	if len(line) == 0 {
 		return false
 	}
+	stops := config.GetQuoteStops()
 
 	// Convert to runes for proper Unicode handling
 	runes := []rune(line)
@@ -44,7 +44,7 @@ func IsQuoteEnhanced(line string, prevLines []string) bool {
 			found = true
 		} else {
 			// Check for quote stop characters or control characters
-			if strings.ContainsRune(QuoteStops, runes[pos]) || runes[pos] == '\r' || runes[pos] == '\n' {
+			if strings.ContainsRune(stops, runes[pos]) || runes[pos] == '\r' || runes[pos] == '\n' {
 				return true
 			}
 		}
@@ -88,6 +88,7 @@ func IsQuoteBasic(line string) bool {
 	}
 	
 	runes := []rune(line)
+	stops := config.GetQuoteStops()
 	ptr := 0
 	endPtr := len(runes)
 	if endPtr > 11 {
@@ -115,7 +116,7 @@ func IsQuoteBasic(line string) bool {
 			return true
 		}
 		if unicode.IsControl(runes[ptr]) || 
-		   strings.ContainsRune(QuoteStops, runes[ptr]) || 
+		   strings.ContainsRune(stops, runes[ptr]) || 
 		   unicode.IsSpace(runes[ptr]) {
 			break
 		}
@@ -180,7 +181,7 @@ func checkPreviousLinesContext(prevLines []string) bool {
 }
 
 // GetQuoteString extracts the quote string from a line
-// Returns the quote string and its length
+// Returns the quote string and its length, at most quote.max_len - 1
 func GetQuoteString(line string) (string, int) {
 	if !IsQuoteBasic(line) {
 		return "", 0
@@ -215,8 +216,9 @@ func GetQuoteString(line string) (string, int) {
 	}
 	
 	// Extract quote string, filtering out line feeds
+	maxLen := config.GetMaxQuoteLen()
 	var result strings.Builder
-	for i := 0; i < end && result.Len() < MaxQuoteLen-1; i++ {
+	for i := 0; i < end && result.Len() < maxLen-1; i++ {
 		if runes[i] != '\n' {
 			result.WriteRune(runes[i])
 		}
@@ -244,10 +246,12 @@ func GetQuoteLevel(line string) int {
 // ShouldEliminateQuote determines if quote string should be eliminated
 // based on cursor position (for Enter key handling)
 func ShouldEliminateQuote(line string, cursorPos int) bool {
-	_, quoteLen := GetQuoteString(line)
-	if quoteLen == 0 {
+	quoteStr, _ := GetQuoteString(line)
+	if quoteStr == "" {
 		return false
 	}
+	// cursorPos counts runes, like the quote length compared to it
+	quoteLen := utf8.RuneCountInString(quoteStr)
 	
 	// Convert to runes for proper Unicode handling
 	runes := []rune(line)
@@ -302,7 +306,7 @@ func WordWrapQuoteAware(text string, width int, quotemargin int) (lines []string
 		}
 		
 		// If line fits within margin, no wrapping needed
-		if len(line) <= margin {
+		if utf8.RuneCountInString(line) <= margin {
 			lines = append(lines, line)
 			continue
 		}
@@ -312,7 +316,7 @@ func WordWrapQuoteAware(text string, width int, quotemargin int) (lines []string
 		content := line[contentStart:]
 		
 		// Wrap the content
-		wrappedContent := wrapTextContent(content, margin - quoteLen)
+		wrappedContent := wrapTextContent(content, margin - utf8.RuneCountInString(quoteStr))
 		
 		// Add quote string to each wrapped line
 		for i, wrappedLine := range wrappedContent {
@@ -335,27 +339,32 @@ func wrapTextContent(content string, maxWidth int) []string {
 		return []string{""}
 	}
 	
-	if len(content) <= maxWidth {
+	if utf8.RuneCountInString(content) <= maxWidth {
 		return []string{content}
 	}
 	
 	var result []string
 	var currentLine strings.Builder
+	currentLen := 0
 	words := strings.Fields(content)
 	
 	for _, word := range words {
+		wordLen := utf8.RuneCountInString(word)
 		// Check if adding this word would exceed the width
-		if currentLine.Len() > 0 && currentLine.Len() + 1 + len(word) > maxWidth {
+		if currentLen > 0 && currentLen + 1 + wordLen > maxWidth {
 			// Start a new line
 			result = append(result, currentLine.String())
 			currentLine.Reset()
 			currentLine.WriteString(word)
+			currentLen = wordLen
 		} else {
 			// Add word to current line
-			if currentLine.Len() > 0 {
+			if currentLen > 0 {
 				currentLine.WriteString(" ")
+				currentLen++
 			}
 			currentLine.WriteString(word)
+			currentLen += wordLen
 		}
 	}
 	
@@ -367,6 +376,89 @@ func wrapTextContent(content string, maxWidth int) []string {
 	return result
 }
 
+// WrapBody hard-wraps message body lines longer than width, quoted lines
+// longer than quotemargin, using the quote-aware wrapper. Kludge lines,
+// tearline and origin are left untouched. A width or quotemargin of zero
+// or less disables wrapping of the respective lines.
+func WrapBody(body string, width int, quotemargin int) string {
+	if width <= 0 && quotemargin <= 0 {
+		return body
+	}
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		margin := width
+		if _, quoteLen := GetQuoteString(line); quoteLen > 0 {
+			margin = quotemargin
+		}
+		if margin <= 0 || isServiceLine(line) || utf8.RuneCountInString(line) <= margin {
+			lines = append(lines, line)
+			continue
+		}
+		lines = append(lines, WordWrapQuoteAware(line, width, quotemargin)...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatBody reflows every paragraph of body: runs of lines with the same
+// quote string are joined and wrapped again with WordWrapQuoteAware, at width
+// or, for quoted ones, at quotemargin. Blank lines, kludges, tearline and
+// origin are kept, as are paragraphs whose margin is zero or less. It also
+// returns the number of lines in paragraphs which changed; formatting its
+// result again changes nothing.
+func FormatBody(body string, width int, quotemargin int) (string, int) {
+	var out, para []string
+	quote := ""
+	changed := 0
+	flush := func() {
+		if len(para) == 0 {
+			return
+		}
+		margin := width
+		if quote != "" {
+			margin = quotemargin
+		}
+		if margin <= 0 {
+			out = append(out, para...)
+			para = nil
+			return
+		}
+		var words []string
+		for _, line := range para {
+			words = append(words, strings.Fields(line[len(quote):])...)
+		}
+		wrapped := WordWrapQuoteAware(quote+strings.Join(words, " "), width, quotemargin)
+		if strings.Join(wrapped, "\n") != strings.Join(para, "\n") {
+			changed += len(para)
+		}
+		out = append(out, wrapped...)
+		para = nil
+	}
+	for _, line := range strings.Split(body, "\n") {
+		q, _ := GetQuoteString(line)
+		if isServiceLine(line) || strings.TrimSpace(line[len(q):]) == "" {
+			flush()
+			out = append(out, line)
+			continue
+		}
+		if q != quote {
+			flush()
+			quote = q
+		}
+		para = append(para, line)
+	}
+	flush()
+	return strings.Join(out, "\n"), changed
+}
+
+// isServiceLine reports whether line is a kludge, tearline or origin line
+func isServiceLine(line string) bool {
+	return strings.HasPrefix(line, "\x01") ||
+		strings.HasPrefix(line, "SEEN-BY:") ||
+		line == "---" ||
+		strings.HasPrefix(line, "--- ") ||
+		strings.HasPrefix(line, " * Origin:")
+}
+
 // CanReflowQuotedLines determines if two quoted lines can be reflowed together
 // Lines can be reflowed if they have the same quote string
 func CanReflowQuotedLines(line1, line2 string) bool {
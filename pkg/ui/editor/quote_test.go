@@ -0,0 +1,118 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	. "github.com/franela/goblin"
+)
+
+func TestWrapBody(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check WrapBody()", func() {
+		long := strings.Repeat("word ", 15) + "end"
+		g.It("check disabled wrapping", func() {
+			g.Assert(WrapBody(long, 0, 0)).Equal(long)
+		})
+		g.It("check quoted lines wrap at the quote margin", func() {
+			body := "Hello\n AS> " + long + "\n--- " + long
+			lines := strings.Split(WrapBody(body, 79, 40), "\n")
+			g.Assert(lines[0]).Equal("Hello")
+			for _, l := range lines[1 : len(lines)-1] {
+				g.Assert(strings.HasPrefix(l, " AS> ")).IsTrue()
+				g.Assert(utf8.RuneCountInString(l) <= 40).IsTrue()
+			}
+			g.Assert(lines[len(lines)-1]).Equal("--- " + long)
+		})
+		g.It("check a deeply nested quote fits max_line_width after prefixing", func() {
+			// a reply to a line already quoted up to the width limit
+			orig := " VP>> " + strings.Repeat("слово ", 11) + "abcdefg"
+			g.Assert(utf8.RuneCountInString(orig)).Equal(79)
+			quoted := strings.Replace(orig, ">>", ">>>", 1)
+			g.Assert(utf8.RuneCountInString(quoted)).Equal(80)
+			for _, l := range strings.Split(WrapBody(quoted, 79, 70), "\n") {
+				g.Assert(strings.HasPrefix(l, " VP>>> ")).IsTrue()
+				g.Assert(utf8.RuneCountInString(l) <= 79).IsTrue()
+			}
+		})
+		g.It("check only quotes are wrapped without a width", func() {
+			body := long + "\n > " + long
+			lines := strings.Split(WrapBody(body, 0, 30), "\n")
+			g.Assert(lines[0]).Equal(long)
+			g.Assert(len(lines) > 2).IsTrue()
+		})
+	})
+}
+
+func TestFormatBody(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check FormatBody()", func() {
+		body := "Hello All!\n\n" +
+			"This is a rather long paragraph which was typed without care\nabout\nthe line width at all.\n" +
+			" AS> quoted text which goes on and on and on\n AS> and is broken badly\n" +
+			"\n--- GoldED+\n * Origin: a long origin line which must never be wrapped by anything (2:5020/9696)"
+		g.It("check paragraphs are rewrapped", func() {
+			text, changed := FormatBody(body, 30, 24)
+			lines := strings.Split(text, "\n")
+			g.Assert(lines[0]).Equal("Hello All!")
+			g.Assert(lines[1]).Equal("")
+			g.Assert(lines[2]).Equal("This is a rather long")
+			g.Assert(lines[3]).Equal("paragraph which was typed")
+			g.Assert(lines[4]).Equal("without care about the line")
+			g.Assert(lines[5]).Equal("width at all.")
+			for _, l := range lines[6:9] {
+				g.Assert(strings.HasPrefix(l, " AS> ")).IsTrue()
+				g.Assert(len(l) <= 24).IsTrue()
+			}
+			g.Assert(lines[len(lines)-2]).Equal("--- GoldED+")
+			g.Assert(strings.HasSuffix(lines[len(lines)-1], "(2:5020/9696)")).IsTrue()
+			g.Assert(changed).Equal(5)
+		})
+		g.It("check formatting twice changes nothing", func() {
+			text, _ := FormatBody(body, 30, 24)
+			again, changed := FormatBody(text, 30, 24)
+			g.Assert(again).Equal(text)
+			g.Assert(changed).Equal(0)
+		})
+		g.It("check disabled width", func() {
+			text, changed := FormatBody(body, 0, 0)
+			g.Assert(text).Equal(body)
+			g.Assert(changed).Equal(0)
+		})
+	})
+}
+
+func TestQuoteStops(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check quote.stops and quote.max_len", func() {
+		g.After(func() {
+			config.Config.Quote.Stops, config.Config.Quote.MaxLen = "", 0
+		})
+		g.It("check the default stop set", func() {
+			g.Assert(IsQuoteBasic("ab|cd> text")).IsTrue()
+			g.Assert(IsQuoteBasic("a-b> text")).IsFalse()
+			q, n := GetQuoteString(" AS> text")
+			g.Assert(q).Equal(" AS> ")
+			g.Assert(n).Equal(5)
+		})
+		g.It("check a custom stop set", func() {
+			config.Config.Quote.Stops = "|:"
+			g.Assert(IsQuoteBasic("ab|cd> text")).IsFalse()
+			g.Assert(IsQuoteBasic("ab:cd> text")).IsFalse()
+			g.Assert(IsQuoteBasic("a-b> text")).IsTrue()
+			g.Assert(IsQuoteEnhanced("a-b> text", nil)).IsTrue()
+			q, _ := GetQuoteString("ab|cd> text")
+			g.Assert(q).Equal("")
+			q, _ = GetQuoteString("a-b> text")
+			g.Assert(q).Equal("a-b> ")
+		})
+		g.It("check a custom max quote length", func() {
+			config.Config.Quote.MaxLen = 4
+			q, n := GetQuoteString(" AS> text")
+			g.Assert(q).Equal(" AS")
+			g.Assert(n).Equal(3)
+		})
+	})
+}
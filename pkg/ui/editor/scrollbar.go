@@ -12,22 +12,41 @@ type ScrollBar struct {
 
 // Display shows the scrollbar
 func (sb *ScrollBar) Display(screen tcell.Screen) {
-	pos := sb.pos()
+	track := sb.view.colorscheme.GetColor(config.ColorElementScrollbar)
+	thumb := sb.view.colorscheme.GetColor(config.ColorElementScrollbarThumb)
+	start, size := sb.pos()
 	x := sb.view.x + sb.view.width - 1
-	y := sb.view.y + pos
-	style := config.StyleDefault.Reverse(true)
-	screen.SetContent(x, y, ' ', nil, style)
+	for i := 0; i < sb.view.height; i++ {
+		style := track
+		if i >= start && i < start+size {
+			style = thumb
+		}
+		screen.SetContent(x, sb.view.y+i, ' ', nil, style)
+	}
 }
 
-func (sb *ScrollBar) pos() int {
+// pos returns the first row and the height of the thumb, proportional to
+// the visible part of the buffer
+func (sb *ScrollBar) pos() (int, int) {
 	numlines := sb.view.Buf.NumLines
 	h := sb.view.height
-	
-	// Avoid division by zero for empty buffers
-	if numlines <= 0 {
-		return 0
+	if h <= 0 {
+		return 0, 0
+	}
+	// The whole buffer fits, the thumb fills the track
+	if numlines <= h {
+		return 0, h
+	}
+	size := h * h / numlines
+	if size < 1 {
+		size = 1
+	}
+	start := sb.view.Topline * h / numlines
+	if start > h-size {
+		start = h - size
+	}
+	if start < 0 {
+		start = 0
 	}
-	
-	filepercent := float32(sb.view.Topline) / float32(numlines)
-	return int(filepercent * float32(h))
+	return start, size
 }
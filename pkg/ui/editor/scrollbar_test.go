@@ -0,0 +1,47 @@
+package editor
+
+import (
+	"testing"
+
+	. "github.com/franela/goblin"
+)
+
+func TestScrollBarPos(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check ScrollBar.pos()", func() {
+		sb := &ScrollBar{view: &View{Buf: &Buffer{}}}
+		g.It("check empty view", func() {
+			sb.view.height, sb.view.Buf.NumLines = 0, 100
+			start, size := sb.pos()
+			g.Assert(start).Equal(0)
+			g.Assert(size).Equal(0)
+		})
+		g.It("check short buffer", func() {
+			sb.view.height, sb.view.Buf.NumLines, sb.view.Topline = 20, 0, 0
+			start, size := sb.pos()
+			g.Assert(start).Equal(0)
+			g.Assert(size).Equal(20)
+			sb.view.Buf.NumLines = 20
+			start, size = sb.pos()
+			g.Assert(start).Equal(0)
+			g.Assert(size).Equal(20)
+		})
+		g.It("check proportional thumb", func() {
+			sb.view.height, sb.view.Buf.NumLines, sb.view.Topline = 20, 40, 20
+			start, size := sb.pos()
+			g.Assert(start).Equal(10)
+			g.Assert(size).Equal(10)
+		})
+		g.It("check long buffer", func() {
+			sb.view.height, sb.view.Buf.NumLines = 20, 100000
+			sb.view.Topline = 0
+			start, size := sb.pos()
+			g.Assert(start).Equal(0)
+			g.Assert(size).Equal(1)
+			sb.view.Topline = 100000
+			start, size = sb.pos()
+			g.Assert(start).Equal(19)
+			g.Assert(size).Equal(1)
+		})
+	})
+}
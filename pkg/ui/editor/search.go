@@ -0,0 +1,215 @@
+package editor
+
+import (
+	"fmt"
+
+	"github.com/askovpen/gossiped/pkg/utils"
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// searchMatch stores the location of a single search match.
+// start and end are on the same line, end is exclusive.
+type searchMatch struct {
+	start Loc
+	end   Loc
+}
+
+// searchState holds the state of the in-view search
+type searchState struct {
+	// Whether the search prompt is active
+	active bool
+	// The text typed into the prompt
+	input []rune
+	// The confirmed search term
+	term string
+	// All matches of the current term
+	matches []searchMatch
+	// Index of the current match in matches
+	current int
+	// The topline before the search prompt was opened
+	origTopline int
+}
+
+// IsSearching returns true if the search prompt is active
+func (v *View) IsSearching() bool {
+	return v.search.active
+}
+
+// HasSearchTerm returns true if a confirmed search term is set, n/N then
+// jump between its matches
+func (v *View) HasSearchTerm() bool {
+	return v.search.term != ""
+}
+
+// StartSearch opens the search prompt
+func (v *View) StartSearch() {
+	v.search.active = true
+	v.search.input = nil
+	v.search.origTopline = v.Topline
+	v.search.matches = nil
+}
+
+// ClearSearch removes the search term and all match highlights
+func (v *View) ClearSearch() {
+	v.search = searchState{}
+}
+
+// findMatches locates all occurrences of term in the buffer, ignoring case
+func (v *View) findMatches(term string) []searchMatch {
+	needle, _ := utils.FoldRunes([]rune(term))
+	if len(needle) == 0 {
+		return nil
+	}
+	var matches []searchMatch
+	for y := 0; y < v.Buf.NumLines; y++ {
+		// folding may change the length of text (ß is ss), matches are
+		// found in the folded line and mapped back to its runes
+		line, origin := utils.FoldRunes([]rune(v.Buf.Line(y)))
+		for x := 0; x+len(needle) <= len(line); x++ {
+			if runesEqual(line[x:x+len(needle)], needle) {
+				matches = append(matches, searchMatch{Loc{origin[x], y}, Loc{origin[x+len(needle)-1] + 1, y}})
+				x += len(needle) - 1
+			}
+		}
+	}
+	return matches
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// updateSearch recalculates matches for term and selects the first match
+// at or below the given line
+func (v *View) updateSearch(term string, fromLine int) {
+	v.search.term = term
+	v.search.matches = v.findMatches(term)
+	v.search.current = 0
+	for i, m := range v.search.matches {
+		if m.start.Y >= fromLine {
+			v.search.current = i
+			break
+		}
+	}
+	v.scrollToMatch()
+}
+
+// refreshSearch recalculates matches after the buffer has been replaced
+func (v *View) refreshSearch() {
+	if v.search.term == "" {
+		return
+	}
+	v.search.matches = v.findMatches(v.search.term)
+	if v.search.current >= len(v.search.matches) {
+		v.search.current = 0
+	}
+}
+
+// SearchNext jumps to the next match, wrapping around at the end. It
+// returns false if the search term has no match.
+func (v *View) SearchNext() bool {
+	if len(v.search.matches) == 0 {
+		return false
+	}
+	v.search.current = (v.search.current + 1) % len(v.search.matches)
+	v.scrollToMatch()
+	return true
+}
+
+// SearchPrev jumps to the previous match, wrapping around at the beginning.
+// It returns false if the search term has no match.
+func (v *View) SearchPrev() bool {
+	if len(v.search.matches) == 0 {
+		return false
+	}
+	v.search.current = (v.search.current - 1 + len(v.search.matches)) % len(v.search.matches)
+	v.scrollToMatch()
+	return true
+}
+
+// scrollToMatch scrolls the view so that the current match is visible
+func (v *View) scrollToMatch() {
+	if len(v.search.matches) == 0 {
+		return
+	}
+	y := v.search.matches[v.search.current].start.Y
+	if y >= v.Topline && y < v.Topline+v.height-1 {
+		return
+	}
+	v.Topline = y - v.height/2
+	if v.Topline+v.height > v.Buf.NumLines {
+		v.Topline = v.Buf.NumLines - v.height
+	}
+	if v.Topline < 0 {
+		v.Topline = 0
+	}
+}
+
+// handleSearchEvent processes a key press while the search prompt is active
+func (v *View) handleSearchEvent(e *tcell.EventKey) {
+	switch e.Key() {
+	case tcell.KeyEnter:
+		v.search.active = false
+		if len(v.search.input) == 0 {
+			v.ClearSearch()
+		}
+	case tcell.KeyEsc:
+		topline := v.search.origTopline
+		v.ClearSearch()
+		v.Topline = topline
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(v.search.input) > 0 {
+			v.search.input = v.search.input[:len(v.search.input)-1]
+			v.updateSearch(string(v.search.input), v.search.origTopline)
+		}
+	case tcell.KeyRune:
+		v.search.input = append(v.search.input, e.Rune())
+		v.updateSearch(string(v.search.input), v.search.origTopline)
+	}
+}
+
+// searchStyle returns the highlight style for loc if it is part of a match
+func (v *View) searchStyle(loc Loc) (tcell.Style, bool) {
+	for i, m := range v.search.matches {
+		if loc.Y == m.start.Y && loc.X >= m.start.X && loc.X < m.end.X {
+			if i == v.search.current {
+				return v.colorscheme.GetColor("search-current"), true
+			}
+			return v.colorscheme.GetColor("search"), true
+		}
+	}
+	return tcell.Style{}, false
+}
+
+// displaySearchPrompt draws the search prompt on the last line of the view
+func (v *View) displaySearchPrompt(screen tcell.Screen) {
+	if !v.search.active {
+		return
+	}
+	style := v.colorscheme.GetColor("search-current")
+	status := "  [not found]"
+	if len(v.search.matches) > 0 {
+		status = fmt.Sprintf("  [%d/%d]", v.search.current+1, len(v.search.matches))
+	} else if len(v.search.input) == 0 {
+		status = ""
+	}
+	prompt := []rune("/" + string(v.search.input) + status)
+	y := v.y + v.height - 1
+	x := v.x
+	for _, r := range prompt {
+		if x >= v.x+v.width {
+			break
+		}
+		screen.SetContent(x, y, r, nil, style)
+		x += runewidth.RuneWidth(r)
+	}
+	for ; x < v.x+v.width; x++ {
+		screen.SetContent(x, y, ' ', nil, style)
+	}
+}
@@ -0,0 +1,84 @@
+package editor
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// lineSelection holds the whole lines marked in a readonly view, from the
+// anchor line to the end line which Shift-Up/Down move
+type lineSelection struct {
+	active bool
+	anchor int
+	end    int
+}
+
+// HasSelection returns true if lines are marked
+func (v *View) HasSelection() bool {
+	return v.selection.active
+}
+
+// ClearSelection unmarks all lines
+func (v *View) ClearSelection() {
+	v.selection = lineSelection{}
+}
+
+// SelectedLines returns the first and last marked line, false if no lines
+// are marked
+func (v *View) SelectedLines() (first, last int, ok bool) {
+	if !v.selection.active {
+		return 0, 0, false
+	}
+	first, last = v.selection.anchor, v.selection.end
+	if first > last {
+		first, last = last, first
+	}
+	return first, last, true
+}
+
+// extendSelection moves the end of the marked lines by n lines, starting
+// at the top line of the view, and scrolls it into view
+func (v *View) extendSelection(n int) {
+	if !v.selection.active {
+		v.selection = lineSelection{active: true, anchor: v.Topline, end: v.Topline}
+		return
+	}
+	v.selection.end = max(0, min(v.selection.end+n, v.Buf.NumLines-1))
+	if v.selection.end < v.Topline {
+		v.Topline = v.selection.end
+	} else if v.height > 0 && v.selection.end >= v.Topline+v.height {
+		v.Topline = v.selection.end - v.height + 1
+	}
+}
+
+// handleSelectionEvent marks lines with Shift-Up/Down and Shift-PageUp/
+// PageDown and unmarks them with Esc. It returns true if the key was used.
+func (v *View) handleSelectionEvent(e *tcell.EventKey) bool {
+	if e.Key() == tcell.KeyEsc && v.selection.active {
+		v.ClearSelection()
+		return true
+	}
+	if e.Modifiers() != tcell.ModShift {
+		return false
+	}
+	switch e.Key() {
+	case tcell.KeyUp:
+		v.extendSelection(-1)
+	case tcell.KeyDown:
+		v.extendSelection(1)
+	case tcell.KeyPgUp:
+		v.extendSelection(-max(v.height, 1))
+	case tcell.KeyPgDn:
+		v.extendSelection(max(v.height, 1))
+	default:
+		return false
+	}
+	return true
+}
+
+// selectionStyle returns the style of line y if it is marked
+func (v *View) selectionStyle(y int) (tcell.Style, bool) {
+	if first, last, ok := v.SelectedLines(); ok && y >= first && y <= last {
+		return v.colorscheme.GetColor("selection"), true
+	}
+	return tcell.Style{}, false
+}
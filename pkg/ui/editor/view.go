@@ -58,8 +58,16 @@ type View struct {
 	// The colorscheme
 	colorscheme *config.ColorScheme
 
+	// The in-view search state
+	search searchState
+
+	// The lines marked in a readonly view
+	selection lineSelection
+
 	// The runtime files
 	done func()
+	// Called on each draw which shows the last line of the buffer
+	bottom func()
 }
 
 // NewView returns a new view with the specified buffer.
@@ -145,6 +153,9 @@ func (v *View) OpenBuffer(buf *Buffer) {
 	// is opened
 	v.isOverwriteMode = false
 	v.Buf.updateRules()
+	// Matches refer to the old buffer, find them again
+	v.refreshSearch()
+	v.ClearSelection()
 	// Prepare color scheme
 	v.SetColorscheme(config.GetColors(config.ColorAreaEditor))
 }
@@ -252,6 +263,27 @@ func (v *View) HandleEvent(event tcell.Event) {
 
 	switch e := event.(type) {
 	case *tcell.EventKey:
+		// The search prompt consumes all keys while it is open
+		if v.search.active {
+			v.handleSearchEvent(e)
+			return
+		}
+		if v.Readonly && v.handleSelectionEvent(e) {
+			return
+		}
+		if v.Readonly && e.Key() == tcell.KeyRune && e.Modifiers() == tcell.ModNone {
+			switch e.Rune() {
+			case '/':
+				v.StartSearch()
+				return
+			case 'n':
+				v.SearchNext()
+				return
+			case 'N':
+				v.SearchPrev()
+				return
+			}
+		}
 		// Check first if input is a key binding, if it is we 'eat' the input and don't insert a rune
 		isBinding := false
 		for key, actions := range v.bindings {
@@ -356,6 +388,11 @@ func (v *View) displayView(screen tcell.Screen) {
 		for _, char := range line {
 			if char != nil {
 				lineStyle := char.style
+				if style, ok := v.searchStyle(char.realLoc); ok {
+					lineStyle = style
+				} else if style, ok := v.selectionStyle(char.realLoc.Y); ok {
+					lineStyle = style
+				}
 
 				for _, c := range v.Buf.cursors {
 					v.SetCursor(c)
@@ -390,6 +427,10 @@ func (v *View) displayView(screen tcell.Screen) {
 			}
 			v.SetCursor(&v.Buf.Cursor)
 		} else if len(line) == 0 {
+			// a marked empty line shows as one marked cell
+			if style, ok := v.selectionStyle(realLineN); ok {
+				screen.SetContent(xOffset, yOffset+visualLineN, ' ', nil, style)
+			}
 			for i, c := range v.Buf.cursors {
 				v.SetCursor(c)
 				if !v.Cursor.HasSelection() &&
@@ -429,6 +470,10 @@ func (v *View) Draw(screen tcell.Screen) {
 	}
 
 	v.displayView(screen)
+	v.displaySearchPrompt(screen)
+	if v.bottom != nil && v.AtBottom() {
+		v.bottom()
+	}
 
 	// Don't draw the cursor if it is out of the viewport or if it has a selection
 	if v.Cursor.Y-v.Topline < 0 || v.Cursor.Y-v.Topline > v.height-1 || v.Cursor.HasSelection() || v.Readonly {
@@ -445,3 +490,16 @@ func (v *View) SetDoneFunc(handler func()) *View {
 	v.done = handler
 	return v
 }
+
+// SetBottomFunc sets a callback run whenever a draw shows the last line of
+// the buffer, so it runs at once for a buffer which fits on the screen. It
+// runs inside Draw, so slow work like writes has to be queued from it.
+func (v *View) SetBottomFunc(handler func()) *View {
+	v.bottom = handler
+	return v
+}
+
+// AtBottom returns true if the last line of the buffer is in the view
+func (v *View) AtBottom() bool {
+	return v.height > 0 && v.Bottomline() >= v.Buf.NumLines
+}
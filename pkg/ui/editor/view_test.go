@@ -0,0 +1,168 @@
+package editor
+
+import (
+	"testing"
+
+	. "github.com/franela/goblin"
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestViewAtBottom(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check View.AtBottom()", func() {
+		v := &View{Buf: &Buffer{LineArray: &LineArray{}}, width: 80}
+		g.It("check view not drawn yet", func() {
+			v.height, v.Buf.NumLines = 0, 5
+			g.Assert(v.AtBottom()).IsFalse()
+		})
+		g.It("check buffer which fits", func() {
+			v.height, v.Buf.NumLines, v.Topline = 20, 5, 0
+			g.Assert(v.AtBottom()).IsTrue()
+		})
+		g.It("check scrolling to the last line", func() {
+			v.height, v.Buf.NumLines, v.Topline = 20, 50, 0
+			g.Assert(v.AtBottom()).IsFalse()
+			v.Topline = 29
+			g.Assert(v.AtBottom()).IsFalse()
+			v.Topline = 30
+			g.Assert(v.AtBottom()).IsTrue()
+		})
+	})
+}
+
+func TestInsertNewlineQuote(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check Enter on quoted lines", func() {
+		newline := func(text string, x, y int) *View {
+			v := NewView(NewBufferFromString(text))
+			v.Cursor.X, v.Cursor.Y = x, y
+			v.InsertNewline()
+			return v
+		}
+		lines := func(v *View) []string {
+			var res []string
+			for i := 0; i < v.Buf.NumLines; i++ {
+				res = append(res, v.Buf.Line(i))
+			}
+			return res
+		}
+		g.It("check Enter mid-line keeps the quote string on the new line", func() {
+			v := newline(" AS> hello world", 11, 0)
+			g.Assert(lines(v)).Equal([]string{" AS> hello ", " AS> world"})
+			g.Assert(v.Cursor.Y).Equal(1)
+			g.Assert(v.Cursor.X).Equal(0)
+		})
+		g.It("check Enter at the end of a quoted line starts an unquoted one", func() {
+			v := newline(" AS> hello", 10, 0)
+			g.Assert(lines(v)).Equal([]string{" AS> hello", ""})
+		})
+		g.It("check Enter inside the quote string inserts an empty line", func() {
+			v := newline(" AS> hello", 0, 0)
+			g.Assert(lines(v)).Equal([]string{"", " AS> hello"})
+			v = newline(" AS> hello", 2, 0)
+			g.Assert(lines(v)).Equal([]string{"", " AS> hello"})
+			g.Assert(v.Cursor.Y).Equal(1)
+			g.Assert(v.Cursor.X).Equal(0)
+		})
+		g.It("check Enter right after the quote string drops the dangling quote", func() {
+			v := newline(" AS> hello", 5, 0)
+			g.Assert(lines(v)).Equal([]string{"", " AS> hello"})
+		})
+		g.It("check Enter on a line with only a quote string empties it", func() {
+			v := newline("Hi\n AS> \nBye", 5, 1)
+			g.Assert(lines(v)).Equal([]string{"Hi", "", "", "Bye"})
+			g.Assert(v.Cursor.Y).Equal(2)
+		})
+		g.It("check quote strings of cyrillic initials", func() {
+			v := newline(" ВП> привет мир", 12, 0)
+			g.Assert(lines(v)).Equal([]string{" ВП> привет ", " ВП> мир"})
+			v = newline(" ВП> привет", 11, 0)
+			g.Assert(lines(v)).Equal([]string{" ВП> привет", ""})
+		})
+	})
+}
+
+func TestViewSearchFolding(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check search ignores case", func() {
+		v := NewView(NewBufferFromString("Привет, МИР\nGroße STRASSE, große Straße"))
+		g.It("check cyrillic terms", func() {
+			g.Assert(v.findMatches("мир")).Equal([]searchMatch{{Loc{8, 0}, Loc{11, 0}}})
+		})
+		g.It("check matches are mapped back through folded ß", func() {
+			g.Assert(v.findMatches("strasse")).Equal([]searchMatch{
+				{Loc{6, 1}, Loc{13, 1}},
+				{Loc{21, 1}, Loc{27, 1}},
+			})
+			g.Assert(len(v.findMatches("GROSSE"))).Equal(2)
+		})
+	})
+}
+
+func TestViewSearchNext(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check jumping between search matches", func() {
+		v := NewView(NewBufferFromString("one\ntwo\nthree\none more"))
+		v.Readonly = true
+		v.height = 2
+		search := func(term string) {
+			v.StartSearch()
+			for _, r := range term {
+				v.HandleEvent(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+			}
+			v.HandleEvent(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+		}
+		g.It("check matches are found and wrap around", func() {
+			search("one")
+			g.Assert(v.search.current).Equal(0)
+			g.Assert(v.SearchNext()).IsTrue()
+			g.Assert(v.search.current).Equal(1)
+			g.Assert(v.Topline).Equal(2)
+			g.Assert(v.SearchNext()).IsTrue()
+			g.Assert(v.search.current).Equal(0)
+			g.Assert(v.SearchPrev()).IsTrue()
+			g.Assert(v.search.current).Equal(1)
+		})
+		g.It("check a term without matches", func() {
+			search("four")
+			g.Assert(v.HasSearchTerm()).IsTrue()
+			g.Assert(v.SearchNext()).IsFalse()
+			g.Assert(v.SearchPrev()).IsFalse()
+		})
+	})
+}
+
+func TestViewSelection(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check marking lines in a readonly view", func() {
+		v := NewView(NewBufferFromString("one\ntwo\nthree\nfour\nfive"))
+		v.Readonly = true
+		v.height = 3
+		shift := func(key tcell.Key) {
+			v.HandleEvent(tcell.NewEventKey(key, 0, tcell.ModShift))
+		}
+		g.It("check marking starts at the top line", func() {
+			_, _, ok := v.SelectedLines()
+			g.Assert(ok).IsFalse()
+			shift(tcell.KeyDown)
+			first, last, ok := v.SelectedLines()
+			g.Assert(ok).IsTrue()
+			g.Assert([]int{first, last}).Equal([]int{0, 0})
+		})
+		g.It("check the marked lines follow Shift-Down and scroll", func() {
+			shift(tcell.KeyDown)
+			shift(tcell.KeyDown)
+			shift(tcell.KeyDown)
+			first, last, _ := v.SelectedLines()
+			g.Assert([]int{first, last}).Equal([]int{0, 3})
+			g.Assert(v.Topline).Equal(1)
+			shift(tcell.KeyPgDn)
+			_, last, _ = v.SelectedLines()
+			g.Assert(last).Equal(4)
+		})
+		g.It("check Esc unmarks", func() {
+			v.HandleEvent(tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone))
+			g.Assert(v.HasSelection()).IsFalse()
+		})
+	})
+}
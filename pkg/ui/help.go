@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/msgapi"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
@@ -43,12 +44,22 @@ func NewModalHelp() *ModalHelp {
 // InputHandler Input Handler
 func (m *ModalHelp) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
 	return m.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
-		if event.Key() == tcell.KeyEscape {
+		if keymap.Match(KeyActionCancel, event) {
 			m.done()
+		} else if handler := m.txt.InputHandler(); handler != nil {
+			// scroll text longer than the screen
+			handler(event, setFocus)
 		}
 	})
 }
 
+// SetTitle Set Title
+func (m *ModalHelp) SetTitle(title string) *ModalHelp {
+	_, bgTitle, styleTitle := config.GetElementStyle(config.ColorAreaHelp, "title").Decompose()
+	m.frame.SetTitle(fmt.Sprintf("[:%s:%s] %s ", bgTitle.String(), config.MaskToStringStyle(styleTitle), tview.Escape(title)))
+	return m
+}
+
 // SetText Set Text
 func (m *ModalHelp) SetText(txt string) *ModalHelp {
 	style := config.GetElementStyle(config.ColorAreaHelp, "text")
@@ -57,6 +68,12 @@ func (m *ModalHelp) SetText(txt string) *ModalHelp {
 	return m
 }
 
+// SetColoredText sets text with tview color tags
+func (m *ModalHelp) SetColoredText(txt string) *ModalHelp {
+	m.txt.SetDynamicColors(true)
+	return m.SetText(txt)
+}
+
 // Draw draw
 func (m *ModalHelp) Draw(screen tcell.Screen) {
 	width, height := screen.Size()
@@ -86,8 +103,20 @@ End          Move selection bar to last area
 Down         Move selection bar to next area
 Up           Move selection bar to previous area
 Enter, Right Enter the Reader for the selected area
+Ctrl-S       Manage link subscriptions for the selected area (jnode-sql)
+Alt-L        Show or hide the number of subscribed links per area (jnode-sql)
+Alt-J        Show or hide the area type: Netmail, Echo, Local, Bad or Dupe
+Ctrl-R       Pick up areas added or removed in the database (jnode-sql)
+Alt-U        Rebuild message counts changed behind gossipEd's back (jnode-sql)
+Ctrl-F       Search messages in all areas, Enter opens the result (jnode-sql)
+Ctrl-B       List bookmarks, Enter opens the message, Del removes the bookmark
+Alt-P        Preview the colorscheme, every element in its configured style
+Ctrl-A       Archive sent netmail older than netmail.archive.days, ask first (jnode-sql)
+Alt-H        List unsent netmail no route is found for, Alt-T routes it again (jnode-sql)
+Alt-Q        Delete outbound queue entries of deleted messages or links, ask first (jnode-sql)
+Alt-G        List lastread positions of all users, Del/Alt-Z reset them (lastread.admin)
 ESC          Exit gossipEd, prompt for final decision
-Ctrl-C       Exit immediately, no questions asked
+Ctrl-C       Exit immediately, no questions asked (an open message is kept as a draft)
 <xyz>        Search for areas containing the string xyz`).
 		SetDoneFunc(func() {
 			a.Pages.HidePage("AreaListHelp")
@@ -102,14 +131,39 @@ func (a *App) ViewMsgHelp() (string, tview.Primitive, bool, bool) {
 Ins, Ctrl-I    Enter a new message
 Del            Delete current/marked message(s), ask first
 Right/Left     Next/Previous message
+Alt-Left/Right Back/Forward through the messages read, across areas
+n              Next unread message, then offer the next unread area
 Home/End       Display first/last part of current message
 </>            Go to First/Last message
 Ctrl-G         Go to message number
 F3, Ctrl-Q     Quote-Reply to message. (Reply to FROM name)
 Ctrl-N         Quote-Reply in another area
+Alt-V          Quote-Reply to the author of echomail privately in netmail
+Shift-Up/Down  Mark lines to quote, Shift-PgUp/PgDn by pages, Esc unmarks
+Alt-J          Quote-Reply with only the marked lines
 Ctrl-L         Enter the Message Lister
-Ctrl-F         Forward message to another area
-Alt-K          Show Kludges
+Space          Tag/untag message in the Message Lister, Del/Alt-M act on tagged
+Alt-S          Mark read up to this message, later ones stay new; in the
+               Message Lister up to the last tagged message
+Alt-B          Bookmark/unbookmark message, marked '#' in the Message Lister
+Ctrl-B         List bookmarks, Enter opens the message, Del removes the bookmark
+Alt-G          List lastread positions of all users in this area (lastread.admin)
+Ctrl-F         Forward message to another area or netmail ("Fwd:" subject)
+F4             Edit and re-save own message in place (jnode-sql)
+Alt-C/Alt-M    Copy/Move message to another area (jnode-sql echomail)
+Alt-Q          Queue message again for links subscribed later (jnode-sql echomail)
+Alt-T          Route unsent netmail again, e.g. after fixing its address with F4
+Alt-D          Send the requested return receipt of a jnode-sql netmail to us,
+               once; in the header of a new netmail request one
+Alt-K          Show/hide kludges (show_kludges sets the default)
+Alt-y/Alt-Y    Copy message text/quoted text to clipboard
+Ctrl-E         Fix double-encoded (CP866) text for display
+Ctrl-T         Pick the charset to read this area in until gossipEd exits
+Ctrl-O         Show message info (addresses, kludges)
+Alt-R          Toggle raw stored text, control characters visible (jnode-sql)
+Alt-X          Load all of a large message shown as a preview (jnode-sql)
+/              Search in message text, ignoring case
+n/N            Jump to next/previous search match (while searching)
 `).
 		SetDoneFunc(func() {
 			a.Pages.HidePage("ViewMsgHelp")
@@ -117,3 +171,17 @@ Alt-K          Show Kludges
 		})
 	return "ViewMsgHelp", modal, true, true
 }
+
+// MessageInfo read-only technical summary of msg
+func (a *App) MessageInfo(msg *msgapi.Message) (string, tview.Primitive, bool, bool) {
+	focus := a.App.GetFocus()
+	modal := NewModalHelp().
+		SetTitle("Message info").
+		SetText(tview.Escape(msg.Info())).
+		SetDoneFunc(func() {
+			a.Pages.HidePage("MessageInfo")
+			a.Pages.RemovePage("MessageInfo")
+			a.App.SetFocus(focus)
+		})
+	return "MessageInfo", modal, true, true
+}
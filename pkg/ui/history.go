@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/askovpen/gossiped/pkg/msgapi"
+)
+
+// historyLen is the number of messages kept in the navigation history
+const historyLen = 100
+
+// historyEntry is a read message, by database id where the area has ids so
+// that deleting other messages does not move it, else by position
+type historyEntry struct {
+	area string
+	id   int64
+	num  uint32
+}
+
+// same returns true if e and o are the same message
+func (e historyEntry) same(o historyEntry) bool {
+	if e.area != o.area || e.id != o.id {
+		return false
+	}
+	return e.id != 0 || e.num == o.num
+}
+
+// navHistory is the back/forward list of the messages read in this
+// session, in memory only so it is gone on quit
+type navHistory struct {
+	entries []historyEntry
+	pos     int
+	moving  bool
+}
+
+// visit records e as the current message, dropping the messages gone back
+// from and the oldest one past historyLen. Nothing is recorded while moving
+// through the history.
+func (h *navHistory) visit(e historyEntry) {
+	if h.moving {
+		return
+	}
+	if len(h.entries) > 0 {
+		if h.entries[h.pos].same(e) {
+			return
+		}
+		h.entries = h.entries[:h.pos+1]
+	}
+	h.entries = append(h.entries, e)
+	if len(h.entries) > historyLen {
+		h.entries = h.entries[len(h.entries)-historyLen:]
+	}
+	h.pos = len(h.entries) - 1
+}
+
+// remove drops entry i, e.g. of a deleted message
+func (h *navHistory) remove(i int) {
+	h.entries = append(h.entries[:i], h.entries[i+1:]...)
+	if h.pos >= i && h.pos > 0 {
+		h.pos--
+	}
+}
+
+// visitMsg records msg of area in the navigation history
+func (a *App) visitMsg(area *msgapi.AreaPrimitive, msg *msgapi.Message) {
+	a.history.visit(historyEntry{area: (*area).GetName(), id: msg.ID, num: msg.MsgNum})
+}
+
+// stepHistory leaves the view of msgNum in area for the message delta steps
+// back (negative) or forward in the history, reopening its area if needed.
+// A message deleted meanwhile is dropped from the history.
+func (a *App) stepHistory(area *msgapi.AreaPrimitive, msgNum uint32, delta int) {
+	i := a.history.pos + delta
+	if i < 0 || i >= len(a.history.entries) {
+		if delta < 0 {
+			a.sb.SetStatus("No earlier message in history")
+		} else {
+			a.sb.SetStatus("No later message in history")
+		}
+		return
+	}
+	e := a.history.entries[i]
+	var dst *msgapi.AreaPrimitive
+	for j := range msgapi.Areas {
+		if msgapi.Areas[j].GetName() == e.area {
+			dst = &msgapi.Areas[j]
+			break
+		}
+	}
+	if dst == nil {
+		a.history.remove(i)
+		a.sb.SetStatus(fmt.Sprintf("Area %s is not loaded", e.area))
+		return
+	}
+	if dst != area {
+		(*dst).Init()
+	}
+	num := e.num
+	if e.id != 0 {
+		msg, err := (*dst).GetMsgByID(e.id)
+		if errors.Is(err, msgapi.ErrMsgNotFound) {
+			a.history.remove(i)
+			a.sb.SetStatus("Message no longer exists, removed from history")
+			return
+		} else if err != nil {
+			a.sb.SetStatus(err.Error())
+			return
+		}
+		num = msg.MsgNum
+	}
+	a.history.pos = i
+	if dst != area {
+		a.clearTags()
+		a.CurrentArea = dst
+	}
+	a.history.moving = true
+	a.switchViewMsg(area, msgNum, dst, num)
+	a.history.moving = false
+}
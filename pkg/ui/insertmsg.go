@@ -6,13 +6,22 @@ import (
 	"github.com/askovpen/gossiped/pkg/msgapi"
 	"github.com/askovpen/gossiped/pkg/types"
 	"github.com/askovpen/gossiped/pkg/ui/editor"
+	"github.com/askovpen/gossiped/pkg/utils"
+	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"log"
+	"slices"
+	"strings"
+	"time"
 )
 
 const (
 	newMsgTypeAnswer        = 1
 	newMsgTypeAnswerNewArea = 2
 	newMsgTypeForward       = 4
+	newMsgTypeEdit          = 8
+	// another new message in the area the last one was posted to
+	newMsgTypeAnother = 16
 )
 
 // IM struct
@@ -24,35 +33,46 @@ type IM struct {
 	postArea   *msgapi.AreaPrimitive
 	newMsgType int
 	buffer     *editor.Buffer
+	draftBody  string
+	stopDraft  chan struct{}
+	editNum    uint32
+	edited     *msgapi.Message
+	// next opens a newMsgTypeAnother message after saving, prev is the
+	// saved message it takes the recipient and charset from
+	next bool
+	prev *msgapi.Message
+	// quote replaces the quote of the whole original in an answer, set
+	// for the lines marked in the message view
+	quote []string
 }
 
 // InsertMsgMenu modal menu
 func (a *App) InsertMsgMenu() (string, tview.Primitive, bool, bool) {
+	buttons := []string{"Yes", "Yes, And New", "No, Drop", "Continue Writing", "Edit Header"}
+	if a.im.newMsgType == newMsgTypeEdit {
+		buttons = slices.Delete(buttons, 1, 2)
+	}
 	modal := NewModalMenu().
 		SetY(6).
 		SetText("Save?").
-		AddButtons([]string{"Yes", "No, Drop", "Continue Writing", "Edit Header"}).
+		AddButtons(buttons).
 		SetDoneFunc(func(buttonIndex int) {
-			switch b := buttonIndex; b {
-			case 0:
-				//a.im.newMsg.Body = a.im.eb.GetText(false)
-				a.im.newMsg.Body = a.im.buffer.String()
-				(*a.im.postArea).SaveMsg(a.im.newMsg.MakeBody())
-				a.Pages.HidePage("InsertMsgMenu")
-				a.Pages.RemovePage("InsertMsgMenu")
-				a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*a.im.curArea).GetName(), (*a.im.curArea).GetLast()))
-				a.Pages.RemovePage(fmt.Sprintf("InsertMsg-%s", (*a.im.curArea).GetName()))
-				a.App.SetFocus(a.Pages)
-			case 1:
+			switch buttons[buttonIndex] {
+			case "Yes", "Yes, And New":
+				a.im.next = buttonIndex == 1
+				a.sendInsertedMsg()
+			case "No, Drop":
+				a.stopDraftAutosave()
+				a.discardDraft()
 				a.Pages.HidePage("InsertMsgMenu")
 				a.Pages.RemovePage("InsertMsgMenu")
 				a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*a.im.curArea).GetName(), (*a.im.curArea).GetLast()))
 				a.Pages.RemovePage(fmt.Sprintf("InsertMsg-%s", (*a.im.curArea).GetName()))
 				a.App.SetFocus(a.Pages)
-			case 2:
+			case "Continue Writing":
 				a.Pages.HidePage("InsertMsgMenu")
 				a.App.SetFocus(a.im.eb)
-			case 3:
+			case "Edit Header":
 				a.Pages.HidePage("InsertMsgMenu")
 				a.App.SetFocus(a.im.eh)
 			}
@@ -60,38 +80,448 @@ func (a *App) InsertMsgMenu() (string, tview.Primitive, bool, bool) {
 	return "InsertMsgMenu", modal, false, false
 }
 
+// sendInsertedMsg saves the message being composed, with confirm_send
+// after showing its summary
+func (a *App) sendInsertedMsg() {
+	if config.Config.ConfirmSend {
+		a.Pages.HidePage("InsertMsgMenu")
+		a.Pages.AddPage(a.ConfirmSendMenu())
+		a.Pages.ShowPage("ConfirmSendMenu")
+		return
+	}
+	a.saveInsertedMsg()
+}
+
+// ConfirmSendMenu modal menu summarizing the message before saving
+func (a *App) ConfirmSendMenu() (string, tview.Primitive, bool, bool) {
+	to := a.im.newMsg.To
+	isNetmail := (*a.im.postArea).GetType() == msgapi.EchoAreaTypeNetmail
+	if isNetmail {
+		to = fmt.Sprintf("%s (%s)", to, a.im.newMsg.ToAddr.String())
+	}
+	modal := NewModalMenu().
+		SetY(6).
+		SetText("Send?").
+		AddText("To:      " + tview.Escape(to)).
+		AddText("Area:    " + tview.Escape((*a.im.postArea).GetName())).
+		AddText("Subject: " + tview.Escape(a.im.newMsg.Subject))
+	if a.im.newMsg.HasAttach() {
+		modal.AddText("Attach:  the file named by the subject")
+	}
+	if sqlArea, ok := (*a.im.postArea).(*msgapi.SQLArea); ok && isNetmail {
+		modal.AddText("Route:   " + tview.Escape(sqlArea.RoutePreview(a.im.newMsg)))
+	}
+	modal.AddButtons([]string{"Send", "Edit", "Cancel"}).
+		SetDoneFunc(func(buttonIndex int) {
+			a.Pages.HidePage("ConfirmSendMenu")
+			a.Pages.RemovePage("ConfirmSendMenu")
+			switch buttonIndex {
+			case 0:
+				a.saveInsertedMsg()
+			case 1:
+				a.App.SetFocus(a.im.eb)
+			default:
+				// nothing is sent, back to the choice of saving the message
+				a.im.next = false
+				a.Pages.ShowPage("InsertMsgMenu")
+			}
+		})
+	return "ConfirmSendMenu", modal, false, true
+}
+
+// saveInsertedMsg saves the message being edited and returns to the reader
+func (a *App) saveInsertedMsg() {
+	//a.im.newMsg.Body = a.im.eb.GetText(false)
+	areaName := (*a.im.postArea).GetName()
+	a.im.newMsg.Body = editor.WrapBody(a.im.buffer.String(), config.GetMaxLineWidth(areaName), config.GetQuoteMargin(areaName))
+	if a.im.newMsgType == newMsgTypeEdit {
+		a.saveEditedMsg()
+		return
+	}
+	if err := msgapi.AttachFile(a.im.newMsg); err != nil {
+		a.sb.SetStatus(err.Error())
+		a.Pages.HidePage("InsertMsgMenu")
+		a.App.SetFocus(a.im.eh)
+		return
+	}
+	if err := (*a.im.postArea).SaveMsg(a.im.newMsg.MakeBody()); err != nil {
+		a.sb.SetStatus(err.Error())
+		a.Pages.HidePage("InsertMsgMenu")
+		a.App.SetFocus(a.im.eb)
+		return
+	}
+	a.stopDraftAutosave()
+	a.discardDraft()
+	a.Pages.HidePage("InsertMsgMenu")
+	a.Pages.RemovePage("InsertMsgMenu")
+	if (*a.im.curArea).GetLast() == 0 && (*a.im.curArea).GetCount() > 0 {
+		// The area was empty, replace its placeholder view with the new message
+		a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-0", (*a.im.curArea).GetName()))
+		a.Pages.AddPage(a.ViewMsg(a.im.curArea, (*a.im.curArea).GetCount()))
+	}
+	a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*a.im.curArea).GetName(), (*a.im.curArea).GetLast()))
+	a.Pages.RemovePage(fmt.Sprintf("InsertMsg-%s", (*a.im.curArea).GetName()))
+	a.App.SetFocus(a.Pages)
+	if a.im.next {
+		a.im.next = false
+		a.im.prev = a.im.newMsg
+		a.sb.SetStatus("Message saved, writing another one")
+		a.composeMsg(a.im.curArea, newMsgTypeAnother)
+	}
+}
+
+// saveEditedMsg stores the corrected message in place and reopens its view
+func (a *App) saveEditedMsg() {
+	name := (*a.im.curArea).GetName()
+	if sqlArea, ok := (*a.im.curArea).(*msgapi.SQLArea); ok {
+		if err := sqlArea.UpdateMsg(a.im.editNum, a.im.newMsg); err != nil {
+			// the correction stays in the editor to save again
+			log.Printf("%v", err)
+			a.sb.SetStatus(err.Error())
+			a.Pages.HidePage("InsertMsgMenu")
+			a.App.SetFocus(a.im.eb)
+			return
+		}
+		a.sb.SetStatus(fmt.Sprintf("Message %d updated", a.im.editNum))
+	}
+	a.Pages.HidePage("InsertMsgMenu")
+	a.Pages.RemovePage("InsertMsgMenu")
+	a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", name, a.im.editNum))
+	a.Pages.AddPage(a.ViewMsg(a.im.curArea, a.im.editNum))
+	a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", name, a.im.editNum))
+	a.Pages.RemovePage(fmt.Sprintf("InsertMsg-%s", name))
+	a.App.SetFocus(a.Pages)
+}
+
+// editMsg opens message msgNum of area in the editor to correct it in place.
+// Only own messages can be edited unless edit.any_author is set.
+func (a *App) editMsg(area *msgapi.AreaPrimitive, msgNum uint32) {
+	sqlArea, ok := (*area).(*msgapi.SQLArea)
+	if !ok || !sqlArea.Capabilities().Has(msgapi.AreaCanEdit) {
+		a.sb.SetStatus("Editing is only available for jnode-sql areas")
+		return
+	}
+	msg, err := sqlArea.GetStoredMsg(msgNum)
+	if err != nil {
+		a.sb.SetStatus(err.Error())
+		return
+	}
+	if !config.Config.Edit.AnyAuthor && !isOwnMsg(msg, (*area).GetName()) {
+		a.sb.SetStatus("Only your own messages can be edited")
+		return
+	}
+	a.im.editNum = msgNum
+	a.im.edited = msg
+	a.Pages.AddPage(a.InsertMsg(area, newMsgTypeEdit))
+	a.Pages.AddPage(a.InsertMsgMenu())
+	a.Pages.SwitchToPage(fmt.Sprintf("InsertMsg-%s", (*area).GetName()))
+}
+
+// isOwnMsg returns true if msg was written from the configured address under
+// the user name or the From name of the area
+func isOwnMsg(msg *msgapi.Message, areaName string) bool {
+	if !config.IsAka(msg.FromAddr) {
+		return false
+	}
+	return utils.NamesEqual(msg.From, config.Config.Username) ||
+		utils.NamesEqual(msg.From, config.GetFromName(areaName))
+}
+
+// readOnlyReason tells why nothing can be posted to area
+func readOnlyReason(area msgapi.AreaPrimitive) string {
+	if err := msgapi.CanPost(area); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s is %s", area.GetName(), msgapi.ErrReadOnly)
+}
+
+// composeMsg opens the message editor, offering to restore a saved draft,
+// unless the area posted to is read only
+func (a *App) composeMsg(area *msgapi.AreaPrimitive, msgType int) {
+	// answers in another area and forwards post to the chosen a.im.postArea,
+	// another message to the area of the one before
+	postArea := area
+	if msgType&(newMsgTypeAnswerNewArea|newMsgTypeForward|newMsgTypeAnother) != 0 {
+		postArea = a.im.postArea
+	}
+	if !(*postArea).Capabilities().Has(msgapi.AreaCanPost) {
+		a.sb.SetStatus("Can not post: " + readOnlyReason(*postArea))
+		return
+	}
+	a.Pages.AddPage(a.InsertMsg(area, msgType))
+	a.Pages.AddPage(a.InsertMsgMenu())
+	a.Pages.SwitchToPage(fmt.Sprintf("InsertMsg-%s", (*area).GetName()))
+	if !config.Config.Drafts.Enabled {
+		return
+	}
+	if d, err := msgapi.LoadDraft((*a.im.postArea).GetName()); err != nil {
+		log.Printf("%v", err)
+	} else if d != nil {
+		a.Pages.AddPage(a.RestoreDraftMenu(d))
+	}
+	a.startDraftAutosave()
+}
+
+// RestoreDraftMenu modal menu offering to restore a saved draft
+func (a *App) RestoreDraftMenu(d *msgapi.Draft) (string, tview.Primitive, bool, bool) {
+	modal := NewModalMenu().
+		SetY(6).
+		SetText(fmt.Sprintf("Restore draft from %s?", d.Saved.Format("2006-01-02 15:04"))).
+		AddButtons([]string{"Restore", "Discard"}).
+		SetDoneFunc(func(buttonIndex int) {
+			a.Pages.HidePage("RestoreDraftMenu")
+			a.Pages.RemovePage("RestoreDraftMenu")
+			if buttonIndex == 0 {
+				a.im.eh.SetInputs(d.From, d.FromAddr, d.To, d.ToAddr, d.Subject)
+				a.im.draftBody = d.Body
+			} else {
+				a.discardDraft()
+			}
+			a.App.SetFocus(a.im.eh)
+		})
+	return "RestoreDraftMenu", modal, true, true
+}
+
+// saveDraft stores the message being composed as a draft of the post area
+func (a *App) saveDraft() {
+	if a.im.eh == nil || a.im.postArea == nil {
+		return
+	}
+	body := a.im.draftBody
+	if a.im.buffer != nil {
+		body = a.im.buffer.String()
+	}
+	in := a.im.eh.sInputs
+	err := msgapi.SaveDraft(&msgapi.Draft{
+		Area:     (*a.im.postArea).GetName(),
+		From:     string(in[0]),
+		FromAddr: string(in[1]),
+		To:       string(in[2]),
+		ToAddr:   string(in[3]),
+		Subject:  string(in[4]),
+		Body:     body,
+	})
+	if err != nil {
+		log.Printf("%v", err)
+	}
+}
+
+// discardDraft removes the draft of the post area
+func (a *App) discardDraft() {
+	if !config.Config.Drafts.Enabled || a.im.newMsgType == newMsgTypeEdit {
+		return
+	}
+	if err := msgapi.DiscardDraft((*a.im.postArea).GetName()); err != nil {
+		log.Printf("%v", err)
+	}
+}
+
+// formatMsg rewraps the whole message being written at the width and quote
+// margin of the post area
+func (a *App) formatMsg() {
+	if a.im.buffer == nil {
+		return
+	}
+	areaName := (*a.im.postArea).GetName()
+	text, changed := editor.FormatBody(a.im.buffer.String(), config.GetMaxLineWidth(areaName), config.GetQuoteMargin(areaName))
+	if changed == 0 {
+		a.sb.SetStatus("Message already formatted")
+		return
+	}
+	line := a.im.eb.Cursor.Y
+	a.im.buffer.Replace(a.im.buffer.Start(), a.im.buffer.End(), text)
+	a.im.eb.Cursor.GotoLoc(editor.Loc{X: 0, Y: min(line, a.im.buffer.NumLines-1)})
+	a.im.eb.Relocate()
+	a.sb.SetStatus(fmt.Sprintf("Message formatted, %d lines changed", changed))
+}
+
+// nextOrigin switches the message being written to the next configured AKA
+func (a *App) nextOrigin() {
+	akas := config.GetAkas()
+	if len(akas) < 2 {
+		a.sb.SetStatus("No AKAs configured")
+		return
+	}
+	cur := types.AddrFromString(string(a.im.eh.sInputs[1]))
+	addr := akas[(slices.IndexFunc(akas, cur.Equal)+1)%len(akas)]
+	a.im.eh.setOrigin(addr)
+	a.im.eh.originSet = true
+	a.setOriginAddr(addr)
+}
+
+// setOriginAddr posts the message being written from addr, updating its
+// origin line if the body is open already
+func (a *App) setOriginAddr(addr *types.FidoAddr) {
+	a.im.newMsg.FromAddr = addr
+	a.sb.SetStatus("Origin address: " + addr.String())
+	if a.im.buffer == nil {
+		return
+	}
+	for y := a.im.buffer.NumLines - 1; y >= 0; y-- {
+		line := a.im.buffer.Line(y)
+		if origin, ok := msgapi.SetOriginAddr(line, addr); ok {
+			a.im.buffer.Replace(editor.Loc{X: 0, Y: y}, editor.Loc{X: len([]rune(line)), Y: y}, origin)
+			return
+		}
+	}
+}
+
+// unsavedMsgArea returns the name of the area of a message still open in
+// the editor, "" if there is none
+func (a *App) unsavedMsgArea() string {
+	if a.im.curArea == nil || a.im.postArea == nil {
+		return ""
+	}
+	if !a.Pages.HasPage(fmt.Sprintf("InsertMsg-%s", (*a.im.curArea).GetName())) {
+		return ""
+	}
+	return (*a.im.postArea).GetName()
+}
+
+// keepUnsavedMsg saves a message still open in the editor as a draft, so
+// quitting never loses it when drafts are enabled
+func (a *App) keepUnsavedMsg() {
+	if a.unsavedMsgArea() == "" {
+		return
+	}
+	a.stopDraftAutosave()
+	if config.Config.Drafts.Enabled && a.im.newMsgType != newMsgTypeEdit {
+		a.saveDraft()
+	}
+}
+
+// startDraftAutosave saves a draft every drafts interval until stopped
+func (a *App) startDraftAutosave() {
+	a.stopDraftAutosave()
+	stop := make(chan struct{})
+	a.im.stopDraft = stop
+	go func() {
+		ticker := time.NewTicker(config.Config.Drafts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				a.App.QueueUpdate(a.saveDraft)
+			}
+		}
+	}()
+}
+
+// stopDraftAutosave stops periodic draft saving
+func (a *App) stopDraftAutosave() {
+	if a.im.stopDraft != nil {
+		close(a.im.stopDraft)
+		a.im.stopDraft = nil
+	}
+}
+
+// forwardSubject prefixes subject with "Fwd:" unless it is already there
+func forwardSubject(subject string) string {
+	if strings.HasPrefix(strings.ToLower(subject), "fwd:") {
+		return subject
+	}
+	return "Fwd: " + subject
+}
+
+// replySubject prefixes subject with "Re:" unless it is already there
+func replySubject(subject string) string {
+	if strings.HasPrefix(strings.ToLower(subject), "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
+
 // InsertMsg widget
 func (a *App) InsertMsg(area *msgapi.AreaPrimitive, msgType int) (string, tview.Primitive, bool, bool) {
 	var omsg *msgapi.Message
+	quote := a.im.quote
+	// false for a private answer to echomail whose author address may lack
+	// the point
+	authorKnown := true
+	// true for echomail forwarded into netmail
+	forwardEcho := false
 	a.im.curArea = area
 	a.im.newMsgType = msgType
-	if a.im.newMsgType == 0 || a.im.newMsgType == newMsgTypeAnswer {
+	a.im.buffer = nil
+	a.im.draftBody = ""
+	if a.im.newMsgType == 0 || a.im.newMsgType == newMsgTypeAnswer || a.im.newMsgType == newMsgTypeEdit {
 		a.im.postArea = area
 	}
-	a.im.newMsg = &msgapi.Message{From: config.Config.Username, FromAddr: config.Config.Address, AreaObject: a.im.postArea}
-	a.im.newMsg.Kludges = make(map[string]string)
-	a.im.newMsg.Kludges["PID:"] = config.PID
-	a.im.newMsg.Kludges["CHRS:"] = config.Config.Chrs.Default
-	if (*a.im.postArea).GetChrs() != "" {
-		a.im.newMsg.Kludges["CHRS:"] = (*a.im.postArea).GetChrs()
+	if a.im.newMsgType == newMsgTypeEdit {
+		a.im.newMsg = a.im.edited
+		a.im.newMsg.AreaObject = a.im.postArea
+	} else {
+		a.im.newMsg = &msgapi.Message{From: config.GetFromName((*a.im.postArea).GetName()), FromAddr: config.Config.Address, AreaObject: a.im.postArea}
+		a.im.newMsg.Kludges = make(map[string]string)
+		a.im.newMsg.Kludges["PID:"] = config.PID
+		a.im.newMsg.Kludges["CHRS:"] = config.Config.Chrs.Default
+		if (*a.im.postArea).GetChrs() != "" {
+			a.im.newMsg.Kludges["CHRS:"] = (*a.im.postArea).GetChrs()
+		}
 	}
 	if (*a.im.postArea).GetType() != msgapi.EchoAreaTypeNetmail && (a.im.newMsgType == 0 || a.im.newMsgType == newMsgTypeForward) {
-		a.im.newMsg.To = "All"
+		a.im.newMsg.To = config.GetEchoDefaultTo()
+	}
+	if a.im.newMsgType == newMsgTypeAnother {
+		// the recipient and charset carry over, subject and body start empty
+		a.im.newMsg.To, a.im.newMsg.ToAddr = a.im.prev.To, a.im.prev.ToAddr
+		if chrs := a.im.prev.Kludges["CHRS:"]; chrs != "" {
+			a.im.newMsg.Kludges["CHRS:"] = chrs
+		}
 	}
 	if (a.im.newMsgType&newMsgTypeAnswer) != 0 || (a.im.newMsgType&newMsgTypeAnswerNewArea) != 0 {
 		omsg, _ = (*area).GetMsg((*a.im.curArea).GetLast())
 		a.im.newMsg.To = omsg.From
 		a.im.newMsg.ToAddr = omsg.FromAddr
-		a.im.newMsg.Kludges["REPLY:"] = omsg.Kludges["MSGID:"]
 		a.im.newMsg.Subject = omsg.Subject
+		if (*a.im.postArea).GetType() == msgapi.EchoAreaTypeNetmail {
+			// gated mail is answered through the gate named by REPLYTO
+			a.im.newMsg.To, a.im.newMsg.ToAddr = omsg.ReplyTarget()
+			if (*area).GetType() != msgapi.EchoAreaTypeNetmail {
+				a.im.newMsg.Subject = replySubject(omsg.Subject)
+				if omsg.Kludges["REPLYTO"] == "" {
+					a.im.newMsg.ToAddr, authorKnown = omsg.AuthorAddr()
+				}
+			}
+		}
+		a.im.newMsg.Kludges["REPLY:"] = omsg.Kludges["MSGID:"]
 	} else if (a.im.newMsgType & newMsgTypeForward) != 0 {
 		omsg, _ = (*area).GetMsg((*a.im.curArea).GetLast())
 		omsg.AreaObject = a.im.curArea
-		a.im.newMsg.Subject = omsg.Subject
+		a.im.newMsg.Subject = forwardSubject(omsg.Subject)
+		if (*a.im.postArea).GetChrs() == "" {
+			if chrs := omsg.GetChrsKludge(); chrs != "" {
+				a.im.newMsg.Kludges["CHRS:"] = chrs
+			}
+		}
+		// a forward into netmail starts without recipient, the address
+		// entered picks the origin and the route like for any netmail
+		forwardEcho = (*a.im.postArea).GetType() == msgapi.EchoAreaTypeNetmail &&
+			(*area).GetType() != msgapi.EchoAreaTypeNetmail
+	}
+	if a.im.newMsgType != newMsgTypeEdit {
+		a.im.newMsg.FromAddr = config.GetOriginAddr(a.im.newMsg.ToAddr)
+	}
+	if len(config.GetAkas()) > 1 {
+		a.sb.SetStatus("Origin address: " + a.im.newMsg.FromAddr.String())
+	}
+	if !authorKnown {
+		a.sb.SetStatus(fmt.Sprintf("Check the address of %s, the echomail may be from a point", a.im.newMsg.To))
+	}
+	if forwardEcho {
+		a.sb.SetStatus(fmt.Sprintf("Forwarding echomail from %s as netmail, enter the recipient and address", (*area).GetName()))
 	}
 	_, boxBg, _ := config.GetElementStyle(config.ColorAreaMessageHeader, config.ColorElementWindow).Decompose()
 	mhStyle := config.GetElementStyle(config.ColorAreaMessageHeader, config.ColorElementTitle)
 	a.im.eh = NewEditHeader(a, a.im.newMsg)
+	// an edited message keeps its origin address
+	a.im.eh.originSet = a.im.newMsgType == newMsgTypeEdit
+	if !authorKnown {
+		// start at the destination address to complete it
+		a.im.eh.sIndex = 3
+	}
 	a.im.eh.SetBackgroundColor(boxBg)
 	a.im.eh.SetBorder(true).
 		SetTitle(config.FormatTextWithStyle(" "+(*a.im.postArea).GetName()+" ", mhStyle)).
@@ -101,6 +531,26 @@ func (a *App) InsertMsg(area *msgapi.AreaPrimitive, msgType int) (string, tview.
 	a.im.eb = editor.NewView(editor.NewBufferFromString(""))
 	//a.im.eb.SetBackgroundColor()
 	//	a.im.eb = NewEditBody().
+	a.im.eb.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if keymap.Match(KeyActionMessageInfo, event) {
+			a.Pages.AddPage(a.MessageInfo(a.im.newMsg))
+			return nil
+		}
+		if keymap.Match(KeyActionFormat, event) {
+			a.formatMsg()
+			return nil
+		}
+		if keymap.Match(KeyActionNextAka, event) {
+			a.nextOrigin()
+			return nil
+		}
+		if keymap.Match(KeyActionSaveNew, event) && a.im.newMsgType != newMsgTypeEdit {
+			a.im.next = true
+			a.sendInsertedMsg()
+			return nil
+		}
+		return event
+	})
 	a.im.eb.SetDoneFunc(func() {
 		a.Pages.ShowPage("InsertMsgMenu")
 		//			//log.Printf("%q",a.App.GetFocus())
@@ -117,12 +567,26 @@ func (a *App) InsertMsg(area *msgapi.AreaPrimitive, msgType int) (string, tview.
 		*/
 		var mv string
 		//var p int
-		if a.im.newMsgType == 0 {
+		if a.im.draftBody != "" {
+			mv = a.im.draftBody
+			a.im.draftBody = ""
+		} else if a.im.newMsgType == 0 || a.im.newMsgType == newMsgTypeAnother {
 			mv = a.im.newMsg.ToEditNewView()
 		} else if a.im.newMsgType == newMsgTypeAnswer || a.im.newMsgType == newMsgTypeAnswerNewArea {
-			mv = a.im.newMsg.ToEditAnswerView(omsg)
+			// Quoting adds a prefix to the original lines, rewrap them to fit
+			if quote != nil {
+				mv = a.im.newMsg.ToEditAnswerQuoting(omsg, quote)
+			} else {
+				mv = a.im.newMsg.ToEditAnswerView(omsg)
+			}
+			mv = editor.WrapBody(mv, 0, config.GetQuoteMargin((*a.im.postArea).GetName()))
+			if line := omsg.ReplyAddrLine(); line != "" && (*a.im.postArea).GetType() == msgapi.EchoAreaTypeNetmail {
+				mv = line + "\n\n" + mv
+			}
 		} else if a.im.newMsgType == newMsgTypeForward {
 			mv = a.im.newMsg.ToEditForwardView(omsg)
+		} else if a.im.newMsgType == newMsgTypeEdit {
+			mv = a.im.newMsg.Body
 		}
 		a.im.buffer = editor.NewBufferFromString(mv)
 		//p = p
@@ -0,0 +1,239 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/askovpen/gossiped/pkg/msgapi"
+	"github.com/askovpen/gossiped/pkg/types"
+	"github.com/askovpen/gossiped/pkg/ui/editor"
+	. "github.com/franela/goblin"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestSaveAndNew(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check saving and writing another message", func() {
+		savedAreas, savedAddress, savedTemplate := msgapi.Areas, config.Config.Address, config.Template
+		savedDrafts := config.Config.Drafts.Enabled
+		g.After(func() {
+			msgapi.Areas, config.Config.Address, config.Template = savedAreas, savedAddress, savedTemplate
+			config.Config.Drafts.Enabled = savedDrafts
+		})
+		g.It("check the next message keeps To and charset", func() {
+			config.Config.Address = types.AddrFromString("2:5020/9696.1")
+			config.Template = []string{"Hello @pseudo!", "@Position"}
+			config.Config.Drafts.Enabled = false
+			a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+			a.sb = NewStatusBar(a)
+			msgapi.Areas = []msgapi.AreaPrimitive{msgapi.NewMemoryArea("ru.golang", msgapi.EchoAreaTypeEcho)}
+			area := msgapi.Areas[0]
+			a.composeMsg(&msgapi.Areas[0], 0)
+			a.im.newMsg.Kludges["CHRS:"] = "CP866 2"
+			a.im.eh.done([5][]rune{[]rune("SysOp"), []rune("2:5020/9696.1"), []rune("Vasily Pupkin"),
+				[]rune(""), []rune("First")})
+			a.im.next = true
+			a.sendInsertedMsg()
+			g.Assert(area.GetCount()).Equal(uint32(1))
+			saved, err := area.GetMsg(1)
+			g.Assert(err).IsNil()
+			g.Assert(saved.Subject).Equal("First")
+			g.Assert(a.im.newMsgType).Equal(newMsgTypeAnother)
+			g.Assert(a.im.newMsg.To).Equal("Vasily Pupkin")
+			g.Assert(a.im.newMsg.Subject).Equal("")
+			g.Assert(a.im.newMsg.Kludges["CHRS:"]).Equal("CP866 2")
+			front, _ := a.Pages.GetFrontPage()
+			g.Assert(front).Equal("InsertMsg-ru.golang")
+		})
+	})
+}
+
+func TestForwardToNetmail(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check forwarding echomail into netmail", func() {
+		savedAreas, savedAddress, savedTemplate := msgapi.Areas, config.Config.Address, config.Template
+		savedDrafts := config.Config.Drafts.Enabled
+		g.After(func() {
+			msgapi.Areas, config.Config.Address, config.Template = savedAreas, savedAddress, savedTemplate
+			config.Config.Drafts.Enabled = savedDrafts
+		})
+		g.It("check the forward is a netmail in the original charset", func() {
+			config.Config.Address = types.AddrFromString("2:5020/9696")
+			config.Template = []string{"@Forward * Forwarded from @OEcho", "@Message"}
+			config.Config.Drafts.Enabled = false
+			a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+			a.sb = NewStatusBar(a)
+			msgapi.Areas = []msgapi.AreaPrimitive{
+				msgapi.NewMemoryArea("ru.golang", msgapi.EchoAreaTypeEcho),
+				msgapi.NewMemoryArea("netmail", msgapi.EchoAreaTypeNetmail),
+			}
+			echo, netmail := &msgapi.Areas[0], &msgapi.Areas[1]
+			orig := &msgapi.Message{AreaObject: echo, From: "Vasily Pupkin", To: "All", Subject: "Hello",
+				FromAddr: types.AddrFromString("2:5030/1"), ToAddr: &types.FidoAddr{},
+				Body: "Hello, All\x0d", Kludges: map[string]string{"CHRS": "CP866"}}
+			g.Assert((*echo).SaveMsg(orig)).IsNil()
+			(*echo).SetLast(1)
+			a.im.postArea = netmail
+			a.composeMsg(echo, newMsgTypeForward)
+			g.Assert(a.im.newMsg.Subject).Equal("Fwd: Hello")
+			g.Assert(a.im.newMsg.To).Equal("")
+			g.Assert(a.im.newMsg.Kludges["CHRS:"]).Equal("CP866 2")
+			a.im.eh.done([5][]rune{[]rune("SysOp"), []rune("2:5020/9696"), []rune("Ivan Petrov"),
+				[]rune("2:5030/2"), []rune("Fwd: Hello")})
+			a.sendInsertedMsg()
+			g.Assert((*netmail).GetCount()).Equal(uint32(1))
+			saved, err := (*netmail).GetMsg(1)
+			g.Assert(err).IsNil()
+			g.Assert(saved.To).Equal("Ivan Petrov")
+			g.Assert(saved.Kludges["INTL"]).Equal("2:5030/2 2:5020/9696")
+		})
+	})
+}
+
+func TestConfirmSend(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check the confirmation before sending", func() {
+		savedAreas, savedAddress, savedTemplate := msgapi.Areas, config.Config.Address, config.Template
+		savedDrafts, savedConfirm := config.Config.Drafts.Enabled, config.Config.ConfirmSend
+		g.After(func() {
+			msgapi.Areas, config.Config.Address, config.Template = savedAreas, savedAddress, savedTemplate
+			config.Config.Drafts.Enabled, config.Config.ConfirmSend = savedDrafts, savedConfirm
+		})
+		g.It("check Cancel sends nothing and returns to the save menu", func() {
+			config.Config.Address = types.AddrFromString("2:5020/9696")
+			config.Template = []string{"@Position"}
+			config.Config.Drafts.Enabled = false
+			config.Config.ConfirmSend = true
+			a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+			a.sb = NewStatusBar(a)
+			msgapi.Areas = []msgapi.AreaPrimitive{msgapi.NewMemoryArea("ru.golang", msgapi.EchoAreaTypeEcho)}
+			area := msgapi.Areas[0]
+			a.composeMsg(&msgapi.Areas[0], 0)
+			a.im.eh.done([5][]rune{[]rune("SysOp"), []rune("2:5020/9696"), []rune("All"),
+				[]rune(""), []rune("Hello")})
+			a.im.next = true
+			a.sendInsertedMsg()
+			g.Assert(a.Pages.HasPage("ConfirmSendMenu")).IsTrue()
+			_, confirm, _, _ := a.ConfirmSendMenu()
+			confirm.(*ModalMenu).done(2)
+			g.Assert(area.GetCount()).Equal(uint32(0))
+			g.Assert(a.im.next).IsFalse()
+			g.Assert(a.Pages.HasPage("ConfirmSendMenu")).IsFalse()
+			front, _ := a.Pages.GetFrontPage()
+			g.Assert(front).Equal("InsertMsgMenu")
+		})
+	})
+}
+
+func TestConfirmInterrupt(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check Ctrl-C with a message open in the editor", func() {
+		savedAreas, savedAddress, savedTemplate := msgapi.Areas, config.Config.Address, config.Template
+		savedDrafts, savedConfirm := config.Config.Drafts.Enabled, config.Config.Drafts.ConfirmQuit
+		g.After(func() {
+			msgapi.Areas, config.Config.Address, config.Template = savedAreas, savedAddress, savedTemplate
+			config.Config.Drafts.Enabled, config.Config.Drafts.ConfirmQuit = savedDrafts, savedConfirm
+		})
+		ctrlC := tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl)
+		g.It("check the first Ctrl-C asks and the second one quits", func() {
+			config.Config.Address = types.AddrFromString("2:5020/9696")
+			config.Template = []string{"@Position"}
+			config.Config.Drafts.Enabled = false
+			config.Config.Drafts.ConfirmQuit = nil
+			a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+			a.sb = NewStatusBar(a)
+			g.Assert(a.confirmInterrupt(ctrlC) == ctrlC).IsTrue()
+			msgapi.Areas = []msgapi.AreaPrimitive{msgapi.NewMemoryArea("ru.golang", msgapi.EchoAreaTypeEcho)}
+			a.composeMsg(&msgapi.Areas[0], 0)
+			a.App.SetFocus(a.im.eb)
+			g.Assert(a.confirmInterrupt(ctrlC) == nil).IsTrue()
+			front, _ := a.Pages.GetFrontPage()
+			g.Assert(front).Equal("QuitUnsaved")
+			g.Assert(a.confirmInterrupt(ctrlC) == ctrlC).IsTrue()
+			_, modal, _, _ := a.QuitUnsaved("ru.golang")
+			a.Pages.RemovePage("QuitUnsaved")
+			a.Pages.AddPage("QuitUnsaved", modal, true, true)
+			modal.(*ModalMenu).done(1)
+			g.Assert(a.Pages.HasPage("QuitUnsaved")).IsFalse()
+			g.Assert(a.App.GetFocus() == a.im.eb).IsTrue()
+		})
+		g.It("check confirm_quit off lets Ctrl-C through", func() {
+			off := false
+			config.Config.Drafts.ConfirmQuit = &off
+			a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+			a.sb = NewStatusBar(a)
+			msgapi.Areas = []msgapi.AreaPrimitive{msgapi.NewMemoryArea("ru.golang", msgapi.EchoAreaTypeEcho)}
+			a.composeMsg(&msgapi.Areas[0], 0)
+			g.Assert(a.confirmInterrupt(ctrlC) == ctrlC).IsTrue()
+		})
+	})
+}
+
+func TestFormatMsg(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check formatting the message being written", func() {
+		savedAreas, savedAddress, savedTemplate := msgapi.Areas, config.Config.Address, config.Template
+		savedDrafts := config.Config.Drafts.Enabled
+		g.After(func() {
+			msgapi.Areas, config.Config.Address, config.Template = savedAreas, savedAddress, savedTemplate
+			config.Config.Drafts.Enabled = savedDrafts
+		})
+		g.It("check the text is replaced in the open buffer", func() {
+			config.Config.Address = types.AddrFromString("2:5020/9696")
+			config.Template = []string{}
+			config.Config.Drafts.Enabled = false
+			a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+			a.sb = NewStatusBar(a)
+			msgapi.Areas = []msgapi.AreaPrimitive{msgapi.NewMemoryArea("ru.golang", msgapi.EchoAreaTypeEcho)}
+			a.composeMsg(&msgapi.Areas[0], 0)
+			a.im.eh.done([5][]rune{[]rune("SysOp"), []rune("2:5020/9696"), []rune("All"),
+				[]rune(""), []rune("Hello")})
+			buffer := a.im.buffer
+			buffer.Replace(buffer.Start(), buffer.End(), strings.Repeat("word ", 30))
+			buffer.IsModified = false
+			a.formatMsg()
+			g.Assert(a.im.buffer == buffer).IsTrue()
+			g.Assert(buffer.IsModified).IsTrue()
+			g.Assert(buffer.NumLines > 1).IsTrue()
+			for y := 0; y < buffer.NumLines; y++ {
+				g.Assert(len(buffer.Line(y)) <= 79).IsTrue()
+			}
+		})
+	})
+}
+
+func TestSaveEditedMsg(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check saving a message edited in place", func() {
+		g.It("check a failed update keeps the correction in the editor", func() {
+			// no tables, every update fails
+			db, err := gorm.Open(sqlite.Dialector{DriverName: "sqlite", DSN: "file::memory:"},
+				&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+			g.Assert(err).IsNil()
+			sqlDB, _ := db.DB()
+			defer sqlDB.Close()
+			var area msgapi.AreaPrimitive = msgapi.NewSQLArea(db, database.Echoarea{ID: 1, Name: "su.general"})
+			a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+			a.sb = NewStatusBar(a)
+			a.im.curArea, a.im.postArea = &area, &area
+			a.im.editNum = 1
+			a.im.newMsg = &msgapi.Message{From: "SysOp", To: "All", Subject: "Fixed", Body: "Fixed",
+				Kludges: map[string]string{}}
+			a.im.eb = editor.NewView(editor.NewBufferFromString("Fixed"))
+			a.Pages.AddPage("InsertMsg-su.general", a.im.eb, true, true)
+			a.Pages.AddPage("InsertMsgMenu", tview.NewBox(), false, true)
+			a.saveEditedMsg()
+			g.Assert(a.Pages.HasPage("InsertMsg-su.general")).IsTrue()
+			g.Assert(a.Pages.HasPage("ViewMsg-su.general-1")).IsFalse()
+			front, _ := a.Pages.GetFrontPage()
+			g.Assert(front).Equal("InsertMsg-su.general")
+			g.Assert(a.App.GetFocus() == a.im.eb).IsTrue()
+		})
+	})
+}
@@ -0,0 +1,228 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/askovpen/gossiped/pkg/ui/editor"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Key actions which can be rebound in the keys config section
+const (
+	KeyActionQuit          = "quit"
+	KeyActionHelp          = "help"
+	KeyActionCancel        = "cancel"
+	KeyActionOpenArea      = "open-area"
+	KeyActionSubscriptions = "subscriptions"
+	KeyActionNext          = "next"
+	KeyActionPrev          = "prev"
+	KeyActionFirst         = "first"
+	KeyActionLast          = "last"
+	KeyActionNew           = "new"
+	KeyActionReply         = "reply"
+	KeyActionReplyArea     = "reply-area"
+	KeyActionReplyNetmail  = "reply-netmail"
+	KeyActionForward       = "forward"
+	KeyActionEdit          = "edit"
+	KeyActionDelete        = "delete"
+	KeyActionKludges       = "kludges"
+	KeyActionMessageList   = "message-list"
+	KeyActionHeader        = "header"
+	KeyActionNodelist      = "nodelist"
+	KeyActionAttach        = "attach"
+	KeyActionFixEncoding   = "fix-encoding"
+	KeyActionSyncAreas     = "sync-areas"
+	KeyActionGlobalSearch  = "global-search"
+	KeyActionMessageInfo   = "message-info"
+	KeyActionCopyMessage   = "copy-message"
+	KeyActionMoveMessage   = "move-message"
+	KeyActionCopyText      = "copy-text"
+	KeyActionCopyQuoted    = "copy-quoted"
+	KeyActionRawMessage    = "raw-message"
+	KeyActionNextUnread    = "next-unread"
+	KeyActionTag           = "tag"
+	KeyActionMarkRead      = "mark-read"
+	KeyActionBookmark      = "bookmark"
+	KeyActionBookmarks     = "bookmarks"
+	KeyActionExpand        = "expand"
+	KeyActionArchiveSent   = "archive-netmail"
+	KeyActionLinkCounts    = "link-counts"
+	KeyActionFormat        = "format-message"
+	KeyActionNextAka       = "next-aka"
+	KeyActionRebuildCounts = "rebuild-counts"
+	KeyActionColorPreview  = "color-preview"
+	KeyActionRequeue       = "requeue"
+	KeyActionHistoryBack   = "history-back"
+	KeyActionHistoryFwd    = "history-forward"
+	KeyActionReceipt       = "receipt"
+	KeyActionUnrouted      = "unrouted-netmail"
+	KeyActionReroute       = "reroute"
+	KeyActionLastReads     = "lastreads"
+	KeyActionResetUser     = "reset-user"
+	KeyActionSaveNew       = "save-new"
+	KeyActionAreaTypes     = "area-types"
+	KeyActionReplyMarked   = "reply-selection"
+	KeyActionReadChrs      = "charset"
+	KeyActionCleanQueue    = "clean-queue"
+)
+
+// defaultKeys maps actions to comma separated key combinations
+var defaultKeys = map[string]string{
+	KeyActionQuit:          "Esc",
+	KeyActionHelp:          "F1",
+	KeyActionCancel:        "Esc",
+	KeyActionOpenArea:      "Right,Enter",
+	KeyActionSubscriptions: "CtrlS",
+	KeyActionNext:          "Right",
+	KeyActionPrev:          "Left",
+	KeyActionFirst:         "<",
+	KeyActionLast:          ">",
+	KeyActionNew:           "Insert,CtrlI",
+	KeyActionReply:         "CtrlQ,F3,q",
+	KeyActionReplyArea:     "CtrlN,Alt-n",
+	KeyActionReplyNetmail:  "Alt-v",
+	KeyActionForward:       "CtrlF,Alt-f",
+	KeyActionEdit:          "F4",
+	KeyActionDelete:        "Delete",
+	KeyActionKludges:       "CtrlK,Alt-k",
+	KeyActionMessageList:   "CtrlL,l",
+	KeyActionHeader:        "CtrlG,g",
+	KeyActionNodelist:      "Tab,F2",
+	KeyActionAttach:        "Alt-a",
+	KeyActionFixEncoding:   "CtrlE,Alt-e",
+	KeyActionSyncAreas:     "CtrlR",
+	KeyActionGlobalSearch:  "CtrlF",
+	KeyActionMessageInfo:   "CtrlO,Alt-i",
+	KeyActionCopyMessage:   "Alt-c",
+	KeyActionMoveMessage:   "Alt-m",
+	KeyActionCopyText:      "Alt-y",
+	KeyActionCopyQuoted:    "Alt-Y",
+	KeyActionRawMessage:    "Alt-r",
+	KeyActionNextUnread:    "n",
+	KeyActionTag:           "Space",
+	KeyActionMarkRead:      "Alt-s",
+	KeyActionBookmark:      "Alt-b",
+	KeyActionBookmarks:     "CtrlB",
+	KeyActionExpand:        "Alt-x",
+	KeyActionArchiveSent:   "CtrlA",
+	KeyActionLinkCounts:    "Alt-l",
+	KeyActionFormat:        "Alt-w",
+	KeyActionNextAka:       "Alt-o",
+	KeyActionRebuildCounts: "Alt-u",
+	KeyActionColorPreview:  "Alt-p",
+	KeyActionRequeue:       "Alt-q",
+	KeyActionHistoryBack:   "Alt-Left",
+	KeyActionHistoryFwd:    "Alt-Right",
+	KeyActionReceipt:       "Alt-d",
+	KeyActionUnrouted:      "Alt-h",
+	KeyActionReroute:       "Alt-t",
+	KeyActionLastReads:     "Alt-g",
+	KeyActionResetUser:     "Alt-z",
+	KeyActionSaveNew:       "CtrlS",
+	KeyActionAreaTypes:     "Alt-j",
+	KeyActionReplyMarked:   "Alt-j",
+	KeyActionReadChrs:      "CtrlT",
+	KeyActionCleanQueue:    "Alt-Q",
+}
+
+// keyBinding holds a single key combination
+type keyBinding struct {
+	key       tcell.Key
+	modifiers tcell.ModMask
+	r         rune
+	name      string
+}
+
+// keyMap associates actions with key combinations
+type keyMap map[string][]keyBinding
+
+var keymap = mustKeyMap(nil)
+
+// InitKeymap builds the keymap from defaults overridden by the keys config section
+func InitKeymap(keys map[string]string) error {
+	km, err := newKeyMap(keys)
+	if err != nil {
+		return err
+	}
+	keymap = km
+	return nil
+}
+
+func mustKeyMap(keys map[string]string) keyMap {
+	km, err := newKeyMap(keys)
+	if err != nil {
+		panic(err)
+	}
+	return km
+}
+
+func newKeyMap(keys map[string]string) (keyMap, error) {
+	km := make(keyMap)
+	for action, combos := range defaultKeys {
+		if err := km.bind(action, combos); err != nil {
+			return nil, err
+		}
+	}
+	for action, combos := range keys {
+		if _, ok := defaultKeys[action]; !ok {
+			return nil, fmt.Errorf("unknown key action '%s'", action)
+		}
+		if err := km.bind(action, combos); err != nil {
+			return nil, err
+		}
+	}
+	return km, nil
+}
+
+// bind replaces key combinations of the action
+func (km keyMap) bind(action string, combos string) error {
+	var bindings []keyBinding
+	for _, combo := range strings.Split(combos, ",") {
+		combo = strings.TrimSpace(combo)
+		if combo == "" {
+			continue
+		}
+		key, modifiers, r, ok := editor.FindKey(combo)
+		if !ok {
+			return fmt.Errorf("unknown key '%s' for action '%s'", combo, action)
+		}
+		bindings = append(bindings, keyBinding{key, modifiers, r, combo})
+	}
+	km[action] = bindings
+	return nil
+}
+
+// Match returns true if event is bound to the action
+func (km keyMap) Match(action string, event *tcell.EventKey) bool {
+	for _, b := range km[action] {
+		if b.match(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Name returns the first key combination bound to the action as written in
+// the keys config section, "" if the action has no key
+func (km keyMap) Name(action string) string {
+	if len(km[action]) == 0 {
+		return ""
+	}
+	return km[action][0].name
+}
+
+func (b keyBinding) match(event *tcell.EventKey) bool {
+	if b.key == tcell.KeyRune {
+		return event.Key() == tcell.KeyRune && event.Rune() == b.r &&
+			event.Modifiers()&tcell.ModAlt == b.modifiers&tcell.ModAlt
+	}
+	if event.Key() != b.key {
+		return false
+	}
+	// control codes already carry the Ctrl modifier in the key itself
+	if b.key < tcell.KeyRune {
+		return true
+	}
+	return event.Modifiers() == b.modifiers
+}
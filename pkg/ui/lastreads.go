@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/rivo/tview"
+)
+
+// showLastReads lists the lastread positions of all users in areaName, or
+// in every area when it is "", for operators with lastread.admin set. leave
+// is called when the window is closed.
+func (a *App) showLastReads(areaName string, leave func()) {
+	if !config.Config.LastRead.Admin {
+		a.sb.SetStatus("Positions of other users are hidden, see lastread.admin in the config")
+		return
+	}
+	if !database.IsLastReadEnabled() {
+		a.sb.SetStatus("Lastread positions need the lastread database")
+		return
+	}
+	modal := NewModalLastReads(areaName)
+	modal.SetStatusFunc(a.sb.SetStatus).
+		SetResetUserFunc(func(user string) {
+			a.confirmResetLastReads(modal, user)
+		}).
+		SetDoneFunc(func() {
+			a.Pages.HidePage("LastReadsModal")
+			a.Pages.RemovePage("LastReadsModal")
+			leave()
+		})
+	a.Pages.AddPage("LastReadsModal", modal, true, true)
+	a.Pages.ShowPage("LastReadsModal")
+}
+
+// confirmResetLastReads asks before removing every lastread position of user
+func (a *App) confirmResetLastReads(lastReads *ModalLastReads, user string) {
+	modal := NewModalMenu().
+		SetText(fmt.Sprintf("Reset all lastread positions of %s?", tview.Escape(user))).
+		AddText("Every area of the user starts over as unread").
+		AddButtons([]string{"Yes", "No"}).
+		SetDoneFunc(func(buttonIndex int) {
+			a.Pages.HidePage("ResetLastReadsModal")
+			a.Pages.RemovePage("ResetLastReadsModal")
+			if buttonIndex == 0 {
+				if err := database.DeleteAllLastReadsForUser(user); err != nil {
+					a.sb.SetStatus(err.Error())
+				} else {
+					a.sb.SetStatus(fmt.Sprintf("Lastread positions of %s reset", user))
+				}
+				lastReads.Reload()
+			}
+			a.App.SetFocus(lastReads)
+		})
+	a.Pages.AddPage("ResetLastReadsModal", modal, true, true)
+	a.Pages.ShowPage("ResetLastReadsModal")
+}
+
+// checkLastRead shows in the status bar whether lastread positions are
+// saved, warning once per session when the lastread database starts
+// failing; reading goes on with the positions kept in memory
+func (a *App) checkLastRead() {
+	if !database.IsLastReadEnabled() {
+		return
+	}
+	err := database.LastReadFailure()
+	if err == nil {
+		if a.sb.Warning() != "" {
+			a.sb.SetWarning("")
+			a.sb.SetStatus("Lastread positions are saved again")
+		}
+		return
+	}
+	a.sb.SetWarning("LASTREAD UNSAVED")
+	if a.lastReadWarned {
+		return
+	}
+	a.lastReadWarned = true
+	modal := NewModalMenu().
+		SetText("Lastread positions can not be saved").
+		AddText(tview.Escape(err.Error())).
+		AddText("They are kept until gossipEd exits, saving is tried again as you read").
+		AddButtons([]string{"OK"}).
+		SetDoneFunc(func(buttonIndex int) {
+			a.Pages.HidePage("LastReadWarningModal")
+			a.Pages.RemovePage("LastReadWarningModal")
+			a.App.SetFocus(a.Pages)
+		})
+	// the view of the message read is switched to after this returns
+	go a.App.QueueUpdateDraw(func() {
+		a.Pages.AddPage("LastReadWarningModal", modal, true, true)
+		a.Pages.ShowPage("LastReadWarningModal")
+	})
+}
@@ -0,0 +1,204 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// attachEntry is a row of the file attach window
+type attachEntry struct {
+	name string
+	dir  bool
+	size int64
+}
+
+// ModalAttach is a window picking the file of a netmail file attach
+type ModalAttach struct {
+	*tview.Box
+	table   *tview.Table
+	frame   *tview.Frame
+	dir     string
+	entries []attachEntry
+	done    func(path string)
+}
+
+// NewModalAttach returns a new file picker starting in the directory of
+// start, or in the working directory if start is not an existing path.
+func NewModalAttach(start string) *ModalAttach {
+	_, defBg, _ := config.StyleDefault.Decompose()
+	m := &ModalAttach{
+		Box: tview.NewBox().SetBackgroundColor(defBg),
+	}
+	borderFg, _, borderAttr := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementBorder).Decompose()
+	selectionStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementSelection)
+	m.table = tview.NewTable().
+		SetBordersColor(borderFg).
+		SetSelectable(true, false).
+		SetSelectedStyle(selectionStyle)
+	m.frame = tview.NewFrame(m.table).SetBorders(0, 0, 1, 0, 0, 0)
+	m.frame.SetBackgroundColor(defBg)
+	m.table.SetBackgroundColor(defBg)
+	m.frame.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderAttributes(borderAttr).
+		SetBorderColor(borderFg).
+		SetBorderPadding(0, 0, 1, 1)
+	m.open(attachStartDir(start))
+	return m
+}
+
+// attachStartDir returns the directory the picker opens in
+func attachStartDir(start string) string {
+	if start != "" {
+		if fi, err := os.Stat(start); err == nil {
+			if !fi.IsDir() {
+				start = filepath.Dir(start)
+			}
+			if abs, err := filepath.Abs(start); err == nil {
+				return abs
+			}
+		}
+	}
+	if wd, err := os.Getwd(); err == nil {
+		return wd
+	}
+	return string(filepath.Separator)
+}
+
+// readAttachDir lists dir with the parent first, then directories, then files
+func readAttachDir(dir string) ([]attachEntry, error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []attachEntry
+	if filepath.Dir(dir) != dir {
+		entries = append(entries, attachEntry{name: "..", dir: true})
+	}
+	for _, de := range des {
+		fi, err := de.Info()
+		if err != nil {
+			continue
+		}
+		if fi.IsDir() {
+			entries = append(entries, attachEntry{name: de.Name(), dir: true})
+		} else if fi.Mode().IsRegular() {
+			entries = append(entries, attachEntry{name: de.Name(), size: fi.Size()})
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b attachEntry) int {
+		switch {
+		case a.dir == b.dir:
+			return 0
+		case a.dir:
+			return -1
+		}
+		return 1
+	})
+	return entries, nil
+}
+
+// open shows the contents of dir, staying in the current directory if it
+// can not be read
+func (m *ModalAttach) open(dir string) {
+	entries, err := readAttachDir(dir)
+	if err != nil {
+		log.Printf("Error reading %s: %v", dir, err)
+		if m.entries != nil {
+			return
+		}
+	}
+	m.dir = dir
+	m.entries = entries
+	itemStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementItem)
+	fgItem, bgItem, attrItem := itemStyle.Decompose()
+	m.table.Clear()
+	for i, e := range m.entries {
+		name, size := e.name, ""
+		if e.dir {
+			name += string(filepath.Separator)
+		} else {
+			size = strconv.FormatInt(e.size, 10)
+		}
+		m.table.SetCell(i, 0, tview.NewTableCell(" "+tview.Escape(name)).
+			SetExpansion(1).
+			SetTextColor(fgItem).SetBackgroundColor(bgItem).SetAttributes(attrItem))
+		m.table.SetCell(i, 1, tview.NewTableCell(size).
+			SetAlign(tview.AlignRight).
+			SetTextColor(fgItem).SetBackgroundColor(bgItem).SetAttributes(attrItem))
+	}
+	m.table.Select(0, 0).ScrollToBeginning()
+	style := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementTitle)
+	m.frame.SetTitle(config.FormatTextWithStyle(fmt.Sprintf(" Attach file [%s] ", tview.Escape(m.dir)), style))
+}
+
+// SetDoneFunc sets a handler which is called with the path of the picked
+// file. The handler is also called with "" when the user presses the Escape key.
+func (m *ModalAttach) SetDoneFunc(handler func(path string)) *ModalAttach {
+	m.done = handler
+	return m
+}
+
+// Focus is called when this primitive receives focus.
+func (m *ModalAttach) Focus(delegate func(p tview.Primitive)) {
+	delegate(m.table)
+}
+
+// HasFocus returns whether or not this primitive has focus.
+func (m *ModalAttach) HasFocus() bool {
+	return m.table.HasFocus()
+}
+
+// Draw draws this primitive onto the screen.
+func (m *ModalAttach) Draw(screen tcell.Screen) {
+	width, height := screen.Size()
+	height -= 7
+	m.frame.Clear()
+	x := 0
+	y := 6
+	m.SetRect(x, y, width, height)
+
+	// Draw the frame.
+	m.frame.SetRect(x, y, width, height)
+	m.frame.Draw(screen)
+}
+
+// InputHandler handle input
+func (m *ModalAttach) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return m.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if m.HasFocus() {
+			if keymap.Match(KeyActionCancel, event) {
+				m.done("")
+				return
+			}
+			switch event.Key() {
+			case tcell.KeyEnter:
+				row, _ := m.table.GetSelection()
+				if row < 0 || row >= len(m.entries) {
+					return
+				}
+				if e := m.entries[row]; e.dir {
+					m.open(filepath.Clean(filepath.Join(m.dir, e.name)))
+				} else {
+					m.done(filepath.Join(m.dir, e.name))
+				}
+				return
+			case tcell.KeyBackspace, tcell.KeyBackspace2:
+				m.open(filepath.Dir(m.dir))
+				return
+			}
+			if handler := m.table.InputHandler(); handler != nil {
+				handler(event, setFocus)
+			}
+			return
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ModalBookmarks is a window listing the bookmarked messages of the user
+type ModalBookmarks struct {
+	*tview.Box
+	table     *tview.Table
+	frame     *tview.Frame
+	bookmarks []database.Bookmark
+	notes     []string
+	fitWidth  int
+	done      func(bm *database.Bookmark)
+}
+
+// NewModalBookmarks returns a new bookmarks window.
+func NewModalBookmarks() *ModalBookmarks {
+	_, defBg, _ := config.StyleDefault.Decompose()
+	m := &ModalBookmarks{
+		Box: tview.NewBox().SetBackgroundColor(defBg),
+	}
+	borderFg, _, borderAttr := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementBorder).Decompose()
+	headerStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementHeader)
+	selectionStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementSelection)
+	titleStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementTitle)
+	fgHeader, bgHeader, attrHeader := headerStyle.Decompose()
+	m.table = tview.NewTable().
+		SetFixed(1, 0).
+		SetBordersColor(borderFg).
+		SetSelectable(true, false).
+		SetSelectedStyle(selectionStyle).
+		SetSelectedFunc(func(row int, column int) {
+			if row > 0 && row <= len(m.bookmarks) {
+				m.done(&m.bookmarks[row-1])
+			}
+		})
+	m.frame = tview.NewFrame(m.table).SetBorders(0, 0, 1, 0, 0, 0)
+	m.frame.SetBackgroundColor(defBg)
+	m.table.SetBackgroundColor(defBg)
+	m.frame.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderAttributes(borderAttr).
+		SetBorderColor(borderFg).
+		SetBorderPadding(0, 0, 1, 1).
+		SetTitle(config.FormatTextWithStyle(" Bookmarks, Del removes ", titleStyle))
+	for i, title := range []string{" Area", "Msg", "Note", "Added"} {
+		cell := tview.NewTableCell(title).
+			SetTextColor(fgHeader).SetBackgroundColor(bgHeader).SetAttributes(attrHeader).
+			SetSelectable(false)
+		if i == 1 || i == 3 {
+			cell.SetAlign(tview.AlignRight)
+		}
+		if i == 2 {
+			cell.SetExpansion(1)
+		}
+		m.table.SetCell(0, i, cell)
+	}
+
+	bookmarks, err := database.ListBookmarks(config.Config.Username)
+	if err != nil {
+		log.Printf("Error loading bookmarks: %v", err)
+	}
+	m.bookmarks = bookmarks
+	m.fill()
+	return m
+}
+
+// fill renders a table row for every bookmark
+func (m *ModalBookmarks) fill() {
+	fgItem, bgItem, attrItem := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementItem).Decompose()
+	for m.table.GetRowCount() > 1 {
+		m.table.RemoveRow(m.table.GetRowCount() - 1)
+	}
+	m.notes = m.notes[:0]
+	m.fitWidth = 0
+	for i, bm := range m.bookmarks {
+		m.notes = append(m.notes, tview.Escape(bm.Note))
+		m.table.SetCell(i+1, 0, tview.NewTableCell(" "+tview.Escape(bm.AreaName)).
+			SetTextColor(fgItem).SetBackgroundColor(bgItem).SetAttributes(attrItem))
+		m.table.SetCell(i+1, 1, tview.NewTableCell(fmt.Sprintf("%d", bm.MsgNum)).
+			SetAlign(tview.AlignRight).
+			SetTextColor(fgItem).SetBackgroundColor(bgItem).SetAttributes(attrItem))
+		m.table.SetCell(i+1, 2, tview.NewTableCell(tview.Escape(bm.Note)).
+			SetTextColor(fgItem).SetBackgroundColor(bgItem).SetAttributes(attrItem))
+		m.table.SetCell(i+1, 3, tview.NewTableCell(time.Unix(bm.Created, 0).Format("02 Jan 2006")).
+			SetAlign(tview.AlignRight).
+			SetTextColor(fgItem).SetBackgroundColor(bgItem).SetAttributes(attrItem))
+	}
+}
+
+// remove deletes the bookmark in the given table row
+func (m *ModalBookmarks) remove(row int) {
+	if row < 1 || row > len(m.bookmarks) {
+		return
+	}
+	if err := database.DeleteBookmark(config.Config.Username, m.bookmarks[row-1].ID); err != nil {
+		log.Printf("Error deleting bookmark: %v", err)
+		return
+	}
+	m.bookmarks = append(m.bookmarks[:row-1], m.bookmarks[row:]...)
+	m.fill()
+	m.table.Select(min(row, max(len(m.bookmarks), 1)), 0)
+}
+
+// SetDoneFunc sets a handler which is called when a bookmark was selected.
+// The handler is also called with nil when the user presses the Escape key.
+func (m *ModalBookmarks) SetDoneFunc(handler func(bm *database.Bookmark)) *ModalBookmarks {
+	m.done = handler
+	return m
+}
+
+// Focus is called when this primitive receives focus.
+func (m *ModalBookmarks) Focus(delegate func(p tview.Primitive)) {
+	delegate(m.table)
+}
+
+// HasFocus returns whether or not this primitive has focus.
+func (m *ModalBookmarks) HasFocus() bool {
+	return m.table.HasFocus()
+}
+
+// Draw draws this primitive onto the screen.
+func (m *ModalBookmarks) Draw(screen tcell.Screen) {
+	width, height := screen.Size()
+	height -= 7
+	m.frame.Clear()
+	x := 0
+	y := 6
+	m.SetRect(x, y, width, height)
+
+	// Long notes are cut to the space left inside the border and padding
+	if inner := width - 4; inner != m.fitWidth {
+		fitColumn(m.table, 2, inner, m.notes)
+		m.fitWidth = inner
+	}
+
+	// Draw the frame.
+	m.frame.SetRect(x, y, width, height)
+	m.frame.Draw(screen)
+}
+
+// InputHandler handle input
+func (m *ModalBookmarks) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return m.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if m.HasFocus() {
+			if keymap.Match(KeyActionCancel, event) {
+				m.done(nil)
+				return
+			}
+			if keymap.Match(KeyActionDelete, event) {
+				row, _ := m.table.GetSelection()
+				m.remove(row)
+				return
+			}
+			if handler := m.table.InputHandler(); handler != nil {
+				handler(event, setFocus)
+			}
+			return
+		}
+	})
+}
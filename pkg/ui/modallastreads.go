@@ -0,0 +1,200 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ModalLastReads is a window listing the lastread positions of all users,
+// for operators of a shared lastread database
+type ModalLastReads struct {
+	*tview.Box
+	table     *tview.Table
+	frame     *tview.Frame
+	areaName  string
+	lastReads []database.LastRead
+	done      func()
+	resetUser func(user string)
+	status    func(text string)
+}
+
+// NewModalLastReads returns a new window of the positions in areaName, or
+// of every area when it is "".
+func NewModalLastReads(areaName string) *ModalLastReads {
+	_, defBg, _ := config.StyleDefault.Decompose()
+	m := &ModalLastReads{
+		Box:      tview.NewBox().SetBackgroundColor(defBg),
+		areaName: areaName,
+	}
+	borderFg, _, borderAttr := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementBorder).Decompose()
+	headerStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementHeader)
+	selectionStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementSelection)
+	titleStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementTitle)
+	fgHeader, bgHeader, attrHeader := headerStyle.Decompose()
+	m.table = tview.NewTable().
+		SetFixed(1, 0).
+		SetBordersColor(borderFg).
+		SetSelectable(true, false).
+		SetSelectedStyle(selectionStyle)
+	m.frame = tview.NewFrame(m.table).SetBorders(0, 0, 1, 0, 0, 0)
+	m.frame.SetBackgroundColor(defBg)
+	m.table.SetBackgroundColor(defBg)
+	title := " Lastread positions, Del resets, Alt-Z resets all of the user "
+	if areaName != "" {
+		title = " Lastread positions in " + areaName + ", Del resets, Alt-Z resets all of the user "
+	}
+	m.frame.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderAttributes(borderAttr).
+		SetBorderColor(borderFg).
+		SetBorderPadding(0, 0, 1, 1).
+		SetTitle(config.FormatTextWithStyle(tview.Escape(title), titleStyle))
+	for i, title := range []string{" User", "Area", "Last", "High", "Updated"} {
+		cell := tview.NewTableCell(title).
+			SetTextColor(fgHeader).SetBackgroundColor(bgHeader).SetAttributes(attrHeader).
+			SetSelectable(false)
+		if i >= 2 {
+			cell.SetAlign(tview.AlignRight)
+		}
+		if i == 1 {
+			cell.SetExpansion(1)
+		}
+		m.table.SetCell(0, i, cell)
+	}
+	m.Reload()
+	return m
+}
+
+// Reload reads the positions again and renders a table row for each
+func (m *ModalLastReads) Reload() {
+	m.lastReads = m.lastReads[:0]
+	if m.areaName != "" {
+		lastReads, err := database.GetLastReadsByArea(m.areaName)
+		if err != nil {
+			log.Printf("Error listing lastreads: %v", err)
+		}
+		m.lastReads = lastReads
+	} else {
+		users, err := database.GetLastReadUsers()
+		if err != nil {
+			log.Printf("Error listing lastread users: %v", err)
+		}
+		for _, user := range users {
+			lastReads, err := database.GetAllLastReads(user)
+			if err != nil {
+				log.Printf("Error listing lastreads: %v", err)
+				continue
+			}
+			m.lastReads = append(m.lastReads, lastReads...)
+		}
+	}
+	fgItem, bgItem, attrItem := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementItem).Decompose()
+	for m.table.GetRowCount() > 1 {
+		m.table.RemoveRow(m.table.GetRowCount() - 1)
+	}
+	for i, lr := range m.lastReads {
+		updated := time.Unix(lr.LastUpdated, 0).Format("02 Jan 2006 15:04")
+		for col, text := range []string{" " + lr.Username, lr.AreaName,
+			fmt.Sprintf("%d", lr.LastReadMsg), fmt.Sprintf("%d", lr.HighReadMsg), updated} {
+			cell := tview.NewTableCell(tview.Escape(text)).
+				SetTextColor(fgItem).SetBackgroundColor(bgItem).SetAttributes(attrItem)
+			if col >= 2 {
+				cell.SetAlign(tview.AlignRight)
+			}
+			m.table.SetCell(i+1, col, cell)
+		}
+	}
+	row, _ := m.table.GetSelection()
+	m.table.Select(min(max(row, 1), max(len(m.lastReads), 1)), 0)
+}
+
+// reset removes the position in the given table row
+func (m *ModalLastReads) reset(row int) {
+	if row < 1 || row > len(m.lastReads) {
+		return
+	}
+	lr := m.lastReads[row-1]
+	if err := database.DeleteLastRead(lr.Username, lr.AreaName); err != nil {
+		m.status(err.Error())
+		return
+	}
+	m.status(fmt.Sprintf("Lastread of %s in %s reset", lr.Username, lr.AreaName))
+	m.Reload()
+}
+
+// SetDoneFunc sets a handler which is called when the user presses the
+// Escape key.
+func (m *ModalLastReads) SetDoneFunc(handler func()) *ModalLastReads {
+	m.done = handler
+	return m
+}
+
+// SetResetUserFunc sets a handler which is called with the user of the
+// selected row to reset all of their positions.
+func (m *ModalLastReads) SetResetUserFunc(handler func(user string)) *ModalLastReads {
+	m.resetUser = handler
+	return m
+}
+
+// SetStatusFunc sets a handler showing the outcome of a reset.
+func (m *ModalLastReads) SetStatusFunc(handler func(text string)) *ModalLastReads {
+	m.status = handler
+	return m
+}
+
+// Focus is called when this primitive receives focus.
+func (m *ModalLastReads) Focus(delegate func(p tview.Primitive)) {
+	delegate(m.table)
+}
+
+// HasFocus returns whether or not this primitive has focus.
+func (m *ModalLastReads) HasFocus() bool {
+	return m.table.HasFocus()
+}
+
+// Draw draws this primitive onto the screen.
+func (m *ModalLastReads) Draw(screen tcell.Screen) {
+	width, height := screen.Size()
+	height -= 7
+	m.frame.Clear()
+	x := 0
+	y := 6
+	m.SetRect(x, y, width, height)
+
+	// Draw the frame.
+	m.frame.SetRect(x, y, width, height)
+	m.frame.Draw(screen)
+}
+
+// InputHandler handle input
+func (m *ModalLastReads) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return m.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if m.HasFocus() {
+			if keymap.Match(KeyActionCancel, event) {
+				m.done()
+				return
+			}
+			row, _ := m.table.GetSelection()
+			if keymap.Match(KeyActionDelete, event) {
+				m.reset(row)
+				return
+			}
+			if keymap.Match(KeyActionResetUser, event) {
+				if row >= 1 && row <= len(m.lastReads) {
+					m.resetUser(m.lastReads[row-1].Username)
+				}
+				return
+			}
+			if handler := m.table.InputHandler(); handler != nil {
+				handler(event, setFocus)
+			}
+			return
+		}
+	})
+}
@@ -13,6 +13,7 @@ type ModalMenu struct {
 	frame     *tview.Frame
 	textColor tcell.Color
 	title     string
+	lines     []string
 	done      func(buttonIndex int)
 	y         int
 	width     int
@@ -72,6 +73,15 @@ func (m *ModalMenu) SetText(text string) *ModalMenu {
 	return m
 }
 
+// AddText adds a line of text shown above the buttons
+func (m *ModalMenu) AddText(text string) *ModalMenu {
+	m.lines = append(m.lines, text)
+	if m.width < stringWidth(text) {
+		m.width = stringWidth(text)
+	}
+	return m
+}
+
 // SetY set Y
 func (m *ModalMenu) SetY(y int) *ModalMenu {
 	m.y = y
@@ -117,6 +127,12 @@ func (m *ModalMenu) Draw(screen tcell.Screen) {
 		width = len(m.title) + 2
 	}
 	m.frame.Clear()
+	if len(m.lines) > 0 {
+		height += len(m.lines) + 1
+		for _, line := range m.lines {
+			m.frame.AddText(line, true, tview.AlignLeft, m.textColor)
+		}
+	}
 	x := 1
 	y := m.y
 	m.SetRect(x, y, width, height)
@@ -21,7 +21,17 @@ type ModalMessageList struct {
 	table     *tview.Table
 	frame     *tview.Frame
 	textColor tcell.Color
+	title     string
+	messages  []msgapi.MessageListItem
+	last      uint32
+	filter    []rune
+	rows      []int
+	subjects  []string
+	fitWidth  int
+	tagged    map[uint32]bool
+	marked    map[uint32]bool
 	done      func(msgNum uint32)
+	batch     func(action string)
 }
 
 // NewModalMessageList returns a new modal message window.
@@ -34,21 +44,25 @@ func NewModalMessageList(area *msgapi.AreaPrimitive) *ModalMessageList {
 	styleBorder := config.GetElementStyle(config.ColorAreaMessageList, config.ColorElementBorder)
 	styleSelection := config.GetElementStyle(config.ColorAreaMessageList, config.ColorElementSelection)
 	fgHeader, bgHeader, attrHeader := config.GetElementStyle(config.ColorAreaMessageList, config.ColorElementHeader).Decompose()
-	fgItem, bgItem, attrItem := config.GetElementStyle(config.ColorAreaMessageList, config.ColorElementItem).Decompose()
 	fgTitle, bgTitle, attrTitle := config.GetElementStyle(config.ColorAreaMessageList, config.ColorElementTitle).Decompose()
-	fgHigh, bgHigh, attrHigh := config.GetElementStyle(config.ColorAreaMessageList, config.ColorElementHighlight).Decompose()
 	//fgCur, bgCur, attrCur := config.GetElementStyle(config.ColorAreaMessageList, config.ColorElementCurrent).Decompose()
 	m.table = tview.NewTable().
 		SetFixed(1, 0).
 		SetSelectable(true, false).
 		SetSelectedStyle(styleSelection).
 		SetSelectedFunc(func(row int, column int) {
-			m.done(uint32(row))
+			if row > 0 && row <= len(m.rows) {
+				m.done(uint32(m.rows[row-1] + 1))
+			}
 		})
 	m.frame = tview.NewFrame(m.table).SetBorders(0, 0, 1, 0, 0, 0)
 	m.frame.SetBackgroundColor(defBg)
 	m.table.SetBackgroundColor(defBg)
-	m.frame.SetTitle(fmt.Sprintf("[%s:%s:%s] List Messages ", fgTitle.String(), bgTitle.String(), config.MaskToStringStyle(attrTitle)))
+	m.title = fmt.Sprintf("[%s:%s:%s] List Messages ", fgTitle.String(), bgTitle.String(), config.MaskToStringStyle(attrTitle))
+	if desc := areaDescription(*area); desc != "" {
+		m.title += tview.Escape("- "+desc) + " "
+	}
+	m.frame.SetTitle(m.title)
 	m.frame.SetBorder(true).
 		SetBorderStyle(styleBorder).
 		SetBorderPadding(0, 0, 1, 1).
@@ -75,40 +89,124 @@ func NewModalMessageList(area *msgapi.AreaPrimitive) *ModalMessageList {
 			SetTextColor(fgHeader).SetBackgroundColor(bgHeader).SetAttributes(attrHeader).
 			SetSelectable(false).
 			SetAlign(tview.AlignRight))
-	for i, mh := range *(*area).GetMessages() {
-		ch := " "
+	m.messages = *(*area).GetMessages()
+	m.last = (*area).GetLast()
+	m.applyFilter()
+	return m
+}
+
+// applyFilter fills the table with messages whose From, To or Subject
+// contains the typed filter
+func (m *ModalMessageList) applyFilter() {
+	fgItem, bgItem, attrItem := config.GetElementStyle(config.ColorAreaMessageList, config.ColorElementItem).Decompose()
+	fgHigh, bgHigh, attrHigh := config.GetElementStyle(config.ColorAreaMessageList, config.ColorElementHighlight).Decompose()
+	for m.table.GetRowCount() > 1 {
+		m.table.RemoveRow(m.table.GetRowCount() - 1)
+	}
+	m.rows = m.rows[:0]
+	m.subjects = m.subjects[:0]
+	m.fitWidth = 0
+	selected := 1
+	filter := string(m.filter)
+	for i, mh := range m.messages {
+		if !mh.Matches(filter) {
+			continue
+		}
+		m.rows = append(m.rows, i)
+		m.subjects = append(m.subjects, mh.Subject)
+		row := len(m.rows)
+		ch := m.marker(i)
 		fg, bg, attr := fgItem, bgItem, attrItem
-		if i == int((*area).GetLast()-1) {
+		if i == int(m.last-1) {
 			//fg, bg, attr = fgCur, bgCur, attrCur
 			fg, bg, attr = fgHigh, bgHigh, attrHigh
-			ch = "*"
+			selected = row
 		}
 		fromCondition := utils.NamesEqual(mh.From, config.Config.Username)
 		toCondition := utils.NamesEqual(mh.To, config.Config.Username)
-		m.table.SetCell(i+1, 0, tview.NewTableCell(strconv.FormatInt(int64(mh.MsgNum), 10)+ch).
+		m.table.SetCell(row, 0, tview.NewTableCell(strconv.FormatInt(int64(mh.MsgNum), 10)+ch).
 			SetAlign(tview.AlignRight).
 			SetTextColor(fg).SetBackgroundColor(bg).SetAttributes(attr))
 		//mh.From, mh.To, mh.Subject, mh.DateWritten.Format("02 Jan 2006"))
 		if fromCondition {
-			m.table.SetCell(i+1, 1, tview.NewTableCell(mh.From).
+			m.table.SetCell(row, 1, tview.NewTableCell(mh.From).
 				SetTextColor(fgHigh).SetBackgroundColor(bgHigh).SetAttributes(attrHigh))
 		} else {
-			m.table.SetCell(i+1, 1, tview.NewTableCell(mh.From).
+			m.table.SetCell(row, 1, tview.NewTableCell(mh.From).
 				SetTextColor(fg).SetBackgroundColor(bg).SetAttributes(attr))
 		}
 		if toCondition {
-			m.table.SetCell(i+1, 2, tview.NewTableCell(mh.To).
+			m.table.SetCell(row, 2, tview.NewTableCell(mh.To).
 				SetTextColor(fgHigh).SetBackgroundColor(bgHigh).SetAttributes(attrHigh))
 		} else {
-			m.table.SetCell(i+1, 2, tview.NewTableCell(mh.To).
+			m.table.SetCell(row, 2, tview.NewTableCell(mh.To).
 				SetTextColor(fg).SetBackgroundColor(bg).SetAttributes(attr))
 		}
-		m.table.SetCell(i+1, 3, tview.NewTableCell(mh.Subject).
+		m.table.SetCell(row, 3, tview.NewTableCell(mh.Subject).
 			SetTextColor(fg).SetBackgroundColor(bg).SetAttributes(attr))
-		m.table.SetCell(i+1, 4, tview.NewTableCell(mh.DateWritten.Format("02 Jan 2006")).
+		m.table.SetCell(row, 4, tview.NewTableCell(mh.DateWritten.Format("02 Jan 2006")).
 			SetTextColor(fg).SetBackgroundColor(bg).SetAttributes(attr))
 	}
-	m.table.Select(int((*area).GetLast()), 0)
+	m.table.Select(selected, 0)
+	if len(m.filter) > 0 {
+		m.frame.SetTitle(m.title + tview.Escape("["+filter+"]") + " ")
+	} else {
+		m.frame.SetTitle(m.title)
+	}
+}
+
+// marker returns the mark after the number of message i: '+' for tagged
+// messages, '#' for bookmarked ones, '*' for the current one
+func (m *ModalMessageList) marker(i int) string {
+	switch {
+	case m.tagged[uint32(i+1)]:
+		return "+"
+	case m.marked[uint32(i+1)]:
+		return "#"
+	case i == int(m.last-1):
+		return "*"
+	}
+	return " "
+}
+
+// toggleTag tags or untags the selected message and moves to the next one
+func (m *ModalMessageList) toggleTag() {
+	row, _ := m.table.GetSelection()
+	if m.tagged == nil || row < 1 || row > len(m.rows) {
+		return
+	}
+	i := m.rows[row-1]
+	pos := uint32(i + 1)
+	if m.tagged[pos] {
+		delete(m.tagged, pos)
+	} else {
+		m.tagged[pos] = true
+	}
+	m.table.GetCell(row, 0).SetText(strconv.FormatInt(int64(m.messages[i].MsgNum), 10) + m.marker(i))
+	if row < len(m.rows) {
+		m.table.Select(row+1, 0)
+	}
+}
+
+// SetTagged sets the tag set the list marks and toggles messages in,
+// keyed by message position
+func (m *ModalMessageList) SetTagged(tagged map[uint32]bool) *ModalMessageList {
+	m.tagged = tagged
+	m.applyFilter()
+	return m
+}
+
+// SetBookmarked sets the bookmarked messages, keyed by message position
+func (m *ModalMessageList) SetBookmarked(marked map[uint32]bool) *ModalMessageList {
+	m.marked = marked
+	m.applyFilter()
+	return m
+}
+
+// SetBatchFunc sets a handler for delete, move and mark-read key actions,
+// called when messages are tagged
+func (m *ModalMessageList) SetBatchFunc(handler func(action string)) *ModalMessageList {
+	m.batch = handler
 	return m
 }
 
@@ -160,6 +258,12 @@ func (m *ModalMessageList) Draw(screen tcell.Screen) {
 	y := 6
 	m.SetRect(x, y, width, height)
 
+	// Long subjects are cut to the space left inside the border and padding
+	if inner := width - 4; inner != m.fitWidth {
+		fitColumn(m.table, 3, inner, m.subjects)
+		m.fitWidth = inner
+	}
+
 	// Draw the frame.
 	m.frame.SetRect(x, y, width, height)
 	m.frame.Draw(screen)
@@ -169,6 +273,36 @@ func (m *ModalMessageList) Draw(screen tcell.Screen) {
 func (m *ModalMessageList) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
 	return m.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
 		if m.HasFocus() {
+			if keymap.Match(KeyActionTag, event) && len(m.filter) == 0 {
+				m.toggleTag()
+				return
+			}
+			if m.batch != nil && len(m.tagged) > 0 {
+				for _, action := range []string{KeyActionDelete, KeyActionMoveMessage, KeyActionMarkRead} {
+					if keymap.Match(action, event) {
+						m.batch(action)
+						return
+					}
+				}
+			}
+			switch event.Key() {
+			case tcell.KeyBackspace, tcell.KeyBackspace2:
+				if len(m.filter) > 0 {
+					m.filter = m.filter[:len(m.filter)-1]
+					m.applyFilter()
+				}
+				return
+			case tcell.KeyRune:
+				m.filter = append(m.filter, event.Rune())
+				m.applyFilter()
+				return
+			case tcell.KeyEscape:
+				if len(m.filter) > 0 {
+					m.filter = nil
+					m.applyFilter()
+					return
+				}
+			}
 			if handler := m.table.InputHandler(); handler != nil {
 				handler(event, setFocus)
 			}
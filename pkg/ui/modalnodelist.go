@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"strings"
+
 	"github.com/askovpen/gossiped/pkg/config"
 	"github.com/askovpen/gossiped/pkg/nodelist"
 	"github.com/gdamore/tcell/v2"
@@ -14,7 +16,10 @@ type ModalNodeList struct {
 	frame     *tview.Frame
 	textColor tcell.Color
 	title     string
-	done      func(buttonIndex int)
+	filter    []rune
+	nodes     []nodelist.Node
+	done      func(node *nodelist.Node)
+	copy      func(node *nodelist.Node)
 }
 
 // NewModalNodeList returns a new modal message window.
@@ -27,8 +32,6 @@ func NewModalNodeList() *ModalNodeList {
 	borderFg, _, borderAttr := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementBorder).Decompose()
 	headerStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementHeader)
 	selectionStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementSelection)
-	itemStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementItem)
-	fgItem, bgItem, attrItem := itemStyle.Decompose()
 	fgHeader, bgHeader, attrHeader := headerStyle.Decompose()
 	m.table = tview.NewTable().
 		SetFixed(1, 0).
@@ -36,7 +39,9 @@ func NewModalNodeList() *ModalNodeList {
 		SetSelectable(true, false).
 		SetSelectedStyle(selectionStyle).
 		SetSelectedFunc(func(row int, column int) {
-			m.done(row)
+			if row > 0 && row <= len(m.nodes) {
+				m.done(&m.nodes[row-1])
+			}
 		})
 	m.frame = tview.NewFrame(m.table).SetBorders(0, 0, 1, 0, 0, 0)
 	m.frame.SetBackgroundColor(defBg)
@@ -63,7 +68,19 @@ func NewModalNodeList() *ModalNodeList {
 			SetTextColor(fgHeader).SetBackgroundColor(bgHeader).SetAttributes(attrHeader).
 			SetExpansion(1).
 			SetSelectable(false))
-	for i, node := range nodelist.Nodelist {
+	m.applyFilter()
+	return m
+}
+
+// applyFilter fills the table with the nodes matching the typed filter
+func (m *ModalNodeList) applyFilter() {
+	itemStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementItem)
+	fgItem, bgItem, attrItem := itemStyle.Decompose()
+	for m.table.GetRowCount() > 1 {
+		m.table.RemoveRow(m.table.GetRowCount() - 1)
+	}
+	m.nodes = nodelist.Search(string(m.filter))
+	for i, node := range m.nodes {
 		m.table.SetCell(i+1, 0, tview.NewTableCell(node.Address.String()).
 			SetTextColor(fgItem).SetBackgroundColor(bgItem).SetAttributes(attrItem))
 		m.table.SetCell(i+1, 1, tview.NewTableCell(node.Sysop).
@@ -73,7 +90,18 @@ func NewModalNodeList() *ModalNodeList {
 		m.table.SetCell(i+1, 3, tview.NewTableCell(node.BBS).
 			SetTextColor(fgItem).SetBackgroundColor(bgItem).SetAttributes(attrItem))
 	}
-	return m
+	m.table.Select(1, 0).ScrollToBeginning()
+	m.updateTitle()
+}
+
+// updateTitle shows the typed filter in the window title
+func (m *ModalNodeList) updateTitle() {
+	text := m.title
+	if len(m.filter) > 0 {
+		text += " [" + string(m.filter) + "] "
+	}
+	style := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementTitle)
+	m.frame.SetTitle(config.FormatTextWithStyle(tview.Escape(text), style))
 }
 
 // SetTextColor sets the color of the message text.
@@ -82,22 +110,52 @@ func (m *ModalNodeList) SetTextColor(color tcell.Color) *ModalNodeList {
 	return m
 }
 
-// SetDoneFunc sets a handler which is called when one of the buttons was
-// pressed. It receives the index of the button as well as its label text. The
-// handler is also called when the user presses the Escape key. The index will
-// then be negative and the label text an emptry string.
-func (m *ModalNodeList) SetDoneFunc(handler func(buttonIndex int)) *ModalNodeList {
+// SetDoneFunc sets a handler which is called when a node was selected. The
+// handler is also called with nil when the user presses the Escape key.
+func (m *ModalNodeList) SetDoneFunc(handler func(node *nodelist.Node)) *ModalNodeList {
 	m.done = handler
 	return m
 }
 
+// SetCopyFunc sets a handler which is called with the selected node when the
+// user presses the copy-text key, leaving the window open.
+func (m *ModalNodeList) SetCopyFunc(handler func(node *nodelist.Node)) *ModalNodeList {
+	m.copy = handler
+	return m
+}
+
+// selectedNode returns the node under the selection bar, nil if none
+func (m *ModalNodeList) selectedNode() *nodelist.Node {
+	row, _ := m.table.GetSelection()
+	if row > 0 && row <= len(m.nodes) {
+		return &m.nodes[row-1]
+	}
+	return nil
+}
+
+// nodeText returns the address, sysop and city of node as one line, for
+// pasting elsewhere
+func nodeText(node *nodelist.Node) string {
+	text := node.Address.String() + " " + strings.ReplaceAll(node.Sysop, "_", " ")
+	if node.City != "" {
+		text += ", " + strings.ReplaceAll(node.City, "_", " ")
+	}
+	return text
+}
+
 // SetText sets the message text of the window. The text may contain line
 // breaks. Note that words are wrapped, too, based on the final size of the
 // window.
 func (m *ModalNodeList) SetText(text string) *ModalNodeList {
 	m.title = text
-	style := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementTitle)
-	m.frame.SetTitle(config.FormatTextWithStyle(text, style))
+	m.updateTitle()
+	return m
+}
+
+// SetFilter sets the initial filter, matched against sysop names and addresses.
+func (m *ModalNodeList) SetFilter(filter string) *ModalNodeList {
+	m.filter = []rune(filter)
+	m.applyFilter()
 	return m
 }
 
@@ -134,9 +192,27 @@ func (m *ModalNodeList) Draw(screen tcell.Screen) {
 func (m *ModalNodeList) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
 	return m.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
 		if m.HasFocus() {
+			if keymap.Match(KeyActionCancel, event) {
+				m.done(nil)
+				return
+			}
+			if keymap.Match(KeyActionCopyText, event) {
+				if node := m.selectedNode(); node != nil && m.copy != nil {
+					m.copy(node)
+				}
+				return
+			}
 			switch event.Key() {
-			case tcell.KeyEscape:
-				m.done(-1)
+			case tcell.KeyBackspace, tcell.KeyBackspace2:
+				if len(m.filter) > 0 {
+					m.filter = m.filter[:len(m.filter)-1]
+					m.applyFilter()
+				}
+				return
+			case tcell.KeyRune:
+				m.filter = append(m.filter, event.Rune())
+				m.applyFilter()
+				return
 			}
 			if handler := m.table.InputHandler(); handler != nil {
 				handler(event, setFocus)
@@ -0,0 +1,206 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ModalRequeue is a window listing the links subscribed to the area of an
+// echomail message, to queue it again for the selected ones
+type ModalRequeue struct {
+	*tview.Box
+	table      *tview.Table
+	frame      *tview.Frame
+	echomailID int64
+	links      []database.Link
+	queued     map[int64]bool
+	selected   map[int64]bool
+	done       func(status string)
+}
+
+// NewModalRequeue returns a new requeue window for the given echomail message.
+func NewModalRequeue(areaName string, areaID int64, echomailID int64) *ModalRequeue {
+	_, defBg, _ := config.StyleDefault.Decompose()
+	m := &ModalRequeue{
+		Box:        tview.NewBox().SetBackgroundColor(defBg),
+		echomailID: echomailID,
+		selected:   make(map[int64]bool),
+	}
+	borderFg, _, borderAttr := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementBorder).Decompose()
+	headerStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementHeader)
+	selectionStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementSelection)
+	titleStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementTitle)
+	fgHeader, bgHeader, attrHeader := headerStyle.Decompose()
+	m.table = tview.NewTable().
+		SetFixed(1, 0).
+		SetBordersColor(borderFg).
+		SetSelectable(true, false).
+		SetSelectedStyle(selectionStyle).
+		SetSelectedFunc(func(row int, column int) {
+			m.requeue(row)
+		})
+	m.frame = tview.NewFrame(m.table).SetBorders(0, 0, 1, 0, 0, 0)
+	m.frame.SetBackgroundColor(defBg)
+	m.table.SetBackgroundColor(defBg)
+	m.frame.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderAttributes(borderAttr).
+		SetBorderColor(borderFg).
+		SetBorderPadding(0, 0, 1, 1).
+		SetTitle(config.FormatTextWithStyle(" Requeue to links: "+areaName+" ", titleStyle))
+	for i, title := range []string{" Sel", "Address", "Station", "State"} {
+		cell := tview.NewTableCell(title).
+			SetTextColor(fgHeader).SetBackgroundColor(bgHeader).SetAttributes(attrHeader).
+			SetSelectable(false)
+		if i == 2 {
+			cell.SetExpansion(1)
+		}
+		m.table.SetCell(0, i, cell)
+	}
+
+	subscriptions, err := database.ListSubscriptions(areaID)
+	if err != nil {
+		log.Printf("Error loading subscriptions for area %s: %v", areaName, err)
+	}
+	for _, ls := range subscriptions {
+		if ls.Subscribed {
+			m.links = append(m.links, ls.Link)
+		}
+	}
+	m.queued, err = database.GetQueuedLinks(echomailID)
+	if err != nil {
+		log.Printf("Error loading queued links for echomail %d: %v", echomailID, err)
+		m.queued = make(map[int64]bool)
+	}
+	for i := range m.links {
+		m.setRow(i)
+	}
+	return m
+}
+
+// setRow renders the table row for the i-th link
+func (m *ModalRequeue) setRow(i int) {
+	itemStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementItem)
+	highlightStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementHighlight)
+	fg, bg, attr := itemStyle.Decompose()
+	link := m.links[i]
+	mark, state := " [ ]", ""
+	if m.selected[link.ID] {
+		fg, bg, attr = highlightStyle.Decompose()
+		mark = " [x]"
+	}
+	if m.queued[link.ID] {
+		mark, state = "    ", "queued"
+	}
+	m.table.SetCell(i+1, 0, tview.NewTableCell(tview.Escape(mark)).
+		SetTextColor(fg).SetBackgroundColor(bg).SetAttributes(attr))
+	m.table.SetCell(i+1, 1, tview.NewTableCell(link.FtnAddress).
+		SetTextColor(fg).SetBackgroundColor(bg).SetAttributes(attr))
+	m.table.SetCell(i+1, 2, tview.NewTableCell(link.StationName).
+		SetTextColor(fg).SetBackgroundColor(bg).SetAttributes(attr))
+	m.table.SetCell(i+1, 3, tview.NewTableCell(state).
+		SetTextColor(fg).SetBackgroundColor(bg).SetAttributes(attr))
+}
+
+// toggle selects or unselects the link in the given table row, links which
+// still have the message queued can not be selected
+func (m *ModalRequeue) toggle(row int) {
+	if row < 1 || row-1 >= len(m.links) {
+		return
+	}
+	link := m.links[row-1]
+	if m.queued[link.ID] {
+		return
+	}
+	m.selected[link.ID] = !m.selected[link.ID]
+	m.setRow(row - 1)
+}
+
+// requeue queues the message for the selected links, or the link in the
+// given table row if none is selected, and closes the window
+func (m *ModalRequeue) requeue(row int) {
+	var linkIDs []int64
+	skipped := 0
+	for _, link := range m.links {
+		if m.selected[link.ID] {
+			linkIDs = append(linkIDs, link.ID)
+		}
+	}
+	if len(linkIDs) == 0 && row >= 1 && row-1 < len(m.links) {
+		if link := m.links[row-1]; m.queued[link.ID] {
+			skipped++
+		} else {
+			linkIDs = append(linkIDs, link.ID)
+		}
+	}
+	if err := database.RequeueEchomail(m.echomailID, linkIDs); err != nil {
+		log.Printf("Error requeueing echomail %d: %v", m.echomailID, err)
+		m.done(fmt.Sprintf("Requeue failed: %v", err))
+		return
+	}
+	status := fmt.Sprintf("Message queued for %d links", len(linkIDs))
+	if skipped > 0 {
+		status = "Message is still queued for this link"
+	}
+	m.done(status)
+}
+
+// SetDoneFunc sets a handler which is called with a status message when
+// the window is closed.
+func (m *ModalRequeue) SetDoneFunc(handler func(status string)) *ModalRequeue {
+	m.done = handler
+	return m
+}
+
+// Focus is called when this primitive receives focus.
+func (m *ModalRequeue) Focus(delegate func(p tview.Primitive)) {
+	delegate(m.table)
+}
+
+// HasFocus returns whether or not this primitive has focus.
+func (m *ModalRequeue) HasFocus() bool {
+	return m.table.HasFocus()
+}
+
+// Draw draws this primitive onto the screen.
+func (m *ModalRequeue) Draw(screen tcell.Screen) {
+	width, height := screen.Size()
+	height -= 7
+	m.frame.Clear()
+	x := 0
+	y := 6
+	m.SetRect(x, y, width, height)
+
+	// Draw the frame.
+	m.frame.SetRect(x, y, width, height)
+	m.frame.Draw(screen)
+}
+
+// InputHandler handle input
+func (m *ModalRequeue) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return m.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if m.HasFocus() {
+			if keymap.Match(KeyActionCancel, event) {
+				m.done("")
+				return
+			}
+			switch event.Key() {
+			case tcell.KeyRune:
+				if event.Rune() == ' ' {
+					row, _ := m.table.GetSelection()
+					m.toggle(row)
+					return
+				}
+			}
+			if handler := m.table.InputHandler(); handler != nil {
+				handler(event, setFocus)
+			}
+			return
+		}
+	})
+}
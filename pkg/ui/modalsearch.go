@@ -0,0 +1,191 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// searchLimit is the maximum number of results shown by the global search
+const searchLimit = 200
+
+// ModalSearch is a window searching echomail of all areas in the database
+type ModalSearch struct {
+	*tview.Box
+	table    *tview.Table
+	frame    *tview.Frame
+	term     []rune
+	searched string
+	hits     []database.SearchHit
+	more     bool
+	subjects []string
+	fitWidth int
+	err      error
+	done     func(hit *database.SearchHit)
+}
+
+// NewModalSearch returns a new global search window.
+func NewModalSearch() *ModalSearch {
+	_, defBg, _ := config.StyleDefault.Decompose()
+	m := &ModalSearch{
+		Box: tview.NewBox().SetBackgroundColor(defBg),
+	}
+	borderFg, _, borderAttr := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementBorder).Decompose()
+	headerStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementHeader)
+	selectionStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementSelection)
+	fgHeader, bgHeader, attrHeader := headerStyle.Decompose()
+	m.table = tview.NewTable().
+		SetFixed(1, 0).
+		SetBordersColor(borderFg).
+		SetSelectable(true, false).
+		SetSelectedStyle(selectionStyle)
+	m.frame = tview.NewFrame(m.table).SetBorders(0, 0, 1, 0, 0, 0)
+	m.frame.SetBackgroundColor(defBg)
+	m.table.SetBackgroundColor(defBg)
+	m.frame.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderAttributes(borderAttr).
+		SetBorderColor(borderFg).
+		SetBorderPadding(0, 0, 1, 1)
+	for i, title := range []string{" Area", "Msg", "From", "Subject"} {
+		cell := tview.NewTableCell(title).
+			SetTextColor(fgHeader).SetBackgroundColor(bgHeader).SetAttributes(attrHeader).
+			SetSelectable(false)
+		if i == 1 {
+			cell.SetAlign(tview.AlignRight)
+		}
+		if i == 3 {
+			cell.SetExpansion(1)
+		}
+		m.table.SetCell(0, i, cell)
+	}
+	m.updateTitle()
+	return m
+}
+
+// search runs the query for the typed term and fills the table
+func (m *ModalSearch) search() {
+	itemStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementItem)
+	fgItem, bgItem, attrItem := itemStyle.Decompose()
+	for m.table.GetRowCount() > 1 {
+		m.table.RemoveRow(m.table.GetRowCount() - 1)
+	}
+	m.searched = string(m.term)
+	m.hits, m.more, m.err = database.SearchEchomail(m.searched, searchLimit)
+	if m.err != nil {
+		log.Printf("Error searching for '%s': %v", m.searched, m.err)
+	}
+	m.subjects = m.subjects[:0]
+	m.fitWidth = 0
+	for i, hit := range m.hits {
+		m.subjects = append(m.subjects, tview.Escape(hit.Subject))
+		m.table.SetCell(i+1, 0, tview.NewTableCell(" "+tview.Escape(hit.AreaName)).
+			SetTextColor(fgItem).SetBackgroundColor(bgItem).SetAttributes(attrItem))
+		m.table.SetCell(i+1, 1, tview.NewTableCell(fmt.Sprintf("%d", hit.Position)).
+			SetAlign(tview.AlignRight).
+			SetTextColor(fgItem).SetBackgroundColor(bgItem).SetAttributes(attrItem))
+		m.table.SetCell(i+1, 2, tview.NewTableCell(tview.Escape(hit.FromName)).
+			SetTextColor(fgItem).SetBackgroundColor(bgItem).SetAttributes(attrItem))
+		m.table.SetCell(i+1, 3, tview.NewTableCell(tview.Escape(hit.Subject)).
+			SetTextColor(fgItem).SetBackgroundColor(bgItem).SetAttributes(attrItem))
+	}
+	m.table.Select(1, 0).ScrollToBeginning()
+	m.updateTitle()
+}
+
+// updateTitle shows the typed term and the number of results in the window title
+func (m *ModalSearch) updateTitle() {
+	text := " Search all areas [" + string(m.term) + "] "
+	switch {
+	case m.err != nil:
+		text += "search failed "
+	case m.searched == "":
+		text += "type a term, Enter to search "
+	case m.more:
+		text += fmt.Sprintf("first %d results, refine the term ", len(m.hits))
+	default:
+		text += fmt.Sprintf("%d results ", len(m.hits))
+	}
+	style := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementTitle)
+	m.frame.SetTitle(config.FormatTextWithStyle(text, style))
+}
+
+// SetDoneFunc sets a handler which is called when a result was selected. The
+// handler is also called with nil when the user presses the Escape key.
+func (m *ModalSearch) SetDoneFunc(handler func(hit *database.SearchHit)) *ModalSearch {
+	m.done = handler
+	return m
+}
+
+// Focus is called when this primitive receives focus.
+func (m *ModalSearch) Focus(delegate func(p tview.Primitive)) {
+	delegate(m.table)
+}
+
+// HasFocus returns whether or not this primitive has focus.
+func (m *ModalSearch) HasFocus() bool {
+	return m.table.HasFocus()
+}
+
+// Draw draws this primitive onto the screen.
+func (m *ModalSearch) Draw(screen tcell.Screen) {
+	width, height := screen.Size()
+	height -= 7
+	m.frame.Clear()
+	x := 0
+	y := 6
+	m.SetRect(x, y, width, height)
+
+	// Long subjects are cut to the space left inside the border and padding
+	if inner := width - 4; inner != m.fitWidth {
+		fitColumn(m.table, 3, inner, m.subjects)
+		m.fitWidth = inner
+	}
+
+	// Draw the frame.
+	m.frame.SetRect(x, y, width, height)
+	m.frame.Draw(screen)
+}
+
+// InputHandler handle input
+func (m *ModalSearch) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return m.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if m.HasFocus() {
+			if keymap.Match(KeyActionCancel, event) {
+				m.done(nil)
+				return
+			}
+			switch event.Key() {
+			case tcell.KeyEnter:
+				// A changed term is searched first, Enter again opens the result
+				if string(m.term) != m.searched {
+					m.search()
+					return
+				}
+				row, _ := m.table.GetSelection()
+				if row > 0 && row <= len(m.hits) {
+					m.done(&m.hits[row-1])
+				}
+				return
+			case tcell.KeyBackspace, tcell.KeyBackspace2:
+				if len(m.term) > 0 {
+					m.term = m.term[:len(m.term)-1]
+					m.updateTitle()
+				}
+				return
+			case tcell.KeyRune:
+				m.term = append(m.term, event.Rune())
+				m.updateTitle()
+				return
+			}
+			if handler := m.table.InputHandler(); handler != nil {
+				handler(event, setFocus)
+			}
+			return
+		}
+	})
+}
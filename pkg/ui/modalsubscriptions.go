@@ -0,0 +1,166 @@
+package ui
+
+import (
+	"log"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ModalSubscriptions is a window listing links and their subscription state for an area
+type ModalSubscriptions struct {
+	*tview.Box
+	table         *tview.Table
+	frame         *tview.Frame
+	areaID        int64
+	subscriptions []database.LinkSubscription
+	done          func()
+}
+
+// NewModalSubscriptions returns a new subscriptions window for the given echoarea.
+func NewModalSubscriptions(areaName string, areaID int64) *ModalSubscriptions {
+	_, defBg, _ := config.StyleDefault.Decompose()
+	m := &ModalSubscriptions{
+		Box:    tview.NewBox().SetBackgroundColor(defBg),
+		areaID: areaID,
+	}
+	borderFg, _, borderAttr := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementBorder).Decompose()
+	headerStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementHeader)
+	selectionStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementSelection)
+	titleStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementTitle)
+	fgHeader, bgHeader, attrHeader := headerStyle.Decompose()
+	m.table = tview.NewTable().
+		SetFixed(1, 0).
+		SetBordersColor(borderFg).
+		SetSelectable(true, false).
+		SetSelectedStyle(selectionStyle).
+		SetSelectedFunc(func(row int, column int) {
+			m.toggle(row)
+		})
+	m.frame = tview.NewFrame(m.table).SetBorders(0, 0, 1, 0, 0, 0)
+	m.frame.SetBackgroundColor(defBg)
+	m.table.SetBackgroundColor(defBg)
+	m.frame.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderAttributes(borderAttr).
+		SetBorderColor(borderFg).
+		SetBorderPadding(0, 0, 1, 1).
+		SetTitle(config.FormatTextWithStyle(" Subscriptions: "+areaName+" ", titleStyle))
+	m.table.SetCell(
+		0, 0, tview.NewTableCell(" Sub").
+			SetTextColor(fgHeader).SetBackgroundColor(bgHeader).SetAttributes(attrHeader).
+			SetSelectable(false))
+	m.table.SetCell(
+		0, 1, tview.NewTableCell("Address").
+			SetTextColor(fgHeader).SetBackgroundColor(bgHeader).SetAttributes(attrHeader).
+			SetSelectable(false))
+	m.table.SetCell(
+		0, 2, tview.NewTableCell("Station").
+			SetTextColor(fgHeader).SetBackgroundColor(bgHeader).SetAttributes(attrHeader).
+			SetExpansion(1).
+			SetSelectable(false))
+
+	subscriptions, err := database.ListSubscriptions(areaID)
+	if err != nil {
+		log.Printf("Error loading subscriptions for area %s: %v", areaName, err)
+	}
+	m.subscriptions = subscriptions
+	for i := range m.subscriptions {
+		m.setRow(i)
+	}
+	return m
+}
+
+// setRow renders the table row for the i-th link
+func (m *ModalSubscriptions) setRow(i int) {
+	itemStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementItem)
+	highlightStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementHighlight)
+	fg, bg, attr := itemStyle.Decompose()
+	mark := " [ ]"
+	if m.subscriptions[i].Subscribed {
+		fg, bg, attr = highlightStyle.Decompose()
+		mark = " [x]"
+	}
+	m.table.SetCell(i+1, 0, tview.NewTableCell(tview.Escape(mark)).
+		SetTextColor(fg).SetBackgroundColor(bg).SetAttributes(attr))
+	m.table.SetCell(i+1, 1, tview.NewTableCell(m.subscriptions[i].Link.FtnAddress).
+		SetTextColor(fg).SetBackgroundColor(bg).SetAttributes(attr))
+	m.table.SetCell(i+1, 2, tview.NewTableCell(m.subscriptions[i].Link.StationName).
+		SetTextColor(fg).SetBackgroundColor(bg).SetAttributes(attr))
+}
+
+// toggle flips the subscription state of the link in the given table row
+func (m *ModalSubscriptions) toggle(row int) {
+	if row < 1 || row-1 >= len(m.subscriptions) {
+		return
+	}
+	ls := &m.subscriptions[row-1]
+	var err error
+	if ls.Subscribed {
+		err = database.UnsubscribeLink(ls.Link.ID, m.areaID)
+	} else {
+		err = database.SubscribeLink(ls.Link.ID, m.areaID)
+	}
+	if err != nil {
+		log.Printf("Error changing subscription for link %s: %v", ls.Link.FtnAddress, err)
+		return
+	}
+	ls.Subscribed = !ls.Subscribed
+	m.setRow(row - 1)
+}
+
+// SetDoneFunc sets a handler which is called when the window is closed.
+func (m *ModalSubscriptions) SetDoneFunc(handler func()) *ModalSubscriptions {
+	m.done = handler
+	return m
+}
+
+// Focus is called when this primitive receives focus.
+func (m *ModalSubscriptions) Focus(delegate func(p tview.Primitive)) {
+	delegate(m.table)
+}
+
+// HasFocus returns whether or not this primitive has focus.
+func (m *ModalSubscriptions) HasFocus() bool {
+	return m.table.HasFocus()
+}
+
+// Draw draws this primitive onto the screen.
+func (m *ModalSubscriptions) Draw(screen tcell.Screen) {
+	width, height := screen.Size()
+	height -= 7
+	m.frame.Clear()
+	x := 0
+	y := 6
+	m.SetRect(x, y, width, height)
+
+	// Draw the frame.
+	m.frame.SetRect(x, y, width, height)
+	m.frame.Draw(screen)
+}
+
+// InputHandler handle input
+func (m *ModalSubscriptions) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return m.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if m.HasFocus() {
+			if keymap.Match(KeyActionCancel, event) {
+				m.done()
+				return
+			}
+			switch event.Key() {
+			case tcell.KeyRune:
+				if event.Rune() == ' ' {
+					row, _ := m.table.GetSelection()
+					m.toggle(row)
+					return
+				}
+			}
+			if handler := m.table.InputHandler(); handler != nil {
+				handler(event, setFocus)
+			}
+			return
+		}
+	})
+}
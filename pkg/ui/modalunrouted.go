@@ -0,0 +1,190 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/msgapi"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ModalUnrouted is a window listing the unsent netmail no route is found for
+type ModalUnrouted struct {
+	*tview.Box
+	table    *tview.Table
+	frame    *tview.Frame
+	area     *msgapi.SQLArea
+	unrouted []msgapi.UnroutedNetmail
+	problems []string
+	fitWidth int
+	done     func(msgNum uint32)
+	status   func(text string)
+}
+
+// NewModalUnrouted returns a new window of the unrouted netmail of area.
+func NewModalUnrouted(area *msgapi.SQLArea) *ModalUnrouted {
+	_, defBg, _ := config.StyleDefault.Decompose()
+	m := &ModalUnrouted{
+		Box:  tview.NewBox().SetBackgroundColor(defBg),
+		area: area,
+	}
+	borderFg, _, borderAttr := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementBorder).Decompose()
+	headerStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementHeader)
+	selectionStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementSelection)
+	titleStyle := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementTitle)
+	fgHeader, bgHeader, attrHeader := headerStyle.Decompose()
+	m.table = tview.NewTable().
+		SetFixed(1, 0).
+		SetBordersColor(borderFg).
+		SetSelectable(true, false).
+		SetSelectedStyle(selectionStyle).
+		SetSelectedFunc(func(row int, column int) {
+			if row > 0 && row <= len(m.unrouted) {
+				m.done(m.unrouted[row-1].MsgNum)
+			}
+		})
+	m.frame = tview.NewFrame(m.table).SetBorders(0, 0, 1, 0, 0, 0)
+	m.frame.SetBackgroundColor(defBg)
+	m.table.SetBackgroundColor(defBg)
+	m.frame.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderAttributes(borderAttr).
+		SetBorderColor(borderFg).
+		SetBorderPadding(0, 0, 1, 1).
+		SetTitle(config.FormatTextWithStyle(unroutedTitle(), titleStyle))
+	for i, title := range []string{" Msg", "To", "Address", "Subject", "Problem"} {
+		cell := tview.NewTableCell(title).
+			SetTextColor(fgHeader).SetBackgroundColor(bgHeader).SetAttributes(attrHeader).
+			SetSelectable(false)
+		if i == 0 {
+			cell.SetAlign(tview.AlignRight)
+		}
+		if i == 4 {
+			cell.SetExpansion(1)
+		}
+		m.table.SetCell(0, i, cell)
+	}
+	m.load()
+	return m
+}
+
+// load reads the unrouted netmail and renders a table row for each
+func (m *ModalUnrouted) load() {
+	unrouted, err := m.area.UnroutedNetmail()
+	if err != nil {
+		log.Printf("Error listing unrouted netmail: %v", err)
+	}
+	m.unrouted = unrouted
+	fgItem, bgItem, attrItem := config.GetElementStyle(config.ColorAreaAreaListModal, config.ColorElementItem).Decompose()
+	for m.table.GetRowCount() > 1 {
+		m.table.RemoveRow(m.table.GetRowCount() - 1)
+	}
+	m.problems = m.problems[:0]
+	m.fitWidth = 0
+	for i, nm := range m.unrouted {
+		m.problems = append(m.problems, tview.Escape(nm.Problem))
+		for col, text := range []string{fmt.Sprintf(" %d", nm.MsgNum), nm.To, nm.ToAddr, nm.Subject, nm.Problem} {
+			cell := tview.NewTableCell(tview.Escape(text)).
+				SetTextColor(fgItem).SetBackgroundColor(bgItem).SetAttributes(attrItem)
+			if col == 0 {
+				cell.SetAlign(tview.AlignRight)
+			}
+			m.table.SetCell(i+1, col, cell)
+		}
+	}
+}
+
+// reroute routes the netmail in the given table row again
+func (m *ModalUnrouted) reroute(row int) {
+	if row < 1 || row > len(m.unrouted) {
+		return
+	}
+	nm := m.unrouted[row-1]
+	if err := m.area.RerouteNetmail(nm.ID); err != nil {
+		m.status(err.Error())
+		return
+	}
+	m.status(fmt.Sprintf("Netmail %d to %s routed", nm.MsgNum, nm.ToAddr))
+	m.load()
+	m.table.Select(min(row, max(len(m.unrouted), 1)), 0)
+}
+
+// SetDoneFunc sets a handler which is called with the number of the
+// selected netmail, or 0 when the user presses the Escape key.
+func (m *ModalUnrouted) SetDoneFunc(handler func(msgNum uint32)) *ModalUnrouted {
+	m.done = handler
+	return m
+}
+
+// SetStatusFunc sets a handler showing the outcome of a reroute.
+func (m *ModalUnrouted) SetStatusFunc(handler func(text string)) *ModalUnrouted {
+	m.status = handler
+	return m
+}
+
+// Len returns the number of unrouted netmail listed.
+func (m *ModalUnrouted) Len() int {
+	return len(m.unrouted)
+}
+
+// Focus is called when this primitive receives focus.
+func (m *ModalUnrouted) Focus(delegate func(p tview.Primitive)) {
+	delegate(m.table)
+}
+
+// HasFocus returns whether or not this primitive has focus.
+func (m *ModalUnrouted) HasFocus() bool {
+	return m.table.HasFocus()
+}
+
+// Draw draws this primitive onto the screen.
+func (m *ModalUnrouted) Draw(screen tcell.Screen) {
+	width, height := screen.Size()
+	height -= 7
+	m.frame.Clear()
+	x := 0
+	y := 6
+	m.SetRect(x, y, width, height)
+
+	// Long problems are cut to the space left inside the border and padding
+	if inner := width - 4; inner != m.fitWidth {
+		fitColumn(m.table, 4, inner, m.problems)
+		m.fitWidth = inner
+	}
+
+	// Draw the frame.
+	m.frame.SetRect(x, y, width, height)
+	m.frame.Draw(screen)
+}
+
+// InputHandler handle input
+func (m *ModalUnrouted) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return m.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if m.HasFocus() {
+			if keymap.Match(KeyActionCancel, event) {
+				m.done(0)
+				return
+			}
+			if keymap.Match(KeyActionReroute, event) {
+				row, _ := m.table.GetSelection()
+				m.reroute(row)
+				return
+			}
+			if handler := m.table.InputHandler(); handler != nil {
+				handler(event, setFocus)
+			}
+			return
+		}
+	})
+}
+
+// unroutedTitle returns the title of the window, naming the reroute key of
+// the keymap
+func unroutedTitle() string {
+	if key := keymap.Name(KeyActionReroute); key != "" {
+		return fmt.Sprintf(" Unrouted netmail, Enter opens, %s routes again ", key)
+	}
+	return " Unrouted netmail, Enter opens "
+}
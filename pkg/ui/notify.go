@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/askovpen/gossiped/pkg/msgapi"
+	"github.com/askovpen/gossiped/pkg/utils"
+	"github.com/gdamore/tcell/v2"
+)
+
+// unreadMail holds the unread messages of all areas as last counted
+type unreadMail struct {
+	counted bool
+	total   uint32
+	toMe    uint32
+}
+
+// countUnreadMail returns the unread messages of all areas and, of those,
+// the netmail to the user
+func countUnreadMail() unreadMail {
+	unread := unreadMail{counted: true}
+	for _, ar := range msgapi.Areas {
+		count, last := ar.GetCount(), ar.GetLast()
+		if count <= last {
+			continue
+		}
+		unread.total += count - last
+		if ar.GetType() != msgapi.EchoAreaTypeNetmail {
+			continue
+		}
+		if sqlArea, ok := ar.(*msgapi.SQLArea); ok {
+			toMe, err := sqlArea.CountUnreadTo(config.Config.Username)
+			if err != nil {
+				log.Printf("Error counting netmail to %s: %v", config.Config.Username, err)
+			}
+			unread.toMe += toMe
+			continue
+		}
+		for _, mh := range *ar.GetMessages() {
+			if mh.MsgNum > last && utils.NamesEqual(mh.To, config.Config.Username) {
+				unread.toMe++
+			}
+		}
+	}
+	return unread
+}
+
+// runMailPoller counts the unread mail every notify_interval until
+// stopMailPoller, reloading the jnode-sql message counts first, off the UI
+// thread, and hands the count to checkNewMail on it
+func (a *App) runMailPoller() {
+	if config.GetNotifyNewMail() == "none" {
+		return
+	}
+	// the mail there at start is not new
+	a.unread = countUnreadMail()
+	a.pollStop = make(chan struct{})
+	stop := a.pollStop
+	poll := time.NewTicker(config.GetNotifyInterval())
+	go func() {
+		defer poll.Stop()
+		for {
+			select {
+			case <-poll.C:
+				if database.DB != nil {
+					if err := msgapi.RefreshMessageCounts(); err != nil {
+						log.Printf("Error refreshing message counts: %v", err)
+						continue
+					}
+				}
+				unread := countUnreadMail()
+				a.App.QueueUpdateDraw(func() {
+					a.checkNewMail(unread)
+				})
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopMailPoller stops the poller of runMailPoller, if it runs
+func (a *App) stopMailPoller() {
+	if a.pollStop == nil {
+		return
+	}
+	select {
+	case <-a.pollStop:
+	default:
+		close(a.pollStop)
+	}
+}
+
+// checkNewMail announces new mail as notify_new_mail says when the unread
+// messages grew since they were last counted, which the first count of the
+// session and messages read since do not
+func (a *App) checkNewMail(unread unreadMail) {
+	notify := config.GetNotifyNewMail()
+	if notify == "none" {
+		return
+	}
+	seen := a.unread
+	a.unread = unread
+	if !seen.counted {
+		return
+	}
+	text, bells := "", 1
+	switch {
+	case a.unread.toMe > seen.toMe:
+		text, bells = fmt.Sprintf("NEW NETMAIL TO YOU: %d", a.unread.toMe-seen.toMe), 2
+	case a.unread.total > seen.total:
+		text = fmt.Sprintf("NEW MAIL: %d", a.unread.total-seen.total)
+	default:
+		return
+	}
+	if notify == "bell" {
+		a.bells += bells
+		a.sb.SetStatus(text)
+		return
+	}
+	a.sb.Flash(text)
+}
+
+// ringBells rings the terminal bell as many times as checkNewMail asked for,
+// once the screen is drawn
+func (a *App) ringBells(screen tcell.Screen) {
+	for ; a.bells > 0; a.bells-- {
+		screen.Beep()
+	}
+}
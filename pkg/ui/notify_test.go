@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/msgapi"
+	"github.com/askovpen/gossiped/pkg/types"
+	. "github.com/franela/goblin"
+	"github.com/rivo/tview"
+)
+
+func TestNewMailNotify(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check new mail notification", func() {
+		var echo, netmail msgapi.AreaPrimitive
+		savedAreas, savedNotify, savedUser := msgapi.Areas, config.Config.NotifyNewMail, config.Config.Username
+		a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+		a.sb = NewStatusBar(a)
+		post := func(area *msgapi.AreaPrimitive, to string) {
+			m := &msgapi.Message{AreaObject: area, From: "SysOp", To: to, Subject: "Test",
+				FromAddr: types.AddrFromNum(2, 5020, 9696, 1), ToAddr: types.AddrFromNum(2, 5020, 9696, 2),
+				Body: "Body", Kludges: map[string]string{}}
+			g.Assert((*area).SaveMsg(m.MakeBody())).IsNil()
+		}
+		g.Before(func() {
+			echo = msgapi.NewMemoryArea("su.general", msgapi.EchoAreaTypeEcho)
+			netmail = msgapi.NewMemoryArea("netmail", msgapi.EchoAreaTypeNetmail)
+			msgapi.Areas = []msgapi.AreaPrimitive{echo, netmail}
+			config.Config.Username = "Alexander Skovpen"
+			config.Config.NotifyNewMail = "flash"
+			post(&echo, "All")
+		})
+		g.After(func() {
+			msgapi.Areas, config.Config.NotifyNewMail, config.Config.Username = savedAreas, savedNotify, savedUser
+		})
+		g.It("check the first count is not announced", func() {
+			a.checkNewMail(countUnreadMail())
+			g.Assert(a.unread.total).Equal(uint32(1))
+			g.Assert(a.sb.Warning()).Equal("")
+		})
+		g.It("check new mail flashes once", func() {
+			post(&echo, "All")
+			post(&netmail, "Someone Else")
+			a.checkNewMail(countUnreadMail())
+			g.Assert(a.sb.Warning()).Equal("NEW MAIL: 2")
+			a.sb.SetWarning("")
+			a.checkNewMail(countUnreadMail())
+			g.Assert(a.sb.Warning()).Equal("")
+		})
+		g.It("check messages read are not announced", func() {
+			echo.SetLast(echo.GetCount())
+			a.checkNewMail(countUnreadMail())
+			g.Assert(a.sb.Warning()).Equal("")
+		})
+		g.It("check netmail to the user rings twice", func() {
+			config.Config.NotifyNewMail = "bell"
+			post(&netmail, "Alexander Skovpen")
+			a.checkNewMail(countUnreadMail())
+			g.Assert(a.bells).Equal(2)
+			g.Assert(a.unread.toMe).Equal(uint32(1))
+		})
+		g.It("check none counts nothing", func() {
+			config.Config.NotifyNewMail = "none"
+			a.bells = 0
+			post(&netmail, "Alexander Skovpen")
+			a.checkNewMail(countUnreadMail())
+			g.Assert(a.bells).Equal(0)
+		})
+	})
+}
+
+func TestMailPoller(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check the new mail poller", func() {
+		savedAreas, savedNotify, savedInterval := msgapi.Areas, config.Config.NotifyNewMail, config.Config.NotifyInterval
+		g.After(func() {
+			msgapi.Areas, config.Config.NotifyNewMail, config.Config.NotifyInterval = savedAreas, savedNotify, savedInterval
+		})
+		g.It("check it only runs when new mail is announced", func() {
+			config.Config.NotifyNewMail = "none"
+			a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+			a.runMailPoller()
+			g.Assert(a.pollStop == nil).IsTrue()
+			a.stopMailPoller()
+		})
+		g.It("check it counts the mail there at start and stops", func() {
+			config.Config.NotifyNewMail = "flash"
+			config.Config.NotifyInterval = time.Hour
+			var area msgapi.AreaPrimitive = msgapi.NewMemoryArea("su.general", msgapi.EchoAreaTypeEcho)
+			m := &msgapi.Message{AreaObject: &area, From: "SysOp", To: "All", Subject: "Test",
+				FromAddr: types.AddrFromNum(2, 5020, 9696, 1), ToAddr: types.AddrFromNum(2, 5020, 9696, 2),
+				Body: "Body", Kludges: map[string]string{}}
+			g.Assert(area.SaveMsg(m.MakeBody())).IsNil()
+			msgapi.Areas = []msgapi.AreaPrimitive{area}
+			a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+			a.runMailPoller()
+			g.Assert(a.unread).Equal(unreadMail{counted: true, total: 1})
+			a.stopMailPoller()
+			a.stopMailPoller()
+			_, open := <-a.pollStop
+			g.Assert(open).IsFalse()
+		})
+	})
+}
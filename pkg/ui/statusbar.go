@@ -1,23 +1,30 @@
 package ui
 
 import (
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/askovpen/gossiped/pkg/config"
 	"github.com/rivo/tview"
 )
 
+// flashDuration is how long Flash shows its text
+const flashDuration = 3 * time.Second
+
 // StatusBar struct
 type StatusBar struct {
 	SB         *tview.Flex
 	status     *tview.TextView
+	warning    *tview.TextView
 	statusTime *tview.TextView
 	app        *App
+	stop       chan struct{}
 }
 
 // NewStatusBar func
 func NewStatusBar(app *App) *StatusBar {
-	sb := &StatusBar{}
+	sb := &StatusBar{stop: make(chan struct{})}
 
 	sb.app = app
 	styleText := config.GetElementStyle(config.ColorAreaStatusBar, config.ColorElementText)
@@ -28,16 +35,21 @@ func NewStatusBar(app *App) *StatusBar {
 		sb.app.App.Draw()
 	})
 
+	sb.warning = tview.NewTextView().SetWrap(false)
+	sb.warning.SetTextStyle(styleText.Reverse(true))
+
 	sb.statusTime = tview.NewTextView().SetWrap(false)
 	sb.statusTime.SetTextStyle(styleText)
 	sb.statusTime.SetDynamicColors(true)
-	sb.statusTime.SetChangedFunc(func() {
-		sb.app.App.Draw()
-	})
 
+	clockWidth := 0
+	if config.Config.Statusbar.Clock {
+		clockWidth = utf8.RuneCountInString(time.Now().Format(config.GetClockFormat())) + 2
+	}
 	sb.SB = tview.NewFlex().
 		AddItem(sb.status, 0, 1, false).
-		AddItem(sb.statusTime, 10, 1, false)
+		AddItem(sb.warning, 0, 0, false).
+		AddItem(sb.statusTime, clockWidth, 0, false)
 	return sb
 }
 
@@ -50,18 +62,65 @@ func (sb StatusBar) SetStatus(s string) {
 	sb.status.SetText(" " + s)
 }
 
+// SetWarning shows a lasting warning like a degraded lastread database left
+// of the clock, "" removes it
+func (sb StatusBar) SetWarning(s string) {
+	if s != "" {
+		s = " " + s + " "
+	}
+	sb.warning.SetText(s)
+	sb.SB.ResizeItem(sb.warning, utf8.RuneCountInString(s), 0)
+}
+
+// Flash shows s in place of the warning for flashDuration, then puts the
+// warning back
+func (sb StatusBar) Flash(s string) {
+	prev := sb.Warning()
+	sb.SetWarning(s)
+	time.AfterFunc(flashDuration, func() {
+		sb.app.App.QueueUpdateDraw(func() {
+			if sb.Warning() == s {
+				sb.SetWarning(prev)
+			}
+		})
+	})
+}
+
+// Warning returns the warning shown, "" if there is none
+func (sb StatusBar) Warning() string {
+	return strings.TrimSpace(sb.warning.GetText(false))
+}
+
 // Run update timers
 func (sb StatusBar) Run() {
-	if config.Config.Statusbar.Clock {
-		styleText := config.GetElementStyle(config.ColorAreaStatusBar, config.ColorElementText)
-		sb.statusTime.SetTextStyle(styleText)
-		sb.statusTime.SetText(time.Now().Format("15:04:05"))
-		clock := time.NewTicker(1 * time.Second)
-		go func() {
-			for t := range clock.C {
-				//fmt.Fprintf(ui.StatusTime,"%s",t.Format("15:04:05"))
-				sb.statusTime.SetText(t.Format("15:04:05"))
+	if !config.Config.Statusbar.Clock {
+		return
+	}
+	format := config.GetClockFormat()
+	styleText := config.GetElementStyle(config.ColorAreaStatusBar, config.ColorElementText)
+	sb.statusTime.SetTextStyle(styleText)
+	sb.statusTime.SetText(time.Now().Format(format))
+	clock := time.NewTicker(1 * time.Second)
+	go func() {
+		defer clock.Stop()
+		for {
+			select {
+			case t := <-clock.C:
+				sb.app.App.QueueUpdateDraw(func() {
+					sb.statusTime.SetText(t.Format(format))
+				})
+			case <-sb.stop:
+				return
 			}
-		}()
+		}
+	}()
+}
+
+// Stop stops the clock
+func (sb *StatusBar) Stop() {
+	select {
+	case <-sb.stop:
+	default:
+		close(sb.stop)
 	}
 }
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/askovpen/gossiped/pkg/msgapi"
+	"github.com/rivo/tview"
+)
+
+// areaTags returns the tag set of area, a new one if tags were kept for
+// another area
+func (a *App) areaTags(area *msgapi.AreaPrimitive) map[uint32]bool {
+	if a.tags == nil || a.tagsArea != (*area).GetName() {
+		a.tags = make(map[uint32]bool)
+		a.tagsArea = (*area).GetName()
+	}
+	return a.tags
+}
+
+// taggedMsgs returns the tagged message numbers of area, sorted
+func (a *App) taggedMsgs(area *msgapi.AreaPrimitive) []uint32 {
+	if a.tagsArea != (*area).GetName() {
+		return nil
+	}
+	var nums []uint32
+	for n, ok := range a.tags {
+		if ok {
+			nums = append(nums, n)
+		}
+	}
+	slices.Sort(nums)
+	return nums
+}
+
+// clearTags drops the tag set, e.g. on leaving the area
+func (a *App) clearTags() {
+	a.tags = nil
+	a.tagsArea = ""
+}
+
+// tagAction runs a batch action of the message list on the tagged messages
+func (a *App) tagAction(area *msgapi.AreaPrimitive, action string) {
+	nums := a.taggedMsgs(area)
+	if len(nums) == 0 {
+		return
+	}
+	if (action == KeyActionDelete || action == KeyActionMoveMessage) && !(*area).Capabilities().Has(msgapi.AreaCanDelete) {
+		a.sb.SetStatus(fmt.Sprintf("Messages of %s can not be deleted", (*area).GetName()))
+		return
+	}
+	switch action {
+	case KeyActionDelete:
+		a.Pages.AddPage(a.showDelTagged(area, nums))
+		a.Pages.ShowPage("DelMsgModal")
+	case KeyActionMoveMessage:
+		a.Pages.AddPage(a.showMoveTagged(area, nums))
+		a.Pages.ShowPage("AreaListModal")
+	case KeyActionMarkRead:
+		// lastread is a single pointer, move it to the last tagged message
+		cur, last := (*area).GetLast(), nums[len(nums)-1]
+		a.markRead(area, last)
+		a.clearTags()
+		a.reopenArea(area, cur, max(cur, last))
+		a.sb.SetStatus(fmt.Sprintf("Marked read up to message %d", last))
+	}
+}
+
+// showDelTagged asks before deleting the tagged messages
+func (a *App) showDelTagged(area *msgapi.AreaPrimitive, nums []uint32) (string, tview.Primitive, bool, bool) {
+	cur := (*area).GetLast()
+	modal := NewModalMenu().
+		SetY(6).
+		SetText(fmt.Sprintf("Delete %d tagged messages?", len(nums))).
+		AddButtons([]string{"Yes", "No"}).
+		SetDoneFunc(func(buttonIndex int) {
+			a.Pages.HidePage("DelMsgModal")
+			a.Pages.RemovePage("DelMsgModal")
+			if buttonIndex == 0 {
+				if err := msgapi.DelMsgs(*area, nums); err != nil {
+					a.sb.SetStatus(err.Error())
+				} else {
+					a.sb.SetStatus(fmt.Sprintf("Deleted %d messages", len(nums)))
+				}
+				a.clearTags()
+				a.reopenArea(area, cur, cur)
+			}
+			a.App.SetFocus(a.Pages)
+		})
+	return "DelMsgModal", modal, true, true
+}
+
+// showMoveTagged picks an area to move the tagged messages to
+func (a *App) showMoveTagged(area *msgapi.AreaPrimitive, nums []uint32) (string, tview.Primitive, bool, bool) {
+	cur := (*area).GetLast()
+	modal := NewModalAreaList().
+		SetDoneFunc(func(buttonIndex int) {
+			a.Pages.HidePage("AreaListModal")
+			a.Pages.RemovePage("AreaListModal")
+			dst := msgapi.Areas[buttonIndex-1]
+			if err := msgapi.MoveMessages(*area, nums, dst); err != nil {
+				a.sb.SetStatus(err.Error())
+			} else {
+				a.sb.SetStatus(fmt.Sprintf("Moved %d messages to %s", len(nums), dst.GetName()))
+			}
+			a.clearTags()
+			a.reopenArea(area, cur, cur)
+			a.App.SetFocus(a.Pages)
+		})
+	modal.SetText(fmt.Sprintf("Move %d Messages To Area:", len(nums)))
+	return "AreaListModal", modal, true, true
+}
+
+// reopenArea replaces the message view of cur after a batch operation with
+// the view of target or the nearest remaining message, closing the message list
+func (a *App) reopenArea(area *msgapi.AreaPrimitive, cur uint32, target uint32) {
+	a.Pages.RemovePage("MessageListModal")
+	a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), cur))
+	msgNum := openMsgNum(target, (*area).GetCount())
+	a.Pages.AddPage(a.ViewMsg(area, msgNum))
+	a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum))
+}
@@ -418,6 +418,56 @@ func stringWidth(text string) (width int) {
 	return
 }
 
+// truncateString shortens text to at most width screen cells, ending it with
+// an ellipsis when it was cut. Grapheme clusters are kept whole, so wide and
+// combining characters are never split.
+func truncateString(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if stringWidth(text) <= width {
+		return text
+	}
+	var cut string
+	var cutWidth int
+	g := uniseg.NewGraphemes(text)
+	for g.Next() {
+		chWidth := stringWidth(g.Str())
+		if cutWidth+chWidth > width-1 {
+			break
+		}
+		cut += g.Str()
+		cutWidth += chWidth
+	}
+	return cut + "…"
+}
+
+// fitColumn truncates the cells of column col of table, below the header
+// row, so that the table is no wider than width cells. texts holds the full
+// texts of the column, one per row.
+func fitColumn(table *tview.Table, col, width int, texts []string) {
+	others := 0
+	for c := 0; c < table.GetColumnCount(); c++ {
+		if c == col {
+			continue
+		}
+		colWidth := 0
+		for r := 0; r < table.GetRowCount(); r++ {
+			if cell := table.GetCell(r, c); cell != nil {
+				colWidth = max(colWidth, stringWidth(cell.Text))
+			}
+		}
+		// one cell between columns
+		others += colWidth + 1
+	}
+	avail := max(width-others, 8)
+	for i, text := range texts {
+		if cell := table.GetCell(i+1, col); cell != nil {
+			cell.SetText(truncateString(text, avail))
+		}
+	}
+}
+
 // WordWrap splits a text such that each resulting line does not exceed the
 // given screen width. Possible split points are after any punctuation or
 // whitespace. Whitespace after split points will be dropped.
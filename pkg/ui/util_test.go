@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"testing"
+
+	. "github.com/franela/goblin"
+	"github.com/rivo/tview"
+)
+
+func TestTruncateString(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check truncateString()", func() {
+		g.It("check short text is kept", func() {
+			g.Assert(truncateString("Re: hello", 9)).Equal("Re: hello")
+			g.Assert(truncateString("Re: hello", 0)).Equal("")
+		})
+		g.It("check long text is cut with an ellipsis", func() {
+			g.Assert(truncateString("Re: hello world", 8)).Equal("Re: hel…")
+			g.Assert(stringWidth(truncateString("Re: hello world", 8))).Equal(8)
+		})
+		g.It("check wide and combining characters", func() {
+			// 2 cells per ideograph, a wide one is not split by the ellipsis
+			g.Assert(truncateString("漢字漢字", 6)).Equal("漢字…")
+			g.Assert(stringWidth(truncateString("漢字漢字", 6))).Equal(5)
+			g.Assert(truncateString("éééé", 3)).Equal("éé…")
+		})
+		g.It("check fitColumn()", func() {
+			table := tview.NewTable()
+			table.SetCellSimple(0, 0, "Msg")
+			table.SetCellSimple(0, 1, "Subj")
+			table.SetCellSimple(1, 0, "1")
+			table.SetCellSimple(1, 1, "a rather long subject line")
+			fitColumn(table, 1, 15, []string{"a rather long subject line"})
+			g.Assert(table.GetCell(1, 1).Text).Equal("a rather l…")
+			fitColumn(table, 1, 80, []string{"a rather long subject line"})
+			g.Assert(table.GetCell(1, 1).Text).Equal("a rather long subject line")
+		})
+	})
+}
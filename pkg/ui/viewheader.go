@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/askovpen/gossiped/pkg/config"
@@ -14,67 +15,134 @@ import (
 // ViewHeader widget
 type ViewHeader struct {
 	*tview.Box
-	sInputs   [10][]rune
+	sInputs   [][]rune
 	sPosition int
-	sCoords   [10]coords
+	sCoords   []coords
+	labels    []headerLabel
+	subject   int
+	program   int
 	done      func(string)
 	msg       *msgapi.Message
 }
 
-// NewViewHeader create new ViewHeader
+// headerLabel is the label of a line of the view header, or a word between
+// its fields if item is set
+type headerLabel struct {
+	text string
+	x, y int
+	item bool
+}
+
+// NewViewHeader create new ViewHeader showing the lines of header_fields;
+// the message number input of the msg line is always sInputs[0]
 func NewViewHeader(msg *msgapi.Message) *ViewHeader {
-	var si [10][]rune
+	eh := &ViewHeader{
+		Box:       tview.NewBox().SetBackgroundColor(tcell.ColorDefault),
+		sPosition: 0,
+		subject:   -1,
+		program:   -1,
+		msg:       msg,
+	}
+	fields := config.GetHeaderFields()
+	y := slices.Index(fields, "msg")
+	eh.labels = append(eh.labels, headerLabel{"Msg  :", 1, y, false}, headerLabel{"of", 14, y, true})
 	if msg == nil {
-		si = [10][]rune{[]rune("0"), []rune("0"), []rune(""), []rune(""), []rune(""), []rune(""), []rune(""), []rune(""), []rune(""), []rune("")}
-	} else {
-		repl := ""
-		if msg.ReplyTo > 0 {
-			repl = fmt.Sprintf("-%d ", msg.ReplyTo)
-		}
-		for _, rn := range msg.Replies {
-			repl += fmt.Sprintf("+%d ", rn)
-		}
-		if msg.Corrupted {
-			msg.Attrs = append(msg.Attrs, "[red]Corrupted")
+		eh.add("0", 8, 13, y)
+		eh.add("0", 17, 22, y)
+		eh.add("", 23, 67, y)
+		for y, field := range fields {
+			if field != "msg" {
+				eh.labels = append(eh.labels, headerLabel{headerFieldLabel(field, nil), 1, y, false})
+			}
 		}
-		if len(msg.Attrs) > 0 {
-			repl += "[" + strings.Join(msg.Attrs, " ") + "]"
+		return eh
+	}
+	repl := ""
+	if msg.ReplyTo > 0 {
+		repl = fmt.Sprintf("-%d ", msg.ReplyTo)
+	}
+	for _, rn := range msg.Replies {
+		repl += fmt.Sprintf("+%d ", rn)
+	}
+	if msg.Corrupted {
+		msg.Attrs = append(msg.Attrs, "[red]Corrupted")
+	}
+	if len(msg.Attrs) > 0 {
+		repl += "[" + strings.Join(msg.Attrs, " ") + "]"
+	}
+	repl += " [" + config.GetCity(msg.FromAddr) + "]"
+	area := msgapi.Areas[msgapi.Lookup(msg.Area)]
+	eh.add(fmt.Sprintf("%d", msg.MsgNum), 8, 13, y)
+	eh.add(fmt.Sprintf("%d", area.GetCount()), 17, 22, y)
+	eh.add(repl, 23, 67, y)
+	for y, field := range fields {
+		if field != "msg" {
+			eh.labels = append(eh.labels, headerLabel{headerFieldLabel(field, msg), 1, y, false})
 		}
-		repl += " [" + config.GetCity(msg.FromAddr) + "]"
-		si = [10][]rune{
-			[]rune(fmt.Sprintf("%d", msg.MsgNum)),
-			[]rune(fmt.Sprintf("%d", msgapi.Areas[msgapi.Lookup(msg.Area)].GetCount())),
-			[]rune(repl),
-			[]rune(msg.From),
-			[]rune(msg.FromAddr.String()),
-			[]rune(msg.DateWritten.Format("02 Jan 2006 15:04:05")),
-			[]rune(msg.To),
-			[]rune(msg.ToAddr.String()),
-			[]rune(msg.DateArrived.Format("02 Jan 2006 15:04:05")),
-			[]rune(msg.Subject),
+		switch field {
+		case "from":
+			eh.add(msg.From, 8, 42, y)
+			eh.add(msg.FromAddr.String(), 43, 58, y)
+			eh.add(msg.DateWritten.Format("02 Jan 2006 15:04:05"), 60, 78, y)
+		case "to":
+			eh.add(msg.To, 8, 42, y)
+			eh.add(msg.ToAddr.String(), 43, 58, y)
+			eh.add(msg.DateArrived.Format("02 Jan 2006 15:04:05"), 60, 78, y)
+		case "subj":
+			eh.subject = eh.add(msg.Subject, 8, 67, y)
+			eh.program = eh.add(string(programName(msg, 10)), 68, 78, y)
+		case "date":
+			eh.add(msg.DateWritten.Format("Mon 02 Jan 2006 15:04:05"), 8, 42, y)
+			eh.add("arrived "+msg.DateArrived.Format("Mon 02 Jan 2006 15:04:05"), 43, 78, y)
+		case "area":
+			name := msg.Area
+			if d, ok := area.(interface{ GetDescription() string }); ok && d.GetDescription() != "" {
+				name += " - " + d.GetDescription()
+			}
+			eh.add(name, 8, 78, y)
+		case "addr":
+			addr := msg.FromAddr.String()
+			if !msg.ToAddr.IsZero() {
+				addr += " -> " + msg.ToAddr.String()
+			}
+			eh.add(addr, 8, 78, y)
 		}
 	}
-	eh := &ViewHeader{
-		Box: tview.NewBox().SetBackgroundColor(tcell.ColorDefault),
-		sCoords: [10]coords{
-			{f: 8, t: 13, y: 0},
-			{f: 17, t: 22, y: 0},
-			{f: 23, t: 67, y: 0},
-			{f: 8, t: 42, y: 1},
-			{f: 43, t: 58, y: 1},
-			{f: 60, t: 78, y: 1},
-			{f: 8, t: 42, y: 2},
-			{f: 43, t: 58, y: 2},
-			{f: 60, t: 78, y: 2},
-			{f: 8, t: 67, y: 3},
-		},
-		sInputs:   si,
-		sPosition: 0,
-		msg:       msg,
-	}
 	return eh
 }
 
+// add adds a field showing text between columns f and t of line y,
+// returning its index
+func (e *ViewHeader) add(text string, f int, t int, y int) int {
+	e.sInputs = append(e.sInputs, []rune(text))
+	e.sCoords = append(e.sCoords, coords{f: f, t: t, y: y})
+	return len(e.sInputs) - 1
+}
+
+// headerFieldLabel returns the label of a header_fields line
+func headerFieldLabel(field string, msg *msgapi.Message) string {
+	switch field {
+	case "from":
+		return "From :"
+	case "to":
+		return "To   :"
+	case "subj":
+		return subjectLabel(msg)
+	case "date":
+		return "Date :"
+	case "area":
+		return "Area :"
+	case "addr":
+		return "Addr :"
+	}
+	return "Msg  :"
+}
+
+// Height returns the rows the header takes, its lines and border
+func (e *ViewHeader) Height() int {
+	return len(config.GetHeaderFields()) + 2
+}
+
 // Draw header
 func (e *ViewHeader) Draw(screen tcell.Screen) {
 	e.Box.Draw(screen)
@@ -88,11 +156,13 @@ func (e *ViewHeader) Draw(screen tcell.Screen) {
 	highlightStyle := config.GetElementStyle(config.ColorAreaMessageHeader, config.ColorElementHighlight)
 	headerStyle := config.GetElementStyle(config.ColorAreaMessageHeader, config.ColorElementHeader)
 	_, bgSel, _ := config.GetElementStyle(config.ColorAreaMessageHeader, config.ColorElementSelection).Decompose()
-	tview.Print(screen, config.FormatTextWithStyle("of", itemStyle), x+14, y, 2, 0, boxFg)
-	tview.Print(screen, config.FormatTextWithStyle("Msg  :", headerStyle), x+1, y, 6, 0, boxFg)
-	tview.Print(screen, config.FormatTextWithStyle("From :", headerStyle), x+1, y+1, 6, 0, boxFg)
-	tview.Print(screen, config.FormatTextWithStyle("To   :", headerStyle), x+1, y+2, 6, 0, boxFg)
-	tview.Print(screen, config.FormatTextWithStyle("Subj :", headerStyle), x+1, y+3, 6, 0, boxFg)
+	for _, l := range e.labels {
+		style := headerStyle
+		if l.item {
+			style = itemStyle
+		}
+		tview.Print(screen, config.FormatTextWithStyle(l.text, style), x+l.x, y+l.y, len(l.text), 0, boxFg)
+	}
 	if e.HasFocus() {
 		for i := e.sCoords[0].f; i < e.sCoords[0].t; i++ {
 			screen.SetContent(x+i, y+e.sCoords[0].y, ' ', nil, defStyle.Background(bgSel))
@@ -101,12 +171,17 @@ func (e *ViewHeader) Draw(screen tcell.Screen) {
 	for i := 0; i < len(e.sCoords); i++ {
 		str := string(e.sInputs[i])
 		style := itemStyle
-		if utils.NamesEqual(config.Config.Username, str) {
+		if utils.NamesEqual(config.Config.Username, str) || (i == e.subject && e.msg != nil && e.msg.HasAttach()) {
 			style = highlightStyle
 		} else {
 			style = itemStyle
 		}
-		tview.Print(screen, config.FormatTextWithStyle(str, style), x+e.sCoords[i].f, y+e.sCoords[i].y, len(e.sInputs[i]), 0, boxFg)
+		width := len(e.sInputs[i])
+		if i == e.subject && len(e.sInputs[e.program]) > 0 {
+			// a long subject would run into the program name
+			width = min(width, e.sCoords[i].t-e.sCoords[i].f)
+		}
+		tview.Print(screen, config.FormatTextWithStyle(str, style), x+e.sCoords[i].f, y+e.sCoords[i].y, width, 0, boxFg)
 	}
 	if e.HasFocus() {
 		screen.ShowCursor(x+e.sCoords[0].f+len(e.sInputs[0][:e.sPosition]), y+e.sCoords[0].y)
@@ -154,6 +229,16 @@ func (e *ViewHeader) InputHandler() func(event *tcell.EventKey, setFocus func(p
 	})
 }
 
+// programName returns the name of the software which wrote msg, without
+// its version, cut to width cells
+func programName(msg *msgapi.Message, width int) []rune {
+	fields := strings.Fields(msg.Program())
+	if len(fields) == 0 {
+		return []rune("")
+	}
+	return []rune(truncateString(fields[0], width))
+}
+
 // SetDoneFunc callback
 func (e *ViewHeader) SetDoneFunc(handler func(string)) *ViewHeader {
 	e.done = handler
@@ -0,0 +1,36 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	. "github.com/franela/goblin"
+)
+
+func TestViewHeaderFields(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check ViewHeader header_fields", func() {
+		g.After(func() {
+			config.Config.HeaderFields = nil
+		})
+		g.It("check default layout", func() {
+			config.Config.HeaderFields = nil
+			h := NewViewHeader(nil)
+			g.Assert(h.Height()).Equal(6)
+			g.Assert(h.sCoords[0].y).Equal(0)
+			g.Assert(h.labels[len(h.labels)-1]).Equal(headerLabel{"Subj :", 1, 3, false})
+		})
+		g.It("check lines follow the configured order", func() {
+			config.Config.HeaderFields = []string{"area", "subj", "msg", "date"}
+			h := NewViewHeader(nil)
+			g.Assert(h.Height()).Equal(6)
+			g.Assert(h.sCoords[0].y).Equal(2)
+			g.Assert(h.labels[:2]).Equal([]headerLabel{{"Msg  :", 1, 2, false}, {"of", 14, 2, true}})
+			g.Assert(h.labels[2:]).Equal([]headerLabel{{"Area :", 1, 0, false}, {"Subj :", 1, 1, false}, {"Date :", 1, 3, false}})
+		})
+		g.It("check optional lines grow the header", func() {
+			config.Config.HeaderFields = []string{"msg", "from", "to", "subj", "date", "area", "addr"}
+			g.Assert(NewViewHeader(nil).Height()).Equal(9)
+		})
+	})
+}
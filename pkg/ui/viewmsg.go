@@ -2,16 +2,23 @@ package ui
 
 import (
 	"fmt"
+	"log"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
 	"github.com/askovpen/gossiped/pkg/msgapi"
 	"github.com/askovpen/gossiped/pkg/ui/editor"
+	"github.com/askovpen/gossiped/pkg/utils"
+	"github.com/atotto/clipboard"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
 func (a *App) SwitchToAreaListPage() {
+	a.clearTags()
 	// When using unread sorting, position cursor at top (first unread area)
 	if config.Config.Sorting["areas"] == msgapi.AreasSortingUnread {
 		a.RefreshAreaListToFirstUnread()
@@ -25,7 +32,15 @@ func (a *App) SwitchToAreaListPage() {
 
 // ViewMsg widget
 func (a *App) ViewMsg(area *msgapi.AreaPrimitive, msgNum uint32) (string, tview.Primitive, bool, bool) {
-	msg, err := (*area).GetMsg(msgNum)
+	var msg *msgapi.Message
+	var err error
+	// Positions past the end are clamped to the last message, so stepping
+	// over it never renders a blank page
+	msgNum = openMsgNum(msgNum, (*area).GetCount())
+	// An empty area gets a placeholder view instead of a phantom message 1
+	if msgNum > 0 {
+		msg, err = (*area).GetMsg(msgNum)
+	}
 	if err != nil {
 		modal := tview.NewModal().
 			SetText(err.Error()).
@@ -35,24 +50,28 @@ func (a *App) ViewMsg(area *msgapi.AreaPrimitive, msgNum uint32) (string, tview.
 			})
 		return fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum), modal, true, true
 	}
+	if msg != nil && config.GetReadPolicy() == "on_open" {
+		a.readMsg(area, msgNum)
+	}
 	if msg != nil {
-		if msgNum == 0 {
-			msgNum = 1
-		}
-		(*area).SetLast(msgNum)
+		a.visitMsg(area, msg)
 	}
-	
+
 	// Set appropriate status message
 	if (*area).GetCount() == 0 {
 		a.sb.SetStatus(fmt.Sprintf("%s: empty area (0 messages)",
 			(*area).GetName()))
 	} else {
-		a.sb.SetStatus(fmt.Sprintf("%s: message %d of %d (%d left)",
+		status := fmt.Sprintf("%s: message %d of %d (%d left)",
 			(*area).GetName(),
 			msgNum,
 			(*area).GetCount(),
 			(*area).GetCount()-msgNum,
-		))
+		)
+		if msg != nil && (*area).GetType() == msgapi.EchoAreaTypeNetmail && msg.WantsReceipt() {
+			status += ", return receipt requested"
+		}
+		a.sb.SetStatus(status)
 	}
 	styleBorder := config.GetElementStyle(config.ColorAreaMessageHeader, config.ColorElementBorder)
 	fgTitle, bgTitle, titleAttrs := config.GetElementStyle(config.ColorAreaMessageHeader, config.ColorElementTitle).Decompose()
@@ -68,42 +87,46 @@ func (a *App) ViewMsg(area *msgapi.AreaPrimitive, msgNum uint32) (string, tview.
 		SetTitleAlign(tview.AlignLeft)
 	var body *editor.View
 	if msg != nil {
-		content := msg.ToView(a.showKludges)
+		content := msgViewText(msg, a.showKludges)
 		body = editor.NewView(editor.NewBufferFromString(content))
 	} else {
-		// For empty areas, use a single newline to ensure background fills the space
-		body = editor.NewView(editor.NewBufferFromString("\n"))
+		body = editor.NewView(editor.NewBufferFromString(emptyAreaText((*area).GetName())))
 	}
 	header.SetDoneFunc(func(s string) {
 		num, _ := strconv.ParseUint(s, 10, 32)
-		if uint32(num) >= (*area).GetCount() {
+		if num == 0 || uint32(num) == msgNum {
 			a.App.SetFocus(body)
 		} else {
-			if a.Pages.HasPage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), num)) {
-				a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), num))
-				go (func() {
-					a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum))
-				})()
-			} else {
-				a.Pages.AddPage(a.ViewMsg(area, uint32(num)))
-				a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), num))
-				go (func() {
-					a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum))
-				})()
-			}
+			a.switchViewMsg(area, msgNum, area, uint32(num))
 		}
 	})
 
 	body.Readonly = true
+	if msg != nil && config.GetReadPolicy() == "on_scroll_end" {
+		read := false
+		body.SetBottomFunc(func() {
+			if !read {
+				read = true
+				// saving the lastread is a database write, kept out of Draw
+				go a.App.QueueUpdateDraw(func() {
+					a.readMsg(area, msgNum)
+				})
+			}
+		})
+	}
+	showRaw := false
 	body.SetDoneFunc(func() {
 		a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum))
 		a.SwitchToAreaListPage()
 	})
 	body.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		var area = a.CurrentArea
-		if event.Key() == tcell.KeyF1 {
+		if body.IsSearching() {
+			return event
+		}
+		if keymap.Match(KeyActionHelp, event) {
 			a.Pages.AddPage(a.ViewMsgHelp())
-		} else if event.Key() == tcell.KeyRight {
+		} else if keymap.Match(KeyActionNext, event) {
 			if msgNum == (*area).GetCount() {
 				if config.Config.Sorting["areas"] == msgapi.AreasSortingUnread {
 					a.RefreshAreaList()
@@ -116,82 +139,175 @@ func (a *App) ViewMsg(area *msgapi.AreaPrimitive, msgNum uint32) (string, tview.
 					a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum))
 				})()
 			} else {
-				if a.Pages.HasPage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum+1)) {
-					a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum+1))
-					go (func() {
-						a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum))
-					})()
-				} else {
-					a.Pages.AddPage(a.ViewMsg(area, msgNum+1))
-					a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum+1))
-					go (func() {
-						a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum))
-					})()
-				}
+				a.switchViewMsg(area, msgNum, area, msgNum+1)
 			}
-		} else if event.Key() == tcell.KeyLeft {
+		} else if keymap.Match(KeyActionPrev, event) {
 			if msgNum <= 1 {
 				a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum))
 				a.SwitchToAreaListPage()
 			} else {
-				if a.Pages.HasPage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum-1)) {
-					a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum-1))
-					go (func() {
-						a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum))
-					})()
-				} else {
-					a.Pages.AddPage(a.ViewMsg(area, msgNum-1))
-					a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum-1))
-					go (func() {
-						a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum))
-					})()
-				}
+				a.switchViewMsg(area, msgNum, area, msgNum-1)
 			}
-		} else if event.Key() == tcell.KeyInsert || event.Key() == tcell.KeyCtrlI {
-			a.Pages.AddPage(a.InsertMsg(area, 0))
-			a.Pages.AddPage(a.InsertMsgMenu())
-			a.Pages.SwitchToPage(fmt.Sprintf("InsertMsg-%s", (*area).GetName()))
+		} else if keymap.Match(KeyActionNew, event) {
+			a.composeMsg(area, 0)
+		} else if keymap.Match(KeyActionHistoryBack, event) {
+			a.stepHistory(area, msgNum, -1)
+			return nil
+		} else if keymap.Match(KeyActionHistoryFwd, event) {
+			a.stepHistory(area, msgNum, 1)
+			return nil
+		} else if keymap.Match(KeyActionNextUnread, event) && !body.HasSearchTerm() {
+			a.nextUnread(area, msgNum)
+			return nil
+		} else if keymap.Match(KeyActionLastReads, event) {
+			a.showLastReads((*area).GetName(), func() {
+				a.App.SetFocus(a.Pages)
+			})
+			return nil
+		} else if keymap.Match(KeyActionBookmarks, event) {
+			if !database.IsLastReadEnabled() {
+				a.sb.SetStatus("Bookmarks need the lastread database")
+				return nil
+			}
+			a.Pages.AddPage(a.showBookmarks(func(page string) {
+				if page != "" {
+					a.removeViewMsg(area, msgNum, page)
+				}
+				a.App.SetFocus(a.Pages)
+			}))
+			a.Pages.ShowPage("BookmarksModal")
+			return nil
 		} else if msg == nil {
 			return event
-		} else if event.Key() == tcell.KeyCtrlK || (event.Rune() == 'k' && event.Modifiers()&tcell.ModAlt > 0) {
+		} else if keymap.Match(KeyActionKludges, event) {
 			a.showKludges = !a.showKludges
-			//body.SetText(msg.ToView(a.showKludges))
-			body.OpenBuffer(editor.NewBufferFromString(msg.ToView(a.showKludges)))
-		} else if event.Key() == tcell.KeyCtrlQ || event.Key() == tcell.KeyF3 || (event.Rune() == 'q') {
-			a.Pages.AddPage(a.InsertMsg(area, newMsgTypeAnswer))
-			a.Pages.AddPage(a.InsertMsgMenu())
-			a.Pages.SwitchToPage(fmt.Sprintf("InsertMsg-%s", (*area).GetName()))
-		} else if event.Key() == tcell.KeyCtrlN || (event.Rune() == 'n' && event.Modifiers()&tcell.ModAlt > 0) {
+			showRaw = false
+			//body.SetText(msgViewText(msg, a.showKludges))
+			body.OpenBuffer(editor.NewBufferFromString(msgViewText(msg, a.showKludges)))
+		} else if keymap.Match(KeyActionBookmark, event) {
+			a.toggleBookmark(area, msg)
+			return nil
+		} else if keymap.Match(KeyActionMarkRead, event) {
+			a.markReadUpTo(area, msgNum)
+			a.sb.SetStatus(fmt.Sprintf("Marked read up to message %d, %d new", msgNum, (*area).GetCount()-msgNum))
+			return nil
+		} else if keymap.Match(KeyActionExpand, event) && msg.Truncated {
+			sqlArea, ok := (*area).(*msgapi.SQLArea)
+			if !ok {
+				return nil
+			}
+			full, err := sqlArea.GetFullMsg(msg.ID)
+			if err != nil {
+				a.sb.SetStatus(err.Error())
+				return nil
+			}
+			a.sb.SetStatus(fmt.Sprintf("Loaded all %d characters of message %d", msg.FullSize, msgNum))
+			msg = full
+			showRaw = false
+			body.OpenBuffer(editor.NewBufferFromString(msgViewText(msg, a.showKludges)))
+			return nil
+		} else if keymap.Match(KeyActionMessageInfo, event) {
+			a.Pages.AddPage(a.MessageInfo(msg))
+			return nil
+		} else if keymap.Match(KeyActionRawMessage, event) {
+			// Display only, toggles back to the normal view
+			if showRaw {
+				showRaw = false
+				body.OpenBuffer(editor.NewBufferFromString(msgViewText(msg, a.showKludges)))
+				return nil
+			}
+			sqlArea, ok := (*area).(*msgapi.SQLArea)
+			if !ok {
+				a.sb.SetStatus("Raw view is only available for jnode-sql areas")
+				return nil
+			}
+			raw, err := sqlArea.GetRawMsg(msgNum)
+			if err != nil {
+				a.sb.SetStatus(err.Error())
+				return nil
+			}
+			showRaw = true
+			body.OpenBuffer(editor.NewBufferFromString(utils.RawView(raw)))
+			return nil
+		} else if keymap.Match(KeyActionRequeue, event) {
+			sqlArea, ok := (*area).(*msgapi.SQLArea)
+			if !ok || sqlArea.GetAreaID() == 0 {
+				a.sb.SetStatus("Requeue is only available for jnode-sql echo areas")
+				return nil
+			}
+			a.Pages.AddPage(a.showRequeue(sqlArea, msg))
+			a.Pages.ShowPage("RequeueModal")
+			return nil
+		} else if keymap.Match(KeyActionReroute, event) {
+			a.rerouteNetmail(area, msg)
+			return nil
+		} else if keymap.Match(KeyActionReceipt, event) {
+			a.sendReceipt(area, msg)
+			return nil
+		} else if keymap.Match(KeyActionFixEncoding, event) {
+			// Display only, the stored message is not changed
+			if fixed, ok := utils.FixDoubleEncoding(msgViewText(msg, a.showKludges)); ok {
+				body.OpenBuffer(editor.NewBufferFromString(fixed))
+				a.sb.SetStatus("Double encoding fixed for display")
+			} else {
+				a.sb.SetStatus("No double encoding detected")
+			}
+		} else if keymap.Match(KeyActionReadChrs, event) {
+			a.Pages.AddPage(a.showReadChrs(area, msgNum))
+			a.Pages.ShowPage("ReadChrsModal")
+			return nil
+		} else if keymap.Match(KeyActionReply, event) {
+			a.composeMsg(area, newMsgTypeAnswer)
+		} else if keymap.Match(KeyActionReplyMarked, event) {
+			a.replySelection(area, msg, body, showRaw)
+			return nil
+		} else if keymap.Match(KeyActionEdit, event) {
+			a.editMsg(area, msgNum)
+		} else if keymap.Match(KeyActionReplyArea, event) {
 			a.Pages.AddPage(a.showAreaList(area, newMsgTypeAnswerNewArea))
 			a.Pages.ShowPage("AreaListModal")
-		} else if event.Key() == tcell.KeyCtrlF || (event.Rune() == 'f' && event.Modifiers()&tcell.ModAlt > 0) {
+		} else if keymap.Match(KeyActionReplyNetmail, event) {
+			a.replyInNetmail(area)
+		} else if keymap.Match(KeyActionForward, event) {
 			a.Pages.AddPage(a.showAreaList(area, newMsgTypeForward))
 			a.Pages.ShowPage("AreaListModal")
-		} else if event.Key() == tcell.KeyDelete {
+		} else if (keymap.Match(KeyActionDelete, event) || keymap.Match(KeyActionMoveMessage, event)) &&
+			!(*area).Capabilities().Has(msgapi.AreaCanDelete) {
+			// a moved message is deleted from its area
+			a.sb.SetStatus(fmt.Sprintf("Messages of %s can not be deleted", (*area).GetName()))
+		} else if keymap.Match(KeyActionDelete, event) && len(a.taggedMsgs(area)) > 0 {
+			a.tagAction(area, KeyActionDelete)
+		} else if keymap.Match(KeyActionMoveMessage, event) && len(a.taggedMsgs(area)) > 0 {
+			a.tagAction(area, KeyActionMoveMessage)
+		} else if keymap.Match(KeyActionDelete, event) {
 			a.Pages.AddPage(a.showDelMsg(area, msgNum))
 			a.Pages.ShowPage("DelMsgModal")
-		} else if event.Key() == tcell.KeyCtrlL || event.Rune() == 'l' {
+		} else if keymap.Match(KeyActionCopyText, event) {
+			a.copyToClipboard(msg.PlainText(), "Message text copied to clipboard")
+			return nil
+		} else if keymap.Match(KeyActionCopyQuoted, event) {
+			a.copyToClipboard(msg.QuotedText(), "Message text copied to clipboard")
+			return nil
+		} else if keymap.Match(KeyActionCopyMessage, event) {
+			a.Pages.AddPage(a.showTransferMsg(area, msgNum, false))
+			a.Pages.ShowPage("AreaListModal")
+		} else if keymap.Match(KeyActionMoveMessage, event) {
+			a.Pages.AddPage(a.showTransferMsg(area, msgNum, true))
+			a.Pages.ShowPage("AreaListModal")
+		} else if keymap.Match(KeyActionMessageList, event) {
 			a.Pages.AddPage(a.showMessageList(area))
 			a.Pages.ShowPage("MessageListModal")
-		} else if event.Key() == tcell.KeyCtrlG || event.Rune() == 'g' {
+		} else if keymap.Match(KeyActionHeader, event) {
 			a.App.SetFocus(header)
 			//a.Pages.AddPage(a.showMessageList(area))
 			//a.Pages.ShowPage("MessageListModal")
-		} else if event.Rune() == '<' {
+		} else if keymap.Match(KeyActionFirst, event) {
 			if msgNum != 1 {
-				a.Pages.AddPage(a.ViewMsg(area, 1))
-				a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), 1))
-				go (func() {
-					a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum))
-				})()
+				a.switchViewMsg(area, msgNum, area, 1)
 			}
-		} else if event.Rune() == '>' {
+		} else if keymap.Match(KeyActionLast, event) {
 			if msgNum != (*area).GetCount() {
-				a.Pages.AddPage(a.ViewMsg(area, (*area).GetCount()))
-				a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), (*area).GetCount()))
-				go (func() {
-					a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum))
-				})()
+				a.switchViewMsg(area, msgNum, area, (*area).GetCount())
 			}
 		}
 
@@ -200,19 +316,64 @@ func (a *App) ViewMsg(area *msgapi.AreaPrimitive, msgNum uint32) (string, tview.
 
 	layout := tview.NewFlex().
 		SetDirection(tview.FlexRow).
-		AddItem(header, 6, 1, false).
+		AddItem(header, header.Height(), 1, false).
 		AddItem(body, 0, 1, true)
 	return fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum), layout, true, true
 }
 
+// sendReceipt saves the return receipt of the netmail msg to its area, from
+// where it is routed back to the sender
+func (a *App) sendReceipt(area *msgapi.AreaPrimitive, msg *msgapi.Message) {
+	receipt, err := msgapi.SendReceipt(area, msg, time.Now())
+	if err != nil {
+		a.sb.SetStatus(err.Error())
+		return
+	}
+	a.sb.SetStatus(fmt.Sprintf("Return receipt sent to %s, %s", receipt.To, receipt.ToAddr.String()))
+}
+
+// rerouteNetmail runs routing again for the shown unsent netmail, e.g.
+// after its address was fixed
+func (a *App) rerouteNetmail(area *msgapi.AreaPrimitive, msg *msgapi.Message) {
+	sqlArea, ok := (*area).(*msgapi.SQLArea)
+	if !ok || sqlArea.GetType() != msgapi.EchoAreaTypeNetmail {
+		a.sb.SetStatus("Rerouting is only available for jnode-sql netmail")
+		return
+	}
+	if err := sqlArea.RerouteNetmail(msg.ID); err != nil {
+		a.sb.SetStatus(err.Error())
+		return
+	}
+	a.sb.SetStatus(fmt.Sprintf("Netmail to %s routed", msg.ToAddr.String()))
+}
+
+// showRequeue queues the shown echomail message again for links selected
+// among the ones subscribed to its area
+func (a *App) showRequeue(area *msgapi.SQLArea, msg *msgapi.Message) (string, tview.Primitive, bool, bool) {
+	modal := NewModalRequeue(area.GetName(), area.GetAreaID(), msg.ID).
+		SetDoneFunc(func(status string) {
+			a.Pages.HidePage("RequeueModal")
+			a.Pages.RemovePage("RequeueModal")
+			if status != "" {
+				a.sb.SetStatus(status)
+			}
+			a.App.SetFocus(a.Pages)
+		})
+	return "RequeueModal", modal, true, true
+}
+
 func (a *App) showMessageList(area *msgapi.AreaPrimitive) (string, tview.Primitive, bool, bool) {
 	modal := NewModalMessageList(area).
+		SetTagged(a.areaTags(area)).
+		SetBookmarked(bookmarkedMsgs(area)).
+		SetBatchFunc(func(action string) {
+			a.tagAction(area, action)
+		}).
 		SetDoneFunc(func(msgNum uint32) {
 			a.Pages.HidePage("MessageListModal")
 			a.Pages.RemovePage("MessageListModal")
 			a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), (*area).GetLast()))
-			a.Pages.AddPage(a.ViewMsg(area, msgNum))
-			a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum))
+			a.showViewMsg(area, msgNum)
 			a.App.SetFocus(a.Pages)
 		})
 	return "MessageListModal", modal, true, true
@@ -224,9 +385,7 @@ func (a *App) showAreaList(area *msgapi.AreaPrimitive, newMsgType int) (string,
 			a.im.postArea = &msgapi.Areas[buttonIndex-1]
 			a.Pages.HidePage("AreaListModal")
 			a.Pages.RemovePage("AreaListModal")
-			a.Pages.AddPage(a.InsertMsg(area, newMsgType))
-			a.Pages.AddPage(a.InsertMsgMenu())
-			a.Pages.SwitchToPage(fmt.Sprintf("InsertMsg-%s", (*area).GetName()))
+			a.composeMsg(area, newMsgType)
 			a.App.SetFocus(a.Pages)
 		})
 	if newMsgType == newMsgTypeAnswerNewArea {
@@ -237,6 +396,73 @@ func (a *App) showAreaList(area *msgapi.AreaPrimitive, newMsgType int) (string,
 	}
 	return "AreaListModal", modal, true, true
 }
+
+// replyInNetmail answers the message privately in the first netmail area
+func (a *App) replyInNetmail(area *msgapi.AreaPrimitive) {
+	if (*area).GetType() == msgapi.EchoAreaTypeNetmail {
+		a.composeMsg(area, newMsgTypeAnswer)
+		return
+	}
+	for i := range msgapi.Areas {
+		if msgapi.Areas[i].GetType() == msgapi.EchoAreaTypeNetmail {
+			a.im.postArea = &msgapi.Areas[i]
+			a.composeMsg(area, newMsgTypeAnswerNewArea)
+			return
+		}
+	}
+	a.sb.SetStatus("No netmail area to answer in")
+}
+
+// replySelection answers msg quoting only the lines marked in body, the
+// view of it
+func (a *App) replySelection(area *msgapi.AreaPrimitive, msg *msgapi.Message, body *editor.View, showRaw bool) {
+	first, last, ok := body.SelectedLines()
+	if !ok {
+		a.sb.SetStatus("Mark the lines to quote with Shift-Up/Down first")
+		return
+	}
+	if showRaw {
+		a.sb.SetStatus("Lines of the raw view can not be quoted")
+		return
+	}
+	quote := msg.GetQuoteOfView(first, last, a.showKludges)
+	if len(quote) == 0 {
+		a.sb.SetStatus("The marked lines have nothing to quote")
+		return
+	}
+	a.im.quote = quote
+	a.composeMsg(area, newMsgTypeAnswer)
+	a.im.quote = nil
+}
+
+// showTransferMsg picks an area to copy or move the message to
+func (a *App) showTransferMsg(area *msgapi.AreaPrimitive, msgNum uint32, move bool) (string, tview.Primitive, bool, bool) {
+	modal := NewModalAreaList().
+		SetDoneFunc(func(buttonIndex int) {
+			a.Pages.HidePage("AreaListModal")
+			a.Pages.RemovePage("AreaListModal")
+			dst := msgapi.Areas[buttonIndex-1]
+			transfer, verb := msgapi.CopyMessage, "copied"
+			if move {
+				transfer, verb = msgapi.MoveMessage, "moved"
+			}
+			if err := transfer(*area, msgNum, dst); err != nil {
+				a.sb.SetStatus(err.Error())
+			} else {
+				a.sb.SetStatus(fmt.Sprintf("Message %s to %s", verb, dst.GetName()))
+				if move {
+					a.replaceViewMsg(area, msgNum, msgNum-1)
+				}
+			}
+			a.App.SetFocus(a.Pages)
+		})
+	if move {
+		modal.SetText("Move To Area:")
+	} else {
+		modal.SetText("Copy To Area:")
+	}
+	return "AreaListModal", modal, true, true
+}
 func (a *App) showDelMsg(area *msgapi.AreaPrimitive, msgNum uint32) (string, tview.Primitive, bool, bool) {
 	modal := NewModalMenu().
 		SetY(6).
@@ -246,14 +472,244 @@ func (a *App) showDelMsg(area *msgapi.AreaPrimitive, msgNum uint32) (string, tvi
 			a.Pages.HidePage("DelMsgModal")
 			a.Pages.RemovePage("DelMsgModal")
 			if buttonIndex == 0 {
+				// positions of tagged messages shift after the deleted one
+				a.clearTags()
 				(*area).DelMsg(msgNum)
-				a.Pages.AddPage(a.ViewMsg(area, msgNum-1))
-				a.Pages.SwitchToPage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum-1))
-				go (func() {
-					a.Pages.RemovePage(fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum))
-				})()
+				a.replaceViewMsg(area, msgNum, msgNum-1)
 			}
 			a.App.SetFocus(a.Pages)
 		})
 	return "DelMsgModal", modal, true, true
 }
+
+// showReadChrs lets the user pick the charset messages of the area are read
+// in for the rest of the session, showing msgNum again in it. Replies are
+// written in the charset picked as well.
+func (a *App) showReadChrs(area *msgapi.AreaPrimitive, msgNum uint32) (string, tview.Primitive, bool, bool) {
+	name := (*area).GetName()
+	current := msgapi.GetReadChrs(name)
+	if current == "" {
+		current = strings.ToUpper(strings.Split((*area).GetChrs(), " ")[0])
+	}
+	charsets := utils.Charsets()
+	labels := make([]string, len(charsets))
+	for i, chrs := range charsets {
+		mark := " "
+		if chrs == current {
+			mark = "*"
+		}
+		labels[i] = mark + " " + chrs
+	}
+	modal := NewModalMenu().
+		SetY(6).
+		SetText("Read Area In:").
+		AddButtons(labels).
+		SetDoneFunc(func(buttonIndex int) {
+			a.Pages.HidePage("ReadChrsModal")
+			a.Pages.RemovePage("ReadChrsModal")
+			if buttonIndex >= 0 && buttonIndex < len(charsets) {
+				chrs := charsets[buttonIndex]
+				// the level of the CHRS kludge: 4 for UTF-8, 2 for 8-bit charsets
+				level := "2"
+				if chrs == "UTF-8" {
+					level = "4"
+				}
+				(*area).SetChrs(chrs + " " + level)
+				msgapi.SetReadChrs(name, chrs)
+				a.showViewMsg(area, msgNum)
+				a.sb.SetStatus(fmt.Sprintf("%s read in %s until gossipEd exits", name, chrs))
+			}
+			a.App.SetFocus(a.Pages)
+		})
+	return "ReadChrsModal", modal, true, true
+}
+
+// highRead returns the high-water mark of the area stored in the lastread
+// database, its lastread if that is higher or there is no database
+func highRead(area *msgapi.AreaPrimitive) uint32 {
+	last := (*area).GetLast()
+	if !database.IsLastReadEnabled() {
+		return last
+	}
+	high, err := database.GetHighRead(config.Config.Username, (*area).GetName())
+	if err != nil {
+		log.Printf("Error getting high read mark of %s: %v", (*area).GetName(), err)
+		return last
+	}
+	return max(high, last)
+}
+
+// setHighRead stores msgNum as the high-water mark of the area
+func setHighRead(area *msgapi.AreaPrimitive, msgNum uint32) {
+	if !database.IsLastReadEnabled() {
+		return
+	}
+	if err := database.SetHighRead(config.Config.Username, (*area).GetName(), msgNum); err != nil {
+		log.Printf("Error saving high read mark of %s: %v", (*area).GetName(), err)
+	}
+}
+
+// markRead raises the high-water mark of the area to msgNum
+func (a *App) markRead(area *msgapi.AreaPrimitive, msgNum uint32) {
+	if msgNum > highRead(area) {
+		setHighRead(area, msgNum)
+	}
+}
+
+// readMsg marks msgNum of the area read, moving its lastread there
+func (a *App) readMsg(area *msgapi.AreaPrimitive, msgNum uint32) {
+	a.markRead(area, msgNum)
+	(*area).SetLast(msgNum)
+	a.checkLastRead()
+}
+
+// markReadUpTo moves the lastread of the area and its high-water mark to
+// msgNum, back too, so the messages after it count as new again
+func (a *App) markReadUpTo(area *msgapi.AreaPrimitive, msgNum uint32) {
+	(*area).SetLast(msgNum)
+	setHighRead(area, msgNum)
+	a.checkLastRead()
+}
+
+// nextUnreadMsgNum returns the first message above both the current one
+// and the high-water mark, false if there is none
+func nextUnreadMsgNum(msgNum, highRead, count uint32) (uint32, bool) {
+	next := max(msgNum, highRead) + 1
+	if next > count {
+		return 0, false
+	}
+	return next, true
+}
+
+// nextUnreadArea returns the index of the first area with unread messages
+// after the one at index cur, wrapping around, or -1
+func nextUnreadArea(cur int) int {
+	for i := 1; i < len(msgapi.Areas); i++ {
+		j := (cur + i) % len(msgapi.Areas)
+		if msgapi.AreaHasUnreadMessages(&msgapi.Areas[j]) {
+			return j
+		}
+	}
+	return -1
+}
+
+// nextUnread jumps to the next unread message of the area or, past the last
+// one, to the next area with unread messages as configured in unread.auto_advance
+func (a *App) nextUnread(area *msgapi.AreaPrimitive, msgNum uint32) {
+	if next, ok := nextUnreadMsgNum(msgNum, highRead(area), (*area).GetCount()); ok {
+		a.switchViewMsg(area, msgNum, area, next)
+		return
+	}
+	idx := nextUnreadArea(msgapi.Lookup((*area).GetName()))
+	if idx < 0 {
+		a.sb.SetStatus("No more unread messages")
+		return
+	}
+	dst := &msgapi.Areas[idx]
+	switch config.GetUnreadAutoAdvance() {
+	case "yes":
+		a.openUnreadArea(area, msgNum, dst)
+	case "no":
+		a.sb.SetStatus(fmt.Sprintf("No more unread messages in %s", (*area).GetName()))
+	default:
+		a.Pages.AddPage(a.showNextUnreadArea(area, msgNum, dst))
+		a.Pages.ShowPage("NextUnreadModal")
+	}
+}
+
+// showNextUnreadArea offers to continue in the next area with unread messages
+func (a *App) showNextUnreadArea(area *msgapi.AreaPrimitive, msgNum uint32, dst *msgapi.AreaPrimitive) (string, tview.Primitive, bool, bool) {
+	modal := NewModalMenu().
+		SetY(6).
+		SetText("No more unread messages").
+		AddText(fmt.Sprintf("Go to %s (%d unread)?", tview.Escape((*dst).GetName()), (*dst).GetCount()-(*dst).GetLast())).
+		AddButtons([]string{"Yes", "No"}).
+		SetDoneFunc(func(buttonIndex int) {
+			a.Pages.HidePage("NextUnreadModal")
+			a.Pages.RemovePage("NextUnreadModal")
+			if buttonIndex == 0 {
+				a.openUnreadArea(area, msgNum, dst)
+			}
+			a.App.SetFocus(a.Pages)
+		})
+	return "NextUnreadModal", modal, true, true
+}
+
+// openUnreadArea leaves the current message for the first unread one of dst
+func (a *App) openUnreadArea(area *msgapi.AreaPrimitive, msgNum uint32, dst *msgapi.AreaPrimitive) {
+	a.clearTags()
+	a.CurrentArea = dst
+	(*dst).Init()
+	a.switchViewMsg(area, msgNum, dst, (*dst).GetLast()+1)
+}
+
+// switchViewMsg replaces the view of msgNum in area with dstNum in dst
+func (a *App) switchViewMsg(area *msgapi.AreaPrimitive, msgNum uint32, dst *msgapi.AreaPrimitive, dstNum uint32) {
+	page := fmt.Sprintf("ViewMsg-%s-%d", (*dst).GetName(), dstNum)
+	if a.Pages.HasPage(page) {
+		a.Pages.SwitchToPage(page)
+	} else {
+		page = a.showViewMsg(dst, dstNum)
+	}
+	a.removeViewMsg(area, msgNum, page)
+}
+
+// replaceViewMsg rebuilds the view of area at dstNum after the messages
+// changed, e.g. a deletion, dropping the view of msgNum
+func (a *App) replaceViewMsg(area *msgapi.AreaPrimitive, msgNum uint32, dstNum uint32) {
+	a.removeViewMsg(area, msgNum, a.showViewMsg(area, dstNum))
+}
+
+// showViewMsg adds and switches to the view of msgNum in area; the page name
+// is returned as ViewMsg clamps msgNum to the messages of the area
+func (a *App) showViewMsg(area *msgapi.AreaPrimitive, msgNum uint32) string {
+	page, item, resize, visible := a.ViewMsg(area, msgNum)
+	a.Pages.AddPage(page, item, resize, visible)
+	a.Pages.SwitchToPage(page)
+	return page
+}
+
+// removeViewMsg drops the view of msgNum in area unless it is the shown page
+func (a *App) removeViewMsg(area *msgapi.AreaPrimitive, msgNum uint32, shown string) {
+	page := fmt.Sprintf("ViewMsg-%s-%d", (*area).GetName(), msgNum)
+	if page == shown {
+		return
+	}
+	go (func() {
+		a.Pages.RemovePage(page)
+	})()
+}
+
+// msgViewText returns the view of msg, noting under the preview of a large
+// message how to load the rest
+func msgViewText(msg *msgapi.Message, showKludges bool) string {
+	content := msg.ToView(showKludges)
+	if msg.Truncated {
+		load := "bind a key to expand to load all of it"
+		if key := keymap.Name(KeyActionExpand); key != "" {
+			load = fmt.Sprintf("press %s to load all of it", key)
+		}
+		content += fmt.Sprintf("\n --- preview, the message has %d characters, %s ---\n", msg.FullSize, load)
+	}
+	return content
+}
+
+// emptyAreaText placeholder shown instead of a message in an empty area
+func emptyAreaText(areaName string) string {
+	return fmt.Sprintf("\n  No messages in %s.\n\n  Press Ins to post a new message, Esc or Left to leave.\n", areaName)
+}
+
+// copyToClipboard puts text on the system clipboard, reporting the outcome
+// in the status bar (e.g. no xclip/xsel/wl-copy in a headless session)
+// with done on success
+func (a *App) copyToClipboard(text string, done string) {
+	if clipboard.Unsupported {
+		a.sb.SetStatus("Clipboard not available")
+		return
+	}
+	if err := clipboard.WriteAll(text); err != nil {
+		a.sb.SetStatus(fmt.Sprintf("Clipboard not available: %v", err))
+		return
+	}
+	a.sb.SetStatus(done)
+}
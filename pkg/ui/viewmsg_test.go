@@ -0,0 +1,332 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/askovpen/gossiped/pkg/config"
+	"github.com/askovpen/gossiped/pkg/database"
+	"github.com/askovpen/gossiped/pkg/msgapi"
+	"github.com/askovpen/gossiped/pkg/types"
+	. "github.com/franela/goblin"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func TestEmptyAreaNavigation(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check empty area navigation", func() {
+		g.It("check openMsgNum()", func() {
+			g.Assert(openMsgNum(0, 0)).Equal(uint32(0))
+			g.Assert(openMsgNum(5, 0)).Equal(uint32(0))
+			g.Assert(openMsgNum(0, 3)).Equal(uint32(1))
+			g.Assert(openMsgNum(2, 3)).Equal(uint32(2))
+			g.Assert(openMsgNum(7, 3)).Equal(uint32(3))
+		})
+		g.It("check empty area view", func() {
+			a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+			a.sb = NewStatusBar(a)
+			var area msgapi.AreaPrimitive = &msgapi.MSG{AreaPath: t.TempDir(), AreaName: "empty"}
+			area.Init()
+			a.CurrentArea = &area
+			a.Pages.AddPage("AreaList", tview.NewBox(), true, false)
+			msgNum := openMsgNum(area.GetLast(), area.GetCount())
+			name, view, _, _ := a.ViewMsg(&area, msgNum)
+			g.Assert(name).Equal("ViewMsg-empty-0")
+			layout, ok := view.(*tview.Flex)
+			g.Assert(ok).IsTrue()
+			a.Pages.AddPage(name, view, true, true)
+			a.Pages.SwitchToPage(name)
+
+			// Leaving the empty area returns to the area list
+			body := layout.GetItem(1)
+			body.InputHandler()(tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone), func(tview.Primitive) {})
+			front, _ := a.Pages.GetFrontPage()
+			g.Assert(front).Equal("AreaList")
+			g.Assert(a.Pages.HasPage(name)).IsFalse()
+		})
+		g.It("check positions past the end", func() {
+			a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+			a.sb = NewStatusBar(a)
+			var area msgapi.AreaPrimitive = &msgapi.MSG{AreaPath: t.TempDir(), AreaName: "short"}
+			area.Init()
+			for i := 0; i < 2; i++ {
+				m := &msgapi.Message{AreaObject: &area, From: "SysOp", To: "All", Subject: "Test",
+					FromAddr: types.AddrFromNum(2, 5020, 9696, 1), ToAddr: types.AddrFromNum(2, 5020, 9696, 2),
+					Body: "Body", Kludges: map[string]string{}}
+				g.Assert(area.SaveMsg(m.MakeBody())).IsNil()
+			}
+			saved := msgapi.Areas
+			defer func() { msgapi.Areas = saved }()
+			msgapi.Areas = []msgapi.AreaPrimitive{area}
+			a.CurrentArea = &area
+			name, _, _, _ := a.ViewMsg(&area, 5)
+			g.Assert(name).Equal("ViewMsg-short-2")
+			name, _, _, _ = a.ViewMsg(&area, 0)
+			g.Assert(name).Equal("ViewMsg-short-1")
+
+			// Stepping onto a stale position keeps the last message shown
+			first := a.showViewMsg(&area, 1)
+			a.switchViewMsg(&area, 1, &area, 9)
+			front, _ := a.Pages.GetFrontPage()
+			g.Assert(front).Equal("ViewMsg-short-2")
+			g.Assert(first).Equal("ViewMsg-short-1")
+		})
+	})
+}
+
+func TestNextUnread(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check next unread jump", func() {
+		g.It("check nextUnreadMsgNum()", func() {
+			next, ok := nextUnreadMsgNum(3, 10, 20)
+			g.Assert(ok).IsTrue()
+			g.Assert(next).Equal(uint32(11))
+			next, ok = nextUnreadMsgNum(12, 10, 20)
+			g.Assert(ok).IsTrue()
+			g.Assert(next).Equal(uint32(13))
+			_, ok = nextUnreadMsgNum(5, 20, 20)
+			g.Assert(ok).IsFalse()
+			_, ok = nextUnreadMsgNum(0, 0, 0)
+			g.Assert(ok).IsFalse()
+		})
+		g.It("check markRead() keeps the high-water mark", func() {
+			a := &App{}
+			g.Assert(database.InitLastReadDatabase(database.LastReadConfig{Enabled: true,
+				DatabasePath: filepath.Join(t.TempDir(), "lastread.db")})).IsNil()
+			defer func() {
+				database.CloseLastReadDatabase()
+				database.LastReadDB = nil
+			}()
+			var area msgapi.AreaPrimitive = &msgapi.MSG{AreaPath: t.TempDir(), AreaName: "test"}
+			area.Init()
+			a.markRead(&area, 7)
+			a.markRead(&area, 3)
+			g.Assert(highRead(&area)).Equal(uint32(7))
+			a.markRead(&area, 9)
+			high, err := database.GetHighRead(config.Config.Username, "test")
+			g.Assert(err).IsNil()
+			g.Assert(high).Equal(uint32(9))
+		})
+		g.It("check markReadUpTo() leaves later messages new", func() {
+			a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+			a.sb = NewStatusBar(a)
+			g.Assert(database.InitLastReadDatabase(database.LastReadConfig{Enabled: true,
+				DatabasePath: filepath.Join(t.TempDir(), "lastread.db")})).IsNil()
+			defer func() {
+				database.CloseLastReadDatabase()
+				database.LastReadDB = nil
+			}()
+			var area msgapi.AreaPrimitive = &msgapi.MSG{AreaPath: t.TempDir(), AreaName: "test"}
+			area.Init()
+			for i := 0; i < 4; i++ {
+				m := &msgapi.Message{AreaObject: &area, From: "SysOp", To: "All", Subject: "Test",
+					FromAddr: types.AddrFromNum(2, 5020, 9696, 1), ToAddr: types.AddrFromNum(2, 5020, 9696, 2),
+					Body: "Body", Kludges: map[string]string{}}
+				g.Assert(area.SaveMsg(m.MakeBody())).IsNil()
+			}
+			a.readMsg(&area, 4)
+			g.Assert(area.GetLast()).Equal(uint32(4))
+			g.Assert(highRead(&area)).Equal(uint32(4))
+			a.markReadUpTo(&area, 2)
+			g.Assert(area.GetLast()).Equal(uint32(2))
+			g.Assert(highRead(&area)).Equal(uint32(2))
+			next, ok := nextUnreadMsgNum(2, highRead(&area), area.GetCount())
+			g.Assert(ok).IsTrue()
+			g.Assert(next).Equal(uint32(3))
+		})
+		g.It("check highRead() falls back to the lastread", func() {
+			var area msgapi.AreaPrimitive = &msgapi.MSG{AreaPath: t.TempDir(), AreaName: "test"}
+			area.Init()
+			g.Assert(highRead(&area)).Equal(area.GetLast())
+		})
+	})
+}
+
+func TestTags(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check message tags", func() {
+		var one msgapi.AreaPrimitive = &msgapi.MSG{AreaName: "one"}
+		var two msgapi.AreaPrimitive = &msgapi.MSG{AreaName: "two"}
+		g.It("check tags are kept per area", func() {
+			a := &App{}
+			tags := a.areaTags(&one)
+			tags[5] = true
+			tags[2] = true
+			g.Assert(a.taggedMsgs(&one)).Equal([]uint32{2, 5})
+			g.Assert(len(a.taggedMsgs(&two))).Equal(0)
+			a.areaTags(&two)[1] = true
+			g.Assert(len(a.taggedMsgs(&one))).Equal(0)
+			a.clearTags()
+			g.Assert(len(a.taggedMsgs(&two))).Equal(0)
+		})
+	})
+}
+
+func TestAreaActivity(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check areaActivity()", func() {
+		loc := time.FixedZone("MSK", 3*3600)
+		now := time.Date(2024, 3, 15, 10, 0, 0, 0, loc)
+		g.It("check empty area", func() {
+			g.Assert(areaActivity(time.Time{}, now)).Equal("")
+		})
+		g.It("check calendar day in the location of now", func() {
+			g.Assert(areaActivity(time.Date(2024, 3, 15, 0, 30, 0, 0, loc), now)).Equal("new today")
+			// 23:30 UTC on the 14th is already the 15th in MSK
+			g.Assert(areaActivity(time.Date(2024, 3, 14, 23, 30, 0, 0, time.UTC), now)).Equal("new today")
+			g.Assert(areaActivity(time.Date(2024, 3, 14, 23, 30, 0, 0, loc), now)).Equal("this week")
+		})
+		g.It("check older ages", func() {
+			g.Assert(areaActivity(now.AddDate(0, 0, -10), now)).Equal("10d")
+			g.Assert(areaActivity(now.AddDate(0, 0, -90), now)).Equal("3mo")
+			g.Assert(areaActivity(now.AddDate(-3, 0, 0), now)).Equal("3y")
+		})
+	})
+}
+
+func TestNavHistory(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check navigation history", func() {
+		g.It("check visit() and remove()", func() {
+			var h navHistory
+			h.visit(historyEntry{area: "one", id: 10, num: 1})
+			h.visit(historyEntry{area: "one", id: 10, num: 3})
+			g.Assert(len(h.entries)).Equal(1)
+			h.visit(historyEntry{area: "one", id: 11, num: 2})
+			h.visit(historyEntry{area: "two", num: 1})
+			g.Assert(h.pos).Equal(2)
+			// visiting after going back drops the messages gone back from
+			h.pos = 0
+			h.visit(historyEntry{area: "two", num: 2})
+			g.Assert(h.entries).Equal([]historyEntry{{area: "one", id: 10, num: 1}, {area: "two", num: 2}})
+			g.Assert(h.pos).Equal(1)
+			h.remove(0)
+			g.Assert(h.entries).Equal([]historyEntry{{area: "two", num: 2}})
+			g.Assert(h.pos).Equal(0)
+			h.moving = true
+			h.visit(historyEntry{area: "two", num: 3})
+			g.Assert(len(h.entries)).Equal(1)
+		})
+		g.It("check the history length is capped", func() {
+			var h navHistory
+			for i := 1; i <= historyLen+5; i++ {
+				h.visit(historyEntry{area: "one", num: uint32(i)})
+			}
+			g.Assert(len(h.entries)).Equal(historyLen)
+			g.Assert(h.entries[0].num).Equal(uint32(6))
+			g.Assert(h.pos).Equal(historyLen - 1)
+		})
+		g.It("check going back and forward across areas", func() {
+			a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+			a.sb = NewStatusBar(a)
+			saved := msgapi.Areas
+			defer func() { msgapi.Areas = saved }()
+			msgapi.Areas = nil
+			for _, name := range []string{"one", "two"} {
+				var area msgapi.AreaPrimitive = &msgapi.MSG{AreaPath: t.TempDir(), AreaName: name}
+				area.Init()
+				for i := 0; i < 2; i++ {
+					m := &msgapi.Message{AreaObject: &area, From: "SysOp", To: "All", Subject: "Test",
+						FromAddr: types.AddrFromNum(2, 5020, 9696, 1), ToAddr: types.AddrFromNum(2, 5020, 9696, 2),
+						Body: "Body", Kludges: map[string]string{}}
+					g.Assert(area.SaveMsg(m.MakeBody())).IsNil()
+				}
+				msgapi.Areas = append(msgapi.Areas, area)
+			}
+			one, two := &msgapi.Areas[0], &msgapi.Areas[1]
+			a.CurrentArea = one
+			a.showViewMsg(one, 1)
+			a.switchViewMsg(one, 1, one, 2)
+			a.CurrentArea = two
+			a.switchViewMsg(one, 2, two, 1)
+			g.Assert(len(a.history.entries)).Equal(3)
+
+			a.stepHistory(two, 1, -1)
+			front, _ := a.Pages.GetFrontPage()
+			g.Assert(front).Equal("ViewMsg-one-2")
+			g.Assert(a.CurrentArea == one).IsTrue()
+			a.stepHistory(one, 2, -1)
+			front, _ = a.Pages.GetFrontPage()
+			g.Assert(front).Equal("ViewMsg-one-1")
+			a.stepHistory(one, 1, -1)
+			front, _ = a.Pages.GetFrontPage()
+			g.Assert(front).Equal("ViewMsg-one-1")
+
+			a.stepHistory(one, 1, 2)
+			front, _ = a.Pages.GetFrontPage()
+			g.Assert(front).Equal("ViewMsg-two-1")
+			g.Assert(a.CurrentArea == two).IsTrue()
+			g.Assert(len(a.history.entries)).Equal(3)
+		})
+	})
+}
+
+func TestMemoryAreaReading(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check reading through memory areas", func() {
+		savedAreas, savedAdvance := msgapi.Areas, config.Config.Unread.AutoAdvance
+		g.After(func() {
+			msgapi.Areas, config.Config.Unread.AutoAdvance = savedAreas, savedAdvance
+		})
+		g.It("check next unread across areas and unread counts", func() {
+			a := &App{App: tview.NewApplication(), Pages: tview.NewPages()}
+			a.sb = NewStatusBar(a)
+			config.Config.Unread.AutoAdvance = "yes"
+			msgapi.Areas = nil
+			for _, c := range []struct {
+				name string
+				n    int
+			}{{"one", 3}, {"two", 2}} {
+				var area msgapi.AreaPrimitive = msgapi.NewMemoryArea(c.name, msgapi.EchoAreaTypeEcho)
+				for i := 0; i < c.n; i++ {
+					m := &msgapi.Message{AreaObject: &area, From: "SysOp", To: "All", Subject: "Test",
+						FromAddr: types.AddrFromNum(2, 5020, 9696, 1), ToAddr: &types.FidoAddr{},
+						Body: "Body", Kludges: map[string]string{}}
+					g.Assert(area.SaveMsg(m.MakeBody())).IsNil()
+				}
+				msgapi.Areas = append(msgapi.Areas, area)
+			}
+			one, two := &msgapi.Areas[0], &msgapi.Areas[1]
+			a.CurrentArea = one
+			a.showViewMsg(one, 1)
+			g.Assert((*one).GetLast()).Equal(uint32(1))
+			a.nextUnread(one, 1)
+			a.nextUnread(one, 2)
+			front, _ := a.Pages.GetFrontPage()
+			g.Assert(front).Equal("ViewMsg-one-3")
+			g.Assert((*one).GetLast()).Equal(uint32(3))
+			g.Assert(msgapi.AreaHasUnreadMessages(one)).IsFalse()
+
+			a.nextUnread(one, 3)
+			front, _ = a.Pages.GetFrontPage()
+			g.Assert(front).Equal("ViewMsg-two-1")
+			g.Assert(a.CurrentArea == two).IsTrue()
+			g.Assert((*two).GetCount() - (*two).GetLast()).Equal(uint32(1))
+
+			g.Assert((*two).DelMsg(1)).IsNil()
+			g.Assert((*two).GetLast()).Equal(uint32(0))
+			g.Assert((*two).GetCount() - (*two).GetLast()).Equal(uint32(1))
+		})
+	})
+}
+
+func TestMsgViewText(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check message view text", func() {
+		g.After(func() {
+			InitKeymap(nil)
+		})
+		g.It("check the preview names the expand key", func() {
+			msg := &msgapi.Message{FromAddr: &types.FidoAddr{}, ToAddr: &types.FidoAddr{},
+				Body: "Body", Kludges: map[string]string{}, Truncated: true, FullSize: 300000}
+			g.Assert(strings.Contains(msgViewText(msg, false), "press Alt-x to load")).IsTrue()
+			g.Assert(InitKeymap(map[string]string{KeyActionExpand: "F7"})).IsNil()
+			g.Assert(strings.Contains(msgViewText(msg, false), "press F7 to load")).IsTrue()
+			g.Assert(InitKeymap(map[string]string{KeyActionExpand: ""})).IsNil()
+			g.Assert(strings.Contains(msgViewText(msg, false), "press")).IsFalse()
+		})
+	})
+}
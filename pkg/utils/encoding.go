@@ -2,6 +2,8 @@ package utils
 
 import (
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/charmap"
@@ -50,6 +52,13 @@ var (
 	}
 )
 
+// Charsets returns the charsets messages can be read in, UTF-8 first and
+// without the aliases of others
+func Charsets() []string {
+	return []string{"UTF-8", "CP866", "CP437", "CP850", "CP852", "CP1250", "CP1251", "CP1252",
+		"CP10000", "KOI8-R", "LATIN-1", "LATIN-2", "LATIN-5", "LATIN-9"}
+}
+
 // DecodeCharmap decode string from charmap
 func DecodeCharmap(s string, c string) string {
 	var dec *encoding.Decoder
@@ -85,3 +94,116 @@ func EncodeCharmap(s string, c string) string {
 	}
 	return out
 }
+
+// encodeChunkSize is the amount of text EncodeCharmapChunked encodes at once
+const encodeChunkSize = 64 * 1024
+
+// EncodeCharmapChunked encodes s to charmap like EncodeCharmap, a piece of
+// about encodeChunkSize ending at a line break at a time, so a huge message
+// body does not need a second full size buffer inside the encoder
+func EncodeCharmapChunked(s string, c string) string {
+	if c == "UTF-8" || len(s) <= encodeChunkSize {
+		return EncodeCharmap(s, c)
+	}
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for len(s) > 0 {
+		n := min(len(s), encodeChunkSize)
+		if i := strings.LastIndexByte(s[:n], '\r'); i >= 0 && n < len(s) {
+			n = i + 1
+		}
+		for n < len(s) && !utf8.RuneStart(s[n]) {
+			n++
+		}
+		sb.WriteString(EncodeCharmap(s[:n], c))
+		s = s[n:]
+	}
+	return sb.String()
+}
+
+// FixDoubleEncoding reverses UTF-8 text that was read as CP866 and encoded to
+// UTF-8 again. It returns false if s does not look double-encoded.
+func FixDoubleEncoding(s string) (string, bool) {
+	raw, err := charmap.CodePage866.NewEncoder().String(s)
+	if err != nil || raw == s || !utf8.ValidString(raw) {
+		return s, false
+	}
+	return raw, true
+}
+
+// DetectDoubleEncoding returns true if s looks like CP866→UTF-8 double-encoded text
+func DetectDoubleEncoding(s string) bool {
+	_, ok := FixDoubleEncoding(s)
+	return ok
+}
+
+// DetectCharset guesses the charset of text without a CHRS kludge among
+// candidates. Valid UTF-8 with 8-bit characters is UTF-8 whatever the
+// candidates, it is never decoded again; otherwise every 8-bit candidate
+// decodes the text and scores its 8-bit bytes: letters count for it, letters
+// of another script inside latin words and control characters against it.
+// The first best candidate wins, def if none scores above zero.
+func DetectCharset(s string, candidates []string, def string) string {
+	if isASCII(s) {
+		return def
+	}
+	if utf8.ValidString(s) {
+		return "UTF-8"
+	}
+	best, bestScore := def, 0
+	for _, c := range candidates {
+		c = strings.ToUpper(c)
+		if _, ok := cDecoder[c]; !ok {
+			continue
+		}
+		if score := charsetScore(s, c); score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	return best
+}
+
+// isASCII returns true if s has no 8-bit bytes
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// charsetScore returns how much the 8-bit bytes of s look like text of the
+// single byte charset c
+func charsetScore(s string, c string) int {
+	// every byte decodes to one rune, so runes match the bytes of s by index
+	decoded := []rune(DecodeCharmap(s, c))
+	if len(decoded) != len(s) {
+		return 0
+	}
+	score := 0
+	for i, r := range decoded {
+		if s[i] < utf8.RuneSelf {
+			continue
+		}
+		switch {
+		case unicode.IsLetter(r):
+			score += 2
+			if (i > 0 && isASCIILetter(s[i-1])) || (i+1 < len(s) && isASCIILetter(s[i+1])) {
+				if unicode.Is(unicode.Latin, r) {
+					score++
+				} else {
+					score -= 3
+				}
+			}
+		case unicode.IsControl(r), r == utf8.RuneError:
+			score -= 2
+		}
+	}
+	return score
+}
+
+// isASCIILetter returns true if b is a latin letter
+func isASCIILetter(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"strings"
 	"testing"
 
 	. "github.com/franela/goblin"
@@ -24,4 +25,66 @@ func TestCharmapEqual(t *testing.T) {
 			g.Assert(EncodeCharmap("Тест", "UTF-8")).Equal("Тест")
 		})
 	})
+	g.Describe("Check EncodeCharmapChunked()", func() {
+		g.It("check a body larger than a chunk", func() {
+			body := strings.Repeat("Тест строки\r", encodeChunkSize/10)
+			g.Assert(EncodeCharmapChunked(body, "CP866")).Equal(EncodeCharmap(body, "CP866"))
+		})
+		g.It("check a body without line breaks", func() {
+			body := strings.Repeat("Тест", encodeChunkSize/3)
+			g.Assert(EncodeCharmapChunked(body, "CP866")).Equal(EncodeCharmap(body, "CP866"))
+		})
+	})
+	g.Describe("Check double encoding", func() {
+		g.It("check DetectDoubleEncoding()", func() {
+			g.Assert(DetectDoubleEncoding("╨в╨╡╤Б╤В")).IsTrue()
+			g.Assert(DetectDoubleEncoding("Тест")).IsFalse()
+			g.Assert(DetectDoubleEncoding("Hello, World")).IsFalse()
+			g.Assert(DetectDoubleEncoding("")).IsFalse()
+			g.Assert(DetectDoubleEncoding("Test ★")).IsFalse()
+		})
+		g.It("check FixDoubleEncoding()", func() {
+			double := DecodeCharmap("Привет, мир! Тест 123", "CP866")
+			fixed, ok := FixDoubleEncoding(double)
+			g.Assert(ok).IsTrue()
+			g.Assert(fixed).Equal("Привет, мир! Тест 123")
+			fixed, ok = FixDoubleEncoding("\x01CHRS: CP866 2\r╨в╨╡╤Б╤В\r")
+			g.Assert(ok).IsTrue()
+			g.Assert(fixed).Equal("\x01CHRS: CP866 2\rТест\r")
+			fixed, ok = FixDoubleEncoding("Тест")
+			g.Assert(ok).IsFalse()
+			g.Assert(fixed).Equal("Тест")
+		})
+	})
+}
+
+func TestDetectCharset(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check DetectCharset()", func() {
+		candidates := []string{"UTF-8", "CP866", "LATIN-1"}
+		g.It("check unlabeled cp866", func() {
+			s := EncodeCharmap("Привет всем! Как дела в эхе?", "CP866")
+			g.Assert(DetectCharset(s, candidates, "UTF-8")).Equal("CP866")
+			s = EncodeCharmap("ПРОВЕРКА СВЯЗИ", "CP866")
+			g.Assert(DetectCharset(s, candidates, "UTF-8")).Equal("CP866")
+		})
+		g.It("check unlabeled utf-8", func() {
+			g.Assert(DetectCharset("Привет всем! Как дела в эхе?", candidates, "CP866")).Equal("UTF-8")
+			g.Assert(DetectCharset("Grüße aus München", candidates, "CP866")).Equal("UTF-8")
+		})
+		g.It("check unlabeled latin-1", func() {
+			s := EncodeCharmap("Grüße aus München, café", "LATIN-1")
+			g.Assert(DetectCharset(s, candidates, "UTF-8")).Equal("LATIN-1")
+		})
+		g.It("check ascii gets the default", func() {
+			g.Assert(DetectCharset("Hello, All!", candidates, "CP866")).Equal("CP866")
+		})
+		g.It("check the candidate set", func() {
+			s := EncodeCharmap("Grüße aus München", "LATIN-1")
+			g.Assert(DetectCharset(s, []string{"UTF-8", "CP866"}, "UTF-8")).Equal("UTF-8")
+			s = EncodeCharmap("Привет всем", "CP866")
+			g.Assert(DetectCharset(s, []string{"cp866"}, "LATIN-1")).Equal("CP866")
+			g.Assert(DetectCharset("Привет всем", []string{"cp866"}, "LATIN-1")).Equal("UTF-8")
+		})
+	})
 }
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+)
+
+// ASCIIFolding makes searches fold only A-Z instead of all of Unicode, set
+// by the case_folding config option
+var ASCIIFolding bool
+
+// FoldCase returns s case folded for caseless matching: with Unicode full
+// case folding "Straße" and "STRASSE" fold to the same text
+func FoldCase(s string) string {
+	if ASCIIFolding {
+		return strings.Map(asciiLower, s)
+	}
+	// a Caser keeps state, so every call gets its own
+	return cases.Fold().String(s)
+}
+
+// ContainsFold reports whether substr is within s, ignoring case
+func ContainsFold(s, substr string) bool {
+	return strings.Contains(FoldCase(s), FoldCase(substr))
+}
+
+// FoldRunes case folds line rune by rune, returning the folded runes and
+// for each of them the index in line of the rune it was folded from, so
+// matches in the folded text map back to line
+func FoldRunes(line []rune) ([]rune, []int) {
+	folded := make([]rune, 0, len(line))
+	origin := make([]int, 0, len(line))
+	caser := cases.Fold()
+	for i, r := range line {
+		if ASCIIFolding || r < 0x80 {
+			folded = append(folded, asciiLower(r))
+			origin = append(origin, i)
+			continue
+		}
+		for _, f := range caser.String(string(r)) {
+			folded = append(folded, f)
+			origin = append(origin, i)
+		}
+	}
+	return folded, origin
+}
+
+func asciiLower(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r + 'a' - 'A'
+	}
+	return r
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"testing"
+
+	. "github.com/franela/goblin"
+)
+
+func TestFoldCase(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check case folding", func() {
+		g.After(func() {
+			ASCIIFolding = false
+		})
+		g.It("check Unicode folding", func() {
+			g.Assert(FoldCase("Straße")).Equal(FoldCase("STRASSE"))
+			g.Assert(FoldCase("ΟΔΥΣΣΕΥΣ")).Equal(FoldCase("οδυσσευς"))
+			g.Assert(ContainsFold("Привет, Мир", "МИР")).IsTrue()
+			g.Assert(ContainsFold("Привет, Мир", "мор")).IsFalse()
+		})
+		g.It("check FoldRunes() maps folded runes back", func() {
+			folded, origin := FoldRunes([]rune("Aß"))
+			g.Assert(string(folded)).Equal("ass")
+			g.Assert(origin).Equal([]int{0, 1, 1})
+		})
+		g.It("check ASCII folding", func() {
+			ASCIIFolding = true
+			g.Assert(ContainsFold("Hello World", "WORLD")).IsTrue()
+			g.Assert(ContainsFold("Привет, Мир", "МИР")).IsFalse()
+			g.Assert(ContainsFold("Straße", "STRASSE")).IsFalse()
+		})
+	})
+}
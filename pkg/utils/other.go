@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"fmt"
 	"os"
+	"strings"
+	"unicode/utf8"
 )
 
 // FileExists Check file exists
@@ -12,3 +15,103 @@ func FileExists(filename string) bool {
 	}
 	return !info.IsDir()
 }
+
+// RawView makes control characters of stored message text visible: ^A as
+// '@', line endings as \r, \n or \r\n markers and other controls in caret
+// notation
+func RawView(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\x01':
+			sb.WriteByte('@')
+		case c == '\r' && i+1 < len(s) && s[i+1] == '\n':
+			sb.WriteString("\\r\\n\n")
+			i++
+		case c == '\r':
+			sb.WriteString("\\r\n")
+		case c == '\n':
+			sb.WriteString("\\n\n")
+		case c == '\t':
+			sb.WriteByte(c)
+		case c < 0x20:
+			sb.WriteString("^" + string(rune(c+'@')))
+		case c == 0x7f:
+			sb.WriteString("^?")
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// StripControls removes control characters from a line of message text
+// for display, ANSI escape sequences as a whole, leaving tabs and line
+// endings. With show set they are shown in caret notation like RawView
+// instead, C1 controls as <XX>.
+func StripControls(s string, show bool) string {
+	if strings.IndexFunc(s, isDisplayControl) == -1 {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !isDisplayControl(r) {
+			sb.WriteString(s[i : i+size])
+			i += size
+			continue
+		}
+		if show {
+			switch {
+			case r == 0x7f:
+				sb.WriteString("^?")
+			case r < 0x20:
+				sb.WriteString("^" + string(r+'@'))
+			default:
+				sb.WriteString(fmt.Sprintf("<%02X>", r))
+			}
+			i += size
+			continue
+		}
+		i += size
+		if r == 0x1b {
+			i += escapeLen(s[i:])
+		}
+	}
+	return sb.String()
+}
+
+// isDisplayControl returns true for the C0 and C1 controls and DEL which
+// can upset the terminal, tabs and line endings are left to the view
+func isDisplayControl(r rune) bool {
+	return (r < 0x20 && r != '\t' && r != '\r' && r != '\n') || (r >= 0x7f && r < 0xa0)
+}
+
+// escapeLen returns the length of the rest of an ANSI escape sequence after
+// its ESC: the parameters and final byte of a CSI sequence like ESC [ 1 ; 31 m,
+// or the intermediate and final bytes of a short one like ESC c or ESC ( B
+func escapeLen(s string) int {
+	if s == "" {
+		return 0
+	}
+	if s[0] != '[' {
+		i := 0
+		for i < len(s) && s[i] >= 0x20 && s[i] <= 0x2f {
+			i++
+		}
+		if i < len(s) && s[i] >= 0x30 && s[i] <= 0x7e {
+			return i + 1
+		}
+		return i
+	}
+	for i := 1; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+		if s[i] < 0x20 || s[i] > 0x3f {
+			// not a parameter or intermediate byte, a broken sequence
+			return i
+		}
+	}
+	return len(s)
+}
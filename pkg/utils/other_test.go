@@ -17,3 +17,37 @@ func TestFileExists(t *testing.T) {
 		})
 	})
 }
+
+func TestRawView(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check RawView()", func() {
+		g.It("Check kludges and line endings", func() {
+			g.Assert(RawView("\x01MSGID: 2:5020/1 1\rHello\nWorld\r\n")).
+				Equal("@MSGID: 2:5020/1 1\\r\nHello\\n\nWorld\\r\\n\n")
+		})
+		g.It("Check other control characters", func() {
+			g.Assert(RawView("a\tb\x00c\x1bd\x7f")).Equal("a\tb^@c^[d^?")
+		})
+	})
+}
+
+func TestStripControls(t *testing.T) {
+	g := Goblin(t)
+	g.Describe("Check StripControls()", func() {
+		g.It("Check plain text is left alone", func() {
+			g.Assert(StripControls("Привет\tall > quoted", false)).Equal("Привет\tall > quoted")
+		})
+		g.It("Check ANSI sequences are stripped whole", func() {
+			g.Assert(StripControls("\x1b[1;31mred\x1b[0m text\x1b[2J", false)).Equal("red text")
+			g.Assert(StripControls("reset\x1bc here", false)).Equal("reset here")
+			g.Assert(StripControls("\x1b(Bcharset", false)).Equal("charset")
+			g.Assert(StripControls("cut \x1b[12", false)).Equal("cut ")
+		})
+		g.It("Check other controls are stripped", func() {
+			g.Assert(StripControls("bell\x07 form\x0cfeed\x7f \u0085next", false)).Equal("bell formfeed next")
+		})
+		g.It("Check show mode uses caret notation", func() {
+			g.Assert(StripControls("\x1b[31mred\x07\x7f\u0085", true)).Equal("^[[31mred^G^?<85>")
+		})
+	})
+}